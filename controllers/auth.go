@@ -11,6 +11,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -58,6 +59,9 @@ func RegisterUser(c *gin.Context) {
 		return
 	}
 
+	// Companies are joined by creating them or accepting an invitation
+	user.CompanyIDs = nil
+
 	user.Password = string(hashedPassword)
 	user.CreatedAt = time.Now()
 	user.UpdatedAt = time.Now()
@@ -73,7 +77,7 @@ func RegisterUser(c *gin.Context) {
 
 // Login godoc
 // @Summary Login user and return JWT token
-// @Description Authenticates the user and returns a JWT token if credentials are valid
+// @Description Authenticates the user and returns a JWT token if credentials are valid. Users of a single company get a token for it straight away; others choose one with /auth/switch-company.
 // @Tags Auth
 // @Accept json
 // @Produce json
@@ -106,18 +110,86 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	expirationTime := time.Now().Add(24 * time.Hour).Unix()
+	// Users of a single company start out in it
+	var companyID, role string
+	if len(user.CompanyIDs) == 1 {
+		var company models.Company
+		err := config.DB.Collection("companies").FindOne(context.Background(), activeCompanyFilter(user.CompanyIDs[0])).Decode(&company)
+		if err == nil {
+			companyID, role = company.ID.Hex(), companyRole(user, company)
+		}
+	}
 
-	claims := jwt.MapClaims{
-		"user_id": user.ID.Hex(),
-		"email":   user.Email,
-		"exp":     expirationTime,
+	tokenString, err := issueToken(user, companyID, role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error while generating token"})
+		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	c.JSON(http.StatusOK, gin.H{
+		"token":       tokenString,
+		"user_id":     user.ID.Hex(),
+		"email":       user.Email,
+		"company_id":  companyID,
+		"role":        role,
+		"company_ids": user.CompanyIDs,
+		"expires_in":  int(TokenLifetime / time.Second),
+	})
+}
+
+// SwitchCompany godoc
+// @Summary Switch the active company
+// @Description Issues a new token for the given company, which the user must own or work for. Company-scoped endpoints then act for that company and refuse requests naming another one.
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param company body models.SwitchCompanyRequest true "Company to switch to"
+// @Success 200 {object} models.TokenResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "Not a member of the company"
+// @Failure 404 {object} models.ErrorResponse "Company not found"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /auth/switch-company [post]
+// @Security BearerAuth
+func SwitchCompany(c *gin.Context) {
+	var req models.SwitchCompanyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	companyID, err := primitive.ObjectIDFromHex(req.CompanyID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication token is required."})
+		return
+	}
 
-	JWT_SECRET := os.Getenv("JWT_SECRET")
-	tokenString, err := token.SignedString([]byte(JWT_SECRET))
+	var user models.User
+	if err := config.DB.Collection("users").FindOne(context.Background(), bson.M{"_id": userID}).Decode(&user); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+	var company models.Company
+	if err := config.DB.Collection("companies").FindOne(context.Background(), activeCompanyFilter(companyID)).Decode(&company); err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	role := companyRole(user, company)
+	if role == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You do not belong to this company"})
+		return
+	}
+
+	tokenString, err := issueToken(user, company.ID.Hex(), role)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error while generating token"})
 		return
@@ -127,6 +199,28 @@ func Login(c *gin.Context) {
 		"token":      tokenString,
 		"user_id":    user.ID.Hex(),
 		"email":      user.Email,
-		"expires_in": 24 * 60 * 60,
+		"company_id": company.ID.Hex(),
+		"role":       role,
+		"expires_in": int(TokenLifetime / time.Second),
 	})
 }
+
+// TokenLifetime is how long a login or company switch token is valid.
+const TokenLifetime = 24 * time.Hour
+
+// issueToken signs a token for the user, scoped to the company when one is
+// given.
+func issueToken(user models.User, companyID, role string) (string, error) {
+	claims := jwt.MapClaims{
+		"user_id": user.ID.Hex(),
+		"email":   user.Email,
+		"exp":     time.Now().Add(TokenLifetime).Unix(),
+	}
+	if companyID != "" {
+		claims["company_id"] = companyID
+		claims["role"] = role
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+}
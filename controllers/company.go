@@ -3,6 +3,7 @@ package controllers
 import (
 	"context"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/bisre1921/billing-and-invoice-system/config"
@@ -11,6 +12,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // CreateCompany godoc
@@ -32,6 +34,11 @@ func CreateCompany(c *gin.Context) {
 		return
 	}
 
+	if err := validateCompanySettings(company.Settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	idFromToken, ok := c.Get("userID")
 	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication token is required."})
@@ -39,6 +46,7 @@ func CreateCompany(c *gin.Context) {
 		return
 	}
 
+	company.DeletedAt = nil
 	company.CreatedAt = time.Now()
 	company.UpdatedAt = time.Now()
 	company.Owner = idFromToken.(string)
@@ -48,13 +56,16 @@ func CreateCompany(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+	if ownerID, err := primitive.ObjectIDFromHex(company.Owner); err == nil {
+		addUserCompany(bson.M{"_id": ownerID}, result.InsertedID.(primitive.ObjectID))
+	}
 
 	c.JSON(http.StatusCreated, gin.H{"message": "Company created successfully", "company_id": result.InsertedID})
 }
 
 // GetCompany godoc
 // @Summary Get company by ID
-// @Description Retrieves a single company by its ID if owned by the authenticated user
+// @Description Retrieves a single company by its ID. Deleted companies are not found.
 // @Tags Company
 // @Accept json
 // @Produce json
@@ -74,7 +85,7 @@ func GetCompany(c *gin.Context) {
 	}
 
 	var company models.Company
-	err = config.DB.Collection("companies").FindOne(context.Background(), bson.M{"_id": companyId}).Decode(&company)
+	err = config.DB.Collection("companies").FindOne(context.Background(), activeCompanyFilter(companyId)).Decode(&company)
 
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
@@ -90,7 +101,7 @@ func GetCompany(c *gin.Context) {
 
 // CheckCompanyForUser godoc
 // @Summary Check if a user has a company
-// @Description Checks if a company exists with the given user ID as the owner
+// @Description Checks if a company exists with the given user ID as the owner. Use GET /company to list all of them.
 // @Tags Company
 // @Accept json
 // @Produce json
@@ -110,7 +121,7 @@ func CheckCompanyForUser(c *gin.Context) {
 	}
 
 	var company models.Company
-	err := config.DB.Collection("companies").FindOne(context.Background(), bson.M{"owner": userID}).Decode(&company)
+	err := config.DB.Collection("companies").FindOne(context.Background(), bson.M{"owner": userID, "deleted_at": bson.M{"$exists": false}}).Decode(&company)
 
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
@@ -123,3 +134,143 @@ func CheckCompanyForUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"company_id": company.ID.Hex()})
 }
+
+// ListCompanies godoc
+// @Summary List the user's companies
+// @Description Lists the companies the authenticated user owns or works for as an employee, oldest first. Deleted companies are left out.
+// @Tags Company
+// @Produce json
+// @Success 200 {array} models.UserCompany
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal Server Error"
+// @Router /company [get]
+// @Security BearerAuth
+func ListCompanies(c *gin.Context) {
+	userID, _ := c.Get("userID")
+	owner, ok := userID.(string)
+	if !ok || owner == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication token is required."})
+		return
+	}
+
+	var user models.User
+	if userObjID, err := primitive.ObjectIDFromHex(owner); err == nil {
+		err = config.DB.Collection("users").FindOne(context.Background(), bson.M{"_id": userObjID}).Decode(&user)
+		if err != nil && err != mongo.ErrNoDocuments {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	memberOf := user.CompanyIDs
+	if memberOf == nil {
+		memberOf = []primitive.ObjectID{}
+	}
+
+	filter := bson.M{
+		"$or":        bson.A{bson.M{"owner": owner}, bson.M{"_id": bson.M{"$in": memberOf}}},
+		"deleted_at": bson.M{"$exists": false},
+	}
+	cursor, err := config.DB.Collection("companies").Find(context.Background(), filter, options.Find().SetSort(bson.M{"created_at": 1}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	companies := []models.UserCompany{}
+	if err := cursor.All(context.Background(), &companies); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range companies {
+		companies[i].Role = "employee"
+		if companies[i].Owner == owner {
+			companies[i].Role = "owner"
+		}
+	}
+
+	c.JSON(http.StatusOK, companies)
+}
+
+// UpdateCompany godoc
+// @Summary Update a company
+// @Description Changes the name, email, address, phone or TIN of a company. Only the fields sent are changed. Only the owner can update a company.
+// @Tags Company
+// @Accept json
+// @Produce json
+// @Param id path string true "Company ID"
+// @Param company body models.UpdateCompanyInput true "Fields to change"
+// @Success 200 {object} models.Company
+// @Failure 400 {object} map[string]interface{} "Bad Request"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Company not found"
+// @Failure 500 {object} map[string]interface{} "Internal Server Error"
+// @Router /company/{id} [put]
+// @Security BearerAuth
+func UpdateCompany(c *gin.Context) {
+	var input models.UpdateCompanyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if input.Name != nil && strings.TrimSpace(*input.Name) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Company name cannot be empty"})
+		return
+	}
+
+	company, ok := fetchOwnedCompany(c)
+	if !ok {
+		return
+	}
+
+	update := bson.M{"updated_at": time.Now()}
+	for field, value := range map[string]*string{
+		"name": input.Name, "email": input.Email, "address": input.Address, "phone": input.Phone, "tin": input.TIN,
+	} {
+		if value != nil {
+			update[field] = strings.TrimSpace(*value)
+		}
+	}
+	err := config.DB.Collection("companies").FindOneAndUpdate(context.Background(),
+		bson.M{"_id": company.ID},
+		bson.M{"$set": update},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&company)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, company)
+}
+
+// DeleteCompany godoc
+// @Summary Delete a company
+// @Description Soft-deletes a company. Its invoices, customers and items are kept, but the company no longer appears in lookups and cannot issue new invoices. Only the owner can delete a company.
+// @Tags Company
+// @Produce json
+// @Param id path string true "Company ID"
+// @Success 200 {object} map[string]interface{} "Company deleted successfully"
+// @Failure 400 {object} map[string]interface{} "Bad Request"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Company not found"
+// @Failure 500 {object} map[string]interface{} "Internal Server Error"
+// @Router /company/{id} [delete]
+// @Security BearerAuth
+func DeleteCompany(c *gin.Context) {
+	company, ok := fetchOwnedCompany(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	_, err := config.DB.Collection("companies").UpdateOne(context.Background(),
+		bson.M{"_id": company.ID},
+		bson.M{"$set": bson.M{"deleted_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	invalidateDashboardCache(company.ID.Hex())
+
+	c.JSON(http.StatusOK, gin.H{"message": "Company deleted successfully"})
+}
@@ -0,0 +1,217 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MaxLogoSize is the largest logo upload accepted, in bytes.
+const MaxLogoSize = 1 << 20
+
+// fetchOwnedCompany loads the company in the id path parameter, unless it was
+// deleted, and checks the authenticated user owns it, writing the error response itself otherwise.
+func fetchOwnedCompany(c *gin.Context) (models.Company, bool) {
+	var company models.Company
+	companyID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return company, false
+	}
+
+	err = config.DB.Collection("companies").FindOne(context.Background(), activeCompanyFilter(companyID)).Decode(&company)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return company, false
+	}
+
+	userID, _ := c.Get("userID")
+	if id, ok := userID.(string); !ok || id != company.Owner {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the company owner can change company settings"})
+		return company, false
+	}
+	return company, true
+}
+
+// UpdateCompanyBranding godoc
+// @Summary Update company branding
+// @Description Sets the accent colour, default invoice layout, font, footer text, payment instructions and bank accounts printed on invoices and receipts
+// @Tags Company
+// @Accept json
+// @Produce json
+// @Param id path string true "Company ID"
+// @Param branding body models.CompanyBranding true "Branding settings"
+// @Success 200 {object} models.CompanyBranding
+// @Failure 400 {object} map[string]interface{} "Bad Request"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Company not found"
+// @Failure 500 {object} map[string]interface{} "Internal Server Error"
+// @Router /company/{id}/branding [put]
+// @Security BearerAuth
+func UpdateCompanyBranding(c *gin.Context) {
+	var branding models.CompanyBranding
+	if err := c.ShouldBindJSON(&branding); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if branding.AccentColor != "" {
+		if _, ok := parseAccentColor(branding.AccentColor); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "accent_color must be a hex colour like #1A73E8"})
+			return
+		}
+	}
+	if branding.InvoiceLayout != "" {
+		if _, ok := findInvoiceLayout(branding.InvoiceLayout); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown invoice_layout: " + branding.InvoiceLayout})
+			return
+		}
+	}
+	if branding.Font != "" {
+		if _, ok := findPDFFont(branding.Font); !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown font: " + branding.Font})
+			return
+		}
+	}
+
+	company, ok := fetchOwnedCompany(c)
+	if !ok {
+		return
+	}
+
+	_, err := config.DB.Collection("companies").UpdateOne(context.Background(),
+		bson.M{"_id": company.ID},
+		bson.M{"$set": bson.M{"branding": branding, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, branding)
+}
+
+// UploadCompanyLogo godoc
+// @Summary Upload company logo
+// @Description Uploads a PNG or JPEG logo (max 1 MB) shown on invoices and receipts
+// @Tags Company
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Company ID"
+// @Param logo formData file true "Logo image"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{} "Bad Request"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Company not found"
+// @Failure 500 {object} map[string]interface{} "Internal Server Error"
+// @Router /company/{id}/logo [post]
+// @Security BearerAuth
+func UploadCompanyLogo(c *gin.Context) {
+	file, header, err := c.Request.FormFile("logo")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Logo file is required"})
+		return
+	}
+	defer file.Close()
+	if header.Size > MaxLogoSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Logo must be at most 1 MB"})
+		return
+	}
+	data, err := io.ReadAll(io.LimitReader(file, MaxLogoSize+1))
+	if err != nil || len(data) > MaxLogoSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Logo must be at most 1 MB"})
+		return
+	}
+
+	contentType := http.DetectContentType(data)
+	if logoImageType(contentType) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Logo must be a PNG or JPEG image"})
+		return
+	}
+	if _, _, err := image.DecodeConfig(bytes.NewReader(data)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Logo image could not be read"})
+		return
+	}
+
+	company, ok := fetchOwnedCompany(c)
+	if !ok {
+		return
+	}
+
+	_, err = config.DB.Collection("companies").UpdateOne(context.Background(),
+		bson.M{"_id": company.ID},
+		bson.M{"$set": bson.M{"logo": data, "logo_content_type": contentType, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logo uploaded successfully", "content_type": contentType, "size": len(data)})
+}
+
+// GetCompanyLogo godoc
+// @Summary Get company logo
+// @Tags Company
+// @Produce png
+// @Produce jpeg
+// @Param id path string true "Company ID"
+// @Success 200 {file} binary
+// @Failure 400 {object} map[string]interface{} "Bad Request"
+// @Failure 404 {object} map[string]interface{} "Logo not found"
+// @Router /company/{id}/logo [get]
+// @Security BearerAuth
+func GetCompanyLogo(c *gin.Context) {
+	companyID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return
+	}
+	company, err := fetchCompanyByID(companyID)
+	if err != nil || len(company.Logo) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Logo not found"})
+		return
+	}
+	c.Data(http.StatusOK, company.LogoContentType, company.Logo)
+}
+
+// DeleteCompanyLogo godoc
+// @Summary Remove company logo
+// @Tags Company
+// @Produce json
+// @Param id path string true "Company ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{} "Bad Request"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Company not found"
+// @Failure 500 {object} map[string]interface{} "Internal Server Error"
+// @Router /company/{id}/logo [delete]
+// @Security BearerAuth
+func DeleteCompanyLogo(c *gin.Context) {
+	company, ok := fetchOwnedCompany(c)
+	if !ok {
+		return
+	}
+	_, err := config.DB.Collection("companies").UpdateOne(context.Background(),
+		bson.M{"_id": company.ID},
+		bson.M{"$unset": bson.M{"logo": "", "logo_content_type": ""}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logo removed successfully"})
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// scopedCompanyID returns the company a request acts for: the active company
+// of the token. Requests without one, and requests naming any other company,
+// are refused.
+func scopedCompanyID(c *gin.Context, requested string) (string, bool) {
+	active := c.GetString("companyID")
+	if active == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in to a company first"})
+		return "", false
+	}
+	if requested != "" && requested != active {
+		c.JSON(http.StatusForbidden, gin.H{"error": "company_id does not match the active company of the token"})
+		return "", false
+	}
+	return active, true
+}
+
+// requiredCompanyID is scopedCompanyID for requests that must act for some
+// company.
+func requiredCompanyID(c *gin.Context, requested string) (string, bool) {
+	return scopedCompanyID(c, requested)
+}
+
+// scopedCompanyObjectID is scopedCompanyID for records that store the company
+// as an ObjectID. A zero ID means none was requested.
+func scopedCompanyObjectID(c *gin.Context, requested primitive.ObjectID) (primitive.ObjectID, bool) {
+	var hex string
+	if !requested.IsZero() {
+		hex = requested.Hex()
+	}
+	scoped, ok := scopedCompanyID(c, hex)
+	if !ok {
+		return primitive.NilObjectID, false
+	}
+	companyID, err := primitive.ObjectIDFromHex(scoped)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return primitive.NilObjectID, false
+	}
+	return companyID, true
+}
+
+// requireOwner lets only the owner of the token's active company through,
+// writing the error response itself otherwise. It returns the owner's user ID.
+func requireOwner(c *gin.Context, action string) (string, bool) {
+	if c.GetString("companyID") == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in to a company to " + action})
+		return "", false
+	}
+	if c.GetString("role") != "owner" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the company owner can " + action})
+		return "", false
+	}
+	return c.GetString("userID"), true
+}
+
+// ownerCompanyObjectID is requireOwner for handlers that act on the owner's
+// company, returning that company's ID.
+func ownerCompanyObjectID(c *gin.Context, action string) (primitive.ObjectID, bool) {
+	if _, ok := requireOwner(c, action); !ok {
+		return primitive.NilObjectID, false
+	}
+	companyID, err := primitive.ObjectIDFromHex(c.GetString("companyID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return primitive.NilObjectID, false
+	}
+	return companyID, true
+}
+
+// activeCompanyID is scopedCompanyID with an error message naming what the
+// caller tried to do.
+func activeCompanyID(c *gin.Context, requested, action string) (string, bool) {
+	if c.GetString("companyID") == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in to a company to " + action})
+		return "", false
+	}
+	return scopedCompanyID(c, requested)
+}
+
+// scopeToActiveCompany limits a filter on records that store their company as
+// an ObjectID to the token's active company.
+func scopeToActiveCompany(c *gin.Context, filter bson.M) bool {
+	active, ok := scopedCompanyID(c, "")
+	if !ok {
+		return false
+	}
+	companyID, err := primitive.ObjectIDFromHex(active)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		return false
+	}
+	filter["company_id"] = companyID
+	return true
+}
+
+// companyRole is the user's role in the company: owner, employee, or "" when
+// they do not belong to it.
+func companyRole(user models.User, company models.Company) string {
+	if company.Owner == user.ID.Hex() {
+		return "owner"
+	}
+	for _, id := range user.CompanyIDs {
+		if id == company.ID {
+			return "employee"
+		}
+	}
+	return ""
+}
+
+// addUserCompany records that the user with the filter belongs to the company.
+// Failures are logged; the user can still be added again later.
+func addUserCompany(filter bson.M, companyID primitive.ObjectID) {
+	_, err := config.DB.Collection("users").UpdateOne(context.Background(), filter,
+		bson.M{"$addToSet": bson.M{"company_ids": companyID}})
+	if err != nil {
+		fmt.Println("Error adding company to user", err)
+	}
+}
+
+func removeUserCompany(filter bson.M, companyID primitive.ObjectID) {
+	_, err := config.DB.Collection("users").UpdateOne(context.Background(), filter,
+		bson.M{"$pull": bson.M{"company_ids": companyID}})
+	if err != nil {
+		fmt.Println("Error removing company from user", err)
+	}
+}
@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"time"
+	_ "time/tzdata" // timezone names work without the host's zoneinfo
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultPaymentTermsDays is how long customers have to pay a credit invoice
+// when neither the invoice nor the company settings say otherwise.
+const DefaultPaymentTermsDays = 7
+
+var (
+	currencyCodePattern  = regexp.MustCompile(`^[A-Z]{3}$`)
+	invoicePrefixPattern = regexp.MustCompile(`^[A-Za-z0-9/_-]{1,10}$`)
+)
+
+// activeCompanyFilter matches the company with the id unless it was deleted.
+func activeCompanyFilter(companyID primitive.ObjectID) bson.M {
+	return bson.M{"_id": companyID, "deleted_at": bson.M{"$exists": false}}
+}
+
+// validateCompanySettings checks the values binding tags cannot.
+func validateCompanySettings(settings models.CompanySettings) error {
+	if settings.BaseCurrency != "" && !currencyCodePattern.MatchString(settings.BaseCurrency) {
+		return fmt.Errorf("base_currency must be an ISO 4217 code like ETB")
+	}
+	if settings.Timezone != "" {
+		if _, err := time.LoadLocation(settings.Timezone); err != nil {
+			return fmt.Errorf("Unknown timezone: %s", settings.Timezone)
+		}
+	}
+	if settings.InvoicePrefix != "" && !invoicePrefixPattern.MatchString(settings.InvoicePrefix) {
+		return fmt.Errorf("invoice_prefix must be at most 10 letters, digits, '-', '_' or '/'")
+	}
+	if m := settings.Mail; m != nil {
+		if m.Transport != "" && m.Transport != "smtp" && m.Transport != "file" {
+			return fmt.Errorf("mail transport must be smtp or file")
+		}
+		if m.From != "" {
+			if _, err := mail.ParseAddress(m.From); err != nil {
+				return fmt.Errorf("mail from must be an email address")
+			}
+		}
+	}
+	return nil
+}
+
+// companyCurrency is the currency the company invoices in.
+func companyCurrency(settings models.CompanySettings) string {
+	if settings.BaseCurrency != "" {
+		return settings.BaseCurrency
+	}
+	return invoiceCurrency()
+}
+
+// companyLocation is the timezone invoice dates and report periods are in.
+func companyLocation(settings models.CompanySettings) *time.Location {
+	if settings.Timezone != "" {
+		if loc, err := time.LoadLocation(settings.Timezone); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// fiscalYearStart returns the start of the fiscal year t falls in.
+func fiscalYearStart(settings models.CompanySettings, t time.Time) time.Time {
+	month := time.Month(settings.FiscalYearStart)
+	if month < time.January || month > time.December {
+		month = time.January
+	}
+	start := time.Date(t.Year(), month, 1, 0, 0, 0, 0, t.Location())
+	if t.Before(start) {
+		start = start.AddDate(-1, 0, 0)
+	}
+	return start
+}
+
+// formatMoney prints an amount the way invoices show it: "$12.50" for US
+// dollars, "ETB 12.50" for any other currency.
+func formatMoney(currency string, amount float64) string {
+	if currency == "USD" {
+		return fmt.Sprintf("$%.2f", amount)
+	}
+	return fmt.Sprintf("%s %.2f", currency, amount)
+}
+
+// fetchCompanySettings returns the settings of a company given as a hex ID,
+// or the zero settings when the company cannot be loaded.
+func fetchCompanySettings(companyID string) models.CompanySettings {
+	objID, err := primitive.ObjectIDFromHex(companyID)
+	if err != nil {
+		return models.CompanySettings{}
+	}
+	company, err := fetchCompanyByID(objID)
+	if err != nil {
+		fmt.Println("Error fetching company settings", err)
+	}
+	return company.Settings
+}
+
+// UpdateCompanySettings godoc
+// @Summary Update company settings
+// @Description Sets the base currency, timezone, fiscal year start month, default payment terms, invoice number prefix, whether customer credit pays new invoices automatically and how the company's mail is sent, used when the company invoices and reports. Only the settings sent are changed. Mail settings left empty fall back to the server's; the mail password is stored encrypted and never returned. The TIN is updated with PUT /company/{id}, bank details with the branding and the logo with /company/{id}/logo.
+// @Tags Company
+// @Accept json
+// @Produce json
+// @Param id path string true "Company ID"
+// @Param settings body models.UpdateCompanySettingsInput true "Settings to change"
+// @Success 200 {object} models.CompanySettings
+// @Failure 400 {object} map[string]interface{} "Bad Request"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Company not found"
+// @Failure 500 {object} map[string]interface{} "Internal Server Error"
+// @Router /company/{id}/settings [put]
+// @Security BearerAuth
+func UpdateCompanySettings(c *gin.Context) {
+	var input models.UpdateCompanySettingsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var sent models.CompanySettings
+	update := applyCompanySettings(&sent, input)
+	if err := validateCompanySettings(sent); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	company, ok := fetchOwnedCompany(c)
+	if !ok {
+		return
+	}
+	applyCompanySettings(&company.Settings, input)
+
+	// The SMTP password is stored encrypted like signing keys
+	if input.Mail != nil && input.Mail.Password != nil {
+		var sealed []byte
+		if *input.Mail.Password != "" {
+			var err error
+			sealed, err = sealSecret([]byte(*input.Mail.Password))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		company.Settings.Mail.Password = sealed
+		update["settings.mail.password"] = sealed
+	}
+
+	// Each setting is set on its own so the ones not sent are kept
+	update["updated_at"] = time.Now()
+	_, err := config.DB.Collection("companies").UpdateOne(context.Background(),
+		bson.M{"_id": company.ID},
+		bson.M{"$set": update},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, company.Settings)
+}
+
+// applyCompanySettings copies the settings sent in the input onto settings and
+// returns them as a $set of the company's settings fields.
+func applyCompanySettings(settings *models.CompanySettings, input models.UpdateCompanySettingsInput) bson.M {
+	update := bson.M{}
+	if input.BaseCurrency != nil {
+		settings.BaseCurrency = *input.BaseCurrency
+		update["settings.base_currency"] = settings.BaseCurrency
+	}
+	if input.Timezone != nil {
+		settings.Timezone = *input.Timezone
+		update["settings.timezone"] = settings.Timezone
+	}
+	if input.FiscalYearStart != nil {
+		settings.FiscalYearStart = *input.FiscalYearStart
+		update["settings.fiscal_year_start"] = settings.FiscalYearStart
+	}
+	if input.DefaultPaymentTermsDays != nil {
+		settings.DefaultPaymentTermsDays = *input.DefaultPaymentTermsDays
+		update["settings.default_payment_terms_days"] = settings.DefaultPaymentTermsDays
+	}
+	if input.InvoicePrefix != nil {
+		settings.InvoicePrefix = *input.InvoicePrefix
+		update["settings.invoice_prefix"] = settings.InvoicePrefix
+	}
+	if input.AutoApplyCredits != nil {
+		settings.AutoApplyCredits = *input.AutoApplyCredits
+		update["settings.auto_apply_credits"] = settings.AutoApplyCredits
+	}
+	if input.CreditHoldOverdueDays != nil {
+		settings.CreditHoldOverdueDays = *input.CreditHoldOverdueDays
+		update["settings.credit_hold_overdue_days"] = settings.CreditHoldOverdueDays
+	}
+	if input.CreditLimitApprovalThreshold != nil {
+		settings.CreditLimitApprovalThreshold = *input.CreditLimitApprovalThreshold
+		update["settings.credit_limit_approval_threshold"] = settings.CreditLimitApprovalThreshold
+	}
+	if input.Mail != nil {
+		applyCompanyMailSettings(settings, *input.Mail, update)
+	}
+	return update
+}
+
+// applyCompanyMailSettings does the same for the mail settings, all but the
+// password, which has to be encrypted first.
+func applyCompanyMailSettings(settings *models.CompanySettings, input models.UpdateCompanyMailSettingsInput, update bson.M) {
+	if settings.Mail == nil {
+		settings.Mail = &models.CompanyMailSettings{}
+	}
+	m := settings.Mail
+	if input.Transport != nil {
+		m.Transport = *input.Transport
+		update["settings.mail.transport"] = m.Transport
+	}
+	if input.Host != nil {
+		m.Host = *input.Host
+		update["settings.mail.host"] = m.Host
+	}
+	if input.Port != nil {
+		m.Port = *input.Port
+		update["settings.mail.port"] = m.Port
+	}
+	if input.Username != nil {
+		m.Username = *input.Username
+		update["settings.mail.username"] = m.Username
+	}
+	if input.From != nil {
+		m.From = *input.From
+		update["settings.mail.from"] = m.From
+	}
+}
@@ -0,0 +1,369 @@
+package controllers
+
+import (
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// maxCertificateUpload bounds the certificate and key files.
+const maxCertificateUpload = 64 << 10
+
+// signingKeyCipher seals stored private keys and passwords with a key derived
+// from CERTIFICATE_KEY_SECRET.
+func signingKeyCipher() (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(os.Getenv("CERTIFICATE_KEY_SECRET")))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func sealSecret(plain []byte) ([]byte, error) {
+	gcm, err := signingKeyCipher()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+// openSecret decrypts what sealSecret sealed; what names the secret in errors.
+func openSecret(sealed []byte, what string) ([]byte, error) {
+	gcm, err := signingKeyCipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, fmt.Errorf("stored %s is corrupt", what)
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("stored %s cannot be decrypted, was CERTIFICATE_KEY_SECRET changed?", what)
+	}
+	return plain, nil
+}
+
+func openSigningKey(sealed []byte) (crypto.Signer, error) {
+	der, err := openSecret(sealed, "signing key")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+	return key.(crypto.Signer), nil
+}
+
+// parseSigningCertificate reads a PEM certificate chain and its private key and
+// checks they can sign documents now.
+func parseSigningCertificate(certPEM, keyPEM []byte, now time.Time) ([]*x509.Certificate, crypto.Signer, error) {
+	var chain []*x509.Certificate
+	for block, rest := pem.Decode(certPEM); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid certificate: %v", err)
+		}
+		chain = append(chain, cert)
+	}
+	if len(chain) == 0 {
+		return nil, nil, fmt.Errorf("certificate must be a PEM encoded X.509 certificate")
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, nil, fmt.Errorf("private key must be PEM encoded")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid private key: %v", err)
+	}
+	var signer crypto.Signer
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		signer = k
+	case *ecdsa.PrivateKey:
+		signer = k
+	default:
+		return nil, nil, fmt.Errorf("only RSA and ECDSA keys are supported")
+	}
+
+	leaf := chain[0]
+	if public, ok := leaf.PublicKey.(interface{ Equal(crypto.PublicKey) bool }); !ok || !public.Equal(signer.Public()) {
+		return nil, nil, fmt.Errorf("private key does not belong to the certificate")
+	}
+	if leaf.KeyUsage != 0 && leaf.KeyUsage&x509.KeyUsageDigitalSignature == 0 {
+		return nil, nil, fmt.Errorf("certificate is not allowed to make digital signatures")
+	}
+	if now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+		return nil, nil, fmt.Errorf("certificate is not valid at this time")
+	}
+	for i := 1; i < len(chain); i++ {
+		if err := chain[i-1].CheckSignatureFrom(chain[i]); err != nil {
+			return nil, nil, fmt.Errorf("certificate chain is out of order: %v", err)
+		}
+	}
+	return chain, signer, nil
+}
+
+// signCompanyPDF signs a document with the company's stored certificate.
+func signCompanyPDF(data []byte, company models.Company, now time.Time) ([]byte, error) {
+	stored := company.Signing
+	if stored == nil {
+		return nil, fmt.Errorf("company has no signing certificate")
+	}
+	if now.After(stored.NotAfter) {
+		return nil, fmt.Errorf("signing certificate expired on %s", stored.NotAfter.Format("2006-01-02"))
+	}
+	key, err := openSigningKey(stored.EncryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	chain := make([]*x509.Certificate, len(stored.Chain))
+	for i, der := range stored.Chain {
+		if chain[i], err = x509.ParseCertificate(der); err != nil {
+			return nil, err
+		}
+	}
+	return signPDF(data, pdfSignature{chain: chain, key: key, name: company.Name, reason: "Invoice issued by " + company.Name, time: now})
+}
+
+func readCertificateUpload(file *multipart.FileHeader) ([]byte, error) {
+	if file.Size > maxCertificateUpload {
+		return nil, fmt.Errorf("%s is too large", file.Filename)
+	}
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, maxCertificateUpload))
+}
+
+// UploadSigningCertificate godoc
+// @Summary Upload invoice signing certificate
+// @Description Stores the X.509 certificate (PEM, optionally followed by its intermediates) and private key (PEM, RSA or ECDSA) used to digitally sign the company's invoice PDFs. Replaces any existing certificate. The key is stored encrypted.
+// @Tags Company
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path string true "Company ID"
+// @Param certificate formData file true "PEM certificate chain"
+// @Param private_key formData file true "PEM private key"
+// @Param auto_sign formData boolean false "Sign every invoice PDF"
+// @Success 200 {object} models.SigningCertificate
+// @Failure 400 {object} map[string]interface{} "Bad Request"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Company not found"
+// @Failure 500 {object} map[string]interface{} "Internal Server Error"
+// @Router /company/{id}/signing-certificate [post]
+// @Security BearerAuth
+func UploadSigningCertificate(c *gin.Context) {
+	certFile, err := c.FormFile("certificate")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Certificate file is required"})
+		return
+	}
+	keyFile, err := c.FormFile("private_key")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Private key file is required"})
+		return
+	}
+	autoSign := false
+	if value := c.PostForm("auto_sign"); value != "" {
+		if autoSign, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "auto_sign must be true or false"})
+			return
+		}
+	}
+	certPEM, err := readCertificateUpload(certFile)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	keyPEM, err := readCertificateUpload(keyFile)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	chain, key, err := parseSigningCertificate(certPEM, keyPEM, now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	company, ok := fetchOwnedCompany(c)
+	if !ok {
+		return
+	}
+
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	sealed, err := sealSecret(keyDER)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	leaf := chain[0]
+	fingerprint := sha256.Sum256(leaf.Raw)
+	signing := models.SigningCertificate{
+		Subject:      leaf.Subject.String(),
+		Issuer:       leaf.Issuer.String(),
+		SerialNumber: leaf.SerialNumber.String(),
+		Fingerprint:  hex.EncodeToString(fingerprint[:]),
+		NotBefore:    leaf.NotBefore,
+		NotAfter:     leaf.NotAfter,
+		AutoSign:     autoSign,
+		UploadedAt:   now,
+		EncryptedKey: sealed,
+	}
+	for _, cert := range chain {
+		signing.Chain = append(signing.Chain, cert.Raw)
+	}
+
+	_, err = config.DB.Collection("companies").UpdateOne(context.Background(),
+		bson.M{"_id": company.ID},
+		bson.M{"$set": bson.M{"signing_certificate": signing, "updated_at": now}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, signing)
+}
+
+// GetSigningCertificate godoc
+// @Summary Get invoice signing certificate
+// @Description Details of the certificate the company signs invoice PDFs with. The private key is never returned.
+// @Tags Company
+// @Produce json
+// @Param id path string true "Company ID"
+// @Success 200 {object} models.SigningCertificate
+// @Failure 400 {object} map[string]interface{} "Bad Request"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "No signing certificate"
+// @Router /company/{id}/signing-certificate [get]
+// @Security BearerAuth
+func GetSigningCertificate(c *gin.Context) {
+	company, ok := fetchOwnedCompany(c)
+	if !ok {
+		return
+	}
+	if company.Signing == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No signing certificate uploaded"})
+		return
+	}
+	c.JSON(http.StatusOK, company.Signing)
+}
+
+// UpdateSigningCertificate godoc
+// @Summary Update invoice signing settings
+// @Description Turns automatic signing of every invoice PDF on or off
+// @Tags Company
+// @Accept json
+// @Produce json
+// @Param id path string true "Company ID"
+// @Param settings body models.UpdateSigningCertificateRequest true "Signing settings"
+// @Success 200 {object} models.SigningCertificate
+// @Failure 400 {object} map[string]interface{} "Bad Request"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "No signing certificate"
+// @Failure 500 {object} map[string]interface{} "Internal Server Error"
+// @Router /company/{id}/signing-certificate [put]
+// @Security BearerAuth
+func UpdateSigningCertificate(c *gin.Context) {
+	var req models.UpdateSigningCertificateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	company, ok := fetchOwnedCompany(c)
+	if !ok {
+		return
+	}
+	if company.Signing == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No signing certificate uploaded"})
+		return
+	}
+
+	_, err := config.DB.Collection("companies").UpdateOne(context.Background(),
+		bson.M{"_id": company.ID},
+		bson.M{"$set": bson.M{"signing_certificate.auto_sign": req.AutoSign, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	company.Signing.AutoSign = req.AutoSign
+	c.JSON(http.StatusOK, company.Signing)
+}
+
+// DeleteSigningCertificate godoc
+// @Summary Remove invoice signing certificate
+// @Description Deletes the certificate and private key. Invoice PDFs are no longer signed.
+// @Tags Company
+// @Produce json
+// @Param id path string true "Company ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{} "Bad Request"
+// @Failure 403 {object} map[string]interface{} "Forbidden"
+// @Failure 404 {object} map[string]interface{} "Company not found"
+// @Failure 500 {object} map[string]interface{} "Internal Server Error"
+// @Router /company/{id}/signing-certificate [delete]
+// @Security BearerAuth
+func DeleteSigningCertificate(c *gin.Context) {
+	company, ok := fetchOwnedCompany(c)
+	if !ok {
+		return
+	}
+	_, err := config.DB.Collection("companies").UpdateOne(context.Background(),
+		bson.M{"_id": company.ID},
+		bson.M{"$unset": bson.M{"signing_certificate": ""}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Signing certificate removed successfully"})
+}
@@ -0,0 +1,712 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreditHoldCheckInterval is how often customers are put on, or taken off,
+// credit hold for overdue invoices.
+var CreditHoldCheckInterval = time.Hour
+
+// recordCreditEvent adds an entry to the customer's credit control history.
+// Failures are logged; the change itself stands.
+func recordCreditEvent(event models.CustomerCreditEvent) {
+	event.CreatedAt = time.Now()
+	if _, err := config.DB.Collection("customer_credit_events").InsertOne(context.Background(), event); err != nil {
+		fmt.Println("Error recording credit event", err)
+	}
+}
+
+// companyOwnerEmail is where credit control notifications for the owner go:
+// the owner's account email, or the company's own when it cannot be found.
+func companyOwnerEmail(company models.Company) string {
+	if ownerID, err := primitive.ObjectIDFromHex(company.Owner); err == nil {
+		var owner models.User
+		if err := config.DB.Collection("users").FindOne(context.Background(), bson.M{"_id": ownerID}).Decode(&owner); err == nil && owner.Email != "" {
+			return owner.Email
+		}
+	}
+	return company.Email
+}
+
+// notifyCreditControl queues a notification email about a customer's credit.
+// Failures are logged; the change itself stands.
+func notifyCreditControl(company models.Company, to, subject, body, referenceID string) {
+	if to == "" {
+		return
+	}
+	_, err := enqueueMail(models.OutboxMessage{
+		CompanyID:   company.ID.Hex(),
+		Category:    "notification",
+		ReferenceID: referenceID,
+		FromName:    company.Name,
+		To:          []string{to},
+		Subject:     subject,
+		TextBody:    body,
+	})
+	if err != nil {
+		fmt.Println("Error queueing credit notification", err)
+	}
+}
+
+// placeCreditHold stops new credit invoices for the customer and reports
+// whether it did. A hold already in place is kept with its original reason.
+func placeCreditHold(companyID, customerID primitive.ObjectID, hold models.CustomerCreditHold) (bool, error) {
+	result, err := config.DB.Collection("customers").UpdateOne(context.Background(),
+		bson.M{"_id": customerID, "credit_hold": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"credit_hold": hold}},
+	)
+	if err != nil || result.ModifiedCount == 0 {
+		return false, err
+	}
+	recordCreditEvent(models.CustomerCreditEvent{
+		CompanyID:   companyID,
+		CustomerID:  customerID,
+		Type:        "hold_placed",
+		Description: hold.Reason,
+		UserID:      hold.PlacedBy,
+	})
+	return true, nil
+}
+
+func releaseCreditHold(companyID, customerID primitive.ObjectID, description, userID string) error {
+	_, err := config.DB.Collection("customers").UpdateOne(context.Background(),
+		bson.M{"_id": customerID},
+		bson.M{"$unset": bson.M{"credit_hold": ""}},
+	)
+	if err != nil {
+		return err
+	}
+	recordCreditEvent(models.CustomerCreditEvent{
+		CompanyID:   companyID,
+		CustomerID:  customerID,
+		Type:        "hold_released",
+		Description: description,
+		UserID:      userID,
+	})
+	return nil
+}
+
+// overdueCutoff is the due date before which unpaid invoices put their
+// customer on credit hold, or the zero time when the company does not hold
+// customers automatically.
+func overdueCutoff(settings models.CompanySettings, now time.Time) time.Time {
+	if settings.CreditHoldOverdueDays == 0 {
+		return time.Time{}
+	}
+	return now.AddDate(0, 0, -settings.CreditHoldOverdueDays)
+}
+
+func overdueHold(settings models.CompanySettings, now time.Time) models.CustomerCreditHold {
+	return models.CustomerCreditHold{
+		Reason:   fmt.Sprintf("Invoices overdue by more than %d days", settings.CreditHoldOverdueDays),
+		Source:   "overdue",
+		PlacedAt: now,
+	}
+}
+
+// checkOverdueCreditHold puts the customer on credit hold when the company
+// holds customers automatically and one of their invoices is overdue beyond
+// its threshold.
+func checkOverdueCreditHold(company models.Company, customer *models.Customer, now time.Time) error {
+	cutoff := overdueCutoff(company.Settings, now)
+	if cutoff.IsZero() || customer.CreditHold != nil {
+		return nil
+	}
+	overdue, err := config.DB.Collection("invoices").CountDocuments(context.Background(), bson.M{
+		"customer_id": customer.ID.Hex(),
+		"status":      "Unpaid",
+		"due_date":    bson.M{"$lt": cutoff},
+	})
+	if err != nil || overdue == 0 {
+		return err
+	}
+	hold := overdueHold(company.Settings, now)
+	placed, err := placeCreditHold(company.ID, customer.ID, hold)
+	if err != nil {
+		return err
+	}
+	customer.CreditHold = &hold
+	if placed {
+		notifyCreditControl(company, companyOwnerEmail(company),
+			customer.Name+" was put on credit hold",
+			fmt.Sprintf("%s was put on credit hold: %s.\n\nNo credit invoices can be issued to them until the hold is released or a credit override is approved.", customer.Name, hold.Reason),
+			customer.ID.Hex())
+	}
+	return nil
+}
+
+// StartCreditHoldMonitor keeps overdue credit holds up to date in the
+// background until ctx is cancelled.
+func StartCreditHoldMonitor(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(CreditHoldCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				RunOverdueCreditHolds(now)
+			}
+		}
+	}()
+}
+
+// RunOverdueCreditHolds puts customers with invoices overdue beyond their
+// company's threshold on credit hold, and releases the automatic holds of
+// customers who have caught up.
+func RunOverdueCreditHolds(now time.Time) {
+	if config.DB == nil {
+		return
+	}
+	cursor, err := config.DB.Collection("companies").Find(context.Background(), bson.M{
+		"settings.credit_hold_overdue_days": bson.M{"$gt": 0},
+		"deleted_at":                        bson.M{"$exists": false},
+	})
+	if err != nil {
+		fmt.Println("Error fetching companies for credit holds:", err)
+		return
+	}
+	var companies []models.Company
+	if err := cursor.All(context.Background(), &companies); err != nil {
+		fmt.Println("Error decoding companies for credit holds:", err)
+		return
+	}
+
+	for _, company := range companies {
+		ids, err := config.DB.Collection("invoices").Distinct(context.Background(), "customer_id", bson.M{
+			"company_id": company.ID.Hex(),
+			"status":     "Unpaid",
+			"due_date":   bson.M{"$lt": overdueCutoff(company.Settings, now)},
+		})
+		if err != nil {
+			fmt.Println("Error fetching overdue customers:", err)
+			continue
+		}
+		overdue := []primitive.ObjectID{}
+		for _, id := range ids {
+			hex, _ := id.(string)
+			if customerID, err := primitive.ObjectIDFromHex(hex); err == nil {
+				overdue = append(overdue, customerID)
+			}
+		}
+
+		cursor, err := config.DB.Collection("customers").Find(context.Background(), bson.M{
+			"_id":         bson.M{"$in": overdue},
+			"credit_hold": bson.M{"$exists": false},
+		})
+		if err != nil {
+			fmt.Println("Error fetching overdue customers:", err)
+			continue
+		}
+		var held []models.Customer
+		if err := cursor.All(context.Background(), &held); err != nil {
+			fmt.Println("Error decoding overdue customers:", err)
+			continue
+		}
+		for _, customer := range held {
+			if err := checkOverdueCreditHold(company, &customer, now); err != nil {
+				fmt.Println("Error placing customer on credit hold:", err)
+			}
+		}
+
+		cursor, err = config.DB.Collection("customers").Find(context.Background(), bson.M{
+			"company_id":         company.ID,
+			"credit_hold.source": "overdue",
+			"_id":                bson.M{"$nin": overdue},
+		})
+		if err != nil {
+			fmt.Println("Error fetching customers on credit hold:", err)
+			continue
+		}
+		var caughtUp []models.Customer
+		if err := cursor.All(context.Background(), &caughtUp); err != nil {
+			fmt.Println("Error decoding customers on credit hold:", err)
+			continue
+		}
+		for _, customer := range caughtUp {
+			if err := releaseCreditHold(company.ID, customer.ID, "No invoices overdue any more", ""); err != nil {
+				fmt.Println("Error releasing credit hold:", err)
+			}
+		}
+	}
+}
+
+// approvedCreditOverride loads the approved, unused override an invoice names
+// for its customer, writing the error response itself when it cannot be used.
+func approvedCreditOverride(c *gin.Context, invoice models.Invoice, customer models.Customer) (*models.CreditRequest, bool) {
+	if invoice.CreditOverrideID == "" {
+		return nil, true
+	}
+	objID, err := primitive.ObjectIDFromHex(invoice.CreditOverrideID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid credit override ID"})
+		return nil, false
+	}
+	var override models.CreditRequest
+	err = config.DB.Collection("credit_requests").FindOne(context.Background(), bson.M{
+		"_id":         objID,
+		"customer_id": customer.ID,
+		"type":        "override",
+		"status":      "Approved",
+	}).Decode(&override)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Credit override not found, not approved or already used"})
+		return nil, false
+	}
+	if invoice.Amount > override.Amount {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invoice amount exceeds the %.2f approved by the credit override", override.Amount)})
+		return nil, false
+	}
+	return &override, true
+}
+
+// claimCreditOverride marks an approved override as used by the invoice before
+// it is stored. It reports false when another invoice claimed it meanwhile.
+func claimCreditOverride(override models.CreditRequest, invoice models.Invoice) (bool, error) {
+	result, err := config.DB.Collection("credit_requests").UpdateOne(context.Background(),
+		bson.M{"_id": override.ID, "status": "Approved"},
+		bson.M{"$set": bson.M{"status": "Used", "invoice_reference": invoice.ReferenceNumber}},
+	)
+	if err != nil || result.ModifiedCount == 0 {
+		return false, err
+	}
+	return true, nil
+}
+
+// releaseCreditOverride approves a claimed override again when its invoice
+// could not be stored.
+func releaseCreditOverride(override models.CreditRequest, invoice models.Invoice) {
+	_, err := config.DB.Collection("credit_requests").UpdateOne(context.Background(),
+		bson.M{"_id": override.ID, "status": "Used", "invoice_reference": invoice.ReferenceNumber},
+		bson.M{"$set": bson.M{"status": "Approved"}, "$unset": bson.M{"invoice_reference": ""}},
+	)
+	if err != nil {
+		fmt.Println("Error releasing credit override", err)
+	}
+}
+
+// recordCreditOverrideUse adds the use of an override by a stored invoice to
+// the customer's credit history.
+func recordCreditOverrideUse(override models.CreditRequest, invoice models.Invoice, userID string) {
+	recordCreditEvent(models.CustomerCreditEvent{
+		CompanyID:   override.CompanyID,
+		CustomerID:  override.CustomerID,
+		Type:        "override_used",
+		Description: "Credit override used for invoice " + invoice.ReferenceNumber,
+		Amount:      invoice.Amount,
+		RequestID:   override.ID.Hex(),
+		UserID:      userID,
+	})
+}
+
+// submitCreditRequest records a pending request by the token's user for the
+// customer and tells the owner about it.
+func submitCreditRequest(c *gin.Context, company models.Company, customer models.Customer, requestType string, amount float64, reason string) (models.CreditRequest, error) {
+	request := models.CreditRequest{
+		CompanyID:        customer.CompanyID,
+		CustomerID:       customer.ID,
+		Type:             requestType,
+		Status:           "Pending",
+		Amount:           round2(amount),
+		Reason:           reason,
+		RequestedBy:      c.GetString("userID"),
+		RequestedByEmail: c.GetString("email"),
+		CreatedAt:        time.Now(),
+	}
+	if requestType == "credit_limit" {
+		request.PreviousLimit = customer.MaxCreditAmount
+	}
+	res, err := config.DB.Collection("credit_requests").InsertOne(context.Background(), request)
+	if err != nil {
+		return request, err
+	}
+	request.ID = res.InsertedID.(primitive.ObjectID)
+
+	description := fmt.Sprintf("Credit override of %.2f requested: %s", request.Amount, request.Reason)
+	eventType := "override_requested"
+	if request.Type == "credit_limit" {
+		description = fmt.Sprintf("Credit limit of %.2f requested, up from %.2f: %s", request.Amount, request.PreviousLimit, request.Reason)
+		eventType = "limit_requested"
+	}
+	recordCreditEvent(models.CustomerCreditEvent{
+		CompanyID:   request.CompanyID,
+		CustomerID:  request.CustomerID,
+		Type:        eventType,
+		Description: description,
+		Amount:      request.Amount,
+		RequestID:   request.ID.Hex(),
+		UserID:      request.RequestedBy,
+	})
+	notifyCreditControl(company, companyOwnerEmail(company),
+		"Approval needed for "+customer.Name,
+		fmt.Sprintf("%s.\n\nCustomer: %s\nRequested by: %s\n\nApprove or reject request %s.", description, customer.Name, request.RequestedByEmail, request.ID.Hex()),
+		request.ID.Hex())
+	return request, nil
+}
+
+// PlaceCustomerCreditHold godoc
+// @Summary Put a customer on credit hold
+// @Description Stops new credit invoices for the customer until the owner releases the hold. Sales staff can still invoice them on credit with an approved credit override.
+// @Tags Customer
+// @Accept json
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Param hold body models.CreditHoldInput true "Reason for the hold"
+// @Success 200 {object} models.CustomerCreditHold
+// @Failure 400 {object} map[string]string "Invalid input"
+// @Failure 403 {object} map[string]string "Customer of another company"
+// @Failure 404 {object} map[string]string "Customer not found"
+// @Failure 409 {object} map[string]string "Customer already on credit hold"
+// @Failure 500 {object} map[string]string "Failed to place credit hold"
+// @Router /customer/{id}/credit-hold [post]
+func PlaceCustomerCreditHold(c *gin.Context) {
+	var input models.CreditHoldInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	customer, ok := fetchScopedCustomer(c)
+	if !ok {
+		return
+	}
+
+	hold := models.CustomerCreditHold{
+		Reason:   input.Reason,
+		Source:   "manual",
+		PlacedBy: c.GetString("userID"),
+		PlacedAt: time.Now(),
+	}
+	placed, err := placeCreditHold(customer.CompanyID, customer.ID, hold)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to place credit hold"})
+		return
+	}
+	if !placed {
+		c.JSON(http.StatusConflict, gin.H{"error": "Customer is already on credit hold"})
+		return
+	}
+	if company, err := fetchCompanyByID(customer.CompanyID); err == nil {
+		notifyCreditControl(company, companyOwnerEmail(company),
+			customer.Name+" was put on credit hold",
+			fmt.Sprintf("%s was put on credit hold: %s.", customer.Name, hold.Reason),
+			customer.ID.Hex())
+	}
+	c.JSON(http.StatusOK, hold)
+}
+
+// ReleaseCustomerCreditHold godoc
+// @Summary Release a customer's credit hold
+// @Description Lets the customer be invoiced on credit again. Only the company owner can release holds, with a token for the company.
+// @Tags Customer
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Customer ID"
+// @Success 200 {object} models.GenericResponse
+// @Failure 400 {object} map[string]string "Customer not on credit hold"
+// @Failure 401 {object} map[string]string "No token for a company"
+// @Failure 403 {object} map[string]string "Not the company owner, or customer of another company"
+// @Failure 404 {object} map[string]string "Customer not found"
+// @Failure 500 {object} map[string]string "Failed to release credit hold"
+// @Router /customer/{id}/credit-hold [delete]
+func ReleaseCustomerCreditHold(c *gin.Context) {
+	owner, ok := requireOwner(c, "release credit holds")
+	if !ok {
+		return
+	}
+	customer, ok := fetchScopedCustomer(c)
+	if !ok {
+		return
+	}
+	if customer.CreditHold == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Customer is not on credit hold"})
+		return
+	}
+	if err := releaseCreditHold(customer.CompanyID, customer.ID, "Released by the owner", owner); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release credit hold"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Credit hold released successfully"})
+}
+
+// RequestCreditApproval godoc
+// @Summary Ask the owner for a credit exception
+// @Description Sales staff ask for an override, which lets one credit invoice of up to the amount through despite a credit hold or the credit limit, or for a higher credit limit. The owner is notified and approves or rejects the request. Approved overrides are used by naming them as credit_override_id when generating the invoice.
+// @Tags Customer
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Customer ID"
+// @Param request body models.CreditRequestInput true "Override or credit limit request"
+// @Success 201 {object} models.CreditRequest
+// @Failure 400 {object} map[string]string "Invalid input or limit below the credit in use"
+// @Failure 401 {object} map[string]string "No token for a company"
+// @Failure 403 {object} map[string]string "Customer of another company"
+// @Failure 404 {object} map[string]string "Customer not found"
+// @Failure 500 {object} map[string]string "Failed to create request"
+// @Router /customer/{id}/credit-requests [post]
+func RequestCreditApproval(c *gin.Context) {
+	var input models.CreditRequestInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	if c.GetString("companyID") == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in to a company to request credit approvals"})
+		return
+	}
+	customer, ok := fetchScopedCustomer(c)
+	if !ok {
+		return
+	}
+	if input.Type == "credit_limit" && input.Amount < customer.MaxCreditAmount-customer.CurrentCreditAvailable {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New maximum credit amount cannot be less than current used credit"})
+		return
+	}
+	company, err := fetchCompanyByID(customer.CompanyID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+
+	request, err := submitCreditRequest(c, company, customer, input.Type, input.Amount, input.Reason)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create request"})
+		return
+	}
+	c.JSON(http.StatusCreated, request)
+}
+
+// ListCreditRequests godoc
+// @Summary List credit override and credit limit requests
+// @Description Lists the requests of the active company, newest first, optionally only those with a status or for one customer.
+// @Tags Customer
+// @Produce json
+// @Param company_id query string false "Company ID, defaults to the token's active company"
+// @Param status query string false "Pending, Approved, Rejected or Used"
+// @Param customer_id query string false "Customer ID"
+// @Success 200 {array} models.CreditRequest
+// @Failure 400 {object} map[string]string "Invalid ID"
+// @Failure 401 {object} map[string]string "No active company in the token"
+// @Failure 403 {object} map[string]string "Another company than the token's"
+// @Failure 500 {object} map[string]string "Failed to fetch requests"
+// @Router /customer/credit-requests [get]
+func ListCreditRequests(c *gin.Context) {
+	requested, ok := requiredCompanyID(c, c.Query("company_id"))
+	if !ok {
+		return
+	}
+	companyID, err := primitive.ObjectIDFromHex(requested)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return
+	}
+	filter := bson.M{"company_id": companyID}
+	if status := c.Query("status"); status != "" {
+		filter["status"] = status
+	}
+	if customer := c.Query("customer_id"); customer != "" {
+		customerID, err := primitive.ObjectIDFromHex(customer)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+			return
+		}
+		filter["customer_id"] = customerID
+	}
+
+	cursor, err := config.DB.Collection("credit_requests").Find(context.Background(), filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch requests"})
+		return
+	}
+	requests := []models.CreditRequest{}
+	if err := cursor.All(context.Background(), &requests); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch requests"})
+		return
+	}
+	c.JSON(http.StatusOK, requests)
+}
+
+// ApproveCreditRequest godoc
+// @Summary Approve a credit override or credit limit request
+// @Description Approves a pending request. Credit limit requests take effect at once; approved overrides can be used for one credit invoice. The requester is notified. Only the company owner can approve requests, with a token for the company.
+// @Tags Customer
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request_id path string true "Request ID"
+// @Param decision body models.CreditDecisionInput false "Note for the requester"
+// @Success 200 {object} models.CreditRequest
+// @Failure 400 {object} map[string]string "Invalid ID or limit below the credit in use"
+// @Failure 401 {object} map[string]string "No token for a company"
+// @Failure 403 {object} map[string]string "Not the company owner, or request of another company"
+// @Failure 404 {object} map[string]string "Request not found"
+// @Failure 409 {object} map[string]string "Request already decided"
+// @Failure 500 {object} map[string]string "Failed to approve request"
+// @Router /customer/credit-requests/{request_id}/approve [post]
+func ApproveCreditRequest(c *gin.Context) {
+	decideCreditRequest(c, "Approved")
+}
+
+// RejectCreditRequest godoc
+// @Summary Reject a credit override or credit limit request
+// @Description Rejects a pending request and notifies the requester. Only the company owner can reject requests, with a token for the company.
+// @Tags Customer
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request_id path string true "Request ID"
+// @Param decision body models.CreditDecisionInput false "Note for the requester"
+// @Success 200 {object} models.CreditRequest
+// @Failure 400 {object} map[string]string "Invalid ID"
+// @Failure 401 {object} map[string]string "No token for a company"
+// @Failure 403 {object} map[string]string "Not the company owner, or request of another company"
+// @Failure 404 {object} map[string]string "Request not found"
+// @Failure 409 {object} map[string]string "Request already decided"
+// @Failure 500 {object} map[string]string "Failed to reject request"
+// @Router /customer/credit-requests/{request_id}/reject [post]
+func RejectCreditRequest(c *gin.Context) {
+	decideCreditRequest(c, "Rejected")
+}
+
+func decideCreditRequest(c *gin.Context, status string) {
+	var input models.CreditDecisionInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+			return
+		}
+	}
+	owner, ok := requireOwner(c, "decide credit requests")
+	if !ok {
+		return
+	}
+	objID, err := primitive.ObjectIDFromHex(c.Param("request_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request ID"})
+		return
+	}
+	var request models.CreditRequest
+	if err := config.DB.Collection("credit_requests").FindOne(context.Background(), bson.M{"_id": objID}).Decode(&request); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Request not found"})
+		return
+	}
+	if _, ok := scopedCompanyID(c, request.CompanyID.Hex()); !ok {
+		return
+	}
+	if request.Status != "Pending" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Request has already been " + request.Status})
+		return
+	}
+	var customer models.Customer
+	if err := config.DB.Collection("customers").FindOne(context.Background(), bson.M{"_id": request.CustomerID}).Decode(&customer); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+		return
+	}
+	usedCredit := customer.MaxCreditAmount - customer.CurrentCreditAvailable
+	if status == "Approved" && request.Type == "credit_limit" && request.Amount < usedCredit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New maximum credit amount cannot be less than current used credit"})
+		return
+	}
+
+	now := time.Now()
+	result, err := config.DB.Collection("credit_requests").UpdateOne(context.Background(),
+		bson.M{"_id": request.ID, "status": "Pending"},
+		bson.M{"$set": bson.M{"status": status, "decided_by": owner, "decision_note": input.Note, "decided_at": now}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update request"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Request has been decided meanwhile"})
+		return
+	}
+	request.Status, request.DecidedBy, request.DecisionNote, request.DecidedAt = status, owner, input.Note, &now
+
+	if status == "Approved" && request.Type == "credit_limit" {
+		_, err := config.DB.Collection("customers").UpdateOne(context.Background(),
+			bson.M{"_id": customer.ID},
+			bson.M{"$set": bson.M{
+				"max_credit_amount":        request.Amount,
+				"current_credit_available": request.Amount - usedCredit,
+				"updated_at":               now,
+			}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Request approved but the credit limit could not be changed"})
+			return
+		}
+	}
+
+	what, eventType := "Credit override", "override_"
+	if request.Type == "credit_limit" {
+		what, eventType = "Credit limit", "limit_"
+	}
+	decision := strings.ToLower(status)
+	description := fmt.Sprintf("%s of %.2f %s", what, request.Amount, decision)
+	if input.Note != "" {
+		description += ": " + input.Note
+	}
+	recordCreditEvent(models.CustomerCreditEvent{
+		CompanyID:   request.CompanyID,
+		CustomerID:  request.CustomerID,
+		Type:        eventType + decision,
+		Description: description,
+		Amount:      request.Amount,
+		RequestID:   request.ID.Hex(),
+		UserID:      owner,
+	})
+	if company, err := fetchCompanyByID(request.CompanyID); err == nil {
+		notifyCreditControl(company, request.RequestedByEmail,
+			fmt.Sprintf("%s request for %s %s", what, customer.Name, decision),
+			fmt.Sprintf("%s for %s.\n\nRequest: %s", description, customer.Name, request.ID.Hex()),
+			request.ID.Hex())
+	}
+	c.JSON(http.StatusOK, request)
+}
+
+// GetCustomerCreditHistory godoc
+// @Summary Get a customer's credit control history
+// @Description Lists credit holds, override and credit limit requests and decisions, and credit limit changes of the customer, newest first.
+// @Tags Customer
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Success 200 {array} models.CustomerCreditEvent
+// @Failure 400 {object} map[string]string "Invalid customer ID"
+// @Failure 403 {object} map[string]string "Customer of another company"
+// @Failure 404 {object} map[string]string "Customer not found"
+// @Failure 500 {object} map[string]string "Failed to fetch history"
+// @Router /customer/{id}/credit-history [get]
+func GetCustomerCreditHistory(c *gin.Context) {
+	customer, ok := fetchScopedCustomer(c)
+	if !ok {
+		return
+	}
+	cursor, err := config.DB.Collection("customer_credit_events").Find(context.Background(),
+		bson.M{"customer_id": customer.ID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch history"})
+		return
+	}
+	events := []models.CustomerCreditEvent{}
+	if err := cursor.All(context.Background(), &events); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch history"})
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
@@ -2,7 +2,9 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/bisre1921/billing-and-invoice-system/config"
@@ -14,13 +16,16 @@ import (
 
 // RegisterCustomer godoc
 // @Summary Register a new customer
-// @Description Business Owner or Employee adds a new customer. CurrentCreditAvailable is initialized to MaxCreditAmount.
+// @Description Business Owner or Employee adds a new customer. CurrentCreditAvailable is initialized to MaxCreditAmount. payment_terms_id sets the default payment terms of their credit invoices. Contacts have a role (billing, purchasing, shipping or other); invoices go to the primary billing contact unless they name another. Notes are added separately with POST /customer/{id}/notes. When someone other than the owner gives a MaxCreditAmount above the company's credit_limit_approval_threshold, the customer is registered without credit and the limit waits for the owner's approval as a credit limit request.
 // @Tags Customer
 // @Accept json
 // @Produce json
 // @Param customer body models.Customer true "Customer Data"
 // @Success 201 {object} models.GenericResponse
+// @Success 202 {object} map[string]interface{} "Registered; the credit limit awaits approval"
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
+// @Failure 404 {object} models.ErrorResponse "Company not found"
 // @Failure 500 {object} models.ErrorResponse
 // @Router /customer/register [post]
 // @Security BearerAuth
@@ -32,10 +37,11 @@ func RegisterCustomer(c *gin.Context) {
 		return
 	}
 	// Validate Company ID
-	if customer.CompanyID.IsZero() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "CompanyID is required"})
+	companyID, ok := scopedCompanyObjectID(c, customer.CompanyID)
+	if !ok {
 		return
 	}
+	customer.CompanyID = companyID
 
 	// Validate TIN (assuming TIN is required)
 	if customer.TIN == "" {
@@ -48,8 +54,38 @@ func RegisterCustomer(c *gin.Context) {
 		return
 	}
 
+	if !checkCustomerPaymentTerms(c, customer.PaymentTermsID, customer.CompanyID) {
+		return
+	}
+	if err := normalizeCustomerDetails(&customer); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// A limit above the company's threshold needs the owner's approval, so
+	// the customer starts without credit until it is given
+	var company models.Company
+	var err error
+	requestedLimit := customer.MaxCreditAmount
+	needsApproval := false
+	if requestedLimit > 0 && c.GetString("role") != "owner" {
+		company, err = fetchCompanyByID(customer.CompanyID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+			return
+		}
+		threshold := company.Settings.CreditLimitApprovalThreshold
+		needsApproval = threshold > 0 && requestedLimit > threshold
+	}
+	if needsApproval {
+		customer.MaxCreditAmount = 0
+	}
+
 	// Initialize CurrentCreditAvailable to MaxCreditAmount
 	customer.CurrentCreditAvailable = customer.MaxCreditAmount
+	customer.CreditBalance = 0
+	customer.CreditHold = nil
+	customer.Notes = nil
 	customer.CreatedAt = time.Now()
 	customer.UpdatedAt = time.Now()
 
@@ -59,6 +95,20 @@ func RegisterCustomer(c *gin.Context) {
 		return
 	}
 
+	if needsApproval {
+		customer.ID = result.InsertedID.(primitive.ObjectID)
+		request, err := submitCreditRequest(c, company, customer, "credit_limit", requestedLimit, "Credit limit given to a new customer")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Customer registered but the credit limit request could not be created"})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":    "Customer registered successfully; the credit limit awaits the owner's approval",
+			"id":         result.InsertedID,
+			"request_id": request.ID.Hex(),
+		})
+		return
+	}
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Customer registered successfully",
 		"id":      result.InsertedID,
@@ -67,37 +117,36 @@ func RegisterCustomer(c *gin.Context) {
 
 // UpdateCustomer godoc
 // @Summary Update a customer by ID
-// @Description Business Owner or Employee updates customer details. MaxCreditAmount cannot be reduced below the amount already used.
+// @Description Business Owner or Employee updates the details of a customer of the token's active company. MaxCreditAmount cannot be reduced below the amount already used. When someone other than the owner raises it above the company's credit_limit_approval_threshold, the other details are saved and the new limit waits for the owner's approval as a credit limit request. Contacts, addresses and tags are replaced by the ones given; contacts keep their id when it is sent back.
 // @Tags Customer
 // @Accept json
 // @Produce json
 // @Param id path string true "Customer ID"
 // @Param customer body models.Customer true "Updated Customer Info"
 // @Success 200 {object} models.GenericResponse
+// @Success 202 {object} map[string]interface{} "Saved; the credit limit awaits approval"
 // @Failure 400 {object} models.ErrorResponse "Invalid ID or credit amount less than used credit"
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
+// @Failure 403 {object} models.ErrorResponse "Customer of another company"
 // @Failure 404 {object} models.ErrorResponse "Customer not found"
 // @Failure 500 {object} models.ErrorResponse
 // @Router /customer/update/{id} [put]
 // @Security BearerAuth
 func UpdateCustomer(c *gin.Context) {
-	customerID := c.Param("id")
-	objID, err := primitive.ObjectIDFromHex(customerID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
-		return
-	}
-
 	var updatedCustomer models.Customer
 	if err := c.ShouldBindJSON(&updatedCustomer); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Get existing customer to calculate the credit difference and ensure we don't reduce below what's being used
-	var existingCustomer models.Customer
-	err = config.DB.Collection("customers").FindOne(context.Background(), bson.M{"_id": objID}).Decode(&existingCustomer)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+	// Get existing customer to calculate the credit difference and ensure we don't reduce below what's being used.
+	// Only customers of the token's company can be changed, so its owner's
+	// approval bypass below never applies to another company's customers.
+	if _, ok := activeCompanyID(c, "", "update customers"); !ok {
+		return
+	}
+	existingCustomer, ok := fetchScopedCustomer(c)
+	if !ok {
 		return
 	}
 
@@ -110,6 +159,32 @@ func UpdateCustomer(c *gin.Context) {
 		return
 	}
 
+	if !checkCustomerPaymentTerms(c, updatedCustomer.PaymentTermsID, existingCustomer.CompanyID) {
+		return
+	}
+	if err := normalizeCustomerDetails(&updatedCustomer); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Raising the limit above the company's threshold needs the owner's approval
+	var company models.Company
+	var err error
+	needsApproval := false
+	if updatedCustomer.MaxCreditAmount > existingCustomer.MaxCreditAmount {
+		company, err = fetchCompanyByID(existingCustomer.CompanyID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+			return
+		}
+		threshold := company.Settings.CreditLimitApprovalThreshold
+		needsApproval = threshold > 0 && updatedCustomer.MaxCreditAmount > threshold && c.GetString("role") != "owner"
+	}
+	requestedLimit := updatedCustomer.MaxCreditAmount
+	if needsApproval {
+		updatedCustomer.MaxCreditAmount = existingCustomer.MaxCreditAmount
+	}
+
 	// Recalculate current credit available based on the new maximum
 	updatedCustomer.CurrentCreditAvailable = updatedCustomer.MaxCreditAmount - usedCredit
 
@@ -119,17 +194,25 @@ func UpdateCustomer(c *gin.Context) {
 			"email":                    updatedCustomer.Email,
 			"phone":                    updatedCustomer.Phone,
 			"address":                  updatedCustomer.Address,
+			"billing_address":          updatedCustomer.BillingAddress,
+			"shipping_address":         updatedCustomer.ShippingAddress,
+			"contacts":                 updatedCustomer.Contacts,
+			"tags":                     updatedCustomer.Tags,
 			"tin":                      updatedCustomer.TIN,
 			"max_credit_amount":        updatedCustomer.MaxCreditAmount,
 			"current_credit_available": updatedCustomer.CurrentCreditAvailable,
-			"company_id":               updatedCustomer.CompanyID,
 			"updated_at":               time.Now(),
 		},
 	}
+	if updatedCustomer.PaymentTermsID != nil {
+		update["$set"].(bson.M)["payment_terms_id"] = updatedCustomer.PaymentTermsID
+	} else {
+		update["$unset"] = bson.M{"payment_terms_id": ""}
+	}
 
 	result, err := config.DB.Collection("customers").UpdateOne(
 		context.Background(),
-		bson.M{"_id": objID},
+		bson.M{"_id": existingCustomer.ID},
 		update,
 	)
 	if err != nil || result.MatchedCount == 0 {
@@ -137,6 +220,29 @@ func UpdateCustomer(c *gin.Context) {
 		return
 	}
 
+	if needsApproval {
+		request, err := submitCreditRequest(c, company, existingCustomer, "credit_limit", requestedLimit, "Credit limit raised in a customer update")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Customer updated but the credit limit request could not be created"})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{
+			"message":    "Customer updated successfully; the new credit limit awaits the owner's approval",
+			"request_id": request.ID.Hex(),
+		})
+		return
+	}
+	if updatedCustomer.MaxCreditAmount != existingCustomer.MaxCreditAmount {
+		recordCreditEvent(models.CustomerCreditEvent{
+			CompanyID:   existingCustomer.CompanyID,
+			CustomerID:  existingCustomer.ID,
+			Type:        "limit_changed",
+			Description: fmt.Sprintf("Credit limit changed from %.2f to %.2f", existingCustomer.MaxCreditAmount, updatedCustomer.MaxCreditAmount),
+			Amount:      updatedCustomer.MaxCreditAmount,
+			UserID:      c.GetString("userID"),
+		})
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Customer updated successfully",
 	})
@@ -149,6 +255,7 @@ func UpdateCustomer(c *gin.Context) {
 // @Param id path string true "Customer ID"
 // @Success 200 {object} models.GenericResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
 // @Failure 500 {object} models.ErrorResponse
 // @Router /customer/delete/{id} [delete]
 // @Security BearerAuth
@@ -160,7 +267,11 @@ func DeleteCustomer(c *gin.Context) {
 		return
 	}
 
-	result, err := config.DB.Collection("customers").DeleteOne(context.Background(), bson.M{"_id": objID})
+	filter := bson.M{"_id": objID}
+	if !scopeToActiveCompany(c, filter) {
+		return
+	}
+	result, err := config.DB.Collection("customers").DeleteOne(context.Background(), filter)
 	if err != nil || result.DeletedCount == 0 {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Customer deletion failed"})
 		return
@@ -177,16 +288,17 @@ func DeleteCustomer(c *gin.Context) {
 // @Tags Customer
 // @Accept json
 // @Produce json
-// @Param company_id query string true "Company ID"
+// @Param company_id query string false "Company ID, defaults to the token's active company"
+// @Param tag query string false "Only customers with this tag"
 // @Success 200 {array} models.Customer
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
 // @Failure 500 {object} models.ErrorResponse
 // @Router /customer/all [get]
 // @Security BearerAuth
 func ListCustomers(c *gin.Context) {
-	companyIDParam := c.Query("company_id")
-	if companyIDParam == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Company ID is required"})
+	companyIDParam, ok := scopedCompanyID(c, c.Query("company_id"))
+	if !ok {
 		return
 	}
 
@@ -197,6 +309,9 @@ func ListCustomers(c *gin.Context) {
 	}
 
 	filter := bson.M{"company_id": companyID}
+	if tag := strings.ToLower(strings.TrimSpace(c.Query("tag"))); tag != "" {
+		filter["tags"] = tag
+	}
 	cursor, err := config.DB.Collection("customers").Find(context.Background(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch customers"})
@@ -222,6 +337,8 @@ func ListCustomers(c *gin.Context) {
 // @Param id path string true "Customer ID"
 // @Success 200 {object} models.Customer
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
+// @Failure 403 {object} models.ErrorResponse "Customer of another company"
 // @Failure 500 {object} models.ErrorResponse
 // @Router /customer/{id} [get]
 // @Security BearerAuth
@@ -239,6 +356,23 @@ func GetCustomer(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch customer"})
 		return
 	}
+	if _, ok := scopedCompanyID(c, customer.CompanyID.Hex()); !ok {
+		return
+	}
 
 	c.JSON(http.StatusOK, customer)
 }
+
+// checkCustomerPaymentTerms makes sure default payment terms given for a
+// customer belong to the customer's company, writing the 400 response itself
+// when they do not.
+func checkCustomerPaymentTerms(c *gin.Context, termsID *primitive.ObjectID, companyID primitive.ObjectID) bool {
+	if termsID == nil {
+		return true
+	}
+	if _, err := fetchCompanyPaymentTerms(*termsID, companyID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Payment terms not found"})
+		return false
+	}
+	return true
+}
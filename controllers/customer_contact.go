@@ -0,0 +1,212 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var customerContactRoles = []string{"billing", "purchasing", "shipping", "other"}
+
+// normalizeCustomerDetails checks the contacts of a customer, giving new ones
+// an ID, and tidies its tags to unique lower-case words.
+func normalizeCustomerDetails(customer *models.Customer) error {
+	primaries := map[string]bool{}
+	for i := range customer.Contacts {
+		contact := &customer.Contacts[i]
+		contact.Name = strings.TrimSpace(contact.Name)
+		contact.Email = strings.TrimSpace(contact.Email)
+		if contact.Name == "" && contact.Email == "" {
+			return fmt.Errorf("contact %d needs a name or an email", i+1)
+		}
+		if contact.Role == "" {
+			contact.Role = "billing"
+		}
+		if !contains(customerContactRoles, contact.Role) {
+			return fmt.Errorf("contact role must be one of %v", customerContactRoles)
+		}
+		if contact.Primary {
+			if primaries[contact.Role] {
+				return fmt.Errorf("only one %s contact can be primary", contact.Role)
+			}
+			primaries[contact.Role] = true
+		}
+		if contact.ID.IsZero() {
+			contact.ID = primitive.NewObjectID()
+		}
+	}
+
+	var tags []string
+	for _, tag := range customer.Tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	customer.Tags = tags
+	return nil
+}
+
+// findCustomerContact returns the customer's contact with the given hex ID.
+func findCustomerContact(customer models.Customer, id string) (models.CustomerContact, bool) {
+	for _, contact := range customer.Contacts {
+		if contact.ID.Hex() == id {
+			return contact, true
+		}
+	}
+	return models.CustomerContact{}, false
+}
+
+// billingContact returns the contact an invoice is addressed to: the one it
+// names, or else the customer's primary billing contact or first billing
+// contact. Invoices of customers without billing contacts have none.
+func billingContact(invoice models.Invoice, customer models.Customer) (models.CustomerContact, bool) {
+	if invoice.BillingContactID != "" {
+		if contact, ok := findCustomerContact(customer, invoice.BillingContactID); ok {
+			return contact, true
+		}
+	}
+	var first *models.CustomerContact
+	for i, contact := range customer.Contacts {
+		if contact.Role != "billing" {
+			continue
+		}
+		if contact.Primary {
+			return contact, true
+		}
+		if first == nil {
+			first = &customer.Contacts[i]
+		}
+	}
+	if first != nil {
+		return *first, true
+	}
+	return models.CustomerContact{}, false
+}
+
+// invoiceRecipientEmail is where an invoice is emailed: its billing contact,
+// falling back to the customer's own email.
+func invoiceRecipientEmail(invoice models.Invoice, customer models.Customer) string {
+	if contact, ok := billingContact(invoice, customer); ok && contact.Email != "" {
+		return contact.Email
+	}
+	return customer.Email
+}
+
+// invoiceBillingAddress is the address printed on an invoice: its billing
+// contact's, else the customer's billing address, else their main address.
+func invoiceBillingAddress(invoice models.Invoice, customer models.Customer) string {
+	if contact, ok := billingContact(invoice, customer); ok && contact.Address != "" {
+		return contact.Address
+	}
+	if customer.BillingAddress != "" {
+		return customer.BillingAddress
+	}
+	return customer.Address
+}
+
+// checkBillingContact makes sure the billing contact named on an invoice is
+// one of the customer's, writing the 400 response itself when it is not.
+func checkBillingContact(c *gin.Context, invoice models.Invoice, customer models.Customer) bool {
+	if invoice.BillingContactID == "" {
+		return true
+	}
+	if _, ok := findCustomerContact(customer, invoice.BillingContactID); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Billing contact not found"})
+		return false
+	}
+	return true
+}
+
+// AddCustomerNote godoc
+// @Summary Add an internal note to a customer
+// @Description Staff notes about a customer, such as agreements made over the phone. Notes are never printed or emailed.
+// @Tags Customer
+// @Accept json
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Param note body models.CustomerNoteInput true "Note"
+// @Success 201 {object} models.CustomerNote
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /customer/{id}/notes [post]
+// @Security BearerAuth
+func AddCustomerNote(c *gin.Context) {
+	var input models.CustomerNoteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	customer, ok := fetchScopedCustomer(c)
+	if !ok {
+		return
+	}
+
+	note := models.CustomerNote{
+		ID:          primitive.NewObjectID(),
+		Text:        strings.TrimSpace(input.Text),
+		AuthorID:    c.GetString("userID"),
+		AuthorEmail: c.GetString("email"),
+		CreatedAt:   time.Now(),
+	}
+	_, err := config.DB.Collection("customers").UpdateOne(context.Background(),
+		bson.M{"_id": customer.ID},
+		bson.M{"$push": bson.M{"notes": note}, "$set": bson.M{"updated_at": note.CreatedAt}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add note"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, note)
+}
+
+// DeleteCustomerNote godoc
+// @Summary Delete an internal note of a customer
+// @Tags Customer
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Param note_id path string true "Note ID"
+// @Success 200 {object} models.GenericResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse "Customer or note not found"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /customer/{id}/notes/{note_id} [delete]
+// @Security BearerAuth
+func DeleteCustomerNote(c *gin.Context) {
+	noteID, err := primitive.ObjectIDFromHex(c.Param("note_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID"})
+		return
+	}
+	customer, ok := fetchScopedCustomer(c)
+	if !ok {
+		return
+	}
+
+	result, err := config.DB.Collection("customers").UpdateOne(context.Background(),
+		bson.M{"_id": customer.ID, "notes.id": noteID},
+		bson.M{"$pull": bson.M{"notes": bson.M{"id": noteID}}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete note"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Note deleted successfully"})
+}
@@ -0,0 +1,504 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// errInvoiceSettled is returned for payments of invoices that were paid or
+// written off after they were read.
+var errInvoiceSettled = errors.New("invoice has been paid or written off meanwhile")
+
+// invoicePayment is how a payment towards an invoice was used.
+type invoicePayment struct {
+	Applied  float64 // towards the invoice balance
+	Discount float64 // early-payment discount earned
+	Excess   float64 // more than the balance, kept as customer credit
+	Paid     bool    // the invoice is settled
+}
+
+// invoiceBalance is what is still owed on an invoice.
+func invoiceBalance(invoice models.Invoice) float64 {
+	if invoice.Status == "Paid" || invoice.Status == "WrittenOff" || invoice.DocumentType == "credit_note" {
+		return 0
+	}
+	return round2(invoice.Amount - invoice.AmountPaid)
+}
+
+// recordInvoicePayment pays amount towards the invoice, or its whole balance
+// when amount is 0, and stores the receipt, which gives the payment date,
+// method and source. Once the invoice is settled it is marked paid and the
+// customer's credit limit is freed again. Invoices no longer unpaid give
+// errInvoiceSettled.
+func recordInvoicePayment(invoice models.Invoice, amount float64, receipt models.Payment) (invoicePayment, error) {
+	var payment invoicePayment
+	paidAt, method := receipt.PaidAt, receipt.Method
+	due := invoiceBalance(invoice) - earlyPaymentDiscount(invoice, paidAt)
+	if amount == 0 {
+		amount = due
+	}
+	if amount >= due-0.005 {
+		payment = invoicePayment{Applied: due, Discount: earlyPaymentDiscount(invoice, paidAt), Excess: round2(amount - due), Paid: true}
+	} else {
+		payment.Applied = amount
+	}
+
+	update := bson.M{"$inc": bson.M{"amount_paid": payment.Applied}}
+	if payment.Paid {
+		set := bson.M{"status": "Paid", "payment_date": paidAt}
+		if payment.Discount > 0 {
+			set["early_payment_discount.applied"] = true
+		}
+		update["$set"] = set
+	}
+	if method != "" {
+		if update["$set"] == nil {
+			update["$set"] = bson.M{}
+		}
+		update["$set"].(bson.M)["payment_method"] = method
+	}
+	// Matching the status keeps two payments, or a payment and a write-off,
+	// from both settling the invoice
+	result, err := config.DB.Collection("invoices").UpdateOne(context.Background(), bson.M{"_id": invoice.ID, "status": "Unpaid"}, update)
+	if err != nil {
+		return payment, err
+	}
+	if result.MatchedCount == 0 {
+		return invoicePayment{}, errInvoiceSettled
+	}
+
+	if payment.Paid {
+		releaseCustomerCredit(invoice)
+	}
+	receipt.Amount = payment.Applied
+	receipt.Discount = payment.Discount
+	receipt.Excess = payment.Excess
+	recordPayment(invoice, receipt)
+	invalidateDashboardCache(invoice.CompanyID)
+	return payment, nil
+}
+
+// recordPayment stores a receipt towards the invoice for the payments report.
+// Failures are logged; the payment itself stands.
+func recordPayment(invoice models.Invoice, receipt models.Payment) {
+	receipt.ID = primitive.NilObjectID
+	receipt.CompanyID = invoice.CompanyID
+	receipt.CustomerID = invoice.CustomerID
+	receipt.InvoiceID = invoice.ID.Hex()
+	receipt.ReferenceNumber = invoice.ReferenceNumber
+	receipt.CreatedAt = time.Now()
+	if _, err := config.DB.Collection("payments").InsertOne(context.Background(), receipt); err != nil {
+		fmt.Println("Error recording payment", err)
+	}
+}
+
+// releaseCustomerCredit gives back the credit limit a credit invoice used up
+// once it is settled or written off.
+func releaseCustomerCredit(invoice models.Invoice) {
+	if invoice.PaymentType != "credit" {
+		return
+	}
+	customerID, err := primitive.ObjectIDFromHex(invoice.CustomerID)
+	if err != nil {
+		return
+	}
+	_, err = config.DB.Collection("customers").UpdateOne(context.Background(),
+		bson.M{"_id": customerID},
+		bson.M{"$inc": bson.M{"current_credit_available": invoice.Amount}},
+	)
+	if err != nil {
+		fmt.Printf("Error restoring customer credit: %v\n", err)
+	}
+}
+
+// addCustomerCredit stores a new credit for the customer and adds it to
+// their credit balance.
+func addCustomerCredit(credit models.CustomerCredit) (models.CustomerCredit, error) {
+	credit.ID = primitive.NilObjectID
+	credit.Remaining = credit.Amount
+	credit.CreatedAt = time.Now()
+	result, err := config.DB.Collection("customer_credits").InsertOne(context.Background(), credit)
+	if err != nil {
+		return credit, err
+	}
+	credit.ID = result.InsertedID.(primitive.ObjectID)
+	_, err = config.DB.Collection("customers").UpdateOne(context.Background(),
+		bson.M{"_id": credit.CustomerID},
+		bson.M{"$inc": bson.M{"credit_balance": credit.Amount}},
+	)
+	return credit, err
+}
+
+// addOverpaymentCredit keeps what was paid beyond an invoice's balance as
+// credit of its customer.
+func addOverpaymentCredit(invoice models.Invoice, amount float64, method, reference string) (models.CustomerCredit, error) {
+	customerID, err := primitive.ObjectIDFromHex(invoice.CustomerID)
+	if err != nil {
+		return models.CustomerCredit{}, err
+	}
+	companyID, err := primitive.ObjectIDFromHex(invoice.CompanyID)
+	if err != nil {
+		return models.CustomerCredit{}, err
+	}
+	return addCustomerCredit(models.CustomerCredit{
+		CompanyID:  companyID,
+		CustomerID: customerID,
+		Source:     "overpayment",
+		SourceID:   invoice.ID.Hex(),
+		Method:     method,
+		Reference:  reference,
+		Amount:     amount,
+	})
+}
+
+// applyCustomerCredits pays the invoice from its customer's credits: the one
+// named, or else oldest first. It applies at most limit, or the invoice
+// balance when limit is 0, and returns how the payment was used.
+func applyCustomerCredits(invoice models.Invoice, creditID *primitive.ObjectID, limit float64) (invoicePayment, error) {
+	now := time.Now()
+	due := invoiceBalance(invoice) - earlyPaymentDiscount(invoice, now)
+	if limit == 0 || limit > due {
+		limit = due
+	}
+	customerID, err := primitive.ObjectIDFromHex(invoice.CustomerID)
+	if err != nil {
+		return invoicePayment{}, err
+	}
+
+	filter := bson.M{"customer_id": customerID, "remaining": bson.M{"$gt": 0}}
+	if creditID != nil {
+		filter["_id"] = *creditID
+	}
+	cursor, err := config.DB.Collection("customer_credits").Find(context.Background(), filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return invoicePayment{}, err
+	}
+	var credits []models.CustomerCredit
+	if err := cursor.All(context.Background(), &credits); err != nil {
+		return invoicePayment{}, err
+	}
+
+	var applied float64
+	taken := map[primitive.ObjectID]float64{}
+	for _, credit := range credits {
+		take := round2(math.Min(credit.Remaining, limit-applied))
+		if take <= 0 {
+			break
+		}
+		// The remaining check keeps two payments from spending the same credit
+		result, err := config.DB.Collection("customer_credits").UpdateOne(context.Background(),
+			bson.M{"_id": credit.ID, "remaining": bson.M{"$gte": take}},
+			bson.M{
+				"$inc":  bson.M{"remaining": -take},
+				"$push": bson.M{"applications": models.CreditApplication{InvoiceID: invoice.ID.Hex(), Amount: take, AppliedAt: now}},
+			},
+		)
+		if err != nil {
+			return invoicePayment{}, err
+		}
+		if result.ModifiedCount > 0 {
+			applied += take
+			taken[credit.ID] = take
+		}
+	}
+	if applied == 0 {
+		return invoicePayment{}, nil
+	}
+
+	_, err = config.DB.Collection("customers").UpdateOne(context.Background(),
+		bson.M{"_id": customerID},
+		bson.M{"$inc": bson.M{"credit_balance": -applied}},
+	)
+	if err != nil {
+		fmt.Println("Error updating customer credit balance", err)
+	}
+	payment, err := recordInvoicePayment(invoice, round2(applied), models.Payment{PaidAt: now, Method: "credit", Source: "customer_credit"})
+	if err != nil {
+		returnCustomerCredits(invoice, customerID, taken)
+	}
+	return payment, err
+}
+
+// returnCustomerCredits gives back credits taken for an invoice payment that
+// could not be recorded.
+func returnCustomerCredits(invoice models.Invoice, customerID primitive.ObjectID, taken map[primitive.ObjectID]float64) {
+	var total float64
+	for creditID, amount := range taken {
+		_, err := config.DB.Collection("customer_credits").UpdateOne(context.Background(),
+			bson.M{"_id": creditID},
+			bson.M{
+				"$inc":  bson.M{"remaining": amount},
+				"$pull": bson.M{"applications": bson.M{"invoice_id": invoice.ID.Hex(), "amount": amount}},
+			},
+		)
+		if err != nil {
+			fmt.Println("Error returning customer credit", err)
+			continue
+		}
+		total += amount
+	}
+	_, err := config.DB.Collection("customers").UpdateOne(context.Background(),
+		bson.M{"_id": customerID},
+		bson.M{"$inc": bson.M{"credit_balance": round2(total)}},
+	)
+	if err != nil {
+		fmt.Println("Error updating customer credit balance", err)
+	}
+}
+
+// fetchScopedCustomer loads the customer named by the id path parameter,
+// writing the error response itself when they are missing or belong to a
+// company other than the token's.
+func fetchScopedCustomer(c *gin.Context) (models.Customer, bool) {
+	var customer models.Customer
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+		return customer, false
+	}
+	if err := config.DB.Collection("customers").FindOne(context.Background(), bson.M{"_id": objID}).Decode(&customer); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+		return customer, false
+	}
+	if _, ok := scopedCompanyID(c, customer.CompanyID.Hex()); !ok {
+		return customer, false
+	}
+	return customer, true
+}
+
+// AddCustomerCredit godoc
+// @Summary Record a customer deposit or prepayment
+// @Description Records money received from the customer ahead of invoicing. It is added to their credit balance and can be applied to invoices with POST /invoice/apply-credit/{id}, or automatically when the company's auto_apply_credits setting is on.
+// @Tags Customer
+// @Accept json
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Param credit body models.CustomerCreditInput true "Deposit or prepayment"
+// @Success 201 {object} models.CustomerCredit
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /customer/{id}/credits [post]
+// @Security BearerAuth
+func AddCustomerCredit(c *gin.Context) {
+	var input models.CustomerCreditInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	customer, ok := fetchScopedCustomer(c)
+	if !ok {
+		return
+	}
+	if input.Source == "" {
+		input.Source = "deposit"
+	}
+
+	credit, err := addCustomerCredit(models.CustomerCredit{
+		CompanyID:  customer.CompanyID,
+		CustomerID: customer.ID,
+		Source:     input.Source,
+		Method:     input.Method,
+		Reference:  input.Reference,
+		Amount:     round2(input.Amount),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record customer credit"})
+		return
+	}
+	c.JSON(http.StatusCreated, credit)
+}
+
+// ListCustomerCredits godoc
+// @Summary List a customer's credits
+// @Description Lists the customer's deposits, prepayments, overpayments and credit notes, oldest first, with what remains of each and where it was applied or refunded.
+// @Tags Customer
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Param open query boolean false "Only credits with a remaining balance"
+// @Success 200 {array} models.CustomerCredit
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /customer/{id}/credits [get]
+// @Security BearerAuth
+func ListCustomerCredits(c *gin.Context) {
+	open, ok := optionalBoolQuery(c, "open")
+	if !ok {
+		return
+	}
+	customer, ok := fetchScopedCustomer(c)
+	if !ok {
+		return
+	}
+
+	filter := bson.M{"customer_id": customer.ID}
+	if open != nil && *open {
+		filter["remaining"] = bson.M{"$gt": 0}
+	}
+	cursor, err := config.DB.Collection("customer_credits").Find(context.Background(), filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch customer credits"})
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	credits := []models.CustomerCredit{}
+	if err := cursor.All(context.Background(), &credits); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode customer credits"})
+		return
+	}
+	c.JSON(http.StatusOK, credits)
+}
+
+// RefundCustomerCredit godoc
+// @Summary Refund a customer credit
+// @Description Pays back part or all of what remains of a credit and takes it off the customer's credit balance.
+// @Tags Customer
+// @Accept json
+// @Produce json
+// @Param id path string true "Customer ID"
+// @Param credit_id path string true "Credit ID"
+// @Param refund body models.RefundCreditRequest true "Refund"
+// @Success 200 {object} models.CustomerCredit
+// @Failure 400 {object} models.ErrorResponse "Invalid input or more than the remaining credit"
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /customer/{id}/credits/{credit_id}/refund [post]
+// @Security BearerAuth
+func RefundCustomerCredit(c *gin.Context) {
+	var req models.RefundCreditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	creditID, err := primitive.ObjectIDFromHex(c.Param("credit_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid credit ID"})
+		return
+	}
+	customer, ok := fetchScopedCustomer(c)
+	if !ok {
+		return
+	}
+
+	amount := round2(req.Amount)
+	refund := models.CreditRefund{Amount: amount, Method: req.Method, Reference: req.Reference, RefundedAt: time.Now()}
+	var credit models.CustomerCredit
+	err = config.DB.Collection("customer_credits").FindOneAndUpdate(context.Background(),
+		bson.M{"_id": creditID, "customer_id": customer.ID, "remaining": bson.M{"$gte": amount}},
+		bson.M{"$inc": bson.M{"remaining": -amount}, "$push": bson.M{"refunds": refund}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&credit)
+	if err != nil {
+		// Tell a missing credit apart from one without enough left
+		if count, _ := config.DB.Collection("customer_credits").CountDocuments(context.Background(),
+			bson.M{"_id": creditID, "customer_id": customer.ID}); count > 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Refund exceeds the remaining credit"})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Credit not found"})
+		return
+	}
+
+	_, err = config.DB.Collection("customers").UpdateOne(context.Background(),
+		bson.M{"_id": customer.ID},
+		bson.M{"$inc": bson.M{"credit_balance": -amount}},
+	)
+	if err != nil {
+		fmt.Println("Error updating customer credit balance", err)
+	}
+	c.JSON(http.StatusOK, credit)
+}
+
+// ApplyCreditToInvoice godoc
+// @Summary Pay an invoice from customer credit
+// @Description Applies the customer's unapplied credit to the invoice: the credit named, or else the oldest credits first. The amount defaults to the invoice balance.
+// @Tags Invoices
+// @Accept json
+// @Produce json
+// @Param id path string true "Invoice ID"
+// @Param body body models.ApplyCreditRequest false "Credit and amount to apply"
+// @Success 200 {object} map[string]interface{} "Credit applied"
+// @Failure 400 {object} map[string]string "Invalid input, invoice already paid or no credit available"
+// @Failure 401 {object} map[string]string "No active company in the token"
+// @Failure 403 {object} map[string]string "Invoice of another company"
+// @Failure 404 {object} map[string]string "Invoice not found"
+// @Failure 409 {object} map[string]string "Invoice paid or written off meanwhile"
+// @Failure 500 {object} map[string]string "Failed to apply credit"
+// @Router /invoice/apply-credit/{id} [post]
+func ApplyCreditToInvoice(c *gin.Context) {
+	var req models.ApplyCreditRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+			return
+		}
+	}
+	var creditID *primitive.ObjectID
+	if req.CreditID != "" {
+		id, err := primitive.ObjectIDFromHex(req.CreditID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid credit ID"})
+			return
+		}
+		creditID = &id
+	}
+
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return
+	}
+	var invoice models.Invoice
+	if err := config.DB.Collection("invoices").FindOne(context.Background(), bson.M{"_id": objID}).Decode(&invoice); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		return
+	}
+	if _, ok := scopedCompanyID(c, invoice.CompanyID); !ok {
+		return
+	}
+	if invoice.DocumentType == "credit_note" || invoiceBalance(invoice) <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invoice has no balance to pay"})
+		return
+	}
+
+	payment, err := applyCustomerCredits(invoice, creditID, round2(req.Amount))
+	if errors.Is(err, errInvoiceSettled) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Invoice has been paid or written off meanwhile"})
+		return
+	}
+	if err != nil {
+		fmt.Println("Error applying customer credit", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply credit"})
+		return
+	}
+	if payment.Applied == 0 && !payment.Paid {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The customer has no credit available"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Credit applied successfully",
+		"credit_applied":   payment.Applied,
+		"discount_applied": payment.Discount,
+		"balance":          round2(invoiceBalance(invoice) - payment.Applied - payment.Discount),
+		"paid":             payment.Paid,
+	})
+}
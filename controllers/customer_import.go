@@ -0,0 +1,473 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Accepted headers of the customer import, the first of each being the one
+// the export writes.
+var (
+	customerLimitColumns   = []string{"max_credit_amount", "credit_limit"}
+	customerContactFields  = []string{"name", "role", "email", "phone", "address", "primary"}
+	customerImportRequired = [][]string{{"name"}}
+)
+
+// customerContactColumns returns the headers of a field of the nth contact;
+// the first contact can also be given without its number, as contact_email.
+func customerContactColumns(n int, field string) []string {
+	columns := []string{fmt.Sprintf("contact_%d_%s", n, field)}
+	if n == 1 {
+		columns = append(columns, "contact_"+field)
+	}
+	return columns
+}
+
+// customerImportContacts reads the numbered contact columns of a row.
+// Contacts that match an existing one by email, or else by name, keep its ID
+// so invoices naming it still find it.
+func customerImportContacts(sheet importSheet, row []string, existing []models.CustomerContact) ([]models.CustomerContact, error) {
+	var contacts []models.CustomerContact
+	for n := 1; ; n++ {
+		present := false
+		for _, field := range customerContactFields {
+			present = present || sheet.has(customerContactColumns(n, field)...)
+		}
+		if !present {
+			break
+		}
+		contact := models.CustomerContact{
+			Name:    sheet.cell(row, customerContactColumns(n, "name")...),
+			Role:    strings.ToLower(sheet.cell(row, customerContactColumns(n, "role")...)),
+			Email:   sheet.cell(row, customerContactColumns(n, "email")...),
+			Phone:   sheet.cell(row, customerContactColumns(n, "phone")...),
+			Address: sheet.cell(row, customerContactColumns(n, "address")...),
+		}
+		if contact.Name == "" && contact.Email == "" && contact.Phone == "" && contact.Address == "" {
+			continue
+		}
+		if primary := sheet.cell(row, customerContactColumns(n, "primary")...); primary != "" {
+			parsed, err := strconv.ParseBool(primary)
+			if err != nil {
+				return nil, fmt.Errorf("contact_%d_primary must be true or false", n)
+			}
+			contact.Primary = parsed
+		}
+		if contact.Email != "" {
+			if _, err := mail.ParseAddress(contact.Email); err != nil {
+				return nil, fmt.Errorf("contact_%d_email is not a valid email address", n)
+			}
+		}
+		for _, old := range existing {
+			if (contact.Email != "" && strings.EqualFold(old.Email, contact.Email)) || (contact.Email == "" && old.Name == contact.Name) {
+				contact.ID = old.ID
+				break
+			}
+		}
+		contacts = append(contacts, contact)
+	}
+	return contacts, nil
+}
+
+// fetchImportedCustomers loads the company's customers an import may update,
+// in one query, keyed by TIN and by lower-case email.
+func fetchImportedCustomers(companyID primitive.ObjectID, sheet importSheet) (map[string]models.Customer, map[string]models.Customer, error) {
+	var tins, emails []string
+	for _, row := range sheet.rows {
+		if tin := sheet.cell(row, "tin"); tin != "" {
+			tins = append(tins, tin)
+		}
+		if email := sheet.cell(row, "email"); email != "" {
+			emails = append(emails, strings.ToLower(email))
+		}
+	}
+	byTIN := map[string]models.Customer{}
+	byEmail := map[string]models.Customer{}
+	if len(tins) == 0 && len(emails) == 0 {
+		return byTIN, byEmail, nil
+	}
+
+	filter := bson.M{"company_id": companyID, "$or": bson.A{
+		bson.M{"tin": bson.M{"$in": tins}},
+		bson.M{"email": bson.M{"$in": emails}},
+	}}
+	// Emails are compared without case, as stored emails may not be lowercase
+	caseless := options.Find().SetCollation(&options.Collation{Locale: "en", Strength: 2})
+	cursor, err := config.DB.Collection("customers").Find(context.Background(), filter, caseless)
+	if err != nil {
+		return nil, nil, err
+	}
+	var customers []models.Customer
+	if err := cursor.All(context.Background(), &customers); err != nil {
+		return nil, nil, err
+	}
+	for _, customer := range customers {
+		if customer.TIN != "" {
+			byTIN[customer.TIN] = customer
+		}
+		if customer.Email != "" {
+			byEmail[strings.ToLower(customer.Email)] = customer
+		}
+	}
+	return byTIN, byEmail, nil
+}
+
+// ImportCustomers godoc
+// @Summary Import customers from a CSV or XLSX file
+// @Description Creates customers from the rows of a file, or updates the existing customer with the same TIN, or else the same email. Columns are matched by their header: name is required, and new customers need a tin. Optional columns are email, phone, address, billing_address, shipping_address, max_credit_amount (or credit_limit), tags separated by semicolons, and contacts as contact_1_name, contact_1_role, contact_1_email, contact_1_phone, contact_1_address, contact_1_primary, contact_2_name and so on. Columns left out keep their current values on updates. Rows with errors are reported by line and left out; the other rows are imported. The errors can be downloaded as CSV through the error_report_id. With dry_run nothing is saved and the result shows what the import would do. Raising a credit limit above the company's credit_limit_approval_threshold needs the owner role.
+// @Tags Customer
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV or XLSX file with customer data"
+// @Param company_id formData string false "Company ID, defaults to the token's active company"
+// @Param dry_run formData boolean false "Validate and preview without saving"
+// @Success 200 {object} models.ImportResult "Dry run result"
+// @Success 201 {object} models.ImportResult "Customers imported"
+// @Failure 400 {object} models.ErrorResponse "Invalid file or missing columns"
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse "Company not found"
+// @Failure 500 {object} models.ErrorResponse "Failed to import customers"
+// @Router /customer/import [post]
+// @Security BearerAuth
+func ImportCustomers(c *gin.Context) {
+	companyID, ok := activeCompanyID(c, c.PostForm("company_id"), "import customers")
+	if !ok {
+		return
+	}
+	objCompanyID, err := primitive.ObjectIDFromHex(companyID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return
+	}
+	dryRun, ok := optionalBoolForm(c, "dry_run")
+	if !ok {
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		return
+	}
+	sheet, err := readImportSheet(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if missing := sheet.missing(customerImportRequired...); len(missing) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required columns: " + strings.Join(missing, ", ")})
+		return
+	}
+	if !sheet.has("tin", "email") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The file needs a tin or an email column to match customers"})
+		return
+	}
+
+	company, err := fetchCompanyByID(objCompanyID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+	byTIN, byEmail, err := fetchImportedCustomers(objCompanyID, sheet)
+	if err != nil {
+		fmt.Println("Error fetching customers for import", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch existing customers"})
+		return
+	}
+
+	result := models.ImportResult{DryRun: dryRun}
+	var writes []mongo.WriteModel
+	var limitEvents []models.CustomerCreditEvent
+	seen := map[string]int{} // TIN or email of rows already read, to their line
+	threshold := company.Settings.CreditLimitApprovalThreshold
+	now := time.Now()
+
+	for i, row := range sheet.rows {
+		if row == nil {
+			continue
+		}
+		result.Rows++
+		line := importLine(i)
+		var rowErrors []models.ImportRowError
+		fail := func(column, value, message string) {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: line, Column: column, Value: value, Error: message})
+		}
+
+		name := sheet.cell(row, "name")
+		tin := sheet.cell(row, "tin")
+		email := sheet.cell(row, "email")
+		if name == "" {
+			fail("name", "", "name is required")
+		}
+		if tin == "" && email == "" {
+			fail("tin", "", "tin or email is required")
+		}
+		if email != "" {
+			if _, err := mail.ParseAddress(email); err != nil {
+				fail("email", email, "not a valid email address")
+			}
+		}
+		for _, key := range []string{"tin:" + tin, "email:" + strings.ToLower(email)} {
+			if strings.HasSuffix(key, ":") {
+				continue
+			}
+			if first, dup := seen[key]; dup {
+				fail("", key[strings.Index(key, ":")+1:], fmt.Sprintf("same customer as line %d", first))
+			} else {
+				seen[key] = line
+			}
+		}
+
+		existing, found := byTIN[tin]
+		if !found && email != "" {
+			existing, found = byEmail[strings.ToLower(email)]
+		}
+		if !found && tin == "" {
+			fail("tin", "", "tin is required for new customers")
+		}
+
+		limit := existing.MaxCreditAmount
+		limitGiven := false
+		if value := sheet.cell(row, customerLimitColumns...); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			switch {
+			case err != nil:
+				fail(customerLimitColumns[0], value, "not a number")
+			case parsed < 0:
+				fail(customerLimitColumns[0], value, "cannot be negative")
+			default:
+				limit, limitGiven = parsed, true
+			}
+		}
+		usedCredit := existing.MaxCreditAmount - existing.CurrentCreditAvailable
+		if limitGiven && found && limit < usedCredit {
+			fail(customerLimitColumns[0], sheet.cell(row, customerLimitColumns...), fmt.Sprintf("cannot be less than the %.2f of credit already used", usedCredit))
+		}
+		if limitGiven && limit > existing.MaxCreditAmount && threshold > 0 && limit > threshold && c.GetString("role") != "owner" {
+			fail(customerLimitColumns[0], sheet.cell(row, customerLimitColumns...), fmt.Sprintf("credit limits above %.2f need the owner's approval", threshold))
+		}
+
+		customer := models.Customer{
+			Name:            name,
+			TIN:             tin,
+			Email:           email,
+			Phone:           sheet.cell(row, "phone"),
+			Address:         sheet.cell(row, "address"),
+			BillingAddress:  sheet.cell(row, "billing_address"),
+			ShippingAddress: sheet.cell(row, "shipping_address"),
+		}
+		if tags := sheet.cell(row, "tags"); tags != "" {
+			customer.Tags = strings.Split(tags, ";")
+		}
+		contacts, err := customerImportContacts(sheet, row, existing.Contacts)
+		if err != nil {
+			fail("", "", err.Error())
+		}
+		customer.Contacts = contacts
+		if err := normalizeCustomerDetails(&customer); err != nil {
+			fail("", "", err.Error())
+		}
+
+		if len(rowErrors) > 0 {
+			result.Failed++
+			result.Errors = append(result.Errors, rowErrors...)
+			continue
+		}
+
+		if !found {
+			customer.ID = primitive.NewObjectID()
+			customer.CompanyID = objCompanyID
+			customer.MaxCreditAmount = limit
+			customer.CurrentCreditAvailable = limit
+			customer.CreatedAt = now
+			customer.UpdatedAt = now
+			writes = append(writes, mongo.NewInsertOneModel().SetDocument(customer))
+			result.Created++
+			continue
+		}
+
+		// Only the columns in the file change an existing customer
+		set := bson.M{"name": name, "updated_at": now}
+		for column, value := range map[string]interface{}{
+			"tin":              customer.TIN,
+			"email":            customer.Email,
+			"phone":            customer.Phone,
+			"address":          customer.Address,
+			"billing_address":  customer.BillingAddress,
+			"shipping_address": customer.ShippingAddress,
+			"tags":             customer.Tags,
+		} {
+			if sheet.has(column) {
+				set[column] = value
+			}
+		}
+		if sheet.has(customerContactColumns(1, "name")...) || sheet.has(customerContactColumns(1, "email")...) {
+			set["contacts"] = customer.Contacts
+		}
+		if limitGiven {
+			set["max_credit_amount"] = limit
+			set["current_credit_available"] = limit - usedCredit
+			if limit != existing.MaxCreditAmount {
+				limitEvents = append(limitEvents, models.CustomerCreditEvent{
+					CompanyID:   objCompanyID,
+					CustomerID:  existing.ID,
+					Type:        "limit_changed",
+					Description: fmt.Sprintf("Credit limit changed from %.2f to %.2f by an import", existing.MaxCreditAmount, limit),
+					Amount:      limit,
+					UserID:      c.GetString("userID"),
+				})
+			}
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": existing.ID}).SetUpdate(bson.M{"$set": set}))
+		result.Updated++
+	}
+
+	if result.Rows == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No customers found in the file"})
+		return
+	}
+	result.ErrorReportID = saveImportErrorReport(objCompanyID, "customers", file.Filename, result.Errors)
+	if dryRun {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+	if err := writeImportBatches("customers", writes); err != nil {
+		fmt.Println("Error importing customers", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import customers", "details": err.Error()})
+		return
+	}
+	for _, event := range limitEvents {
+		recordCreditEvent(event)
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// customerExportTable lays out customers in the columns ImportCustomers reads,
+// so an export can be edited and imported again.
+func customerExportTable(customers []models.Customer) reportTable {
+	maxContacts := 0
+	for _, customer := range customers {
+		if len(customer.Contacts) > maxContacts {
+			maxContacts = len(customer.Contacts)
+		}
+	}
+
+	table := reportTable{Name: "Customers"}
+	table.Columns = []reportColumn{
+		{"name", columnText},
+		{"tin", columnText},
+		{"email", columnText},
+		{"phone", columnText},
+		{"address", columnText},
+		{"billing_address", columnText},
+		{"shipping_address", columnText},
+		{customerLimitColumns[0], columnNumber},
+		{"current_credit_available", columnNumber},
+		{"tags", columnText},
+	}
+	for n := 1; n <= maxContacts; n++ {
+		for _, field := range customerContactFields {
+			table.Columns = append(table.Columns, reportColumn{customerContactColumns(n, field)[0], columnText})
+		}
+	}
+
+	var totalLimit float64
+	for _, customer := range customers {
+		row := []interface{}{
+			customer.Name, customer.TIN, customer.Email, customer.Phone, customer.Address,
+			customer.BillingAddress, customer.ShippingAddress,
+			customer.MaxCreditAmount, customer.CurrentCreditAvailable, strings.Join(customer.Tags, ";"),
+		}
+		for n := 0; n < maxContacts; n++ {
+			if n >= len(customer.Contacts) {
+				row = append(row, "", "", "", "", "", "")
+				continue
+			}
+			contact := customer.Contacts[n]
+			row = append(row, contact.Name, contact.Role, contact.Email, contact.Phone, contact.Address, strconv.FormatBool(contact.Primary))
+		}
+		table.Rows = append(table.Rows, row)
+		totalLimit += customer.MaxCreditAmount
+	}
+	table.Summary = []reportSummaryRow{
+		{"Customers", len(customers)},
+		{"Total Credit Limit", totalLimit},
+	}
+	return table
+}
+
+// ExportCustomers godoc
+// @Summary Export all customers of a company
+// @Description Downloads the company's customers as CSV or XLSX, in the columns ImportCustomers accepts, contacts included.
+// @Tags Customer
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param company_id query string false "Company ID, defaults to the token's active company"
+// @Param format query string false "csv (default) or xlsx"
+// @Success 200 {file} binary
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /customer/export [get]
+// @Security BearerAuth
+func ExportCustomers(c *gin.Context) {
+	companyID, ok := scopedCompanyID(c, c.Query("company_id"))
+	if !ok {
+		return
+	}
+	objCompanyID, err := primitive.ObjectIDFromHex(companyID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return
+	}
+	format := c.DefaultQuery("format", "csv")
+	if !contains(importFormats, format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("format must be one of %v", importFormats)})
+		return
+	}
+
+	cursor, err := config.DB.Collection("customers").Find(context.Background(),
+		bson.M{"company_id": objCompanyID}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch customers"})
+		return
+	}
+	var customers []models.Customer
+	if err := cursor.All(context.Background(), &customers); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode customers"})
+		return
+	}
+
+	table := customerExportTable(customers)
+	var data []byte
+	contentType := "text/csv"
+	if format == "xlsx" {
+		data, err = reportTableToXLSX(table)
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	} else {
+		data, err = reportTableToCSV(table)
+	}
+	if err != nil {
+		fmt.Println("Error exporting customers", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export customers"})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=customers_"+companyID+"."+format)
+	c.Data(http.StatusOK, contentType, data)
+}
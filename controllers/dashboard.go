@@ -0,0 +1,251 @@
+package controllers
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// DashboardCacheTTL is how long computed dashboard figures are served from memory.
+var DashboardCacheTTL = time.Minute
+
+var dashboardPeriods = map[string]int{
+	"week":    7,
+	"month":   30,
+	"quarter": 90,
+	"year":    365,
+}
+
+type DashboardKPIs struct {
+	CompanyID             string               `json:"company_id"`
+	Period                string               `json:"period"`
+	PeriodStart           time.Time            `json:"period_start"`
+	PeriodEnd             time.Time            `json:"period_end"`
+	Revenue               float64              `json:"revenue"`
+	PreviousRevenue       float64              `json:"previous_revenue"`
+	RevenueChangePercent  *float64             `json:"revenue_change_percent"`
+	OutstandingReceivable float64              `json:"outstanding_receivables"`
+	OverdueAmount         float64              `json:"overdue_amount"`
+	BadDebt               float64              `json:"bad_debt"` // written off in the period
+	InvoicesIssued        int                  `json:"invoices_issued"`
+	InvoicesPaid          int                  `json:"invoices_paid"`
+	AverageDaysToPay      float64              `json:"average_days_to_pay"`
+	TopCustomers          []DashboardTopEntry  `json:"top_customers"`
+	TopItems              []DashboardTopEntry  `json:"top_items"`
+	DailyRevenue          []DashboardDailyStat `json:"daily_revenue"`
+	GeneratedAt           time.Time            `json:"generated_at"`
+}
+
+type DashboardTopEntry struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Revenue  float64 `json:"revenue"`
+	Quantity int     `json:"quantity,omitempty"`
+	Invoices int     `json:"invoices,omitempty"`
+}
+
+type DashboardDailyStat struct {
+	Date    string  `json:"date"` // YYYY-MM-DD
+	Revenue float64 `json:"revenue"`
+}
+
+type dashboardCacheEntry struct {
+	kpis    DashboardKPIs
+	expires time.Time
+}
+
+var (
+	dashboardCache   = map[string]dashboardCacheEntry{}
+	dashboardCacheMu sync.Mutex
+)
+
+// GetDashboard godoc
+// @Summary Get dashboard KPIs
+// @Description Returns revenue for the period compared to the previous one, receivables, overdue amount, invoice counts, average days to pay, top customers and items and a daily revenue series. Days are counted in the company's timezone. Results are cached briefly.
+// @Tags Dashboard
+// @Produce json
+// @Param company_id path string true "Company ID"
+// @Param period query string false "week, month (default), quarter or year"
+// @Success 200 {object} DashboardKPIs
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "No active company in the token"
+// @Failure 500 {object} map[string]string
+// @Router /dashboard/{company_id} [get]
+// @Security BearerAuth
+func GetDashboard(c *gin.Context) {
+	companyID, ok := scopedCompanyID(c, c.Param("company_id"))
+	if !ok {
+		return
+	}
+	period := c.DefaultQuery("period", "month")
+	days, ok := dashboardPeriods[period]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid period, expected week, month, quarter or year"})
+		return
+	}
+
+	cacheKey := companyID + "|" + period
+	now := time.Now()
+	dashboardCacheMu.Lock()
+	entry, found := dashboardCache[cacheKey]
+	dashboardCacheMu.Unlock()
+	if found && now.Before(entry.expires) {
+		c.JSON(http.StatusOK, entry.kpis)
+		return
+	}
+
+	kpis, err := computeDashboard(companyID, period, days, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute dashboard"})
+		return
+	}
+
+	dashboardCacheMu.Lock()
+	dashboardCache[cacheKey] = dashboardCacheEntry{kpis: kpis, expires: now.Add(DashboardCacheTTL)}
+	dashboardCacheMu.Unlock()
+
+	c.JSON(http.StatusOK, kpis)
+}
+
+func computeDashboard(companyID, period string, days int, now time.Time) (DashboardKPIs, error) {
+	// Days start at midnight in the company's timezone
+	loc := companyLocation(fetchCompanySettings(companyID))
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	end := today.AddDate(0, 0, 1)
+	start := end.AddDate(0, 0, -days)
+	previousStart := start.AddDate(0, 0, -days)
+
+	invoices, err := fetchCompanyInvoices(companyID, bson.M{"$or": []bson.M{
+		{"date": bson.M{"$gte": previousStart}},
+		{"payment_date": bson.M{"$gte": start}},
+		{"status": "Unpaid"},
+		{"write_off.written_off_at": bson.M{"$gte": start}},
+	}})
+	if err != nil {
+		return DashboardKPIs{}, err
+	}
+
+	kpis := DashboardKPIs{
+		CompanyID:   companyID,
+		Period:      period,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		GeneratedAt: now,
+	}
+
+	daily := map[string]float64{}
+	customers := map[string]*DashboardTopEntry{}
+	items := map[string]*DashboardTopEntry{}
+	var daysToPay float64
+
+	for _, inv := range invoices {
+		inCurrent := !inv.Date.Before(start) && inv.Date.Before(end)
+		inPrevious := !inv.Date.Before(previousStart) && inv.Date.Before(start)
+		// Credit notes take back revenue of the period they are issued in
+		sign := documentSign(inv)
+
+		if inPrevious {
+			kpis.PreviousRevenue += sign * inv.Amount
+		}
+		if inCurrent {
+			kpis.Revenue += sign * inv.Amount
+			if sign > 0 {
+				kpis.InvoicesIssued++
+			}
+			daily[inv.Date.In(loc).Format("2006-01-02")] += sign * inv.Amount
+
+			customer, ok := customers[inv.CustomerID]
+			if !ok {
+				customer = &DashboardTopEntry{ID: inv.CustomerID}
+				customers[inv.CustomerID] = customer
+			}
+			customer.Revenue += sign * inv.Amount
+			if sign > 0 {
+				customer.Invoices++
+			}
+
+			for _, it := range inv.Items {
+				key := it.ItemID
+				if key == "" {
+					key = it.ItemName
+				}
+				item, ok := items[key]
+				if !ok {
+					item = &DashboardTopEntry{ID: it.ItemID, Name: it.ItemName}
+					items[key] = item
+				}
+				item.Revenue += sign * it.Subtotal
+				item.Quantity += int(sign) * it.Quantity
+				item.Invoices++
+			}
+		}
+
+		if inv.Status == "Paid" && !inv.PaymentDate.Before(start) && inv.PaymentDate.Before(end) {
+			kpis.InvoicesPaid++
+			daysToPay += inv.PaymentDate.Sub(inv.Date).Hours() / 24
+		}
+
+		if inv.WriteOff != nil && !inv.WriteOff.WrittenOffAt.Before(start) && inv.WriteOff.WrittenOffAt.Before(end) {
+			kpis.BadDebt += inv.WriteOff.Amount
+		}
+
+		if inv.Status == "Unpaid" {
+			kpis.OutstandingReceivable += invoiceBalance(inv)
+			if inv.DueDate != nil && inv.DueDate.Before(now) {
+				kpis.OverdueAmount += invoiceBalance(inv)
+			}
+		}
+	}
+
+	if kpis.PreviousRevenue > 0 {
+		change := (kpis.Revenue - kpis.PreviousRevenue) / kpis.PreviousRevenue * 100
+		kpis.RevenueChangePercent = &change
+	}
+	if kpis.InvoicesPaid > 0 {
+		kpis.AverageDaysToPay = daysToPay / float64(kpis.InvoicesPaid)
+	}
+
+	var customerIDs []string
+	for id := range customers {
+		customerIDs = append(customerIDs, id)
+	}
+	names := lookupCustomerNames(customerIDs)
+	for id, customer := range customers {
+		customer.Name = names[id]
+	}
+	kpis.TopCustomers = topDashboardEntries(customers, 10)
+	kpis.TopItems = topDashboardEntries(items, 10)
+
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		key := day.Format("2006-01-02")
+		kpis.DailyRevenue = append(kpis.DailyRevenue, DashboardDailyStat{Date: key, Revenue: daily[key]})
+	}
+	return kpis, nil
+}
+
+func topDashboardEntries(entries map[string]*DashboardTopEntry, limit int) []DashboardTopEntry {
+	result := []DashboardTopEntry{}
+	for _, entry := range entries {
+		result = append(result, *entry)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Revenue > result[j].Revenue })
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// invalidateDashboardCache drops cached figures for a company so the next
+// request reflects a change immediately.
+func invalidateDashboardCache(companyID string) {
+	dashboardCacheMu.Lock()
+	defer dashboardCacheMu.Unlock()
+	for period := range dashboardPeriods {
+		delete(dashboardCache, companyID+"|"+period)
+	}
+}
@@ -0,0 +1,480 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"net/http"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var emailTemplateKinds = []string{"invoice", "receipt"}
+
+// InvoiceEmailData is what invoice and receipt templates can refer to.
+type InvoiceEmailData struct {
+	CompanyName     string
+	CompanyEmail    string
+	CustomerName    string
+	ReferenceNumber string
+	InvoiceDate     string
+	DueDate         string
+	PaymentDate     string
+	Amount          string
+	Subtotal        string
+	TaxAmount       string
+	PaymentLink     string
+	Items           []InvoiceEmailItem
+}
+
+type InvoiceEmailItem struct {
+	Name      string
+	Quantity  int
+	UnitPrice string
+	Discount  string
+	Subtotal  string
+}
+
+var defaultEmailTemplates = map[string]models.EmailTemplate{
+	"invoice": {
+		Kind:    "invoice",
+		Subject: "Your Invoice",
+		TextBody: `Hello {{.CustomerName}},
+
+Here is your invoice:
+
+Reference: {{.ReferenceNumber}}
+Date: {{.InvoiceDate}}
+Amount: {{.Amount}}
+Due Date: {{.DueDate}}
+
+Items:
+{{range .Items}}- {{.Name}} x{{.Quantity}} @ {{.UnitPrice}} (Discount: {{.Discount}}) → Subtotal: {{.Subtotal}}
+{{end}}{{if .PaymentLink}}
+Pay online: {{.PaymentLink}}
+{{end}}
+Please make payment by the due date.
+Thank you for your business!
+`,
+		HTMLBody: `<p>Hello {{.CustomerName}},</p>
+<p>Here is your invoice from {{.CompanyName}}. The PDF copy is attached.</p>
+<table cellpadding="4">
+<tr><td>Reference</td><td>{{.ReferenceNumber}}</td></tr>
+<tr><td>Date</td><td>{{.InvoiceDate}}</td></tr>
+<tr><td>Amount</td><td><strong>{{.Amount}}</strong></td></tr>
+<tr><td>Due Date</td><td>{{.DueDate}}</td></tr>
+</table>
+<table cellpadding="4" border="1" style="border-collapse:collapse">
+<tr><th>Item</th><th>Qty</th><th>Unit Price</th><th>Discount</th><th>Subtotal</th></tr>
+{{range .Items}}<tr><td>{{.Name}}</td><td>{{.Quantity}}</td><td>{{.UnitPrice}}</td><td>{{.Discount}}</td><td>{{.Subtotal}}</td></tr>
+{{end}}</table>
+{{if .PaymentLink}}<p><a href="{{.PaymentLink}}">Pay this invoice online</a></p>{{end}}
+<p>Please make payment by the due date.<br>Thank you for your business!</p>
+`,
+	},
+	"receipt": {
+		Kind:    "receipt",
+		Subject: "Payment Receipt",
+		TextBody: `Hello {{.CustomerName}},
+
+This is a receipt for your payment:
+
+Reference: {{.ReferenceNumber}}
+Payment Date: {{.PaymentDate}}
+Amount Paid: {{.Amount}}
+
+Items:
+{{range .Items}}- {{.Name}} x{{.Quantity}} @ {{.UnitPrice}} (Discount: {{.Discount}}) → Subtotal: {{.Subtotal}}
+{{end}}
+Thank you for your payment!
+`,
+		HTMLBody: `<p>Hello {{.CustomerName}},</p>
+<p>This is a receipt for your payment to {{.CompanyName}}. The PDF copy is attached.</p>
+<table cellpadding="4">
+<tr><td>Reference</td><td>{{.ReferenceNumber}}</td></tr>
+<tr><td>Payment Date</td><td>{{.PaymentDate}}</td></tr>
+<tr><td>Amount Paid</td><td><strong>{{.Amount}}</strong></td></tr>
+</table>
+<table cellpadding="4" border="1" style="border-collapse:collapse">
+<tr><th>Item</th><th>Qty</th><th>Unit Price</th><th>Discount</th><th>Subtotal</th></tr>
+{{range .Items}}<tr><td>{{.Name}}</td><td>{{.Quantity}}</td><td>{{.UnitPrice}}</td><td>{{.Discount}}</td><td>{{.Subtotal}}</td></tr>
+{{end}}</table>
+<p>Thank you for your payment!</p>
+`,
+	},
+}
+
+// sampleInvoiceEmailData is used to preview templates without a real invoice.
+var sampleInvoiceEmailData = InvoiceEmailData{
+	CompanyName:     "Example Company",
+	CompanyEmail:    "billing@example.com",
+	CustomerName:    "Jane Customer",
+	ReferenceNumber: "INV-0001",
+	InvoiceDate:     "2025-01-15",
+	DueDate:         "2025-01-22",
+	PaymentDate:     "2025-01-20",
+	Amount:          "$115.00",
+	Subtotal:        "$100.00",
+	TaxAmount:       "$15.00",
+	PaymentLink:     "https://example.com/invoice/INV-0001/pay",
+	Items: []InvoiceEmailItem{
+		{Name: "Consulting", Quantity: 2, UnitPrice: "$50.00", Discount: "0.00%", Subtotal: "$100.00"},
+	},
+}
+
+func invoiceEmailKind(invoice models.Invoice) string {
+	if invoice.Status == "Paid" {
+		return "receipt"
+	}
+	return "invoice"
+}
+
+func buildInvoiceEmailData(invoice models.Invoice, customer models.Customer, company models.Company) InvoiceEmailData {
+	currency := companyCurrency(company.Settings)
+	loc := companyLocation(company.Settings)
+	data := InvoiceEmailData{
+		CompanyName:     company.Name,
+		CompanyEmail:    company.Email,
+		CustomerName:    customer.Name,
+		ReferenceNumber: invoice.ReferenceNumber,
+		InvoiceDate:     invoice.Date.In(loc).Format("2006-01-02"),
+		Amount:          formatMoney(currency, invoice.Amount),
+		Subtotal:        formatMoney(currency, invoice.Subtotal),
+		TaxAmount:       formatMoney(currency, invoice.TaxAmount),
+	}
+	if invoice.DueDate != nil {
+		data.DueDate = invoice.DueDate.In(loc).Format("2006-01-02")
+	}
+	if !invoice.PaymentDate.IsZero() {
+		data.PaymentDate = invoice.PaymentDate.In(loc).Format("2006-01-02")
+	}
+	if frontendURL := os.Getenv("FRONTEND_URL"); frontendURL != "" && invoice.Status != "Paid" {
+		data.PaymentLink = fmt.Sprintf("%s/invoice/%s/pay", frontendURL, invoice.ID.Hex())
+	}
+	for _, item := range invoice.Items {
+		data.Items = append(data.Items, InvoiceEmailItem{
+			Name:      item.ItemName,
+			Quantity:  item.Quantity,
+			UnitPrice: formatMoney(currency, item.UnitPrice),
+			Discount:  fmt.Sprintf("%.2f%%", item.Discount),
+			Subtotal:  formatMoney(currency, item.Subtotal),
+		})
+	}
+	return data
+}
+
+// loadEmailTemplate returns the company's template for kind, or the built-in
+// default when it has not customised one.
+func loadEmailTemplate(companyID, kind string) (models.EmailTemplate, error) {
+	var tmpl models.EmailTemplate
+	err := config.DB.Collection("email_templates").FindOne(context.Background(), bson.M{"company_id": companyID, "kind": kind}).Decode(&tmpl)
+	if err == mongo.ErrNoDocuments {
+		tmpl = defaultEmailTemplates[kind]
+		tmpl.CompanyID = companyID
+		tmpl.IsDefault = true
+		return tmpl, nil
+	}
+	if err != nil {
+		return tmpl, err
+	}
+	if tmpl.TextBody == "" {
+		tmpl.TextBody = defaultEmailTemplates[kind].TextBody
+	}
+	return tmpl, nil
+}
+
+// renderEmailTemplate executes the subject and text body as text templates and
+// the HTML body as an HTML template, so values are escaped there.
+func renderEmailTemplate(tmpl models.EmailTemplate, data interface{}) (models.EmailPreview, error) {
+	var preview models.EmailPreview
+
+	render := func(name, source string) (string, error) {
+		t, err := template.New(name).Option("missingkey=error").Parse(source)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", name, err)
+		}
+		var buf bytes.Buffer
+		if err := t.Execute(&buf, data); err != nil {
+			return "", fmt.Errorf("%s: %v", name, err)
+		}
+		return buf.String(), nil
+	}
+
+	var err error
+	if preview.Subject, err = render("subject", tmpl.Subject); err != nil {
+		return preview, err
+	}
+	if preview.TextBody, err = render("text_body", tmpl.TextBody); err != nil {
+		return preview, err
+	}
+
+	t, err := htmltemplate.New("html_body").Option("missingkey=error").Parse(tmpl.HTMLBody)
+	if err != nil {
+		return preview, fmt.Errorf("html_body: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return preview, fmt.Errorf("html_body: %v", err)
+	}
+	preview.HTMLBody = buf.String()
+	return preview, nil
+}
+
+// composeInvoiceEmail renders the invoice or receipt email for an invoice and
+// its PDF attachment.
+func composeInvoiceEmail(invoice models.Invoice, customer models.Customer) (models.OutboxMessage, models.EmailPreview, error) {
+	var company models.Company
+	if objID, err := primitive.ObjectIDFromHex(invoice.CompanyID); err == nil {
+		company, _ = fetchCompanyByID(objID)
+	}
+
+	tmpl, err := loadEmailTemplate(invoice.CompanyID, invoiceEmailKind(invoice))
+	if err != nil {
+		return models.OutboxMessage{}, models.EmailPreview{}, err
+	}
+	preview, err := renderEmailTemplate(tmpl, buildInvoiceEmailData(invoice, customer, company))
+	if err != nil {
+		return models.OutboxMessage{}, preview, err
+	}
+
+	pdf, filename, err := renderInvoicePDF(&invoice, company, customer, "")
+	if err != nil {
+		return models.OutboxMessage{}, preview, err
+	}
+	if company.Signing != nil && company.Signing.AutoSign {
+		if pdf, err = signCompanyPDF(pdf, company, time.Now()); err != nil {
+			return models.OutboxMessage{}, preview, err
+		}
+	}
+	preview.Attachment = filename
+
+	msg := models.OutboxMessage{
+		CompanyID:   invoice.CompanyID,
+		Category:    "invoice",
+		ReferenceID: invoice.ID.Hex(),
+		FromName:    company.Name,
+		ReplyTo:     company.Email,
+		To:          []string{invoiceRecipientEmail(invoice, customer)},
+		Subject:     preview.Subject,
+		TextBody:    preview.TextBody,
+		HTMLBody:    preview.HTMLBody,
+		Attachments: []models.MailAttachment{{Filename: filename, ContentType: "application/pdf", Content: pdf}},
+	}
+	return msg, preview, nil
+}
+
+func validEmailTemplateKind(c *gin.Context) (string, bool) {
+	kind := c.Param("kind")
+	if !contains(emailTemplateKinds, kind) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("kind must be one of %v", emailTemplateKinds)})
+		return "", false
+	}
+	return kind, true
+}
+
+// GetEmailTemplate godoc
+// @Summary Get a company's email template
+// @Description Returns the company's customised template, or the built-in default (is_default true)
+// @Tags Email Templates
+// @Produce json
+// @Param company_id path string true "Company ID"
+// @Param kind path string true "invoice or receipt"
+// @Success 200 {object} models.EmailTemplate
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "No active company in the token"
+// @Failure 500 {object} map[string]string
+// @Router /email-template/{company_id}/{kind} [get]
+// @Security BearerAuth
+func GetEmailTemplate(c *gin.Context) {
+	companyID, ok := scopedCompanyID(c, c.Param("company_id"))
+	if !ok {
+		return
+	}
+	kind, ok := validEmailTemplateKind(c)
+	if !ok {
+		return
+	}
+	tmpl, err := loadEmailTemplate(companyID, kind)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch email template"})
+		return
+	}
+	c.JSON(http.StatusOK, tmpl)
+}
+
+// UpdateEmailTemplate godoc
+// @Summary Customise a company's email template
+// @Description Saves the subject, HTML body and optional text body. Placeholders use Go template syntax, e.g. {{.CustomerName}}, {{.Amount}}, {{.DueDate}}, {{.PaymentLink}}, and are checked against sample data before saving.
+// @Tags Email Templates
+// @Accept json
+// @Produce json
+// @Param company_id path string true "Company ID"
+// @Param kind path string true "invoice or receipt"
+// @Param template body models.EmailTemplateInput true "Template"
+// @Success 200 {object} models.EmailTemplate
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "No active company in the token"
+// @Failure 500 {object} map[string]string
+// @Router /email-template/{company_id}/{kind} [put]
+// @Security BearerAuth
+func UpdateEmailTemplate(c *gin.Context) {
+	companyID, ok := scopedCompanyID(c, c.Param("company_id"))
+	if !ok {
+		return
+	}
+	kind, ok := validEmailTemplateKind(c)
+	if !ok {
+		return
+	}
+	var input models.EmailTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+
+	tmpl := models.EmailTemplate{
+		CompanyID: companyID,
+		Kind:      kind,
+		Subject:   input.Subject,
+		HTMLBody:  input.HTMLBody,
+		TextBody:  input.TextBody,
+		UpdatedAt: time.Now(),
+	}
+	check := tmpl
+	if check.TextBody == "" {
+		check.TextBody = defaultEmailTemplates[kind].TextBody
+	}
+	if _, err := renderEmailTemplate(check, sampleInvoiceEmailData); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template", "details": err.Error()})
+		return
+	}
+
+	_, err := config.DB.Collection("email_templates").UpdateOne(context.Background(),
+		bson.M{"company_id": companyID, "kind": kind},
+		bson.M{
+			"$set":         bson.M{"subject": tmpl.Subject, "html_body": tmpl.HTMLBody, "text_body": tmpl.TextBody, "updated_at": tmpl.UpdatedAt},
+			"$setOnInsert": bson.M{"created_at": tmpl.UpdatedAt},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save email template"})
+		return
+	}
+	c.JSON(http.StatusOK, tmpl)
+}
+
+// ResetEmailTemplate godoc
+// @Summary Restore the default email template
+// @Tags Email Templates
+// @Produce json
+// @Param company_id path string true "Company ID"
+// @Param kind path string true "invoice or receipt"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "No active company in the token"
+// @Failure 500 {object} map[string]string
+// @Router /email-template/{company_id}/{kind} [delete]
+// @Security BearerAuth
+func ResetEmailTemplate(c *gin.Context) {
+	companyID, ok := scopedCompanyID(c, c.Param("company_id"))
+	if !ok {
+		return
+	}
+	kind, ok := validEmailTemplateKind(c)
+	if !ok {
+		return
+	}
+	_, err := config.DB.Collection("email_templates").DeleteOne(context.Background(), bson.M{"company_id": companyID, "kind": kind})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset email template"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Email template reset to default"})
+}
+
+// PreviewEmailTemplate godoc
+// @Summary Preview an email template with sample data
+// @Description Renders the given template, or the saved one when no body is sent, against sample invoice data
+// @Tags Email Templates
+// @Accept json
+// @Produce json
+// @Param company_id path string true "Company ID"
+// @Param kind path string true "invoice or receipt"
+// @Param template body models.EmailTemplateInput false "Unsaved template to preview"
+// @Success 200 {object} models.EmailPreview
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "No active company in the token"
+// @Failure 500 {object} map[string]string
+// @Router /email-template/{company_id}/{kind}/preview [post]
+// @Security BearerAuth
+func PreviewEmailTemplate(c *gin.Context) {
+	companyID, ok := scopedCompanyID(c, c.Param("company_id"))
+	if !ok {
+		return
+	}
+	kind, ok := validEmailTemplateKind(c)
+	if !ok {
+		return
+	}
+
+	var tmpl models.EmailTemplate
+	var input models.EmailTemplateInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+			return
+		}
+		tmpl = models.EmailTemplate{Kind: kind, Subject: input.Subject, HTMLBody: input.HTMLBody, TextBody: input.TextBody}
+		if tmpl.TextBody == "" {
+			tmpl.TextBody = defaultEmailTemplates[kind].TextBody
+		}
+	} else {
+		var err error
+		if tmpl, err = loadEmailTemplate(companyID, kind); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch email template"})
+			return
+		}
+	}
+
+	preview, err := renderEmailTemplate(tmpl, sampleInvoiceEmailData)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+// PreviewInvoiceEmail godoc
+// @Summary Preview the email for an invoice
+// @Description Renders the invoice or receipt email exactly as SendInvoice would queue it, without sending it
+// @Tags Invoices
+// @Produce json
+// @Param id path string true "Invoice ID"
+// @Success 200 {object} models.EmailPreview
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /invoice/email-preview/{id} [get]
+func PreviewInvoiceEmail(c *gin.Context) {
+	invoice, customer, ok := fetchInvoiceAndCustomer(c)
+	if !ok {
+		return
+	}
+	_, preview, err := composeInvoiceEmail(invoice, customer)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render email", "details": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
@@ -2,7 +2,10 @@ package controllers
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/bisre1921/billing-and-invoice-system/config"
@@ -10,20 +13,22 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"fmt"
 	"os"
-	"net/smtp"
 )
 
 // AddEmployee godoc
 // @Summary Add a new employee
-// @Description Business Owner adds a new employee
+// @Description Business Owner adds a new employee to the active company of the token and emails them an invitation. They join the company once they accept it with /employee/invitation/accept.
 // @Tags Employee
 // @Accept json
 // @Produce json
 // @Param employee body models.Employee true "Employee Data"
 // @Success 201 {object} models.GenericResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "Not the company owner"
 // @Failure 500 {object} models.ErrorResponse
 // @Router /employee/add [post]
 // @Security BearerAuth
@@ -35,11 +40,14 @@ func AddEmployee(c *gin.Context) {
 		return
 	}
 
-	// Validate Company ID
-	if invitationRequest.CompanyID.IsZero() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "CompanyID is required"})
+	if _, ok := requireOwner(c, "add employees"); !ok {
 		return
 	}
+	companyID, ok := scopedCompanyObjectID(c, invitationRequest.CompanyID)
+	if !ok {
+		return
+	}
+	invitationRequest.CompanyID = companyID
 
 	// Construct Employee struct
 	employee := models.Employee{
@@ -54,14 +62,18 @@ func AddEmployee(c *gin.Context) {
 	}
 
 	// Insert Invitation
-	token := invitationRequest.CompanyID.Hex()
+	token, err := newInvitationToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
+		return
+	}
 	invitation := models.Invitation{
 		Token:     token,
 		Email:     invitationRequest.Email,
 		CompanyID: invitationRequest.CompanyID,
 		CreatedAt: time.Now(),
 	}
-	_, err := config.DB.Collection("invitations").InsertOne(context.Background(), invitation)
+	_, err = config.DB.Collection("invitations").InsertOne(context.Background(), invitation)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invitation"})
 		return
@@ -87,11 +99,6 @@ func AddEmployee(c *gin.Context) {
 		Best regards,
 	`, company.Name, invitationLink)
 
-	err = sendEmail(invitationRequest.Email, subject, body)
-	if err != nil {
-		fmt.Println("Error sending invitation email", err)
-	}
-
 	// Save employee
 	result, err := config.DB.Collection("employees").InsertOne(context.Background(), employee)
 	if err != nil {
@@ -99,12 +106,102 @@ func AddEmployee(c *gin.Context) {
 		return
 	}
 
+	_, err = enqueueMail(models.OutboxMessage{
+		CompanyID: invitationRequest.CompanyID.Hex(),
+		Category:  "invitation",
+		FromName:  company.Name,
+		ReplyTo:   company.Email,
+		To:        []string{invitationRequest.Email},
+		Subject:   subject,
+		TextBody:  body,
+	})
+	if err != nil {
+		fmt.Println("Error queueing invitation email", err)
+	}
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Employee added successfully, invitation sent successfully",
 		"id":      result.InsertedID,
 	})
 }
 
+// newInvitationToken returns a random token for an invitation link, which
+// only the invited email receives.
+func newInvitationToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// AcceptInvitation godoc
+// @Summary Accept an employee invitation
+// @Description Adds the inviting company to the signed-in user's companies. The invitation must have been sent to the user's email and not accepted before; afterwards the user can switch to the company with /auth/switch-company.
+// @Tags Employee
+// @Accept json
+// @Produce json
+// @Param invitation body models.AcceptInvitationRequest true "Invitation token from the email"
+// @Success 200 {object} models.GenericResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse "Invitation sent to another email"
+// @Failure 404 {object} models.ErrorResponse "Invitation not found or already accepted"
+// @Failure 500 {object} models.ErrorResponse
+// @Router /employee/invitation/accept [post]
+// @Security BearerAuth
+func AcceptInvitation(c *gin.Context) {
+	var req models.AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(c.GetString("userID"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication token is required."})
+		return
+	}
+	var user models.User
+	if err := config.DB.Collection("users").FindOne(context.Background(), bson.M{"_id": userID}).Decode(&user); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return
+	}
+
+	filter := bson.M{"token": req.Token, "accepted_at": bson.M{"$exists": false}}
+	var invitation models.Invitation
+	err = config.DB.Collection("invitations").FindOne(context.Background(), filter).Decode(&invitation)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found or already accepted"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitation"})
+		return
+	}
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This invitation was sent to another email"})
+		return
+	}
+
+	// Accepting is claimed first so a token cannot be used twice
+	result, err := config.DB.Collection("invitations").UpdateOne(context.Background(),
+		bson.M{"_id": invitation.ID, "accepted_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"accepted_at": time.Now(), "user_id": userID}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found or already accepted"})
+		return
+	}
+	addUserCompany(bson.M{"_id": userID}, invitation.CompanyID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Invitation accepted",
+		"id":      invitation.CompanyID.Hex(),
+	})
+}
 
 func fetchCompanyByID(companyID primitive.ObjectID) (models.Company, error) {
 	var company models.Company
@@ -112,18 +209,6 @@ func fetchCompanyByID(companyID primitive.ObjectID) (models.Company, error) {
 	return company, err
 }
 
-func sendEmail(to, subject, body string) error {
-	from := os.Getenv("EMAIL_USERNAME")
-	password := os.Getenv("EMAIL_PASSWORD")
-	host := os.Getenv("EMAIL_HOST")
-	port := os.Getenv("EMAIL_PORT")
-
-	auth := smtp.PlainAuth("", from, password, host)
-	message := []byte(fmt.Sprintf("To: %s\r\nSubject: %s\r\n\r\n%s", to, subject, body))
-
-	return smtp.SendMail(fmt.Sprintf("%s:%s", host, port), auth, from, []string{to}, message)
-}
-
 // DeleteEmployee godoc
 // @Summary Delete an employee
 // @Description Business Owner deletes an employee by ID
@@ -133,6 +218,7 @@ func sendEmail(to, subject, body string) error {
 // @Param id path string true "Employee ID"
 // @Success 200 {object} models.GenericResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
 // @Failure 500 {object} models.ErrorResponse
 // @Router /employee/delete/{id} [delete]
 // @Security BearerAuth
@@ -143,18 +229,23 @@ func DeleteEmployee(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid employee ID"})
 		return
 	}
-
-	filter := bson.M{"_id": objID}
-	res, err := config.DB.Collection("employees").DeleteOne(context.Background(), filter)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete employee"})
+	companyID, ok := ownerCompanyObjectID(c, "remove employees")
+	if !ok {
 		return
 	}
 
-	if res.DeletedCount == 0 {
+	filter := bson.M{"_id": objID, "company_id": companyID}
+	var employee models.Employee
+	err = config.DB.Collection("employees").FindOneAndDelete(context.Background(), filter).Decode(&employee)
+	if err == mongo.ErrNoDocuments {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Employee not found"})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete employee"})
+		return
+	}
+	removeUserCompany(bson.M{"email": employee.Email}, employee.CompanyID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Employee deleted successfully",
@@ -168,16 +259,16 @@ func DeleteEmployee(c *gin.Context) {
 // @Tags Employee
 // @Accept json
 // @Produce json
-// @Param company_id query string true "Company ID"
+// @Param company_id query string false "Company ID, defaults to the token's active company"
 // @Success 200 {array} models.Employee
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
 // @Failure 500 {object} models.ErrorResponse
 // @Router /employee/all [get]
 // @Security BearerAuth
 func GetAllEmployees(c *gin.Context) {
-	companyIDParam := c.Query("company_id")
-	if companyIDParam == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Company ID is required"})
+	companyIDParam, ok := scopedCompanyID(c, c.Query("company_id"))
+	if !ok {
 		return
 	}
 
@@ -213,6 +304,7 @@ func GetAllEmployees(c *gin.Context) {
 // @Param id path string true "Employee ID"
 // @Success 200 {object} models.Employee
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
 // @Failure 500 {object} models.ErrorResponse
 // @Router /employee/{id} [get]
 // @Security BearerAuth
@@ -231,6 +323,9 @@ func GetEmployee(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch employee"})
 		return
 	}
+	if _, ok := scopedCompanyID(c, employee.CompanyID.Hex()); !ok {
+		return
+	}
 
 	c.JSON(http.StatusOK, employee)
 }
@@ -245,6 +340,7 @@ func GetEmployee(c *gin.Context) {
 // @Param employee body models.Employee true "Employee Data"
 // @Success 200 {object} models.GenericResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
 // @Failure 500 {object} models.ErrorResponse
 // @Router /employee/update/{id} [put]
 // @Security BearerAuth
@@ -262,9 +358,14 @@ func UpdateEmployee(c *gin.Context) {
 		return
 	}
 
+	companyID, ok := ownerCompanyObjectID(c, "update employees")
+	if !ok {
+		return
+	}
+	employee.CompanyID = companyID
 	employee.UpdatedAt = time.Now()
 
-	filter := bson.M{"_id": objID}
+	filter := bson.M{"_id": objID, "company_id": companyID}
 	update := bson.M{"$set": employee}
 	result, err := config.DB.Collection("employees").UpdateOne(context.Background(), filter, update)
 	if err != nil {
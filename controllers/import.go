@@ -0,0 +1,269 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MaxImportRows limits the data rows of one imported file.
+const MaxImportRows = 50000
+
+// MaxImportFileSize limits the size in bytes of one imported file.
+const MaxImportFileSize = 10 << 20
+
+// importBatchSize is how many rows are written to the database at a time.
+const importBatchSize = 500
+
+var importFormats = []string{"csv", "xlsx"}
+
+// importSheet is an uploaded CSV or XLSX file read into rows of cells, with
+// columns looked up by their header rather than their position.
+type importSheet struct {
+	columns map[string]int
+	rows    [][]string
+}
+
+// normalizeImportHeader makes "Selling Price" and "selling-price" both match
+// the selling_price column.
+func normalizeImportHeader(header string) string {
+	header = strings.TrimPrefix(header, "\ufeff") // byte order mark of Excel CSVs
+	header = strings.ToLower(strings.TrimSpace(header))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(header)
+}
+
+// readImportSheet reads an uploaded file by its extension: CSV, or the first
+// sheet of an XLSX workbook. The first row holds the column headers; blank
+// rows come back nil so the others keep their line numbers.
+func readImportSheet(file *multipart.FileHeader) (importSheet, error) {
+	sheet := importSheet{columns: map[string]int{}}
+	if file.Size > MaxImportFileSize {
+		return sheet, fmt.Errorf("files can be at most %d MB", MaxImportFileSize>>20)
+	}
+	tooLong := fmt.Errorf("files can have at most %d rows", MaxImportRows)
+	src, err := file.Open()
+	if err != nil {
+		return sheet, fmt.Errorf("failed to open file")
+	}
+	defer src.Close()
+
+	var records [][]string
+	switch strings.ToLower(filepath.Ext(file.Filename)) {
+	case ".csv":
+		reader := csv.NewReader(src)
+		reader.FieldsPerRecord = -1 // short rows are checked column by column
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return sheet, fmt.Errorf("invalid CSV: %v", err)
+			}
+			if len(records) > MaxImportRows { // the header and MaxImportRows rows are read
+				return sheet, tooLong
+			}
+			records = append(records, record)
+		}
+	case ".xlsx":
+		workbook, err := excelize.OpenReader(src)
+		if err != nil {
+			return sheet, fmt.Errorf("invalid XLSX file: %v", err)
+		}
+		defer workbook.Close()
+		rows, err := workbook.Rows(workbook.GetSheetName(0))
+		if err != nil {
+			return sheet, fmt.Errorf("invalid XLSX file: %v", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			if len(records) > MaxImportRows {
+				return sheet, tooLong
+			}
+			// Raw values, as formatted numbers such as 1,500.00 would not parse
+			record, err := rows.Columns(excelize.Options{RawCellValue: true})
+			if err != nil {
+				return sheet, fmt.Errorf("invalid XLSX file: %v", err)
+			}
+			records = append(records, record)
+		}
+	default:
+		return sheet, fmt.Errorf("only %s files are allowed", strings.Join(importFormats, " and "))
+	}
+
+	if len(records) == 0 {
+		return sheet, fmt.Errorf("the file has no header row")
+	}
+	for i, header := range records[0] {
+		if name := normalizeImportHeader(header); name != "" {
+			if _, seen := sheet.columns[name]; !seen {
+				sheet.columns[name] = i
+			}
+		}
+	}
+	for _, record := range records[1:] {
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			record = nil
+		}
+		sheet.rows = append(sheet.rows, record)
+	}
+	return sheet, nil
+}
+
+// has reports whether any of the named columns is in the file.
+func (s importSheet) has(names ...string) bool {
+	for _, name := range names {
+		if _, ok := s.columns[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// cell returns the trimmed value of the first named column in the file, or ""
+// when the row is too short to have it.
+func (s importSheet) cell(row []string, names ...string) string {
+	for _, name := range names {
+		if i, ok := s.columns[name]; ok {
+			if i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+	}
+	return ""
+}
+
+// missing returns the required columns the file lacks; each entry lists
+// accepted names for one column.
+func (s importSheet) missing(required ...[]string) []string {
+	var missing []string
+	for _, names := range required {
+		if !s.has(names...) {
+			missing = append(missing, names[0])
+		}
+	}
+	return missing
+}
+
+// importLine is the line of the file a data row came from.
+func importLine(index int) int {
+	return index + 2
+}
+
+// optionalBoolForm reads a true/false form field that defaults to false,
+// writing the 400 response itself for any other value.
+func optionalBoolForm(c *gin.Context, name string) (bool, bool) {
+	value := c.PostForm(name)
+	if value == "" {
+		return false, true
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be true or false"})
+		return false, false
+	}
+	return parsed, true
+}
+
+// writeImportBatches writes the changes of an import importBatchSize at a
+// time, so large files do not become one huge request.
+func writeImportBatches(collection string, writes []mongo.WriteModel) error {
+	for start := 0; start < len(writes); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(writes) {
+			end = len(writes)
+		}
+		_, err := config.DB.Collection(collection).BulkWrite(context.Background(), writes[start:end], options.BulkWrite().SetOrdered(true))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveImportErrorReport keeps the row errors of an import so they can be
+// downloaded, returning the report ID, or "" when it could not be saved.
+func saveImportErrorReport(companyID primitive.ObjectID, kind, fileName string, rowErrors []models.ImportRowError) string {
+	if len(rowErrors) == 0 {
+		return ""
+	}
+	report := models.ImportErrorReport{
+		CompanyID: companyID,
+		Kind:      kind,
+		FileName:  fileName,
+		Errors:    rowErrors,
+		CreatedAt: time.Now(),
+	}
+	result, err := config.DB.Collection("import_reports").InsertOne(context.Background(), report)
+	if err != nil {
+		fmt.Println("Error saving import error report", err)
+		return ""
+	}
+	if id, ok := result.InsertedID.(primitive.ObjectID); ok {
+		return id.Hex()
+	}
+	return ""
+}
+
+// DownloadImportErrorReport godoc
+// @Summary Download the row errors of an import
+// @Description The errors of an item or customer import as CSV with the row, column, value and error of each, named by the error_report_id of the import result.
+// @Tags Import
+// @Produce text/csv
+// @Param id path string true "Error report ID"
+// @Success 200 {file} binary
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /import/errors/{id} [get]
+// @Security BearerAuth
+func DownloadImportErrorReport(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return
+	}
+	var report models.ImportErrorReport
+	if err := config.DB.Collection("import_reports").FindOne(context.Background(), bson.M{"_id": objID}).Decode(&report); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Error report not found"})
+		return
+	}
+	if _, ok := scopedCompanyID(c, report.CompanyID.Hex()); !ok {
+		return
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"row", "column", "value", "error"})
+	for _, rowError := range report.Errors {
+		w.Write([]string{strconv.Itoa(rowError.Row), rowError.Column, rowError.Value, rowError.Error})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write error report"})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+report.Kind+"_import_errors_"+report.ID.Hex()+".csv")
+	c.Data(http.StatusOK, "text/csv", buf.Bytes())
+}
@@ -2,28 +2,32 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/bisre1921/billing-and-invoice-system/config"
 	"github.com/bisre1921/billing-and-invoice-system/models"
 	"github.com/gin-gonic/gin"
-	"github.com/jung-kurt/gofpdf"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"gopkg.in/gomail.v2"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // GenerateInvoice godoc
 // @Summary Generate a new invoice
-// @Description Generate a new invoice for a customer with item list and auto-calculated total.
+// @Description Generate a new invoice for a customer with item list and auto-calculated total. The company's invoice prefix is prepended to the reference number, and credit invoices without a due date fall due by the payment terms named on the invoice, the customer's default terms or the company's default payment terms days, in that order. Terms can split the invoice into installments and offer an early-payment discount. With the company's auto_apply_credits setting on, the customer's unapplied credit pays credit invoices oldest credit first. Customers on credit hold, including those put on hold automatically for invoices overdue beyond the company's credit_hold_overdue_days, and credit invoices above the available credit need an approved credit override named as credit_override_id. billing_contact_id picks the customer contact the invoice is emailed and addressed to, defaulting to their primary billing contact. Lines naming an item_id that tracks stock take their quantity out of its stock, and the invoice is refused when there is not enough on hand.
 // @Tags Invoices
 // @Accept json
 // @Produce json
 // @Param invoice body models.Invoice true "Invoice data"
 // @Success 200 {object} map[string]interface{} "Invoice generated successfully"
 // @Failure 400 {object} map[string]string "Invalid invoice input"
+// @Failure 401 {object} map[string]string "No active company in the token"
+// @Failure 404 {object} map[string]string "Company not found"
 // @Failure 500 {object} map[string]string "Failed to generate invoice"
 // @Router /invoice/generate [post]
 func GenerateInvoice(c *gin.Context) {
@@ -33,25 +37,66 @@ func GenerateInvoice(c *gin.Context) {
 		return
 	}
 
-	var total float64 = 0
+	var subtotalSum, taxSum float64
 	for i, item := range invoice.Items {
 		// Validate discount is a float and between 0-100
 		if item.Discount < 0 || item.Discount > 100 {
 			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Discount for item %s must be a float between 0 and 100", item.ItemName)})
 			return
 		}
+		category, err := validateLineTax(item)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid tax for item %s: %v", item.ItemName, err)})
+			return
+		}
 		discountAmount := item.UnitPrice * float64(item.Discount) / 100
 		subtotal := float64(item.Quantity) * (item.UnitPrice - discountAmount)
 		invoice.Items[i].Subtotal = subtotal
-		total += subtotal
+		invoice.Items[i].TaxCategory = category
+		invoice.Items[i].TaxAmount = subtotal * item.TaxRate / 100
+		subtotalSum += subtotal
+		taxSum += invoice.Items[i].TaxAmount
+	}
+
+	if invoice.WithholdingRate < 0 || invoice.WithholdingRate > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Withholding rate must be between 0 and 100"})
+		return
+	}
+
+	var ok bool
+	if invoice.CompanyID, ok = requiredCompanyID(c, invoice.CompanyID); !ok {
+		return
+	}
+	companyID, err := primitive.ObjectIDFromHex(invoice.CompanyID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return
+	}
+	var company models.Company
+	if err := config.DB.Collection("companies").FindOne(context.Background(), activeCompanyFilter(companyID)).Decode(&company); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+	if prefix := company.Settings.InvoicePrefix; prefix != "" && !strings.HasPrefix(invoice.ReferenceNumber, prefix) {
+		invoice.ReferenceNumber = prefix + invoice.ReferenceNumber
 	}
 
+	total := subtotalSum + taxSum
+	invoice.Subtotal = subtotalSum
+	invoice.TaxAmount = taxSum
+	invoice.WithholdingAmount = subtotalSum * invoice.WithholdingRate / 100
+	invoice.DocumentType = "invoice"
 	invoice.Amount = total
 	invoice.CreatedAt = time.Now()
 	invoice.UpdatedAt = time.Now()
 	invoice.Date = time.Now()
 	invoice.Status = "Unpaid"
+	invoice.AmountPaid = 0
+	invoice.Installments = nil
+	invoice.EarlyPayment = nil
 
+	var customer models.Customer
+	var creditOverride *models.CreditRequest
 	if invoice.PaymentType == "credit" {
 		// Check if customer has enough credit
 		customerID, err := primitive.ObjectIDFromHex(invoice.CustomerID)
@@ -59,44 +104,180 @@ func GenerateInvoice(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
 			return
 		}
-		var customer models.Customer
-		err = config.DB.Collection("customers").FindOne(context.Background(), bson.M{"_id": customerID}).Decode(&customer)
+		err = config.DB.Collection("customers").FindOne(context.Background(), bson.M{"_id": customerID, "company_id": companyID}).Decode(&customer)
 		if err != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
 			return
 		}
-		if customer.CurrentCreditAvailable < total {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invoice amount exceeds customer's available credit"})
+		if !checkBillingContact(c, invoice, customer) {
+			return
+		}
+		// Terms named on the invoice, or else the customer's default ones,
+		// decide when it falls due unless a due date is given
+		termsID := customer.PaymentTermsID
+		if invoice.PaymentTermsID != "" {
+			if invoice.DueDate != nil && !invoice.DueDate.IsZero() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Give either payment_terms_id or due_date, not both"})
+				return
+			}
+			id, err := primitive.ObjectIDFromHex(invoice.PaymentTermsID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment terms ID"})
+				return
+			}
+			termsID = &id
+		}
+		if termsID != nil && (invoice.DueDate == nil || invoice.DueDate.IsZero()) {
+			terms, err := fetchCompanyPaymentTerms(*termsID, companyID)
+			if err == mongo.ErrNoDocuments {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Payment terms not found"})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payment terms"})
+				return
+			}
+			applyPaymentTerms(&invoice, terms, companyLocation(company.Settings))
+		}
+		// An approved override lets the invoice through despite a credit
+		// hold or the credit limit
+		override, ok := approvedCreditOverride(c, invoice, customer)
+		if !ok {
 			return
 		}
-		// Deduct the invoice amount from the customer's available credit
-		_, err = config.DB.Collection("customers").UpdateOne(
+		if override == nil {
+			if err := checkOverdueCreditHold(company, &customer, time.Now()); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check overdue invoices"})
+				return
+			}
+			if customer.CreditHold != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Customer is on credit hold: " + customer.CreditHold.Reason + ". Request a credit override to invoice them on credit"})
+				return
+			}
+			if customer.CurrentCreditAvailable < total {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invoice amount exceeds customer's available credit"})
+				return
+			}
+		} else {
+			claimed, err := claimCreditOverride(*override, invoice)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to use credit override"})
+				return
+			}
+			if !claimed {
+				c.JSON(http.StatusConflict, gin.H{"error": "Credit override has been used meanwhile"})
+				return
+			}
+		}
+		creditOverride = override
+		// Deduct the invoice amount from the customer's available credit, which
+		// must still cover it unless an override lets the invoice through
+		creditFilter := bson.M{"_id": customerID, "company_id": companyID}
+		if override == nil {
+			creditFilter["current_credit_available"] = bson.M{"$gte": total}
+		}
+		result, err := config.DB.Collection("customers").UpdateOne(
 			context.Background(),
-			bson.M{"_id": customerID},
-			bson.M{"$set": bson.M{"current_credit_available": customer.CurrentCreditAvailable - total}},
+			creditFilter,
+			bson.M{"$inc": bson.M{"current_credit_available": -total}},
 		)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update customer credit"})
+		if err != nil || result.MatchedCount == 0 {
+			if override != nil {
+				releaseCreditOverride(*override, invoice)
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update customer credit"})
+			} else {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invoice amount exceeds customer's available credit"})
+			}
 			return
 		}
 		// Set due date if not provided
 		if invoice.DueDate == nil || invoice.DueDate.IsZero() {
-			d := invoice.Date.Add(7 * 24 * time.Hour)
+			days := company.Settings.DefaultPaymentTermsDays
+			if days == 0 {
+				days = DefaultPaymentTermsDays
+			}
+			d := invoice.Date.AddDate(0, 0, days)
 			invoice.DueDate = &d
 		}
 	} else if invoice.PaymentType == "cash" {
+		if invoice.BillingContactID != "" {
+			customerID, err := primitive.ObjectIDFromHex(invoice.CustomerID)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
+				return
+			}
+			err = config.DB.Collection("customers").FindOne(context.Background(), bson.M{"_id": customerID, "company_id": companyID}).Decode(&customer)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
+				return
+			}
+			if !checkBillingContact(c, invoice, customer) {
+				return
+			}
+		}
 		invoice.Status = "Paid"
 		invoice.PaymentDate = time.Now()
+		invoice.AmountPaid = total
 		invoice.DueDate = nil
+		invoice.PaymentTermsID = ""
+		invoice.CreditOverrideID = ""
+	}
+
+	// Give back what an invoice that is not stored took
+	undo := func() {
+		releaseCustomerCredit(invoice)
+		if creditOverride != nil {
+			releaseCreditOverride(*creditOverride, invoice)
+		}
+	}
+
+	// Items that track stock must have the invoiced quantities on hand
+	short, err := takeInvoicedStock(companyID, invoice.Items)
+	if err != nil {
+		undo()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update item stock"})
+		return
+	}
+	if short != "" {
+		undo()
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Not enough stock of item %s", short)})
+		return
 	}
 
 	res, err := config.DB.Collection("invoices").InsertOne(context.Background(), invoice)
 	if err != nil {
+		undo()
+		restockReturnedItems(invoice.Items)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate invoice"})
 		return
 	}
 
 	invoice.ID = res.InsertedID.(primitive.ObjectID)
+	if creditOverride != nil {
+		recordCreditOverrideUse(*creditOverride, invoice, c.GetString("userID"))
+	}
+	if invoice.PaymentType == "cash" {
+		recordPayment(invoice, models.Payment{Amount: total, Method: "cash", Source: "payment", PaidAt: invoice.PaymentDate})
+	}
+
+	// Companies can have the customer's unapplied credit settle new invoices
+	if company.Settings.AutoApplyCredits && invoice.Status == "Unpaid" && customer.CreditBalance > 0 {
+		payment, err := applyCustomerCredits(invoice, nil, 0)
+		if err != nil {
+			fmt.Println("Error applying customer credit", err)
+		}
+		invoice.AmountPaid += payment.Applied
+		if payment.Paid {
+			invoice.Status = "Paid"
+			invoice.PaymentDate = time.Now()
+			if invoice.EarlyPayment != nil {
+				invoice.EarlyPayment.Applied = payment.Discount > 0
+			}
+		}
+	}
+	invalidateDashboardCache(invoice.CompanyID)
 	c.JSON(http.StatusOK, gin.H{"message": "Invoice generated successfully", "invoice": invoice})
 }
 
@@ -108,6 +289,8 @@ func GenerateInvoice(c *gin.Context) {
 // @Param id path string true "Invoice ID"
 // @Success 200 {object} map[string]interface{} "Invoice retrieved successfully"
 // @Failure 400 {object} map[string]string "Invalid invoice ID"
+// @Failure 401 {object} map[string]string "No active company in the token"
+// @Failure 403 {object} map[string]string "Invoice of another company"
 // @Failure 404 {object} map[string]string "Invoice not found"
 // @Router /invoice/{id} [get]
 func GetInvoice(c *gin.Context) {
@@ -124,6 +307,9 @@ func GetInvoice(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
 		return
 	}
+	if _, ok := scopedCompanyID(c, invoice.CompanyID); !ok {
+		return
+	}
 
 	c.JSON(http.StatusOK, invoice)
 }
@@ -136,11 +322,15 @@ func GetInvoice(c *gin.Context) {
 // @Param company_id path string true "Company ID"
 // @Success 200 {array} []models.Invoice "Invoices retrieved successfully"
 // @Failure 400 {object} map[string]string "Invalid company ID"
+// @Failure 401 {object} map[string]string "No active company in the token"
 // @Failure 404 {object} map[string]string "No invoices found for this company"
 // @Failure 500 {object} map[string]string "Failed to retrieve invoices"
 // @Router /invoice/companies/{company_id} [get]
 func GetInvoicesByCompanyID(c *gin.Context) {
-	companyID := c.Param("company_id")
+	companyID, ok := scopedCompanyID(c, c.Param("company_id"))
+	if !ok {
+		return
+	}
 
 	var invoices []models.Invoice
 	cursor, err := config.DB.Collection("invoices").Find(context.Background(), bson.M{"company_id": companyID})
@@ -164,105 +354,108 @@ func GetInvoicesByCompanyID(c *gin.Context) {
 
 // SendInvoice godoc
 // @Summary Send an invoice or receipt via email
-// @Description Sends either an invoice (if unpaid) or a receipt (if paid) to the customer via email.
+// @Description Queues either an invoice (if unpaid) or a receipt (if paid) for email delivery to the invoice's billing contact, or the customer's email when they have none, rendered from the company's email template with the PDF attached. Delivery status can be followed through the mail outbox.
 // @Tags Invoices
 // @Produce json
 // @Param id path string true "Invoice ID"
-// @Success 200 {object} map[string]string "Email sent successfully"
+// @Success 200 {object} map[string]string "Email queued"
 // @Failure 400 {object} map[string]string "Invalid ID or customer"
+// @Failure 403 {object} map[string]string "Invoice of another company"
 // @Failure 404 {object} map[string]string "Invoice or customer not found"
-// @Failure 500 {object} map[string]string "Failed to send email"
+// @Failure 500 {object} map[string]string "Failed to queue email"
 // @Router /invoice/send/{id} [post]
 func SendInvoice(c *gin.Context) {
-	invoiceID := c.Param("id")
-	objID, err := primitive.ObjectIDFromHex(invoiceID)
+	invoice, customer, ok := fetchInvoiceAndCustomer(c)
+	if !ok {
+		return
+	}
+
+	msg, preview, err := composeInvoiceEmail(invoice, customer)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render email", "details": err.Error()})
 		return
 	}
+	msg, err = enqueueMail(msg)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue email", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    fmt.Sprintf("%s queued for delivery to %s", preview.Subject, strings.Join(msg.To, ", ")),
+		"message_id": msg.ID.Hex(),
+	})
+}
 
+// fetchInvoiceAndCustomer loads the invoice named by the id path parameter and
+// its customer, writing the error response itself when either is missing.
+func fetchInvoiceAndCustomer(c *gin.Context) (models.Invoice, models.Customer, bool) {
 	var invoice models.Invoice
+	var customer models.Customer
+
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return invoice, customer, false
+	}
+
 	err = config.DB.Collection("invoices").FindOne(context.Background(), bson.M{"_id": objID}).Decode(&invoice)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
-		return
+		return invoice, customer, false
+	}
+	if _, ok := scopedCompanyID(c, invoice.CompanyID); !ok {
+		return invoice, customer, false
 	}
 
 	customerID, err := primitive.ObjectIDFromHex(invoice.CustomerID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid customer ID"})
-		return
+		return invoice, customer, false
 	}
-	var customer models.Customer
-	err = config.DB.Collection("customers").FindOne(context.Background(), bson.M{"_id": customerID}).Decode(&customer)
+	// Customers store their company as an ObjectID, so an invoice with a
+	// malformed company matches no customer
+	companyID, _ := primitive.ObjectIDFromHex(invoice.CompanyID)
+	err = config.DB.Collection("customers").FindOne(context.Background(), bson.M{"_id": customerID, "company_id": companyID}).Decode(&customer)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Customer not found"})
-		return
-	}
-
-	var subject string
-	var emailBody string
-
-	if invoice.Status == "Paid" {
-		subject = "Payment Receipt"
-		emailBody = fmt.Sprintf(
-			"Hello %s,\n\nThis is a receipt for your payment:\n\nReference: %s\nPayment Date: %s\nAmount Paid: $%.2f\n\nItems:\n",
-			customer.Name,
-			invoice.ReferenceNumber,
-			invoice.PaymentDate.Format("2006-01-02"),
-			invoice.Amount,
-		)
-		for _, item := range invoice.Items {
-			emailBody += fmt.Sprintf("- %s x%d @ %.2f (Discount: %.2f%%) → Subtotal: $%.2f\n",
-				item.ItemName, item.Quantity, item.UnitPrice, item.Discount, item.Subtotal)
-		}
-		emailBody += "\nThank you for your payment!\n"
-	} else {
-		subject = "Your Invoice"
-		emailBody = fmt.Sprintf(
-			"Hello %s,\n\nHere is your invoice:\n\nReference: %s\nDate: %s\nAmount: $%.2f\nDue Date: %s\n\nItems:\n",
-			customer.Name,
-			invoice.ReferenceNumber,
-			invoice.Date.Format("2006-01-02"),
-			invoice.Amount,
-			invoice.DueDate.Format("2006-01-02"),
-		)
-		for _, item := range invoice.Items {
-			emailBody += fmt.Sprintf("- %s x%d @ %.2f (Discount: %.2f%%) → Subtotal: $%.2f\n",
-				item.ItemName, item.Quantity, item.UnitPrice, item.Discount, item.Subtotal)
-		}
-		emailBody += "\nPlease make payment by the due date.\nThank you for your business!\n"
+		return invoice, customer, false
 	}
-
-	m := gomail.NewMessage()
-	m.SetHeader("From", "bisrattewodros3@gmail.com")
-	m.SetHeader("To", customer.Email)
-	m.SetHeader("Subject", subject)
-	m.SetBody("text/plain", emailBody)
-
-	d := gomail.NewDialer("smtp.gmail.com", 587, "bisrattewodros3@@gmail.com", "xtkd pntw wrfq rdak")
-
-	if err := d.DialAndSend(m); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send email", "details": err.Error()})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("%s sent successfully to %s", subject, customer.Email)})
+	return invoice, customer, true
 }
 
 // DownloadInvoice godoc
 // @Summary Download invoice or receipt as PDF
-// @Description Download a specific invoice or receipt by ID based on its status.
+// @Description Download a specific invoice or receipt by ID based on its status, in the requested layout or the company's default one.
 // @Tags Invoices
 // @Produce application/pdf
 // @Param id path string true "Invoice ID"
+// @Param layout query string false "classic_a4, modern_a4, letter or thermal_80mm"
+// @Param sign query boolean false "Digitally sign with the company certificate, defaults to the company's auto_sign setting"
+// @Param facturx query boolean false "Produce a Factur-X / ZUGFeRD PDF/A-3 with the EN 16931 CII invoice embedded"
 // @Success 200 {file} binary
-// @Failure 400 {object} map[string]string "Invalid ID format"
+// @Failure 400 {object} map[string]string "Invalid ID format or layout"
+// @Failure 401 {object} map[string]string "No active company in the token"
+// @Failure 403 {object} map[string]string "Invoice of another company"
 // @Failure 404 {object} map[string]string "Invoice not found"
+// @Failure 422 {object} map[string]interface{} "Invoice cannot be expressed as a valid EN 16931 document"
 // @Failure 500 {object} map[string]string "Failed to generate or send PDF"
 // @Router /invoice/download/{id} [get]
 func DownloadInvoice(c *gin.Context) {
 	invoiceID := c.Param("id")
+	layout := c.Query("layout")
+	if _, ok := findInvoiceLayout(layout); layout != "" && !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown layout: " + layout})
+		return
+	}
+	sign, ok := optionalBoolQuery(c, "sign")
+	if !ok {
+		return
+	}
+	facturX, ok := optionalBoolQuery(c, "facturx")
+	if !ok {
+		return
+	}
 
 	objID, err := primitive.ObjectIDFromHex(invoiceID)
 	if err != nil {
@@ -276,142 +469,90 @@ func DownloadInvoice(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
 		return
 	}
-
-	pdf := gofpdf.New("P", "mm", "A4", "")
-	pdf.AddPage()
-
-	pdf.SetFont("Arial", "B", 20)
-	companyName := "Your Company Name"
-	pdf.Cell(0, 15, companyName)
-	pdf.Ln(10)
-
-	pdf.SetFont("Arial", "", 10)
-	companyAddress := "Your Company Address"
-	pdf.Cell(0, 5, companyAddress)
-	pdf.Ln(5)
-	pdf.Cell(0, 5, "Email: your_company_email@example.com")
-	pdf.Ln(8)
-
-	if invoice.Status == "Paid" {
-		generateReceiptPDFContent(pdf, &invoice)
-		c.Header("Content-Disposition", "attachment; filename=receipt_"+invoice.ReferenceNumber+".pdf")
-	} else {
-		generateInvoicePDFContent(pdf, &invoice)
-		c.Header("Content-Disposition", "attachment; filename=invoice_"+invoice.ReferenceNumber+".pdf")
+	if _, ok := scopedCompanyID(c, invoice.CompanyID); !ok {
+		return
 	}
 
-	c.Header("Content-Type", "application/pdf")
+	company, customer := fetchInvoiceParties(invoice)
+	if sign == nil {
+		autoSign := company.Signing != nil && company.Signing.AutoSign
+		sign = &autoSign
+	} else if *sign && company.Signing == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The company has no signing certificate"})
+		return
+	}
 
-	err = pdf.Output(c.Writer)
+	data, filename, err := renderInvoicePDF(&invoice, company, customer, layout)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate PDF"})
 		return
 	}
+	if facturX != nil && *facturX {
+		var violations []string
+		data, violations, err = renderFacturXInvoice(data, invoice, company, customer)
+		if len(violations) > 0 {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "The invoice is missing data required for a Factur-X e-invoice", "violations": violations})
+			return
+		}
+		if err != nil {
+			fmt.Println("Error embedding Factur-X invoice", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate Factur-X PDF"})
+			return
+		}
+	}
+	// Signing comes last so the signature covers the embedded invoice too
+	if *sign {
+		if data, err = signCompanyPDF(data, company, time.Now()); err != nil {
+			fmt.Println("Error signing invoice PDF", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign PDF: " + err.Error()})
+			return
+		}
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(http.StatusOK, "application/pdf", data)
 }
 
-func generateInvoicePDFContent(pdf *gofpdf.Fpdf, invoice *models.Invoice) {
-	pdf.SetFont("Arial", "B", 16)
-	pdf.Cell(40, 10, "INVOICE")
-	pdf.Ln(10)
-
-	pdf.SetFont("Arial", "", 12)
-	pdf.Cell(40, 10, fmt.Sprintf("Invoice ID: %s", invoice.ID.Hex()))
-	pdf.Ln(6)
-	pdf.Cell(40, 10, fmt.Sprintf("Reference #: %s", invoice.ReferenceNumber))
-	pdf.Ln(6)
-	pdf.Cell(40, 10, fmt.Sprintf("Date: %s", invoice.Date.Format("2006-01-02")))
-	pdf.Ln(6)
-	pdf.Cell(40, 10, fmt.Sprintf("Due Date: %s", invoice.DueDate.Format("2006-01-02")))
-	pdf.Ln(10)
-
-	pdf.SetFont("Arial", "B", 12)
-	pdf.Cell(70, 8, "Item")
-	pdf.Cell(20, 8, "Qty")
-	pdf.Cell(30, 8, "Unit Price")
-	pdf.Cell(30, 8, "Discount")
-	pdf.Cell(40, 8, "Subtotal")
-	pdf.Ln(8)
-	pdf.SetLineWidth(0.2)
-	pdf.Line(10, pdf.GetY(), 200, pdf.GetY())
-	pdf.Ln(1)
-	pdf.SetFont("Arial", "", 12)
-
-	for _, item := range invoice.Items {
-		pdf.Cell(70, 8, item.ItemName)
-		pdf.Cell(20, 8, fmt.Sprintf("%d", item.Quantity))
-		pdf.Cell(30, 8, fmt.Sprintf("$%.2f", item.UnitPrice))
-		pdf.Cell(30, 8, fmt.Sprintf("%.0f%%", item.Discount))
-		pdf.Cell(40, 8, fmt.Sprintf("$%.2f", item.Subtotal))
-		pdf.Ln(8)
-	}
-
-	pdf.SetFont("Arial", "B", 12)
-	pdf.Ln(5)
-	pdf.Cell(160, 8, "Total Amount:")
-	pdf.SetFont("Arial", "", 12)
-	pdf.Cell(40, 8, fmt.Sprintf("$%.2f", invoice.Amount))
-	pdf.Ln(10)
-
-	pdf.SetFont("Arial", "", 10)
-	pdf.Cell(0, 5, "Thank you for your business!")
+// optionalBoolQuery reads a true/false query parameter, returning nil when it
+// is absent. It writes the 400 response itself for any other value.
+func optionalBoolQuery(c *gin.Context, name string) (*bool, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": name + " must be true or false"})
+		return nil, false
+	}
+	return &parsed, true
 }
 
-func generateReceiptPDFContent(pdf *gofpdf.Fpdf, invoice *models.Invoice) {
-	pdf.SetFont("Arial", "B", 16)
-	pdf.Cell(40, 10, "PAYMENT RECEIPT")
-	pdf.Ln(10)
-
-	pdf.SetFont("Arial", "", 12)
-	pdf.Cell(40, 10, fmt.Sprintf("Receipt ID: %s", invoice.ID.Hex()))
-	pdf.Ln(6)
-	pdf.Cell(40, 10, fmt.Sprintf("Reference #: %s", invoice.ReferenceNumber))
-	pdf.Ln(6)
-	pdf.Cell(40, 10, fmt.Sprintf("Payment Date: %s", invoice.PaymentDate.Format("2006-01-02")))
-	pdf.Ln(10)
-
-	pdf.SetFont("Arial", "B", 12)
-	pdf.Cell(70, 8, "Item")
-	pdf.Cell(20, 8, "Qty")
-	pdf.Cell(30, 8, "Unit Price")
-	pdf.Cell(30, 8, "Discount")
-	pdf.Cell(40, 8, "Subtotal")
-	pdf.Ln(8)
-	pdf.SetLineWidth(0.2)
-	pdf.Line(10, pdf.GetY(), 200, pdf.GetY())
-	pdf.Ln(1)
-	pdf.SetFont("Arial", "", 12)
-
-	for _, item := range invoice.Items {
-		pdf.Cell(70, 8, item.ItemName)
-		pdf.Cell(20, 8, fmt.Sprintf("%d", item.Quantity))
-		pdf.Cell(30, 8, fmt.Sprintf("$%.2f", item.UnitPrice))
-		pdf.Cell(30, 8, fmt.Sprintf("%.0f%%", item.Discount))
-		pdf.Cell(40, 8, fmt.Sprintf("$%.2f", item.Subtotal))
-		pdf.Ln(8)
-	}
-
-	pdf.SetFont("Arial", "B", 12)
-	pdf.Ln(5)
-	pdf.Cell(160, 8, "Total Amount Paid:")
-	pdf.SetFont("Arial", "", 12)
-	pdf.Cell(40, 8, fmt.Sprintf("$%.2f", invoice.Amount))
-	pdf.Ln(10)
-
-	pdf.SetFont("Arial", "", 10)
-	pdf.Cell(0, 5, "Payment Received. Thank you!")
+// ListInvoiceLayouts godoc
+// @Summary List invoice PDF layouts
+// @Description Layouts accepted by the layout parameter of the invoice download and by company branding
+// @Tags Invoices
+// @Produce json
+// @Success 200 {array} InvoiceLayout
+// @Router /invoice/layouts [get]
+func ListInvoiceLayouts(c *gin.Context) {
+	c.JSON(http.StatusOK, invoiceLayouts)
 }
 
 // MarkInvoiceAsPaid godoc
-// @Summary Mark an invoice as paid
-// @Description Update the status of a specific invoice to "Paid" and optionally set the payment date.
+// @Summary Record a payment of an invoice
+// @Description Records a payment of the invoice on the payment date, today by default. Without an amount the whole balance is paid and the invoice is marked "Paid". A smaller amount is recorded as a part payment; anything beyond the balance is kept as credit of the customer. Invoices settled by their early-payment discount deadline get the discount.
 // @Tags Invoices
 // @Accept json
 // @Produce json
 // @Param id path string true "Invoice ID"
-// @Param body body models.UpdatePaymentStatusRequest true "Optional payment_date"
-// @Success 200 {object} map[string]string "Invoice marked as paid successfully"
-// @Failure 400 {object} map[string]string "Invalid invoice ID or input"
+// @Param body body models.UpdatePaymentStatusRequest true "Optional payment_date, amount, method and reference"
+// @Success 200 {object} map[string]interface{} "Payment recorded"
+// @Failure 400 {object} map[string]string "Invalid invoice ID or input, or invoice already paid"
+// @Failure 401 {object} map[string]string "No active company in the token"
+// @Failure 403 {object} map[string]string "Invoice of another company"
 // @Failure 404 {object} map[string]string "Invoice not found"
+// @Failure 409 {object} map[string]string "Invoice paid or written off meanwhile"
 // @Failure 500 {object} map[string]string "Failed to update invoice status"
 // @Router /invoice/mark-as-paid/{id} [put]
 func MarkInvoiceAsPaid(c *gin.Context) {
@@ -435,48 +576,55 @@ func MarkInvoiceAsPaid(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
 		return
 	}
-
-	update := bson.M{"status": "Paid"}
-	if !updateRequest.PaymentDate.IsZero() {
-		update["payment_date"] = updateRequest.PaymentDate
-	} else {
-		update["payment_date"] = time.Now()
+	if _, ok := scopedCompanyID(c, invoice.CompanyID); !ok {
+		return
+	}
+	if invoice.Status == "Paid" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invoice is already paid"})
+		return
+	}
+	if invoice.Status == "WrittenOff" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invoice has been written off"})
+		return
 	}
 
-	result, err := config.DB.Collection("invoices").UpdateOne(
-		context.Background(),
-		bson.M{"_id": objID},
-		bson.M{"$set": update},
-	)
+	paidAt := updateRequest.PaymentDate
+	if paidAt.IsZero() {
+		paidAt = time.Now()
+	}
+	payment, err := recordInvoicePayment(invoice, round2(updateRequest.Amount), models.Payment{
+		PaidAt:    paidAt,
+		Method:    updateRequest.Method,
+		Source:    "payment",
+		Reference: updateRequest.Reference,
+	})
+	if errors.Is(err, errInvoiceSettled) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Invoice has been paid or written off meanwhile"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update invoice status"})
 		return
 	}
 
-	if result.ModifiedCount == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
-		return
+	response := gin.H{
+		"message":          "Invoice marked as paid successfully",
+		"discount_applied": payment.Discount,
+		"amount_paid":      payment.Applied,
+		"balance":          round2(invoiceBalance(invoice) - payment.Applied - payment.Discount),
 	}
-
-	// If it was a credit payment and not already paid, restore the customer's available credit
-	if invoice.PaymentType == "credit" && invoice.Status != "Paid" {
-		customerID, err := primitive.ObjectIDFromHex(invoice.CustomerID)
-		if err == nil {
-			var customer models.Customer
-			err = config.DB.Collection("customers").FindOne(context.Background(), bson.M{"_id": customerID}).Decode(&customer)
-			if err == nil {
-				_, err = config.DB.Collection("customers").UpdateOne(
-					context.Background(),
-					bson.M{"_id": customerID},
-					bson.M{"$set": bson.M{"current_credit_available": customer.CurrentCreditAvailable + invoice.Amount}},
-				)
-				// If error, just log, don't fail the operation
-				if err != nil {
-					fmt.Printf("Error restoring customer credit: %v\n", err)
-				}
-			}
+	if !payment.Paid {
+		response["message"] = "Part payment recorded successfully"
+	}
+	if payment.Excess > 0 {
+		credit, err := addOverpaymentCredit(invoice, payment.Excess, updateRequest.Method, updateRequest.Reference)
+		if err != nil {
+			fmt.Println("Error recording overpayment credit", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invoice paid but the overpayment could not be kept as credit"})
+			return
 		}
+		response["credit_id"] = credit.ID.Hex()
+		response["credit_added"] = payment.Excess
 	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Invoice marked as paid successfully"})
+	c.JSON(http.StatusOK, response)
 }
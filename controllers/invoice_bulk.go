@@ -0,0 +1,248 @@
+package controllers
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BulkDownloadWorkers is how many PDFs a bulk download renders at once. At
+// most twice as many rendered documents are held in memory while they wait
+// their turn in the archive.
+var BulkDownloadWorkers = runtime.NumCPU()
+
+var zipNameReplacer = strings.NewReplacer("/", "-", "\\", "-", ":", "-")
+
+type bulkInvoiceResult struct {
+	filename string
+	data     []byte
+	err      error
+}
+
+// bulkInvoiceJob is one invoice of a bulk download. The result channel is
+// buffered so workers never wait for the archive writer.
+type bulkInvoiceJob struct {
+	invoice  models.Invoice
+	customer models.Customer
+	result   chan bulkInvoiceResult
+}
+
+// DownloadInvoicesZip godoc
+// @Summary Download a company's invoices as a ZIP
+// @Description Streams a ZIP with the invoice or receipt PDF of every invoice of the company dated in the period, optionally limited to some statuses, plus an index.csv listing them. The PDFs are signed when the company signs invoices automatically. Invoices that fail to render or sign are listed in the index with the error.
+// @Tags Invoices
+// @Produce application/zip
+// @Param company_id path string true "Company ID"
+// @Param date_range query string true "today, last_7_days, last_month, last_3_months or custom"
+// @Param custom_start query string false "Start date for custom ranges (YYYY-MM-DD)"
+// @Param custom_end query string false "End date for custom ranges (YYYY-MM-DD)"
+// @Param status query []string false "Only invoices with these statuses, e.g. Paid" collectionFormat(multi)
+// @Param layout query string false "classic_a4, modern_a4, letter or thermal_80mm"
+// @Success 200 {file} binary
+// @Failure 400 {object} map[string]string "Invalid company ID, period or layout"
+// @Failure 401 {object} map[string]string "No active company in the token"
+// @Failure 404 {object} map[string]string "Company or invoices not found"
+// @Failure 500 {object} map[string]string "Failed to retrieve invoices"
+// @Router /invoice/companies/{company_id}/download [get]
+func DownloadInvoicesZip(c *gin.Context) {
+	scoped, ok := scopedCompanyID(c, c.Param("company_id"))
+	if !ok {
+		return
+	}
+	companyID, err := primitive.ObjectIDFromHex(scoped)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return
+	}
+	layout := c.Query("layout")
+	if _, ok := findInvoiceLayout(layout); layout != "" && !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown layout: " + layout})
+		return
+	}
+
+	company, err := fetchCompanyByID(companyID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Company not found"})
+		return
+	}
+
+	// The period is in the company's timezone
+	var customStart, customEnd *string
+	if value, ok := c.GetQuery("custom_start"); ok {
+		customStart = &value
+	}
+	if value, ok := c.GetQuery("custom_end"); ok {
+		customEnd = &value
+	}
+	start, end, err := resolveDateRange(c.Query("date_range"), customStart, customEnd, company.Settings)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	loc := companyLocation(company.Settings)
+
+	filter := bson.M{
+		"company_id": companyID.Hex(),
+		"date":       bson.M{"$gte": start, "$lt": end},
+	}
+	if statuses := c.QueryArray("status"); len(statuses) > 0 {
+		filter["status"] = bson.M{"$in": statuses}
+	}
+	findOptions := options.Find().SetSort(bson.D{{Key: "date", Value: 1}, {Key: "_id", Value: 1}}).SetBatchSize(100)
+	cursor, err := config.DB.Collection("invoices").Find(context.Background(), filter, findOptions)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve invoices"})
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	// Look at the first invoice before committing to a 200 response
+	if !cursor.Next(context.Background()) {
+		if cursor.Err() != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve invoices"})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "No invoices found for this period"})
+		return
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	workers := BulkDownloadWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan *bulkInvoiceJob)
+	ordered := make(chan *bulkInvoiceJob, workers)
+
+	autoSign := company.Signing != nil && company.Signing.AutoSign
+	for i := 0; i < workers; i++ {
+		go func() {
+			for job := range jobs {
+				data, filename, err := renderInvoicePDF(&job.invoice, company, job.customer, layout)
+				if err == nil && autoSign {
+					data, err = signCompanyPDF(data, company, time.Now())
+				}
+				job.result <- bulkInvoiceResult{filename: filename, data: data, err: err}
+			}
+		}()
+	}
+
+	// The cursor and customer lookups stay on this one goroutine
+	go func() {
+		defer close(ordered)
+		defer close(jobs)
+		customers := map[string]models.Customer{}
+		for more := true; more; more = cursor.Next(context.Background()) {
+			var invoice models.Invoice
+			if err := cursor.Decode(&invoice); err != nil {
+				log.Printf("Error decoding invoice %v for bulk download: %v", cursor.Current.Lookup("_id"), err)
+				// Listed in the index with the error so it is not missing silently
+				var unreadable models.Invoice
+				unreadable.ReferenceNumber, _ = cursor.Current.Lookup("reference_number").StringValueOK()
+				if date, ok := cursor.Current.Lookup("date").DateTimeOK(); ok {
+					unreadable.Date = time.UnixMilli(date)
+				}
+				job := &bulkInvoiceJob{invoice: unreadable, result: make(chan bulkInvoiceResult, 1)}
+				job.result <- bulkInvoiceResult{err: fmt.Errorf("invoice could not be read: %v", err)}
+				select {
+				case ordered <- job:
+				case <-ctx.Done():
+					return
+				}
+				continue
+			}
+			customer, ok := customers[invoice.CustomerID]
+			if !ok {
+				customer = lookupCustomers([]string{invoice.CustomerID})[invoice.CustomerID]
+				customers[invoice.CustomerID] = customer
+			}
+			job := &bulkInvoiceJob{invoice: invoice, customer: customer, result: make(chan bulkInvoiceResult, 1)}
+			select {
+			case ordered <- job:
+			case <-ctx.Done():
+				return
+			}
+			jobs <- job
+		}
+		if err := cursor.Err(); err != nil {
+			log.Printf("Error reading invoices for bulk download: %v", err)
+		}
+	}()
+
+	filename := fmt.Sprintf("invoices_%s_%s_%s.zip", zipNameReplacer.Replace(company.Name), start.Format("20060102"), end.Add(-time.Second).Format("20060102"))
+	c.Header("Content-Disposition", "attachment; filename="+strings.ReplaceAll(filename, " ", "_"))
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+
+	archive := zip.NewWriter(c.Writer)
+	var index [][]string
+	used := map[string]int{}
+	for job := range ordered {
+		result := <-job.result
+		if ctx.Err() != nil {
+			continue // drain so the producer and workers can finish
+		}
+		invoice := job.invoice
+		row := []string{"", invoice.ReferenceNumber, "", job.customer.Name, invoice.Status,
+			invoice.DocumentType, fmt.Sprintf("%.2f", invoice.Amount), ""}
+		if !invoice.Date.IsZero() {
+			row[2] = invoice.Date.In(loc).Format("2006-01-02")
+		}
+		if invoice.DueDate != nil {
+			row[7] = invoice.DueDate.In(loc).Format("2006-01-02")
+		}
+		if result.err != nil {
+			log.Printf("Error rendering invoice %s for bulk download: %v", invoice.ID.Hex(), result.err)
+			index = append(index, append(row, result.err.Error()))
+			continue
+		}
+
+		name := zipNameReplacer.Replace(result.filename)
+		if used[name]++; used[name] > 1 {
+			name = fmt.Sprintf("%s_%d.pdf", strings.TrimSuffix(name, ".pdf"), used[name])
+		}
+		row[0] = name
+		index = append(index, append(row, ""))
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: invoice.Date.In(loc)})
+		if err == nil {
+			_, err = w.Write(result.data)
+		}
+		if err != nil {
+			log.Printf("Error writing bulk download: %v", err)
+			cancel()
+			continue
+		}
+		c.Writer.Flush()
+	}
+	if ctx.Err() != nil {
+		return
+	}
+
+	w, err := archive.Create("index.csv")
+	if err == nil {
+		csvWriter := csv.NewWriter(w)
+		csvWriter.Write([]string{"file", "reference_number", "date", "customer", "status", "document_type", "amount", "due_date", "error"})
+		csvWriter.WriteAll(index)
+		err = csvWriter.Error()
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		log.Printf("Error writing bulk download: %v", err)
+	}
+}
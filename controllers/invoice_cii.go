@@ -0,0 +1,284 @@
+package controllers
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// Factur-X 1.0 / ZUGFeRD 2 EN 16931 profile
+const (
+	facturXGuideline = "urn:cen.eu:en16931:2017"
+
+	ciiRSMNamespace = "urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100"
+	ciiRAMNamespace = "urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100"
+	ciiUDTNamespace = "urn:un:unece:uncefact:data:standard:UnqualifiedDataType:100"
+)
+
+type ciiDate struct {
+	DateTimeString struct {
+		Format string `xml:"format,attr"`
+		Value  string `xml:",chardata"`
+	} `xml:"udt:DateTimeString"`
+}
+
+func newCIIDate(isoDate string) *ciiDate {
+	date := &ciiDate{}
+	date.DateTimeString.Format = "102"
+	date.DateTimeString.Value = strings.ReplaceAll(isoDate, "-", "")
+	return date
+}
+
+type ciiIndicator struct {
+	Indicator bool `xml:"udt:Indicator"`
+}
+
+type ciiURI struct {
+	URIID ublIdentifier `xml:"ram:URIID"`
+}
+
+type ciiPhone struct {
+	CompleteNumber string `xml:"ram:CompleteNumber"`
+}
+
+type ciiContact struct {
+	PersonName string    `xml:"ram:PersonName,omitempty"`
+	Telephone  *ciiPhone `xml:"ram:TelephoneUniversalCommunication"`
+	Email      *ciiURI   `xml:"ram:EmailURIUniversalCommunication"`
+}
+
+type ciiTaxRegistration struct {
+	ID ublIdentifier `xml:"ram:ID"`
+}
+
+type ciiTradeParty struct {
+	Name    string      `xml:"ram:Name"`
+	Contact *ciiContact `xml:"ram:DefinedTradeContact"`
+	Address struct {
+		LineOne   string `xml:"ram:LineOne,omitempty"`
+		CountryID string `xml:"ram:CountryID"`
+	} `xml:"ram:PostalTradeAddress"`
+	URI             *ciiURI             `xml:"ram:URIUniversalCommunication"`
+	TaxRegistration *ciiTaxRegistration `xml:"ram:SpecifiedTaxRegistration"`
+}
+
+type ciiTradeTax struct {
+	CalculatedAmount string  `xml:"ram:CalculatedAmount,omitempty"`
+	TypeCode         string  `xml:"ram:TypeCode"`
+	ExemptionReason  string  `xml:"ram:ExemptionReason,omitempty"`
+	BasisAmount      string  `xml:"ram:BasisAmount,omitempty"`
+	CategoryCode     string  `xml:"ram:CategoryCode"`
+	RatePercent      *string `xml:"ram:RateApplicablePercent"`
+}
+
+type ciiPriceDiscount struct {
+	ChargeIndicator ciiIndicator `xml:"ram:ChargeIndicator"`
+	ActualAmount    string       `xml:"ram:ActualAmount"`
+}
+
+type ciiPrice struct {
+	ChargeAmount    string            `xml:"ram:ChargeAmount"`
+	AllowanceCharge *ciiPriceDiscount `xml:"ram:AppliedTradeAllowanceCharge"`
+}
+
+type ciiLineItem struct {
+	LineDocument struct {
+		LineID string `xml:"ram:LineID"`
+	} `xml:"ram:AssociatedDocumentLineDocument"`
+	Product struct {
+		SellerAssignedID string `xml:"ram:SellerAssignedID,omitempty"`
+		Name             string `xml:"ram:Name"`
+	} `xml:"ram:SpecifiedTradeProduct"`
+	Agreement struct {
+		GrossPrice *ciiPrice `xml:"ram:GrossPriceProductTradePrice"`
+		NetPrice   ciiPrice  `xml:"ram:NetPriceProductTradePrice"`
+	} `xml:"ram:SpecifiedLineTradeAgreement"`
+	Delivery struct {
+		BilledQuantity ublQuantity `xml:"ram:BilledQuantity"`
+	} `xml:"ram:SpecifiedLineTradeDelivery"`
+	Settlement struct {
+		Tax       ciiTradeTax `xml:"ram:ApplicableTradeTax"`
+		Summation struct {
+			LineTotalAmount string `xml:"ram:LineTotalAmount"`
+		} `xml:"ram:SpecifiedTradeSettlementLineMonetarySummation"`
+	} `xml:"ram:SpecifiedLineTradeSettlement"`
+}
+
+type ciiCreditorAccount struct {
+	AccountName   string `xml:"ram:AccountName,omitempty"`
+	ProprietaryID string `xml:"ram:ProprietaryID"`
+}
+
+type ciiCreditorInstitution struct {
+	BICID string `xml:"ram:BICID"`
+}
+
+type ciiPaymentMeans struct {
+	TypeCode         string                  `xml:"ram:TypeCode"`
+	PayeeAccount     *ciiCreditorAccount     `xml:"ram:PayeePartyCreditorFinancialAccount"`
+	PayeeInstitution *ciiCreditorInstitution `xml:"ram:PayeeSpecifiedCreditorFinancialInstitution"`
+}
+
+type ciiPaymentTerms struct {
+	Description string   `xml:"ram:Description,omitempty"`
+	DueDate     *ciiDate `xml:"ram:DueDateDateTime"`
+}
+
+// ciiDocument is a Factur-X CrossIndustryInvoice, with fields in the order the
+// D16B schema requires.
+type ciiDocument struct {
+	XMLName  xml.Name `xml:"rsm:CrossIndustryInvoice"`
+	XmlnsRSM string   `xml:"xmlns:rsm,attr"`
+	XmlnsRAM string   `xml:"xmlns:ram,attr"`
+	XmlnsUDT string   `xml:"xmlns:udt,attr"`
+	Context  struct {
+		Guideline struct {
+			ID string `xml:"ram:ID"`
+		} `xml:"ram:GuidelineSpecifiedDocumentContextParameter"`
+	} `xml:"rsm:ExchangedDocumentContext"`
+	Document struct {
+		ID        string  `xml:"ram:ID"`
+		TypeCode  string  `xml:"ram:TypeCode"`
+		IssueDate ciiDate `xml:"ram:IssueDateTime"`
+	} `xml:"rsm:ExchangedDocument"`
+	Transaction struct {
+		Lines     []ciiLineItem `xml:"ram:IncludedSupplyChainTradeLineItem"`
+		Agreement struct {
+			BuyerReference string        `xml:"ram:BuyerReference"`
+			Seller         ciiTradeParty `xml:"ram:SellerTradeParty"`
+			Buyer          ciiTradeParty `xml:"ram:BuyerTradeParty"`
+		} `xml:"ram:ApplicableHeaderTradeAgreement"`
+		Delivery   struct{} `xml:"ram:ApplicableHeaderTradeDelivery"`
+		Settlement struct {
+			PaymentReference string           `xml:"ram:PaymentReference,omitempty"`
+			Currency         string           `xml:"ram:InvoiceCurrencyCode"`
+			PaymentMeans     *ciiPaymentMeans `xml:"ram:SpecifiedTradeSettlementPaymentMeans"`
+			Taxes            []ciiTradeTax    `xml:"ram:ApplicableTradeTax"`
+			PaymentTerms     *ciiPaymentTerms `xml:"ram:SpecifiedTradePaymentTerms"`
+			Summation        struct {
+				LineTotalAmount     string    `xml:"ram:LineTotalAmount"`
+				TaxBasisTotalAmount string    `xml:"ram:TaxBasisTotalAmount"`
+				TaxTotalAmount      ublAmount `xml:"ram:TaxTotalAmount"`
+				GrandTotalAmount    string    `xml:"ram:GrandTotalAmount"`
+				TotalPrepaidAmount  string    `xml:"ram:TotalPrepaidAmount,omitempty"`
+				DuePayableAmount    string    `xml:"ram:DuePayableAmount"`
+			} `xml:"ram:SpecifiedTradeSettlementHeaderMonetarySummation"`
+		} `xml:"ram:ApplicableHeaderTradeSettlement"`
+	} `xml:"rsm:SupplyChainTradeTransaction"`
+}
+
+func ciiPartyFrom(party ublParty) ciiTradeParty {
+	var trade ciiTradeParty
+	trade.Name = party.PartyLegalEntity.RegistrationName
+	if party.Contact != nil {
+		trade.Contact = &ciiContact{}
+		if party.Contact.Telephone != "" {
+			trade.Contact.Telephone = &ciiPhone{CompleteNumber: party.Contact.Telephone}
+		}
+		if party.Contact.ElectronicMail != "" {
+			trade.Contact.Email = &ciiURI{URIID: ublIdentifier{Value: party.Contact.ElectronicMail}}
+		}
+	}
+	trade.Address.LineOne = party.PostalAddress.StreetName
+	trade.Address.CountryID = party.PostalAddress.Country.IdentificationCode
+	if party.EndpointID.Value != "" {
+		trade.URI = &ciiURI{URIID: party.EndpointID}
+	}
+	if party.PartyTaxScheme != nil {
+		trade.TaxRegistration = &ciiTaxRegistration{ID: ublIdentifier{SchemeID: "VA", Value: party.PartyTaxScheme.CompanyID}}
+	}
+	return trade
+}
+
+// buildCIIDocument expresses a BIS document in the CII syntax. Both carry the
+// EN 16931 semantic model, so the validated UBL document is the single source
+// for the amounts and the two exports cannot drift apart.
+func buildCIIDocument(doc ublDocument) ciiDocument {
+	var cii ciiDocument
+	cii.XmlnsRSM = ciiRSMNamespace
+	cii.XmlnsRAM = ciiRAMNamespace
+	cii.XmlnsUDT = ciiUDTNamespace
+	cii.Context.Guideline.ID = facturXGuideline
+
+	cii.Document.ID = doc.ID
+	cii.Document.TypeCode = doc.InvoiceTypeCode
+	lines := doc.InvoiceLines
+	if doc.CreditNoteTypeCode != "" {
+		cii.Document.TypeCode = doc.CreditNoteTypeCode
+		lines = doc.CreditNoteLines
+	}
+	cii.Document.IssueDate = *newCIIDate(doc.IssueDate)
+
+	transaction := &cii.Transaction
+	for _, line := range lines {
+		var item ciiLineItem
+		item.LineDocument.LineID = line.ID
+		if line.Item.SellersItemIdentification != nil {
+			item.Product.SellerAssignedID = line.Item.SellersItemIdentification.ID
+		}
+		item.Product.Name = line.Item.Name
+		item.Agreement.NetPrice.ChargeAmount = line.Price.PriceAmount.Value
+		if discount := line.Price.AllowanceCharge; discount != nil {
+			item.Agreement.GrossPrice = &ciiPrice{
+				ChargeAmount:    discount.BaseAmount.Value,
+				AllowanceCharge: &ciiPriceDiscount{ActualAmount: discount.Amount.Value},
+			}
+		}
+		if line.InvoicedQuantity != nil {
+			item.Delivery.BilledQuantity = *line.InvoicedQuantity
+		} else {
+			item.Delivery.BilledQuantity = *line.CreditedQuantity
+		}
+		category := line.Item.ClassifiedTaxCategory
+		item.Settlement.Tax = ciiTradeTax{TypeCode: "VAT", CategoryCode: category.ID, RatePercent: category.Percent}
+		item.Settlement.Summation.LineTotalAmount = line.LineExtensionAmount.Value
+		transaction.Lines = append(transaction.Lines, item)
+	}
+
+	transaction.Agreement.BuyerReference = doc.BuyerReference
+	transaction.Agreement.Seller = ciiPartyFrom(doc.AccountingSupplierParty.Party)
+	transaction.Agreement.Buyer = ciiPartyFrom(doc.AccountingCustomerParty.Party)
+
+	settlement := &transaction.Settlement
+	settlement.Currency = doc.DocumentCurrencyCode
+	if means := doc.PaymentMeans; means != nil {
+		settlement.PaymentReference = means.PaymentID
+		settlement.PaymentMeans = &ciiPaymentMeans{TypeCode: means.PaymentMeansCode}
+		if account := means.PayeeFinancialAccount; account != nil {
+			settlement.PaymentMeans.PayeeAccount = &ciiCreditorAccount{AccountName: account.Name, ProprietaryID: account.ID}
+			if account.FinancialInstitutionBranch != nil {
+				settlement.PaymentMeans.PayeeInstitution = &ciiCreditorInstitution{BICID: account.FinancialInstitutionBranch.ID}
+			}
+		}
+	}
+	for _, subtotal := range doc.TaxTotal.TaxSubtotals {
+		settlement.Taxes = append(settlement.Taxes, ciiTradeTax{
+			CalculatedAmount: subtotal.TaxAmount.Value,
+			TypeCode:         "VAT",
+			ExemptionReason:  subtotal.TaxCategory.TaxExemptionReason,
+			BasisAmount:      subtotal.TaxableAmount.Value,
+			CategoryCode:     subtotal.TaxCategory.ID,
+			RatePercent:      subtotal.TaxCategory.Percent,
+		})
+	}
+	if doc.PaymentTerms != nil || doc.DueDate != "" {
+		settlement.PaymentTerms = &ciiPaymentTerms{}
+		if doc.PaymentTerms != nil {
+			settlement.PaymentTerms.Description = doc.PaymentTerms.Note
+		}
+		if doc.DueDate != "" {
+			settlement.PaymentTerms.DueDate = newCIIDate(doc.DueDate)
+		}
+	}
+
+	totals := doc.LegalMonetaryTotal
+	summation := &settlement.Summation
+	summation.LineTotalAmount = totals.LineExtensionAmount.Value
+	summation.TaxBasisTotalAmount = totals.TaxExclusiveAmount.Value
+	summation.TaxTotalAmount = doc.TaxTotal.TaxAmount
+	summation.GrandTotalAmount = totals.TaxInclusiveAmount.Value
+	if totals.PrepaidAmount != nil {
+		summation.TotalPrepaidAmount = totals.PrepaidAmount.Value
+	}
+	summation.DuePayableAmount = totals.PayableAmount.Value
+	return cii
+}
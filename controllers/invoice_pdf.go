@@ -0,0 +1,831 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/jung-kurt/gofpdf"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PDFCompression compresses invoice PDF streams. Tests turn it off so golden
+// files stay readable.
+var PDFCompression = true
+
+// DefaultInvoiceLayout is used when neither the request nor the company picks one.
+const DefaultInvoiceLayout = "classic_a4"
+
+// InvoiceLayout describes a page format invoices and receipts can be rendered in.
+type InvoiceLayout struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	PageSize    string  `json:"page_size"`
+	pageWidth   float64 // mm, for roll paper without a fixed page height
+	margin      float64
+	fontSize    float64
+	style       string // classic, modern, thermal
+}
+
+var invoiceLayouts = []InvoiceLayout{
+	{Name: "classic_a4", Description: "Classic A4 layout with the company header on the left", PageSize: "A4", margin: 10, fontSize: 10, style: "classic"},
+	{Name: "modern_a4", Description: "A4 layout with a coloured header band and striped item rows", PageSize: "A4", margin: 15, fontSize: 9, style: "modern"},
+	{Name: "letter", Description: "Classic layout on US Letter paper", PageSize: "Letter", margin: 12.7, fontSize: 10, style: "classic"},
+	{Name: "thermal_80mm", Description: "Single continuous 80mm receipt for thermal printers", PageSize: "80mm", pageWidth: 80, margin: 4, fontSize: 8, style: "thermal"},
+}
+
+func findInvoiceLayout(name string) (InvoiceLayout, bool) {
+	for _, layout := range invoiceLayouts {
+		if layout.Name == name {
+			return layout, true
+		}
+	}
+	return InvoiceLayout{}, false
+}
+
+type rgbColor struct{ r, g, b int }
+
+// parseAccentColor reads a #RRGGBB colour, reporting false for anything else.
+func parseAccentColor(hex string) (rgbColor, bool) {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) != 6 {
+		return rgbColor{}, false
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return rgbColor{}, false
+	}
+	return rgbColor{int(v >> 16 & 0xff), int(v >> 8 & 0xff), int(v & 0xff)}, true
+}
+
+// fetchInvoiceParties loads the issuing company and billed customer of an
+// invoice. Missing records come back empty so the document still renders.
+func fetchInvoiceParties(invoice models.Invoice) (models.Company, models.Customer) {
+	var company models.Company
+	if objID, err := primitive.ObjectIDFromHex(invoice.CompanyID); err == nil {
+		if company, err = fetchCompanyByID(objID); err != nil {
+			fmt.Println("Error fetching company for invoice", err)
+		}
+	}
+	customers := lookupCustomers([]string{invoice.CustomerID})
+	return company, customers[invoice.CustomerID]
+}
+
+// invoiceDocument is everything a layout needs to draw an invoice or receipt.
+type invoiceDocument struct {
+	invoice  *models.Invoice
+	company  models.Company
+	customer models.Customer
+	receipt  bool
+	refund   *models.Refund // set for refund receipts of a credit note
+	accent   rgbColor
+	layout   InvoiceLayout
+	font     PDFFont
+	verify   string // signed verification link encoded in the QR code
+}
+
+func (d invoiceDocument) money(amount float64) string {
+	return formatMoney(companyCurrency(d.company.Settings), amount)
+}
+
+// date prints a date in the company's timezone.
+func (d invoiceDocument) date(t time.Time) string {
+	return t.In(companyLocation(d.company.Settings)).Format("2006-01-02")
+}
+
+// text is everything printed from the company, customer and invoice records,
+// used to pick a font that has glyphs for it.
+func (d invoiceDocument) text() []string {
+	branding := d.company.Branding
+	texts := []string{
+		d.company.Name, d.company.Address, branding.FooterText, branding.PaymentInstructions,
+		d.customer.Name, invoiceBillingAddress(*d.invoice, d.customer), d.invoice.Terms, d.invoice.Reason,
+	}
+	if contact, ok := billingContact(*d.invoice, d.customer); ok {
+		texts = append(texts, contact.Name)
+	}
+	for _, account := range branding.BankAccounts {
+		texts = append(texts, bankAccountLine(account))
+	}
+	for _, item := range d.invoice.Items {
+		texts = append(texts, item.ItemName)
+	}
+	return texts
+}
+
+func (d invoiceDocument) creditNote() bool {
+	return d.invoice.DocumentType == "credit_note"
+}
+
+func (d invoiceDocument) title() string {
+	switch {
+	case d.refund != nil:
+		return "REFUND RECEIPT"
+	case d.creditNote():
+		return "CREDIT NOTE"
+	case d.receipt:
+		return "PAYMENT RECEIPT"
+	}
+	return "INVOICE"
+}
+
+func (d invoiceDocument) customerTitle() string {
+	switch {
+	case d.refund != nil:
+		return "Refunded To"
+	case d.creditNote():
+		return "Credit To"
+	case d.receipt:
+		return "Received From"
+	}
+	return "Bill To"
+}
+
+var refundMethodLabels = map[string]string{
+	"cash":   "Cash",
+	"bank":   "Bank Transfer",
+	"credit": "Customer Credit",
+}
+
+func refundMethodLabel(method string) string {
+	if label, ok := refundMethodLabels[method]; ok {
+		return label
+	}
+	return method
+}
+
+// metaLines are the identifying label/value pairs printed under the title.
+func (d invoiceDocument) metaLines() [][2]string {
+	if d.refund != nil {
+		lines := [][2]string{
+			{"Refund ID", d.refund.ID.Hex()},
+			{"Credit Note #", d.invoice.ReferenceNumber},
+			{"Invoice #", d.invoice.OriginalReference},
+			{"Refund Date", d.date(d.refund.CreatedAt)},
+			{"Method", refundMethodLabel(d.refund.Method)},
+		}
+		if d.refund.Reference != "" {
+			lines = append(lines, [2]string{"Reference", d.refund.Reference})
+		}
+		return lines
+	}
+	if d.creditNote() {
+		lines := [][2]string{
+			{"Credit Note ID", d.invoice.ID.Hex()},
+			{"Reference #", d.invoice.ReferenceNumber},
+			{"Date", d.date(d.invoice.Date)},
+			{"Invoice #", d.invoice.OriginalReference},
+		}
+		if d.invoice.Reason != "" {
+			lines = append(lines, [2]string{"Reason", d.invoice.Reason})
+		}
+		return lines
+	}
+	if d.receipt {
+		return [][2]string{
+			{"Receipt ID", d.invoice.ID.Hex()},
+			{"Reference #", d.invoice.ReferenceNumber},
+			{"Payment Date", d.date(d.invoice.PaymentDate)},
+		}
+	}
+	lines := [][2]string{
+		{"Invoice ID", d.invoice.ID.Hex()},
+		{"Reference #", d.invoice.ReferenceNumber},
+		{"Date", d.date(d.invoice.Date)},
+	}
+	if d.invoice.DueDate != nil {
+		lines = append(lines, [2]string{"Due Date", d.date(*d.invoice.DueDate)})
+	}
+	if d.invoice.Terms != "" {
+		lines = append(lines, [2]string{"Terms", d.invoice.Terms})
+	}
+	for _, installment := range d.invoice.Installments {
+		lines = append(lines, [2]string{
+			fmt.Sprintf("Installment %d", installment.Number),
+			d.date(installment.DueDate) + "  " + d.money(installment.Amount),
+		})
+	}
+	if discount := d.invoice.EarlyPayment; discount != nil {
+		lines = append(lines, [2]string{
+			fmt.Sprintf("%g%% Early Payment Discount", discount.Percent),
+			d.money(discount.Amount) + " if paid by " + d.date(discount.Deadline),
+		})
+	}
+	return lines
+}
+
+// totalLines are the label/amount pairs printed under the item table.
+func (d invoiceDocument) totalLines() [][2]string {
+	var lines [][2]string
+	if d.invoice.TaxAmount > 0 {
+		lines = append(lines,
+			[2]string{"Subtotal:", d.money(d.invoice.Subtotal)},
+			[2]string{"Tax:", d.money(d.invoice.TaxAmount)},
+		)
+	}
+	if d.refund != nil {
+		lines = append(lines, [2]string{"Total Credit:", d.money(d.invoice.Amount)})
+		if d.refund.AppliedToBalance > 0 {
+			lines = append(lines, [2]string{"Applied To Invoice Balance:", d.money(-d.refund.AppliedToBalance)})
+		}
+		return append(lines, [2]string{"Total Refunded:", d.money(d.refund.Amount)})
+	}
+	if d.creditNote() {
+		return append(lines, [2]string{"Total Credit:", d.money(d.invoice.Amount)})
+	}
+	if !d.receipt {
+		return append(lines, [2]string{"Total Amount:", d.money(d.invoice.Amount)})
+	}
+	paid := d.invoice.Amount
+	if discount := d.invoice.EarlyPayment; discount != nil && discount.Applied {
+		lines = append(lines,
+			[2]string{"Total Amount:", d.money(d.invoice.Amount)},
+			[2]string{"Early Payment Discount:", d.money(-discount.Amount)},
+		)
+		paid -= discount.Amount
+	}
+	return append(lines, [2]string{"Total Amount Paid:", d.money(paid)})
+}
+
+func (d invoiceDocument) closingLine() string {
+	switch {
+	case d.refund != nil:
+		return "Refund Issued. Thank you!"
+	case d.creditNote():
+		return "This credit is deducted from what you owe us."
+	case d.receipt:
+		return "Payment Received. Thank you!"
+	}
+	return "Thank you for your business!"
+}
+
+// renderInvoicePDF builds the invoice, or the receipt once it is paid, in the
+// named layout and returns it with its download filename. An empty layout
+// falls back to the company's choice and then to DefaultInvoiceLayout.
+// Credit notes render as such whatever their status.
+func renderInvoicePDF(invoice *models.Invoice, company models.Company, customer models.Customer, layoutName string) ([]byte, string, error) {
+	doc, err := newInvoiceDocument(invoice, company, customer, layoutName)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := renderDocumentPDF(doc)
+	if err != nil {
+		return nil, "", err
+	}
+
+	filename := "invoice_" + invoice.ReferenceNumber + ".pdf"
+	if doc.creditNote() {
+		filename = "credit_note_" + invoice.ReferenceNumber + ".pdf"
+	} else if doc.receipt {
+		filename = "receipt_" + invoice.ReferenceNumber + ".pdf"
+	}
+	return data, filename, nil
+}
+
+// renderRefundReceiptPDF builds the receipt of a refund, listing the items of
+// its credit note, in the named layout.
+func renderRefundReceiptPDF(refund models.Refund, creditNote *models.Invoice, company models.Company, customer models.Customer, layoutName string) ([]byte, string, error) {
+	doc, err := newInvoiceDocument(creditNote, company, customer, layoutName)
+	if err != nil {
+		return nil, "", err
+	}
+	doc.refund = &refund
+	data, err := renderDocumentPDF(doc)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, "refund_" + creditNote.ReferenceNumber + ".pdf", nil
+}
+
+func newInvoiceDocument(invoice *models.Invoice, company models.Company, customer models.Customer, layoutName string) (invoiceDocument, error) {
+	if layoutName == "" {
+		layoutName = company.Branding.InvoiceLayout
+	}
+	if layoutName == "" {
+		layoutName = DefaultInvoiceLayout
+	}
+	layout, ok := findInvoiceLayout(layoutName)
+	if !ok {
+		return invoiceDocument{}, fmt.Errorf("unknown invoice layout: %s", layoutName)
+	}
+
+	accent, ok := parseAccentColor(company.Branding.AccentColor)
+	if !ok {
+		accent = rgbColor{0, 0, 0}
+	}
+	doc := invoiceDocument{
+		invoice:  invoice,
+		company:  company,
+		customer: customer,
+		receipt:  invoice.Status == "Paid" && invoice.DocumentType != "credit_note",
+		accent:   accent,
+		layout:   layout,
+	}
+	doc.font = selectPDFFont(company.Branding.Font, doc.text()...)
+	doc.verify = InvoiceVerificationURL(*invoice, company)
+	return doc, nil
+}
+
+func renderDocumentPDF(doc invoiceDocument) ([]byte, error) {
+	var pdf *gofpdf.Fpdf
+	if doc.layout.style == "thermal" {
+		// Roll paper has no fixed height: lay the receipt out on a very long
+		// page first to measure it, then render it again at the exact height.
+		measure := newLayoutPDF(doc, 2000)
+		drawThermalDocument(measure, doc)
+		pdf = newLayoutPDF(doc, measure.GetY()+doc.layout.margin)
+		drawThermalDocument(pdf, doc)
+	} else {
+		pdf = newLayoutPDF(doc, 0)
+		drawPagedDocument(pdf, doc)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func newLayoutPDF(doc invoiceDocument, height float64) *gofpdf.Fpdf {
+	layout := doc.layout
+	init := &gofpdf.InitType{OrientationStr: "P", UnitStr: "mm", SizeStr: layout.PageSize}
+	if layout.pageWidth > 0 {
+		init.SizeStr = ""
+		init.Size = gofpdf.SizeType{Wd: layout.pageWidth, Ht: height}
+	}
+	pdf := gofpdf.NewCustom(init)
+	pdf.SetMargins(layout.margin, layout.margin, layout.margin)
+	pdf.SetCompression(PDFCompression)
+	// Fixed dates and resource order make the output reproducible
+	pdf.SetCatalogSort(true)
+	pdf.SetCreationDate(doc.invoice.Date)
+	pdf.SetModificationDate(doc.invoice.Date)
+	family := applyPDFFont(pdf, doc.font)
+
+	if layout.style == "thermal" {
+		pdf.SetAutoPageBreak(false, 0)
+	} else {
+		pdf.SetAutoPageBreak(true, layout.margin+8)
+		pdf.AliasNbPages("{nb}")
+		footer := doc.company.Branding.FooterText
+		pdf.SetFooterFunc(func() {
+			pdf.SetY(-(layout.margin + 6))
+			pdf.SetFont(family, "I", 8)
+			pdf.SetTextColor(100, 100, 100)
+			if footer != "" {
+				pdf.CellFormat(0, 6, footer, "", 0, "C", false, 0, "")
+				pdf.SetX(layout.margin)
+			}
+			pdf.CellFormat(0, 6, fmt.Sprintf("Page %d of {nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+			pdf.SetTextColor(0, 0, 0)
+		})
+	}
+	pdf.AddPage()
+	return pdf
+}
+
+// registerLogo adds the company logo to the document and returns its image
+// type, or "" when there is no usable logo.
+func registerLogo(pdf *gofpdf.Fpdf, company models.Company) string {
+	imageType := logoImageType(company.LogoContentType)
+	if imageType == "" || len(company.Logo) == 0 {
+		return ""
+	}
+	pdf.RegisterImageOptionsReader("logo", gofpdf.ImageOptions{ImageType: imageType}, bytes.NewReader(company.Logo))
+	if !pdf.Ok() {
+		// A logo that cannot be decoded should not stop the document
+		fmt.Println("Error rendering company logo", pdf.Error())
+		pdf.ClearError()
+		return ""
+	}
+	return imageType
+}
+
+// qrCodeSize is the printed width of the verification QR code in mm.
+const qrCodeSize = 24.0
+
+// drawVerificationQR prints the verification QR code with a caption under it
+// and makes it a link in digital copies. It reports false if the code could
+// not be generated, which leaves the document without one.
+func drawVerificationQR(pdf *gofpdf.Fpdf, doc invoiceDocument, x, y, size float64) bool {
+	code, err := invoiceQRCode(doc.verify)
+	if err != nil {
+		fmt.Println("Error generating verification QR code", err)
+		return false
+	}
+	options := gofpdf.ImageOptions{ImageType: "PNG"}
+	pdf.RegisterImageOptionsReader("verification_qr", options, bytes.NewReader(code))
+	if !pdf.Ok() {
+		fmt.Println("Error rendering verification QR code", pdf.Error())
+		pdf.ClearError()
+		return false
+	}
+	pdf.ImageOptions("verification_qr", x, y, size, size, false, options, 0, doc.verify)
+	pdf.SetFont(doc.font.Name, "", 6)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.SetXY(x, y+size)
+	pdf.CellFormat(size, 3, "Scan to verify", "", 0, "C", false, 0, "")
+	pdf.SetTextColor(0, 0, 0)
+	return true
+}
+
+func drawPagedDocument(pdf *gofpdf.Fpdf, doc invoiceDocument) {
+	if doc.layout.style == "modern" {
+		drawModernHeader(pdf, doc)
+	} else {
+		drawClassicHeader(pdf, doc)
+	}
+
+	writeCustomerBlock(pdf, doc, doc.customerTitle())
+
+	fontSize := doc.layout.fontSize
+	top := pdf.GetY()
+	pdf.SetFont(doc.font.Name, "B", fontSize+6)
+	pdf.SetTextColor(doc.accent.r, doc.accent.g, doc.accent.b)
+	pdf.Cell(40, 10, doc.title())
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(10)
+
+	pdf.SetFont(doc.font.Name, "", fontSize+1)
+	for _, line := range doc.metaLines() {
+		pdf.Cell(0, 6, line[0]+": "+line[1])
+		pdf.Ln(6)
+	}
+
+	// The verification code sits to the right of the title and meta lines
+	width, _ := contentBox(pdf)
+	left, _, _, _ := pdf.GetMargins()
+	y := pdf.GetY()
+	if drawVerificationQR(pdf, doc, left+width-qrCodeSize, top, qrCodeSize) && y < top+qrCodeSize+4 {
+		y = top + qrCodeSize + 4
+	}
+	pdf.SetXY(left, y)
+	pdf.Ln(4)
+
+	drawItemTable(pdf, doc)
+	drawTotals(pdf, doc)
+	if !doc.receipt && !doc.creditNote() {
+		writePaymentDetails(pdf, doc)
+	}
+
+	pdf.Ln(4)
+	pdf.SetFont(doc.font.Name, "", fontSize)
+	pdf.Cell(0, 5, doc.closingLine())
+}
+
+func drawClassicHeader(pdf *gofpdf.Fpdf, doc invoiceDocument) {
+	margin := doc.layout.margin
+	left := margin
+	if imageType := registerLogo(pdf, doc.company); imageType != "" {
+		pdf.ImageOptions("logo", margin, margin, 0, 22, false, gofpdf.ImageOptions{ImageType: imageType}, 0, "")
+		left = margin + 35
+	}
+
+	pdf.SetXY(left, margin)
+	pdf.SetFont(doc.font.Name, "B", 20)
+	pdf.SetTextColor(doc.accent.r, doc.accent.g, doc.accent.b)
+	pdf.Cell(0, 12, doc.company.Name)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(12)
+
+	pdf.SetFont(doc.font.Name, "", doc.layout.fontSize)
+	for _, line := range companyDetailLines(doc.company) {
+		pdf.SetX(left)
+		pdf.Cell(0, 5, line)
+		pdf.Ln(5)
+	}
+	if pdf.GetY() < margin+24 {
+		pdf.SetY(margin + 24)
+	}
+	pdf.Ln(3)
+}
+
+// drawModernHeader fills a band in the accent colour across the top of the
+// page with the logo and company name, and lists the details under it.
+func drawModernHeader(pdf *gofpdf.Fpdf, doc invoiceDocument) {
+	margin := doc.layout.margin
+	pageWidth, _ := pdf.GetPageSize()
+	band := doc.accent
+	if band == (rgbColor{}) {
+		band = rgbColor{45, 55, 72}
+	}
+	pdf.SetFillColor(band.r, band.g, band.b)
+	pdf.Rect(0, 0, pageWidth, margin+18, "F")
+
+	left := margin
+	if imageType := registerLogo(pdf, doc.company); imageType != "" {
+		pdf.ImageOptions("logo", margin, 4, 0, margin+10, false, gofpdf.ImageOptions{ImageType: imageType}, 0, "")
+		left = margin + 30
+	}
+	pdf.SetXY(left, margin/2+4)
+	pdf.SetFont(doc.font.Name, "B", 18)
+	pdf.SetTextColor(255, 255, 255)
+	pdf.Cell(0, 12, doc.company.Name)
+	pdf.SetTextColor(0, 0, 0)
+
+	pdf.SetY(margin + 22)
+	pdf.SetFont(doc.font.Name, "", doc.layout.fontSize)
+	pdf.SetTextColor(90, 90, 90)
+	pdf.Cell(0, 5, strings.Join(companyDetailLines(doc.company), "  |  "))
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Ln(10)
+}
+
+func companyDetailLines(company models.Company) []string {
+	var lines []string
+	for _, line := range []string{
+		company.Address,
+		labelled("Email", company.Email),
+		labelled("Phone", company.Phone),
+		labelled("TIN", company.TIN),
+	} {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func writeCustomerBlock(pdf *gofpdf.Fpdf, doc invoiceDocument, title string) {
+	fontSize := doc.layout.fontSize
+	pdf.SetFont(doc.font.Name, "B", fontSize+1)
+	pdf.Cell(0, 6, title+":")
+	pdf.Ln(6)
+	pdf.SetFont(doc.font.Name, "", fontSize)
+	// Invoices are addressed to the billing contact when the customer has one
+	contact, _ := billingContact(*doc.invoice, doc.customer)
+	phone := contact.Phone
+	if phone == "" {
+		phone = doc.customer.Phone
+	}
+	for _, line := range []string{
+		doc.customer.Name,
+		labelled("Attn", contact.Name),
+		invoiceBillingAddress(*doc.invoice, doc.customer),
+		labelled("Phone", phone),
+		labelled("TIN", doc.customer.TIN),
+	} {
+		if line == "" {
+			continue
+		}
+		pdf.Cell(0, 5, line)
+		pdf.Ln(5)
+	}
+	pdf.Ln(4)
+}
+
+type itemColumn struct {
+	title string
+	share float64 // of the content width
+	align string
+}
+
+var itemColumns = []itemColumn{
+	{"Item", 0.40, "L"},
+	{"Qty", 0.10, "R"},
+	{"Unit Price", 0.17, "R"},
+	{"Discount", 0.13, "R"},
+	{"Subtotal", 0.20, "R"},
+}
+
+// contentBox returns the printable width and the y coordinate below which
+// content must move to the next page.
+func contentBox(pdf *gofpdf.Fpdf) (float64, float64) {
+	pageWidth, pageHeight := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	_, bottom := pdf.GetAutoPageBreak()
+	return pageWidth - left - right, pageHeight - bottom
+}
+
+// drawItemTable prints the line items with wrapped descriptions, starting a
+// new page with a repeated header whenever a row would not fit.
+func drawItemTable(pdf *gofpdf.Fpdf, doc invoiceDocument) {
+	width, limit := contentBox(pdf)
+	fontSize := doc.layout.fontSize
+	lineHeight := fontSize * 0.55
+	modern := doc.layout.style == "modern"
+
+	widths := make([]float64, len(itemColumns))
+	for i, col := range itemColumns {
+		widths[i] = width * col.share
+	}
+
+	drawHeader := func() {
+		pdf.SetFont(doc.font.Name, "B", fontSize+1)
+		if modern {
+			pdf.SetFillColor(doc.accent.r, doc.accent.g, doc.accent.b)
+			pdf.SetTextColor(255, 255, 255)
+		}
+		for i, col := range itemColumns {
+			pdf.CellFormat(widths[i], 8, col.title, "", 0, col.align, modern, 0, "")
+		}
+		pdf.SetTextColor(0, 0, 0)
+		pdf.Ln(8)
+		if !modern {
+			left, _, _, _ := pdf.GetMargins()
+			pdf.SetFillColor(doc.accent.r, doc.accent.g, doc.accent.b)
+			pdf.Rect(left, pdf.GetY(), width, 0.6, "F")
+			pdf.Ln(1)
+		}
+		pdf.SetFont(doc.font.Name, "", fontSize)
+	}
+	drawHeader()
+
+	for row, item := range doc.invoice.Items {
+		nameLines := pdf.SplitText(item.ItemName, widths[0]-2)
+		if len(nameLines) == 0 {
+			nameLines = []string{""}
+		}
+		rowHeight := float64(len(nameLines))*lineHeight + 3
+
+		if pdf.GetY()+rowHeight > limit {
+			pdf.AddPage()
+			drawHeader()
+		}
+
+		left, _, _, _ := pdf.GetMargins()
+		top := pdf.GetY()
+		if modern && row%2 == 1 {
+			pdf.SetFillColor(243, 244, 246)
+			pdf.Rect(left, top, width, rowHeight, "F")
+		}
+
+		for i, line := range nameLines {
+			pdf.SetXY(left, top+1.5+float64(i)*lineHeight)
+			pdf.CellFormat(widths[0], lineHeight, line, "", 0, "L", false, 0, "")
+		}
+		values := []string{
+			fmt.Sprintf("%d", item.Quantity),
+			doc.money(item.UnitPrice),
+			fmt.Sprintf("%.0f%%", item.Discount),
+			doc.money(item.Subtotal),
+		}
+		x := left + widths[0]
+		for i, value := range values {
+			pdf.SetXY(x, top+1.5)
+			pdf.CellFormat(widths[i+1], lineHeight, value, "", 0, itemColumns[i+1].align, false, 0, "")
+			x += widths[i+1]
+		}
+		pdf.SetXY(left, top+rowHeight)
+	}
+}
+
+func drawTotals(pdf *gofpdf.Fpdf, doc invoiceDocument) {
+	width, limit := contentBox(pdf)
+	lines := doc.totalLines()
+	if pdf.GetY()+5+float64(len(lines))*8 > limit {
+		pdf.AddPage()
+	}
+
+	pdf.Ln(5)
+	fontSize := doc.layout.fontSize + 2
+	for i, line := range lines {
+		style := ""
+		if i == len(lines)-1 {
+			style = "B"
+		}
+		pdf.SetFont(doc.font.Name, style, fontSize)
+		pdf.CellFormat(width*0.8, 8, line[0], "", 0, "R", false, 0, "")
+		pdf.CellFormat(width*0.2, 8, line[1], "", 0, "R", false, 0, "")
+		pdf.Ln(8)
+	}
+}
+
+// writePaymentDetails prints how to pay: free-form instructions followed by
+// the company's bank accounts.
+func writePaymentDetails(pdf *gofpdf.Fpdf, doc invoiceDocument) {
+	branding := doc.company.Branding
+	if branding.PaymentInstructions == "" && len(branding.BankAccounts) == 0 {
+		return
+	}
+	fontSize := doc.layout.fontSize
+	pdf.Ln(6)
+	pdf.SetFont(doc.font.Name, "B", fontSize+1)
+	pdf.Cell(0, 6, "Payment Details")
+	pdf.Ln(7)
+	pdf.SetFont(doc.font.Name, "", fontSize)
+	if branding.PaymentInstructions != "" {
+		pdf.MultiCell(0, 5, branding.PaymentInstructions, "", "L", false)
+		pdf.Ln(2)
+	}
+	for _, account := range branding.BankAccounts {
+		pdf.MultiCell(0, 5, bankAccountLine(account), "", "L", false)
+	}
+}
+
+func bankAccountLine(account models.BankAccount) string {
+	line := fmt.Sprintf("%s - %s - Account No. %s", account.BankName, account.AccountName, account.AccountNumber)
+	if account.Branch != "" {
+		line += " - " + account.Branch + " branch"
+	}
+	if account.SwiftCode != "" {
+		line += " - SWIFT " + account.SwiftCode
+	}
+	return line
+}
+
+// drawThermalDocument lays a receipt out as a single narrow column for 80mm
+// roll printers.
+func drawThermalDocument(pdf *gofpdf.Fpdf, doc invoiceDocument) {
+	width, _ := contentBox(pdf)
+	left, _, _, _ := pdf.GetMargins()
+	fontSize := doc.layout.fontSize
+	lineHeight := fontSize * 0.5
+
+	centered := func(text, style string, size float64) {
+		pdf.SetFont(doc.font.Name, style, size)
+		for _, line := range pdf.SplitText(text, width) {
+			pdf.CellFormat(width, lineHeight+0.5, line, "", 1, "C", false, 0, "")
+		}
+	}
+	separator := func() {
+		pdf.Ln(1)
+		pdf.SetDrawColor(120, 120, 120)
+		pdf.SetDashPattern([]float64{1, 1}, 0)
+		pdf.Line(left, pdf.GetY(), left+width, pdf.GetY())
+		pdf.SetDashPattern([]float64{}, 0)
+		pdf.SetDrawColor(0, 0, 0)
+		pdf.Ln(2)
+	}
+	amountLine := func(label, amount, style string) {
+		pdf.SetFont(doc.font.Name, style, fontSize)
+		pdf.CellFormat(width*0.6, lineHeight+0.5, label, "", 0, "L", false, 0, "")
+		pdf.CellFormat(width*0.4, lineHeight+0.5, amount, "", 1, "R", false, 0, "")
+	}
+
+	if imageType := registerLogo(pdf, doc.company); imageType != "" {
+		pdf.ImageOptions("logo", left+width/2-6, pdf.GetY(), 0, 12, true, gofpdf.ImageOptions{ImageType: imageType}, 0, "")
+		pdf.Ln(1)
+	}
+	centered(doc.company.Name, "B", fontSize+3)
+	for _, line := range companyDetailLines(doc.company) {
+		centered(line, "", fontSize-1)
+	}
+	separator()
+
+	centered(doc.title(), "B", fontSize+1)
+	pdf.Ln(1)
+	for _, line := range doc.metaLines() {
+		amountLine(line[0], line[1], "")
+	}
+	if doc.customer.Name != "" {
+		amountLine("Customer", doc.customer.Name, "")
+	}
+	if doc.customer.TIN != "" {
+		amountLine("Customer TIN", doc.customer.TIN, "")
+	}
+	separator()
+
+	for _, item := range doc.invoice.Items {
+		pdf.SetFont(doc.font.Name, "B", fontSize)
+		for _, line := range pdf.SplitText(item.ItemName, width) {
+			pdf.CellFormat(width, lineHeight+0.5, line, "", 1, "L", false, 0, "")
+		}
+		detail := fmt.Sprintf("  %d x %s", item.Quantity, doc.money(item.UnitPrice))
+		if item.Discount > 0 {
+			detail += fmt.Sprintf(" (-%.0f%%)", item.Discount)
+		}
+		amountLine(detail, doc.money(item.Subtotal), "")
+	}
+	separator()
+
+	lines := doc.totalLines()
+	for i, line := range lines {
+		style := ""
+		if i == len(lines)-1 {
+			style = "B"
+		}
+		amountLine(strings.TrimSuffix(line[0], ":"), line[1], style)
+	}
+	separator()
+
+	if drawVerificationQR(pdf, doc, left+(width-qrCodeSize)/2, pdf.GetY(), qrCodeSize) {
+		pdf.SetXY(left, pdf.GetY()+qrCodeSize+5)
+	}
+
+	if footer := doc.company.Branding.FooterText; footer != "" {
+		centered(footer, "I", fontSize-1)
+	}
+	centered(doc.closingLine(), "", fontSize)
+}
+
+func labelled(label, value string) string {
+	if value == "" {
+		return ""
+	}
+	return label + ": " + value
+}
+
+func logoImageType(contentType string) string {
+	switch contentType {
+	case "image/png":
+		return "PNG"
+	case "image/jpeg":
+		return "JPG"
+	}
+	return ""
+}
@@ -0,0 +1,356 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// documentSign is 1 for invoices and -1 for credit notes, whose positive
+// amounts take sales back.
+func documentSign(invoice models.Invoice) float64 {
+	if invoice.DocumentType == "credit_note" {
+		return -1
+	}
+	return 1
+}
+
+// returnCreditNoteItems works out the credit note lines for the returned
+// quantities, pricing each at its share of the original line. It checks the
+// quantities against what was sold and not yet returned.
+func returnCreditNoteItems(invoice models.Invoice, lines []models.InvoiceReturnLine) ([]models.InvoiceItem, error) {
+	var items []models.InvoiceItem
+	seen := map[int]bool{}
+	for _, line := range lines {
+		if line.Line >= len(invoice.Items) {
+			return nil, fmt.Errorf("the invoice has no line %d", line.Line)
+		}
+		if seen[line.Line] {
+			return nil, fmt.Errorf("line %d is listed twice", line.Line)
+		}
+		seen[line.Line] = true
+
+		sold := invoice.Items[line.Line]
+		if left := sold.Quantity - sold.ReturnedQuantity; line.Quantity > left {
+			return nil, fmt.Errorf("only %d of %s on line %d can still be returned", left, sold.ItemName, line.Line)
+		}
+		share := float64(line.Quantity) / float64(sold.Quantity)
+		item := sold
+		item.Quantity = line.Quantity
+		item.Subtotal = round2(sold.Subtotal * share)
+		item.TaxAmount = round2(sold.TaxAmount * share)
+		item.ReturnedQuantity = 0
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// originalRefundMethod is how the invoice was paid, so a refund can go back
+// the same way. Unknown methods refund to customer credit, which can be paid
+// out later once the method is known.
+func originalRefundMethod(invoice models.Invoice) string {
+	if invoice.PaymentMethod != "" {
+		return invoice.PaymentMethod
+	}
+	if invoice.PaymentType == "cash" {
+		return "cash"
+	}
+	return "credit"
+}
+
+// ReturnInvoiceItems godoc
+// @Summary Return items of an invoice
+// @Description Takes back quantities of the invoice's lines and issues a credit note for them. The credit first reduces what is still owed on the invoice; the rest is refunded by cash, bank transfer, the invoice's original payment method, or kept as customer credit. Stock of tracked items can be put back. The refund receipt is downloaded from /invoice/refund/download/{id}.
+// @Tags Invoices
+// @Accept json
+// @Produce json
+// @Param id path string true "Invoice ID"
+// @Param return body models.InvoiceReturnRequest true "Returned lines and refund"
+// @Success 201 {object} map[string]interface{} "Credit note and refund"
+// @Failure 400 {object} map[string]string "Invalid input or more than can be returned"
+// @Failure 401 {object} map[string]string "No active company in the token"
+// @Failure 403 {object} map[string]string "Invoice of another company"
+// @Failure 404 {object} map[string]string "Invoice not found"
+// @Failure 409 {object} map[string]string "The lines were returned meanwhile"
+// @Failure 500 {object} map[string]string "Failed to record the return"
+// @Router /invoice/return/{id} [post]
+func ReturnInvoiceItems(c *gin.Context) {
+	var req models.InvoiceReturnRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return
+	}
+
+	var invoice models.Invoice
+	if err := config.DB.Collection("invoices").FindOne(context.Background(), bson.M{"_id": objID}).Decode(&invoice); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		return
+	}
+	if _, ok := scopedCompanyID(c, invoice.CompanyID); !ok {
+		return
+	}
+	if invoice.DocumentType == "credit_note" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Items can only be returned against an invoice"})
+		return
+	}
+	if invoice.Status == "WrittenOff" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Items of a written-off invoice cannot be returned"})
+		return
+	}
+	items, err := returnCreditNoteItems(invoice, req.Lines)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Reserve the quantities so two returns cannot take back the same goods,
+	// and take the next credit note number of the invoice along with them
+	filter := bson.M{"_id": invoice.ID}
+	inc := bson.M{"credit_notes_issued": 1}
+	for _, line := range req.Lines {
+		field := fmt.Sprintf("items.%d.returned_quantity", line.Line)
+		filter[field] = bson.M{"$not": bson.M{"$gt": invoice.Items[line.Line].Quantity - line.Quantity}}
+		inc[field] = line.Quantity
+	}
+	var reserved models.Invoice
+	err = config.DB.Collection("invoices").FindOneAndUpdate(context.Background(), filter, bson.M{"$inc": inc},
+		options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"credit_notes_issued": 1}),
+	).Decode(&reserved)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusConflict, gin.H{"error": "Some of the quantities have been returned meanwhile"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record the return"})
+		return
+	}
+
+	now := time.Now()
+	creditNote := models.Invoice{
+		CustomerID:        invoice.CustomerID,
+		CompanyID:         invoice.CompanyID,
+		Date:              now,
+		Reason:            req.Reason,
+		Status:            "Issued",
+		DocumentType:      "credit_note",
+		OriginalInvoiceID: invoice.ID.Hex(),
+		OriginalReference: invoice.ReferenceNumber,
+		PaymentType:       invoice.PaymentType,
+		WithholdingRate:   invoice.WithholdingRate,
+		Items:             items,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+	for _, item := range items {
+		creditNote.Subtotal += item.Subtotal
+		creditNote.TaxAmount += item.TaxAmount
+	}
+	creditNote.Subtotal = round2(creditNote.Subtotal)
+	creditNote.TaxAmount = round2(creditNote.TaxAmount)
+	creditNote.Amount = round2(creditNote.Subtotal + creditNote.TaxAmount)
+	creditNote.WithholdingAmount = round2(creditNote.Subtotal * creditNote.WithholdingRate / 100)
+
+	creditNote.ReferenceNumber = fmt.Sprintf("CN-%s-%d", invoice.ReferenceNumber, reserved.CreditNotesIssued)
+	res, err := config.DB.Collection("invoices").InsertOne(context.Background(), creditNote)
+	if err != nil {
+		releaseReturnedQuantities(invoice, req.Lines)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create credit note"})
+		return
+	}
+	creditNote.ID = res.InsertedID.(primitive.ObjectID)
+
+	// The credit settles what is still owed before anything is paid back.
+	// A credit note worth nothing pays nothing: an amount of 0 would settle
+	// the whole balance.
+	var payment invoicePayment
+	if balance := invoiceBalance(invoice); balance > 0 && creditNote.Amount > 0 {
+		payment, err = recordInvoicePayment(invoice, math.Min(creditNote.Amount, balance), models.Payment{
+			PaidAt:    now,
+			Method:    "credit_note",
+			Source:    "credit_note",
+			Reference: creditNote.ReferenceNumber,
+		})
+		if errors.Is(err, errInvoiceSettled) {
+			// Settled meanwhile, so the whole credit is paid back
+			payment, err = invoicePayment{}, nil
+		}
+		if err != nil {
+			fmt.Println("Error applying credit note to invoice", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Credit note created but it could not be applied to the invoice"})
+			return
+		}
+	}
+
+	response := gin.H{
+		"message":            "Return recorded successfully",
+		"credit_note":        creditNote,
+		"applied_to_balance": payment.Applied,
+	}
+	if amount := round2(creditNote.Amount - payment.Applied); amount > 0 {
+		refund, err := issueRefund(invoice, creditNote, amount, payment.Applied, req)
+		if err != nil {
+			fmt.Println("Error issuing refund", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Credit note created but the refund could not be recorded"})
+			return
+		}
+		response["refund"] = refund
+	}
+
+	if req.Restock {
+		restockReturnedItems(items)
+	}
+	invalidateDashboardCache(invoice.CompanyID)
+	c.JSON(http.StatusCreated, response)
+}
+
+// releaseReturnedQuantities undoes the reservation of returned quantities
+// when no credit note could be issued for them.
+func releaseReturnedQuantities(invoice models.Invoice, lines []models.InvoiceReturnLine) {
+	inc := bson.M{}
+	for _, line := range lines {
+		inc[fmt.Sprintf("items.%d.returned_quantity", line.Line)] = -line.Quantity
+	}
+	_, err := config.DB.Collection("invoices").UpdateOne(context.Background(), bson.M{"_id": invoice.ID}, bson.M{"$inc": inc})
+	if err != nil {
+		fmt.Println("Error releasing returned quantities", err)
+	}
+}
+
+// issueRefund records the refund of a credit note. Refunds kept as customer
+// credit are added to the customer's credit balance.
+func issueRefund(invoice, creditNote models.Invoice, amount, appliedToBalance float64, req models.InvoiceReturnRequest) (models.Refund, error) {
+	method := req.RefundMethod
+	if method == "" || method == "original" {
+		method = originalRefundMethod(invoice)
+	}
+	refund := models.Refund{
+		CompanyID:        invoice.CompanyID,
+		CustomerID:       invoice.CustomerID,
+		InvoiceID:        invoice.ID.Hex(),
+		CreditNoteID:     creditNote.ID.Hex(),
+		Amount:           amount,
+		AppliedToBalance: appliedToBalance,
+		Method:           method,
+		Reference:        req.Reference,
+		Reason:           req.Reason,
+		CreatedAt:        creditNote.Date,
+	}
+
+	if method == "credit" {
+		customerID, err := primitive.ObjectIDFromHex(invoice.CustomerID)
+		if err != nil {
+			return refund, err
+		}
+		companyID, err := primitive.ObjectIDFromHex(invoice.CompanyID)
+		if err != nil {
+			return refund, err
+		}
+		_, err = addCustomerCredit(models.CustomerCredit{
+			CompanyID:  companyID,
+			CustomerID: customerID,
+			Source:     "credit_note",
+			SourceID:   creditNote.ID.Hex(),
+			Reference:  creditNote.ReferenceNumber,
+			Amount:     amount,
+		})
+		if err != nil {
+			return refund, err
+		}
+	}
+
+	res, err := config.DB.Collection("refunds").InsertOne(context.Background(), refund)
+	if err != nil {
+		return refund, err
+	}
+	refund.ID = res.InsertedID.(primitive.ObjectID)
+	return refund, nil
+}
+
+// restockReturnedItems puts returned quantities back in stock for the items
+// that track it. Failures are logged; the return itself stands.
+func restockReturnedItems(items []models.InvoiceItem) {
+	for _, item := range items {
+		itemID, err := primitive.ObjectIDFromHex(item.ItemID)
+		if err != nil {
+			continue
+		}
+		_, err = config.DB.Collection("items").UpdateOne(context.Background(),
+			bson.M{"_id": itemID, "stock": bson.M{"$exists": true}},
+			bson.M{"$inc": bson.M{"stock": item.Quantity}},
+		)
+		if err != nil {
+			fmt.Println("Error restocking returned item", err)
+		}
+	}
+}
+
+// DownloadRefundReceipt godoc
+// @Summary Download a refund receipt as PDF
+// @Description Renders the receipt of a refund with the returned items, in the requested layout or the company's default one.
+// @Tags Invoices
+// @Produce application/pdf
+// @Param id path string true "Refund ID"
+// @Param layout query string false "classic_a4, modern_a4, letter or thermal_80mm"
+// @Success 200 {file} binary
+// @Failure 400 {object} map[string]string "Invalid ID or layout"
+// @Failure 401 {object} map[string]string "No active company in the token"
+// @Failure 403 {object} map[string]string "Refund of another company"
+// @Failure 404 {object} map[string]string "Refund not found"
+// @Failure 500 {object} map[string]string "Failed to generate PDF"
+// @Router /invoice/refund/download/{id} [get]
+func DownloadRefundReceipt(c *gin.Context) {
+	layout := c.Query("layout")
+	if _, ok := findInvoiceLayout(layout); layout != "" && !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown layout: " + layout})
+		return
+	}
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid refund ID"})
+		return
+	}
+
+	var refund models.Refund
+	if err := config.DB.Collection("refunds").FindOne(context.Background(), bson.M{"_id": objID}).Decode(&refund); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Refund not found"})
+		return
+	}
+	if _, ok := scopedCompanyID(c, refund.CompanyID); !ok {
+		return
+	}
+	creditNoteID, err := primitive.ObjectIDFromHex(refund.CreditNoteID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Credit note not found"})
+		return
+	}
+	var creditNote models.Invoice
+	if err := config.DB.Collection("invoices").FindOne(context.Background(), bson.M{"_id": creditNoteID}).Decode(&creditNote); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Credit note not found"})
+		return
+	}
+
+	company, customer := fetchInvoiceParties(creditNote)
+	data, filename, err := renderRefundReceiptPDF(refund, &creditNote, company, customer, layout)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate PDF"})
+		return
+	}
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(http.StatusOK, "application/pdf", data)
+}
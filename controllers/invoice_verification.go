@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	qrcode "github.com/skip2/go-qrcode"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// invoiceVerificationFields are the query parameters of a verification link,
+// in the order they are signed.
+var invoiceVerificationFields = []string{"id", "number", "amount", "date", "tin"}
+
+// invoiceVerificationSecret signs verification links. It is kept apart from
+// the JWT secret so rotating one does not affect the other.
+func invoiceVerificationSecret() []byte {
+	return []byte(os.Getenv("INVOICE_VERIFICATION_SECRET"))
+}
+
+// invoiceVerificationValues are the facts printed on the document that the
+// link vouches for.
+func invoiceVerificationValues(invoice models.Invoice, company models.Company) url.Values {
+	values := url.Values{}
+	values.Set("id", invoice.ID.Hex())
+	values.Set("number", invoice.ReferenceNumber)
+	values.Set("amount", fmt.Sprintf("%.2f", invoice.Amount))
+	values.Set("date", invoice.Date.Format("2006-01-02"))
+	values.Set("tin", company.TIN)
+	return values
+}
+
+func signInvoiceVerification(values url.Values) string {
+	parts := make([]string, len(invoiceVerificationFields))
+	for i, field := range invoiceVerificationFields {
+		parts[i] = values.Get(field)
+	}
+	mac := hmac.New(sha256.New, invoiceVerificationSecret())
+	mac.Write([]byte(strings.Join(parts, "\n")))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// InvoiceVerificationURL is the signed public link printed as a QR code on
+// invoices and receipts. API_BASE_URL sets the host it points to.
+func InvoiceVerificationURL(invoice models.Invoice, company models.Company) string {
+	baseURL := os.Getenv("API_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080/api"
+	}
+	values := invoiceVerificationValues(invoice, company)
+	values.Set("sig", signInvoiceVerification(values))
+	return strings.TrimSuffix(baseURL, "/") + "/invoice/verify?" + values.Encode()
+}
+
+// invoiceQRCode encodes the verification link as a PNG QR code.
+func invoiceQRCode(link string) ([]byte, error) {
+	return qrcode.Encode(link, qrcode.Medium, 256)
+}
+
+// VerifyInvoice godoc
+// @Summary Verify a printed invoice
+// @Description Public endpoint behind the QR code on invoices and receipts. Confirms the link was issued by this system and that the invoice number, amount, date and company TIN still match the stored invoice. Nothing beyond the values in the link is returned.
+// @Tags Invoices
+// @Produce json
+// @Param id query string true "Invoice ID"
+// @Param number query string true "Invoice reference number"
+// @Param amount query string true "Invoice amount"
+// @Param date query string true "Invoice date (YYYY-MM-DD)"
+// @Param tin query string false "Company TIN"
+// @Param sig query string true "Link signature"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{} "Bad Request"
+// @Failure 404 {object} map[string]interface{} "Invoice not found"
+// @Router /invoice/verify [get]
+func VerifyInvoice(c *gin.Context) {
+	query := c.Request.URL.Query()
+	invoiceID, err := primitive.ObjectIDFromHex(query.Get("id"))
+	if err != nil || query.Get("sig") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"valid": false, "result": "invalid_link", "message": "The verification link is incomplete"})
+		return
+	}
+
+	if !hmac.Equal([]byte(signInvoiceVerification(query)), []byte(query.Get("sig"))) {
+		c.JSON(http.StatusOK, gin.H{"valid": false, "result": "invalid_signature", "message": "The verification link was not issued by this system or has been edited"})
+		return
+	}
+
+	var invoice models.Invoice
+	err = config.DB.Collection("invoices").FindOne(context.Background(), bson.M{"_id": invoiceID}).Decode(&invoice)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"valid": false, "result": "not_found", "message": "No such invoice was issued"})
+		return
+	}
+	var company models.Company
+	if companyID, err := primitive.ObjectIDFromHex(invoice.CompanyID); err == nil {
+		company, _ = fetchCompanyByID(companyID)
+	}
+
+	stored := invoiceVerificationValues(invoice, company)
+	if signInvoiceVerification(stored) != query.Get("sig") {
+		c.JSON(http.StatusOK, gin.H{"valid": false, "result": "altered", "message": "The invoice has changed since this document was issued"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"valid":            true,
+		"result":           "genuine",
+		"message":          "The document matches the issued invoice",
+		"reference_number": stored.Get("number"),
+		"amount":           stored.Get("amount"),
+		"date":             stored.Get("date"),
+		"company_tin":      stored.Get("tin"),
+	})
+}
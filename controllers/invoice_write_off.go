@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// holdForWriteOff puts the customer of a written-off invoice on credit hold.
+func holdForWriteOff(invoice models.Invoice, hold models.CustomerCreditHold) error {
+	companyID, err := primitive.ObjectIDFromHex(invoice.CompanyID)
+	if err != nil {
+		return err
+	}
+	customerID, err := primitive.ObjectIDFromHex(invoice.CustomerID)
+	if err != nil {
+		return err
+	}
+	_, err = placeCreditHold(companyID, customerID, hold)
+	return err
+}
+
+// WriteOffInvoice godoc
+// @Summary Write off an unpaid invoice as bad debt
+// @Description Closes an invoice that will not be collected with the "WrittenOff" status. What is still owed is written off: the full amount, or the remaining balance of a part-paid invoice. The customer's credit limit used by the invoice is given back, and the customer can be put on credit hold. Only the company owner can approve write-offs, with a token for the company; the loss shows in the dashboard and the bad debt and customer activity reports.
+// @Tags Invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Invoice ID"
+// @Param write_off body models.WriteOffRequest true "Reason and credit hold"
+// @Success 200 {object} map[string]interface{} "Amount written off"
+// @Failure 400 {object} map[string]string "Invalid input or invoice not unpaid"
+// @Failure 401 {object} map[string]string "No token for a company"
+// @Failure 403 {object} map[string]string "Not the company owner, or invoice of another company"
+// @Failure 404 {object} map[string]string "Invoice not found"
+// @Failure 409 {object} map[string]string "The invoice was paid or written off meanwhile"
+// @Failure 500 {object} map[string]string "Failed to write off invoice"
+// @Router /invoice/write-off/{id} [post]
+func WriteOffInvoice(c *gin.Context) {
+	var req models.WriteOffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	approver, ok := requireOwner(c, "approve write-offs")
+	if !ok {
+		return
+	}
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invoice ID"})
+		return
+	}
+
+	var invoice models.Invoice
+	if err := config.DB.Collection("invoices").FindOne(context.Background(), bson.M{"_id": objID}).Decode(&invoice); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invoice not found"})
+		return
+	}
+	if _, ok := scopedCompanyID(c, invoice.CompanyID); !ok {
+		return
+	}
+	if invoice.DocumentType == "credit_note" || invoice.Status != "Unpaid" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only unpaid invoices can be written off"})
+		return
+	}
+
+	now := time.Now()
+	writeOff := models.InvoiceWriteOff{
+		Amount:       invoiceBalance(invoice),
+		Reason:       req.Reason,
+		ApprovedBy:   approver,
+		WrittenOffAt: now,
+	}
+	// Matching the status keeps a payment and a write-off from both closing it
+	result, err := config.DB.Collection("invoices").UpdateOne(context.Background(),
+		bson.M{"_id": invoice.ID, "status": "Unpaid"},
+		bson.M{"$set": bson.M{"status": "WrittenOff", "write_off": writeOff, "updated_at": now}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to write off invoice"})
+		return
+	}
+	if result.MatchedCount == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Invoice has been paid or written off meanwhile"})
+		return
+	}
+	releaseCustomerCredit(invoice)
+
+	if req.CreditHold {
+		err := holdForWriteOff(invoice, models.CustomerCreditHold{
+			Reason:   fmt.Sprintf("Invoice %s written off: %s", invoice.ReferenceNumber, req.Reason),
+			Source:   "write_off",
+			PlacedBy: approver,
+			PlacedAt: now,
+		})
+		if err != nil {
+			fmt.Println("Error placing customer on credit hold", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invoice written off but the customer could not be put on credit hold"})
+			return
+		}
+	}
+	invalidateDashboardCache(invoice.CompanyID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Invoice written off successfully",
+		"invoice_id":  invoice.ID.Hex(),
+		"written_off": writeOff.Amount,
+		"credit_hold": req.CreditHold,
+	})
+}
@@ -2,11 +2,7 @@ package controllers
 
 import (
 	"context"
-	"encoding/csv"
-	"fmt"
-	"io"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/bisre1921/billing-and-invoice-system/config"
@@ -26,6 +22,7 @@ import (
 // @Param item body models.Item true "Item Data"
 // @Success 201 {object} models.GenericResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
 // @Failure 500 {object} models.ErrorResponse
 // @Router /item/add [post]
 // @Security BearerAuth
@@ -36,10 +33,11 @@ func AddItem(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if item.CompanyID.IsZero() {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "CompanyID is required"})
+	companyID, ok := scopedCompanyObjectID(c, item.CompanyID)
+	if !ok {
 		return
 	}
+	item.CompanyID = companyID
 
 	// Validate item code
 	if item.Code == "" {
@@ -91,6 +89,7 @@ func AddItem(c *gin.Context) {
 // @Param item body models.Item true "Updated item data"
 // @Success 200 {object} models.GenericResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /item/update/{id} [put]
@@ -135,8 +134,15 @@ func UpdateItem(c *gin.Context) {
 			"updated_at":    item.UpdatedAt,
 		},
 	}
+	if item.Stock != nil {
+		update["$set"].(bson.M)["stock"] = *item.Stock
+	}
 
-	result, err := config.DB.Collection("items").UpdateByID(context.Background(), itemID, update)
+	filter := bson.M{"_id": itemID}
+	if !scopeToActiveCompany(c, filter) {
+		return
+	}
+	result, err := config.DB.Collection("items").UpdateOne(context.Background(), filter, update)
 	if err != nil || result.MatchedCount == 0 {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update item"})
 		return
@@ -154,6 +160,7 @@ func UpdateItem(c *gin.Context) {
 // @Param id path string true "Item ID"
 // @Success 200 {object} models.GenericResponse
 // @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /item/delete/{id} [delete]
@@ -166,7 +173,11 @@ func DeleteItem(c *gin.Context) {
 		return
 	}
 
-	result, err := config.DB.Collection("items").DeleteOne(context.Background(), bson.M{"_id": itemID})
+	filter := bson.M{"_id": itemID}
+	if !scopeToActiveCompany(c, filter) {
+		return
+	}
+	result, err := config.DB.Collection("items").DeleteOne(context.Background(), filter)
 	if err != nil || result.DeletedCount == 0 {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete item"})
 		return
@@ -182,12 +193,17 @@ func DeleteItem(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Success 200 {array} models.Item
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
 // @Failure 404 {object} models.ErrorResponse
 // @Failure 500 {object} models.ErrorResponse
 // @Router /item/all [get]
 // @Security BearerAuth
 func ListItems(c *gin.Context) {
-	cursor, err := config.DB.Collection("items").Find(context.Background(), bson.M{})
+	filter := bson.M{}
+	if !scopeToActiveCompany(c, filter) {
+		return
+	}
+	cursor, err := config.DB.Collection("items").Find(context.Background(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong while retrieving items"})
 		return
@@ -217,13 +233,13 @@ func ListItems(c *gin.Context) {
 // @Param company_id path string true "Company ID"
 // @Success 200 {array} models.Item "Successfully retrieved items"
 // @Failure 400 {object} models.ErrorResponse "Invalid company ID"
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
 // @Failure 404 {object} models.ErrorResponse "No items found for the company"
 // @Failure 500 {object} models.ErrorResponse "Failed to retrieve items"
 // @Router /item/company/company_id [get]
 func GetItemsByCompanyID(c *gin.Context) {
-	companyIDParam := c.Param("company_id")
-	if companyIDParam == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Company ID is required"})
+	companyIDParam, ok := scopedCompanyID(c, c.Param("company_id"))
+	if !ok {
 		return
 	}
 
@@ -259,6 +275,8 @@ func GetItemsByCompanyID(c *gin.Context) {
 // @Param id path string true "Item ID"
 // @Success 200 {object} models.Item "Item found"
 // @Failure 400 {object} models.ErrorResponse "Invalid item ID"
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
+// @Failure 403 {object} models.ErrorResponse "Item of another company"
 // @Failure 404 {object} models.ErrorResponse "Item not found"
 // @Failure 500 {object} models.ErrorResponse "Failed to fetch item"
 // @Router /item/{id} [get]
@@ -281,158 +299,47 @@ func GetItem(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch item"})
 		return
 	}
-
-	c.JSON(http.StatusOK, item)
-}
-
-// ImportItems godoc
-// @Summary Import items from CSV file
-// @Description Import multiple items from a CSV file
-// @Tags Item
-// @Accept multipart/form-data
-// @Produce json
-// @Param file formData file true "CSV file containing item data"
-// @Param company_id formData string true "Company ID"
-// @Success 201 {object} models.GenericResponse "Items imported successfully"
-// @Failure 400 {object} models.ErrorResponse "Invalid file format or data"
-// @Failure 500 {object} models.ErrorResponse "Failed to import items"
-// @Router /item/import [post]
-// @Security BearerAuth
-func ImportItems(c *gin.Context) {
-	// Get company ID from form data
-	companyID := c.PostForm("company_id")
-	if companyID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Company ID is required"})
-		return
-	}
-
-	objCompanyID, err := primitive.ObjectIDFromHex(companyID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
-		return
-	}
-
-	// Get the file from the request
-	file, err := c.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
-		return
-	}
-
-	// Check file extension
-	if file.Filename[len(file.Filename)-4:] != ".csv" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Only CSV files are allowed"})
-		return
-	}
-
-	// Open the file
-	src, err := file.Open()
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open file"})
-		return
-	}
-	defer src.Close()
-
-	// Create CSV reader
-	reader := csv.NewReader(src)
-
-	// Read header
-	header, err := reader.Read()
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read CSV header"})
-		return
-	}
-
-	// Validate header
-	expectedHeaders := []string{"code", "name", "description", "category", "selling_price", "unit"}
-	if len(header) < len(expectedHeaders) {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid CSV format. Required columns: code, name, description, category, selling_price, unit"})
+	if _, ok := scopedCompanyID(c, item.CompanyID.Hex()); !ok {
 		return
 	}
 
-	var items []interface{}
-	var duplicateCodes []string
-	lineNumber := 2 // Start from 2 because line 1 is header
-
-	// Read and process each row
-	for {
-		row, err := reader.Read()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Error reading line %d: %v", lineNumber, err)})
-			return
-		}
-
-		// Check if item code already exists
-		var existingItem models.Item
-		err = config.DB.Collection("items").FindOne(context.Background(),
-			bson.M{
-				"code":       row[0],
-				"company_id": objCompanyID,
-			}).Decode(&existingItem)
+	c.JSON(http.StatusOK, item)
+}
 
-		if err == nil {
-			// Item code already exists
-			duplicateCodes = append(duplicateCodes, row[0])
-			lineNumber++
+// takeInvoicedStock takes the invoiced quantities out of stock for the items
+// of the company that track it. When one of them has too little on hand, the
+// quantities taken so far are put back and its name is returned.
+func takeInvoicedStock(companyID primitive.ObjectID, items []models.InvoiceItem) (string, error) {
+	var taken []models.InvoiceItem
+	for _, item := range items {
+		itemID, err := primitive.ObjectIDFromHex(item.ItemID)
+		if err != nil || item.Quantity <= 0 {
 			continue
 		}
-
-		// Parse selling price
-		sellingPrice, err := strconv.ParseFloat(row[4], 64)
+		result, err := config.DB.Collection("items").UpdateOne(context.Background(),
+			bson.M{"_id": itemID, "company_id": companyID, "stock": bson.M{"$gte": item.Quantity}},
+			bson.M{"$inc": bson.M{"stock": -item.Quantity}},
+		)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": fmt.Sprintf("Invalid selling price at line %d: %s", lineNumber, row[4]),
-			})
-			return
+			restockReturnedItems(taken)
+			return "", err
 		}
-
-		// Create new item
-		item := models.Item{
-			ID:           primitive.NewObjectID(),
-			Code:         row[0],
-			Name:         row[1],
-			Description:  row[2],
-			Category:     row[3],
-			SellingPrice: sellingPrice,
-			Unit:         row[5],
-			CompanyID:    objCompanyID,
-			CreatedAt:    time.Now(),
-			UpdatedAt:    time.Now(),
-		}
-
-		items = append(items, item)
-		lineNumber++
-	}
-
-	if len(items) == 0 {
-		if len(duplicateCodes) > 0 {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": fmt.Sprintf("All items have duplicate codes: %v", duplicateCodes),
-			})
-			return
+		if result.MatchedCount == 0 {
+			// Items without stock tracking are not matched either
+			tracked, err := config.DB.Collection("items").CountDocuments(context.Background(),
+				bson.M{"_id": itemID, "company_id": companyID, "stock": bson.M{"$exists": true}},
+			)
+			if err != nil {
+				restockReturnedItems(taken)
+				return "", err
+			}
+			if tracked > 0 {
+				restockReturnedItems(taken)
+				return item.ItemName, nil
+			}
+			continue
 		}
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid items found in CSV"})
-		return
+		taken = append(taken, item)
 	}
-
-	// Insert all valid items
-	_, err = config.DB.Collection("items").InsertMany(context.Background(), items)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import items"})
-		return
-	}
-
-	response := gin.H{
-		"message": fmt.Sprintf("Successfully imported %d items", len(items)),
-	}
-	if len(duplicateCodes) > 0 {
-		response["skipped_codes"] = duplicateCodes
-		response["message"] = fmt.Sprintf("Imported %d items. Skipped %d items with duplicate codes",
-			len(items), len(duplicateCodes))
-	}
-
-	c.JSON(http.StatusCreated, response)
+	return "", nil
 }
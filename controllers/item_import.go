@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	itemImportModes    = []string{"create", "upsert"}
+	itemPriceColumns   = []string{"selling_price", "price"}
+	itemImportRequired = [][]string{{"code"}, {"name"}, {"category"}, itemPriceColumns}
+)
+
+// fetchImportedItems loads the company's items with the codes of an import,
+// in one query, keyed by code.
+func fetchImportedItems(companyID primitive.ObjectID, sheet importSheet) (map[string]models.Item, error) {
+	var codes []string
+	for _, row := range sheet.rows {
+		if code := sheet.cell(row, "code"); code != "" {
+			codes = append(codes, code)
+		}
+	}
+	items := map[string]models.Item{}
+	if len(codes) == 0 {
+		return items, nil
+	}
+
+	cursor, err := config.DB.Collection("items").Find(context.Background(), bson.M{"company_id": companyID, "code": bson.M{"$in": codes}})
+	if err != nil {
+		return nil, err
+	}
+	var existing []models.Item
+	if err := cursor.All(context.Background(), &existing); err != nil {
+		return nil, err
+	}
+	for _, item := range existing {
+		items[item.Code] = item
+	}
+	return items, nil
+}
+
+// rollbackItemImport undoes the batches an all-or-nothing import wrote before
+// one of them failed: new items are deleted and updated ones put back.
+func rollbackItemImport(inserted []primitive.ObjectID, previous []models.Item) {
+	if len(inserted) > 0 {
+		if _, err := config.DB.Collection("items").DeleteMany(context.Background(), bson.M{"_id": bson.M{"$in": inserted}}); err != nil {
+			fmt.Println("Error rolling back imported items", err)
+		}
+	}
+	var restores []mongo.WriteModel
+	for _, item := range previous {
+		restores = append(restores, mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": item.ID}).SetReplacement(item))
+	}
+	if err := writeImportBatches("items", restores); err != nil {
+		fmt.Println("Error rolling back updated items", err)
+	}
+}
+
+// ImportItems godoc
+// @Summary Import items from a CSV or XLSX file
+// @Description Creates items from the rows of a file, with columns matched by their header: code, name, category and selling_price (or price) are required; description, unit and stock are optional. In the default create mode rows with codes the company already has are errors; in upsert mode they update the existing items, changing only the columns in the file. Rows with errors are reported by line, with a CSV of them downloadable through the error_report_id; the other rows are imported unless all_or_nothing is set, in which case any error, or a failure while saving, leaves the items unchanged. Rows are saved in batches. With dry_run nothing is saved and the result shows what the import would do.
+// @Tags Item
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV or XLSX file with item data"
+// @Param company_id formData string false "Company ID, defaults to the token's active company"
+// @Param mode formData string false "create (default) or upsert"
+// @Param dry_run formData boolean false "Validate and preview without saving"
+// @Param all_or_nothing formData boolean false "Import nothing when any row has an error"
+// @Success 200 {object} models.ImportResult "Dry run result"
+// @Success 201 {object} models.ImportResult "Items imported"
+// @Failure 400 {object} map[string]interface{} "Invalid file or missing columns, or no rows imported with the result"
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse "Failed to import items"
+// @Router /item/import [post]
+// @Security BearerAuth
+func ImportItems(c *gin.Context) {
+	companyID, ok := activeCompanyID(c, c.PostForm("company_id"), "import items")
+	if !ok {
+		return
+	}
+	objCompanyID, err := primitive.ObjectIDFromHex(companyID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return
+	}
+	mode := c.DefaultPostForm("mode", "create")
+	if !contains(itemImportModes, mode) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("mode must be one of %v", itemImportModes)})
+		return
+	}
+	dryRun, ok := optionalBoolForm(c, "dry_run")
+	if !ok {
+		return
+	}
+	allOrNothing, ok := optionalBoolForm(c, "all_or_nothing")
+	if !ok {
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is required"})
+		return
+	}
+	sheet, err := readImportSheet(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if missing := sheet.missing(itemImportRequired...); len(missing) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing required columns: " + strings.Join(missing, ", ")})
+		return
+	}
+
+	existingItems, err := fetchImportedItems(objCompanyID, sheet)
+	if err != nil {
+		fmt.Println("Error fetching items for import", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch existing items"})
+		return
+	}
+
+	result := models.ImportResult{DryRun: dryRun}
+	var writes []mongo.WriteModel
+	var inserted []primitive.ObjectID
+	var previous []models.Item
+	seen := map[string]int{} // codes of rows already read, to their line
+	now := time.Now()
+
+	for i, row := range sheet.rows {
+		if row == nil {
+			continue
+		}
+		result.Rows++
+		line := importLine(i)
+		var rowErrors []models.ImportRowError
+		fail := func(column, value, message string) {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: line, Column: column, Value: value, Error: message})
+		}
+
+		item := models.Item{
+			Code:        sheet.cell(row, "code"),
+			Name:        sheet.cell(row, "name"),
+			Description: sheet.cell(row, "description"),
+			Category:    sheet.cell(row, "category"),
+			Unit:        sheet.cell(row, "unit"),
+		}
+		for _, column := range []string{"code", "name", "category"} {
+			if sheet.cell(row, column) == "" {
+				fail(column, "", column+" is required")
+			}
+		}
+		price := sheet.cell(row, itemPriceColumns...)
+		if parsed, err := strconv.ParseFloat(price, 64); err != nil {
+			fail(itemPriceColumns[0], price, "not a number")
+		} else if parsed < 0 {
+			fail(itemPriceColumns[0], price, "cannot be negative")
+		} else {
+			item.SellingPrice = parsed
+		}
+		if stock := sheet.cell(row, "stock"); stock != "" {
+			parsed, err := strconv.Atoi(stock)
+			if err != nil || parsed < 0 {
+				fail("stock", stock, "must be a whole number of at least 0")
+			} else {
+				item.Stock = &parsed
+			}
+		}
+
+		existing, found := existingItems[item.Code]
+		if item.Code != "" {
+			if first, dup := seen[item.Code]; dup {
+				fail("code", item.Code, fmt.Sprintf("same code as line %d", first))
+			} else {
+				seen[item.Code] = line
+			}
+			if found && mode == "create" {
+				fail("code", item.Code, "an item with this code already exists; import with mode upsert to update it")
+			}
+		}
+
+		if len(rowErrors) > 0 {
+			result.Failed++
+			result.Errors = append(result.Errors, rowErrors...)
+			continue
+		}
+
+		if !found {
+			item.ID = primitive.NewObjectID()
+			item.CompanyID = objCompanyID
+			item.CreatedAt = now
+			item.UpdatedAt = now
+			writes = append(writes, mongo.NewInsertOneModel().SetDocument(item))
+			inserted = append(inserted, item.ID)
+			result.Created++
+			continue
+		}
+
+		// Only the columns in the file change an existing item
+		set := bson.M{
+			"name":          item.Name,
+			"category":      item.Category,
+			"selling_price": item.SellingPrice,
+			"updated_at":    now,
+		}
+		for _, column := range []string{"description", "unit"} {
+			if sheet.has(column) {
+				set[column] = sheet.cell(row, column)
+			}
+		}
+		if item.Stock != nil {
+			set["stock"] = *item.Stock
+		}
+		writes = append(writes, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": existing.ID}).SetUpdate(bson.M{"$set": set}))
+		previous = append(previous, existing)
+		result.Updated++
+	}
+
+	if result.Rows == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No items found in the file"})
+		return
+	}
+	result.ErrorReportID = saveImportErrorReport(objCompanyID, "items", file.Filename, result.Errors)
+	if dryRun {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+	if allOrNothing && result.Failed > 0 {
+		result.Created, result.Updated = 0, 0
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Nothing imported: %d rows have errors", result.Failed), "result": result})
+		return
+	}
+	if len(writes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid items found in the file", "result": result})
+		return
+	}
+
+	if err := writeImportBatches("items", writes); err != nil {
+		fmt.Println("Error importing items", err)
+		if allOrNothing {
+			rollbackItemImport(inserted, previous)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import items", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
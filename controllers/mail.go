@@ -0,0 +1,342 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/mailer"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// MailWorkerInterval is how often the outbox is checked for messages to send.
+	MailWorkerInterval = 15 * time.Second
+	// MailMaxAttempts is how many deliveries are tried before a message is dead-lettered.
+	MailMaxAttempts = 5
+	// MailRetryBaseDelay is the wait after the first failure; it doubles on every further failure.
+	MailRetryBaseDelay = time.Minute
+	mailRetryMaxDelay  = 6 * time.Hour
+	// mailSendingLease lets another worker pick up a message whose sender died mid-delivery.
+	mailSendingLease = 5 * time.Minute
+)
+
+var outboxStatuses = []string{"Pending", "Sending", "Sent", "Dead"}
+
+// enqueueMail stores a message in the outbox for the mail worker to deliver.
+func enqueueMail(msg models.OutboxMessage) (models.OutboxMessage, error) {
+	if len(msg.To) == 0 {
+		return msg, fmt.Errorf("message has no recipients")
+	}
+	now := time.Now()
+	msg.Status = "Pending"
+	msg.Attempts = 0
+	if msg.MaxAttempts == 0 {
+		msg.MaxAttempts = MailMaxAttempts
+	}
+	for i := range msg.Attachments {
+		msg.Attachments[i].Size = len(msg.Attachments[i].Content)
+	}
+	msg.NextAttemptAt = now
+	msg.CreatedAt = now
+	msg.UpdatedAt = now
+
+	res, err := config.DB.Collection("mail_outbox").InsertOne(context.Background(), msg)
+	if err != nil {
+		return msg, err
+	}
+	msg.ID = res.InsertedID.(primitive.ObjectID)
+	return msg, nil
+}
+
+// StartMailWorker delivers queued mail in the background until ctx is cancelled.
+func StartMailWorker(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(MailWorkerInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if config.DB == nil {
+					continue
+				}
+				ProcessMailOutbox(now)
+			}
+		}
+	}()
+}
+
+// ProcessMailOutbox sends every message that is due and returns how many were
+// attempted.
+func ProcessMailOutbox(now time.Time) int {
+	// Each company's transport is built once per run
+	transports := map[string]mailer.Transport{}
+
+	attempted := 0
+	for {
+		msg, err := claimOutboxMessage(now)
+		if err == mongo.ErrNoDocuments {
+			return attempted
+		}
+		if err != nil {
+			fmt.Println("Error claiming outbox message:", err)
+			return attempted
+		}
+		transport, ok := transports[msg.CompanyID]
+		if !ok {
+			transport, err = companyMailTransport(msg.CompanyID)
+			if err != nil {
+				fmt.Println("Error configuring mail transport:", err)
+				transport = unconfiguredTransport{err: err}
+			}
+			transports[msg.CompanyID] = transport
+		}
+		deliverOutboxMessage(transport, msg, now)
+		attempted++
+	}
+}
+
+// companyMailTransport returns the transport mail from the company is sent
+// with: its own mail settings over the server's, or the server's default
+// transport when it has none.
+func companyMailTransport(companyID string) (mailer.Transport, error) {
+	m := fetchCompanySettings(companyID).Mail
+	if m == nil || (m.Transport == "" && m.Host == "" && m.Port == 0 && m.Username == "" && len(m.Password) == 0 && m.From == "") {
+		return mailer.Default()
+	}
+
+	cfg, err := mailer.ConfigFromEnv()
+	if err != nil && m.Port == 0 {
+		return nil, err
+	}
+	if m.Transport != "" {
+		cfg.Transport = m.Transport
+	}
+	if m.Host != "" {
+		// The server's login and sender are not used on another server
+		cfg.Host, cfg.Username, cfg.Password, cfg.From = m.Host, "", "", ""
+	}
+	if m.Port != 0 {
+		cfg.Port = m.Port
+	}
+	if m.Username != "" {
+		cfg.Username = m.Username
+	}
+	if len(m.Password) > 0 {
+		password, err := openSecret(m.Password, "mail password")
+		if err != nil {
+			return nil, err
+		}
+		cfg.Password = string(password)
+	}
+	if m.From != "" {
+		cfg.From = m.From
+	}
+	return mailer.New(cfg)
+}
+
+// unconfiguredTransport fails every delivery with the error that kept the
+// transport from being built, so it is recorded on the message.
+type unconfiguredTransport struct {
+	err error
+}
+
+func (t unconfiguredTransport) Send(msg mailer.Message) error {
+	return fmt.Errorf("mail transport: %v", t.err)
+}
+
+// claimOutboxMessage marks the next due message as Sending so concurrent
+// workers do not deliver it twice.
+func claimOutboxMessage(now time.Time) (models.OutboxMessage, error) {
+	var msg models.OutboxMessage
+	err := config.DB.Collection("mail_outbox").FindOneAndUpdate(context.Background(),
+		bson.M{
+			"status":          bson.M{"$in": []string{"Pending", "Sending"}},
+			"next_attempt_at": bson.M{"$lte": now},
+		},
+		bson.M{"$set": bson.M{"status": "Sending", "next_attempt_at": now.Add(mailSendingLease), "updated_at": now}},
+		options.FindOneAndUpdate().SetSort(bson.M{"next_attempt_at": 1}).SetReturnDocument(options.After),
+	).Decode(&msg)
+	return msg, err
+}
+
+func deliverOutboxMessage(transport mailer.Transport, msg models.OutboxMessage, now time.Time) {
+	sendErr := transport.Send(outboxToMailerMessage(msg))
+	attempts := msg.Attempts + 1
+
+	set := bson.M{"attempts": attempts, "updated_at": time.Now()}
+	switch {
+	case sendErr == nil:
+		set["status"] = "Sent"
+		set["sent_at"] = time.Now()
+		set["last_error"] = ""
+	case attempts >= msg.MaxAttempts:
+		set["status"] = "Dead"
+		set["last_error"] = sendErr.Error()
+		fmt.Println("Error delivering mail, giving up:", msg.ID.Hex(), sendErr)
+	default:
+		set["status"] = "Pending"
+		set["last_error"] = sendErr.Error()
+		set["next_attempt_at"] = now.Add(mailRetryDelay(attempts))
+	}
+
+	_, err := config.DB.Collection("mail_outbox").UpdateOne(context.Background(), bson.M{"_id": msg.ID}, bson.M{"$set": set})
+	if err != nil {
+		fmt.Println("Error updating outbox message:", err)
+	}
+}
+
+// mailRetryDelay is the exponential backoff after the given number of failed
+// attempts.
+func mailRetryDelay(attempts int) time.Duration {
+	delay := MailRetryBaseDelay
+	for i := 1; i < attempts && delay < mailRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > mailRetryMaxDelay {
+		delay = mailRetryMaxDelay
+	}
+	return delay
+}
+
+func outboxToMailerMessage(msg models.OutboxMessage) mailer.Message {
+	m := mailer.Message{
+		FromName: msg.FromName,
+		ReplyTo:  msg.ReplyTo,
+		To:       msg.To,
+		Cc:       msg.Cc,
+		Subject:  msg.Subject,
+		TextBody: msg.TextBody,
+		HTMLBody: msg.HTMLBody,
+	}
+	for _, a := range msg.Attachments {
+		m.Attachments = append(m.Attachments, mailer.Attachment{Filename: a.Filename, ContentType: a.ContentType, Content: a.Content})
+	}
+	return m
+}
+
+// ListOutboxMessages godoc
+// @Summary List queued and sent emails
+// @Description Lists the active company's outbox messages with their delivery status, newest first
+// @Tags Mail
+// @Produce json
+// @Param company_id query string false "Company ID, defaults to the token's active company"
+// @Param status query string false "Pending, Sending, Sent or Dead"
+// @Success 200 {array} models.OutboxMessage
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /mail/outbox [get]
+// @Security BearerAuth
+func ListOutboxMessages(c *gin.Context) {
+	companyID, ok := activeCompanyID(c, c.Query("company_id"), "view its outbox")
+	if !ok {
+		return
+	}
+	filter := bson.M{"company_id": companyID}
+	if status := c.Query("status"); status != "" {
+		if !contains(outboxStatuses, status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("status must be one of %v", outboxStatuses)})
+			return
+		}
+		filter["status"] = status
+	}
+
+	opts := options.Find().SetSort(bson.M{"created_at": -1}).SetProjection(bson.M{"attachments.content": 0})
+	cursor, err := config.DB.Collection("mail_outbox").Find(context.Background(), filter, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch outbox"})
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	messages := []models.OutboxMessage{}
+	if err := cursor.All(context.Background(), &messages); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode outbox"})
+		return
+	}
+	c.JSON(http.StatusOK, messages)
+}
+
+// GetOutboxMessage godoc
+// @Summary Get an email's delivery status
+// @Tags Mail
+// @Produce json
+// @Param id path string true "Message ID"
+// @Success 200 {object} models.OutboxMessage
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /mail/outbox/{id} [get]
+// @Security BearerAuth
+func GetOutboxMessage(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	companyID, ok := activeCompanyID(c, "", "view its outbox")
+	if !ok {
+		return
+	}
+
+	var msg models.OutboxMessage
+	err = config.DB.Collection("mail_outbox").FindOne(context.Background(), bson.M{"_id": objID, "company_id": companyID},
+		options.FindOne().SetProjection(bson.M{"attachments.content": 0})).Decode(&msg)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+	c.JSON(http.StatusOK, msg)
+}
+
+// RetryOutboxMessage godoc
+// @Summary Retry a dead-lettered email
+// @Description Puts a message that exhausted its delivery attempts back in the queue
+// @Tags Mail
+// @Produce json
+// @Param id path string true "Message ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /mail/outbox/{id}/retry [post]
+// @Security BearerAuth
+func RetryOutboxMessage(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	companyID, ok := activeCompanyID(c, "", "retry its emails")
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	res, err := config.DB.Collection("mail_outbox").UpdateOne(context.Background(),
+		bson.M{"_id": objID, "company_id": companyID, "status": "Dead"},
+		bson.M{"$set": bson.M{"status": "Pending", "attempts": 0, "next_attempt_at": now, "updated_at": now}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to requeue message"})
+		return
+	}
+	if res.MatchedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No dead-lettered message with this ID"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Message queued for another delivery attempt"})
+}
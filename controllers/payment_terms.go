@@ -0,0 +1,340 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var paymentTermsTypes = []string{"net", "end_of_month", "due_on_receipt", "installments"}
+
+// validatePaymentTerms checks the values binding tags cannot.
+func validatePaymentTerms(terms models.PaymentTerms) error {
+	known := false
+	for _, t := range paymentTermsTypes {
+		known = known || terms.Type == t
+	}
+	if !known {
+		return fmt.Errorf("type must be one of net, end_of_month, due_on_receipt or installments")
+	}
+
+	if terms.Type == "installments" {
+		if len(terms.Installments) < 2 {
+			return fmt.Errorf("installments terms need at least two installments")
+		}
+		var total float64
+		for i, installment := range terms.Installments {
+			if i > 0 && installment.Days <= terms.Installments[i-1].Days {
+				return fmt.Errorf("installments must fall due on increasing days")
+			}
+			total += installment.Percent
+		}
+		if math.Abs(total-100) > 0.001 {
+			return fmt.Errorf("installment percentages must add up to 100, not %g", total)
+		}
+		if terms.DiscountPercent > 0 {
+			return fmt.Errorf("installments terms cannot have an early-payment discount")
+		}
+		return nil
+	}
+	if len(terms.Installments) > 0 {
+		return fmt.Errorf("only installments terms can have installments")
+	}
+	if terms.Type == "due_on_receipt" && terms.Days != 0 {
+		return fmt.Errorf("due_on_receipt terms cannot have days")
+	}
+
+	if terms.DiscountPercent == 0 && terms.DiscountDays > 0 {
+		return fmt.Errorf("discount_days needs a discount_percent")
+	}
+	if terms.DiscountPercent > 0 {
+		if terms.Type == "due_on_receipt" {
+			return fmt.Errorf("due_on_receipt terms cannot have an early-payment discount")
+		}
+		if terms.Type == "net" && terms.DiscountDays >= terms.Days {
+			return fmt.Errorf("discount_days must be fewer than days")
+		}
+	}
+	return nil
+}
+
+// applyPaymentTerms sets the due date, installment schedule and early-payment
+// discount of a credit invoice from the terms. Dates are worked out in the
+// company's timezone so "end of month" is the company's month end.
+func applyPaymentTerms(invoice *models.Invoice, terms models.PaymentTerms, loc *time.Location) {
+	issued := invoice.Date.In(loc)
+	var due time.Time
+	switch terms.Type {
+	case "end_of_month":
+		due = time.Date(issued.Year(), issued.Month()+1, 0, issued.Hour(), issued.Minute(), issued.Second(), 0, loc).AddDate(0, 0, terms.Days)
+	case "due_on_receipt":
+		due = issued
+	case "installments":
+		invoice.Installments = installmentSchedule(terms.Installments, issued, invoice.Amount)
+		due = invoice.Installments[len(invoice.Installments)-1].DueDate
+	default:
+		due = issued.AddDate(0, 0, terms.Days)
+	}
+	invoice.DueDate = &due
+	invoice.PaymentTermsID = terms.ID.Hex()
+	if invoice.Terms == "" {
+		invoice.Terms = terms.Name
+	}
+
+	if terms.DiscountPercent > 0 {
+		last := issued.AddDate(0, 0, terms.DiscountDays)
+		invoice.EarlyPayment = &models.EarlyPaymentDiscount{
+			Percent:  terms.DiscountPercent,
+			Amount:   round2(invoice.Amount * terms.DiscountPercent / 100),
+			Deadline: time.Date(last.Year(), last.Month(), last.Day(), 23, 59, 59, 0, loc),
+		}
+	}
+}
+
+// installmentSchedule splits the amount by the installment percentages. The
+// last installment takes the rounding difference so the parts add up.
+func installmentSchedule(installments []models.PaymentTermsInstallment, issued time.Time, amount float64) []models.InvoiceInstallment {
+	schedule := make([]models.InvoiceInstallment, len(installments))
+	remaining := amount
+	for i, installment := range installments {
+		part := round2(amount * installment.Percent / 100)
+		if i == len(installments)-1 {
+			part = round2(remaining)
+		}
+		remaining -= part
+		schedule[i] = models.InvoiceInstallment{
+			Number:  i + 1,
+			DueDate: issued.AddDate(0, 0, installment.Days),
+			Amount:  part,
+		}
+	}
+	return schedule
+}
+
+// earlyPaymentDiscount is the discount a payment made at paidAt earns, or 0
+// when the invoice has none or the payment is late.
+func earlyPaymentDiscount(invoice models.Invoice, paidAt time.Time) float64 {
+	if invoice.EarlyPayment == nil || paidAt.After(invoice.EarlyPayment.Deadline) {
+		return 0
+	}
+	return invoice.EarlyPayment.Amount
+}
+
+// fetchCompanyPaymentTerms loads payment terms that belong to the company.
+func fetchCompanyPaymentTerms(termsID, companyID primitive.ObjectID) (models.PaymentTerms, error) {
+	var terms models.PaymentTerms
+	err := config.DB.Collection("payment_terms").FindOne(context.Background(),
+		bson.M{"_id": termsID, "company_id": companyID}).Decode(&terms)
+	return terms, err
+}
+
+// fetchScopedPaymentTerms loads the payment terms named by the id path
+// parameter, writing the error response itself when they are missing or
+// belong to a company other than the token's.
+func fetchScopedPaymentTerms(c *gin.Context) (models.PaymentTerms, bool) {
+	var terms models.PaymentTerms
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payment terms ID"})
+		return terms, false
+	}
+	err = config.DB.Collection("payment_terms").FindOne(context.Background(), bson.M{"_id": objID}).Decode(&terms)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Payment terms not found"})
+		return terms, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payment terms"})
+		return terms, false
+	}
+	if _, ok := scopedCompanyID(c, terms.CompanyID.Hex()); !ok {
+		return terms, false
+	}
+	return terms, true
+}
+
+// CreatePaymentTerms godoc
+// @Summary Create payment terms
+// @Description Defines named payment terms for the company's credit invoices: net (due days after the invoice date), end_of_month (due days after the month end), due_on_receipt, or installments (percentages of the total due on set days). Net and end-of-month terms can offer an early-payment discount, e.g. 2/10 Net 30 is discount_percent 2, discount_days 10, days 30.
+// @Tags PaymentTerms
+// @Accept json
+// @Produce json
+// @Param terms body models.PaymentTerms true "Payment terms"
+// @Success 201 {object} models.PaymentTerms
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /payment-terms [post]
+// @Security BearerAuth
+func CreatePaymentTerms(c *gin.Context) {
+	var terms models.PaymentTerms
+	if err := c.ShouldBindJSON(&terms); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	companyID, ok := scopedCompanyObjectID(c, terms.CompanyID)
+	if !ok {
+		return
+	}
+	if err := validatePaymentTerms(terms); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	terms.ID = primitive.NilObjectID
+	terms.CompanyID = companyID
+	terms.CreatedAt = time.Now()
+	terms.UpdatedAt = time.Now()
+	result, err := config.DB.Collection("payment_terms").InsertOne(context.Background(), terms)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create payment terms"})
+		return
+	}
+	terms.ID = result.InsertedID.(primitive.ObjectID)
+	c.JSON(http.StatusCreated, terms)
+}
+
+// ListPaymentTerms godoc
+// @Summary List payment terms
+// @Description Lists the company's payment terms by name
+// @Tags PaymentTerms
+// @Produce json
+// @Param company_id query string false "Company ID, defaults to the token's active company"
+// @Success 200 {array} models.PaymentTerms
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 401 {object} models.ErrorResponse "No active company in the token"
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /payment-terms [get]
+// @Security BearerAuth
+func ListPaymentTerms(c *gin.Context) {
+	companyIDParam, ok := requiredCompanyID(c, c.Query("company_id"))
+	if !ok {
+		return
+	}
+	companyID, err := primitive.ObjectIDFromHex(companyIDParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return
+	}
+
+	cursor, err := config.DB.Collection("payment_terms").Find(context.Background(),
+		bson.M{"company_id": companyID}, options.Find().SetSort(bson.M{"name": 1}))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payment terms"})
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	terms := []models.PaymentTerms{}
+	if err := cursor.All(context.Background(), &terms); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode payment terms"})
+		return
+	}
+	c.JSON(http.StatusOK, terms)
+}
+
+// GetPaymentTerms godoc
+// @Summary Get payment terms by ID
+// @Tags PaymentTerms
+// @Produce json
+// @Param id path string true "Payment terms ID"
+// @Success 200 {object} models.PaymentTerms
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Router /payment-terms/{id} [get]
+// @Security BearerAuth
+func GetPaymentTerms(c *gin.Context) {
+	terms, ok := fetchScopedPaymentTerms(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, terms)
+}
+
+// UpdatePaymentTerms godoc
+// @Summary Update payment terms
+// @Description Replaces the definition of the payment terms. Invoices already issued keep the due dates, installments and discount they were given.
+// @Tags PaymentTerms
+// @Accept json
+// @Produce json
+// @Param id path string true "Payment terms ID"
+// @Param terms body models.PaymentTerms true "Payment terms"
+// @Success 200 {object} models.PaymentTerms
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /payment-terms/{id} [put]
+// @Security BearerAuth
+func UpdatePaymentTerms(c *gin.Context) {
+	var update models.PaymentTerms
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validatePaymentTerms(update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	terms, ok := fetchScopedPaymentTerms(c)
+	if !ok {
+		return
+	}
+
+	update.ID = terms.ID
+	update.CompanyID = terms.CompanyID
+	update.CreatedAt = terms.CreatedAt
+	update.UpdatedAt = time.Now()
+	_, err := config.DB.Collection("payment_terms").ReplaceOne(context.Background(), bson.M{"_id": terms.ID}, update)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update payment terms"})
+		return
+	}
+	c.JSON(http.StatusOK, update)
+}
+
+// DeletePaymentTerms godoc
+// @Summary Delete payment terms
+// @Description Deletes the payment terms. Customers that defaulted to them fall back to the company's default payment terms days; issued invoices are unchanged.
+// @Tags PaymentTerms
+// @Produce json
+// @Param id path string true "Payment terms ID"
+// @Success 200 {object} models.GenericResponse
+// @Failure 400 {object} models.ErrorResponse
+// @Failure 403 {object} models.ErrorResponse
+// @Failure 404 {object} models.ErrorResponse
+// @Failure 500 {object} models.ErrorResponse
+// @Router /payment-terms/{id} [delete]
+// @Security BearerAuth
+func DeletePaymentTerms(c *gin.Context) {
+	terms, ok := fetchScopedPaymentTerms(c)
+	if !ok {
+		return
+	}
+
+	if _, err := config.DB.Collection("payment_terms").DeleteOne(context.Background(), bson.M{"_id": terms.ID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete payment terms"})
+		return
+	}
+	_, err := config.DB.Collection("customers").UpdateMany(context.Background(),
+		bson.M{"payment_terms_id": terms.ID},
+		bson.M{"$unset": bson.M{"payment_terms_id": ""}},
+	)
+	if err != nil {
+		fmt.Println("Error clearing customer payment terms", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Payment terms deleted successfully"})
+}
@@ -0,0 +1,291 @@
+package controllers
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/bisre1921/billing-and-invoice-system/models"
+)
+
+// FacturXFilename is the name the Factur-X and ZUGFeRD specifications require
+// for the embedded invoice XML.
+const FacturXFilename = "factur-x.xml"
+
+var (
+	pdfXrefEntryPattern = regexp.MustCompile(`(?m)^(\d{10}) (\d{5}) n`)
+	pdfStartXrefNumber  = regexp.MustCompile(`startxref\n(\d+)`)
+)
+
+// facturXMetadata is what the PDF/A metadata says about the document.
+type facturXMetadata struct {
+	title    string
+	author   string
+	producer string
+	date     time.Time
+}
+
+// pdfTextString encodes text for the document information dictionary, as
+// UTF-16 when it is not plain ASCII.
+func pdfTextString(s string) string {
+	for _, r := range s {
+		if r > 126 {
+			var buf bytes.Buffer
+			buf.WriteString("<FEFF")
+			for _, unit := range utf16.Encode([]rune(s)) {
+				fmt.Fprintf(&buf, "%04X", unit)
+			}
+			return buf.String() + ">"
+		}
+	}
+	return pdfString(s)
+}
+
+func xmlText(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// pdfWithBinaryComment adds the comment of high-bit bytes PDF/A wants after
+// the header line, shifting the cross-reference offsets of a single-section
+// file such as the ones gofpdf writes.
+func pdfWithBinaryComment(data []byte) ([]byte, error) {
+	header := bytes.IndexByte(data, '\n') + 1
+	if header <= 0 || !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return nil, fmt.Errorf("not a PDF file")
+	}
+	if header+1 < len(data) && data[header] == '%' && data[header+1] > 127 {
+		return data, nil
+	}
+	comment := []byte("%\xE2\xE3\xCF\xD3\n")
+	file, err := parsePDFFile(data)
+	if err != nil {
+		return nil, err
+	}
+	shift := func(re *regexp.Regexp, b []byte) []byte {
+		return re.ReplaceAllFunc(b, func(m []byte) []byte {
+			sub := re.FindSubmatch(m)
+			offset, _ := strconv.Atoi(string(sub[1]))
+			return bytes.Replace(m, sub[1], []byte(fmt.Sprintf("%0*d", len(sub[1]), offset+len(comment))), 1)
+		})
+	}
+	trailer := bytes.LastIndex(data, []byte("startxref"))
+
+	var out bytes.Buffer
+	out.Write(data[:header])
+	out.Write(comment)
+	out.Write(data[header:file.xref])
+	out.Write(shift(pdfXrefEntryPattern, data[file.xref:trailer]))
+	out.Write(pdfStartXrefNumber.ReplaceAll(data[trailer:], []byte(fmt.Sprintf("startxref\n%d", file.xref+len(comment)))))
+	return out.Bytes(), nil
+}
+
+// embedFacturX turns a generated invoice PDF into a Factur-X / ZUGFeRD hybrid:
+// a PDF/A-3b file with the CII invoice attached as factur-x.xml and declared
+// in the XMP metadata. The additions go in an incremental update so the page
+// content is left exactly as rendered.
+func embedFacturX(data, invoiceXML []byte, meta facturXMetadata) ([]byte, error) {
+	data, err := pdfWithBinaryComment(data)
+	if err != nil {
+		return nil, err
+	}
+	file, err := parsePDFFile(data)
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := file.object(file.root)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := file.pages()
+	if err != nil {
+		return nil, err
+	}
+
+	pdfDate := meta.date.UTC().Format("D:20060102150405Z")
+	var objects []pdfObject
+
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	writer.Write(invoiceXML)
+	writer.Close()
+	sum := md5.Sum(invoiceXML)
+	embeddedNum := file.newObject()
+	objects = append(objects, pdfObject{num: embeddedNum, stream: compressed.Bytes(), dict: fmt.Sprintf(
+		"<</Type /EmbeddedFile /Subtype /text#2Fxml /Filter /FlateDecode /Length %d\n/Params <</ModDate %s /Size %d /CheckSum <%s>>>>>",
+		compressed.Len(), pdfString(pdfDate), len(invoiceXML), hex.EncodeToString(sum[:]))})
+
+	specNum := file.newObject()
+	objects = append(objects, pdfObject{num: specNum, dict: fmt.Sprintf(
+		"<</Type /Filespec /F %s /UF %s /Desc (Factur-X invoice) /AFRelationship /Alternative\n/EF <</F %d 0 R /UF %d 0 R>>>>",
+		pdfString(FacturXFilename), pdfString(FacturXFilename), embeddedNum, embeddedNum)})
+
+	xmp := facturXMP(meta)
+	metadataNum := file.newObject()
+	objects = append(objects, pdfObject{num: metadataNum, stream: xmp, dict: fmt.Sprintf("<</Type /Metadata /Subtype /XML /Length %d>>", len(xmp))})
+
+	icc := srgbICCProfile()
+	iccNum := file.newObject()
+	objects = append(objects, pdfObject{num: iccNum, stream: icc, dict: fmt.Sprintf("<</N 3 /Length %d>>", len(icc))})
+
+	// The information dictionary has to agree with the XMP metadata
+	file.info = file.newObject()
+	objects = append(objects, pdfObject{num: file.info, dict: fmt.Sprintf("<</Title %s /Author %s /Producer %s /CreationDate %s /ModDate %s>>",
+		pdfTextString(meta.title), pdfTextString(meta.author), pdfTextString(meta.producer), pdfString(pdfDate), pdfString(pdfDate))})
+
+	catalog = strings.TrimSuffix(pdfDictWithout(catalog, "/Names"), ">>") + fmt.Sprintf(
+		"/Names <</EmbeddedFiles <</Names [%s %d 0 R]>>>>\n/AF [%d 0 R]\n/Metadata %d 0 R\n"+
+			"/OutputIntents [<</Type /OutputIntent /S /GTS_PDFA1 /OutputConditionIdentifier (sRGB IEC61966-2.1) /Info (sRGB IEC61966-2.1) /DestOutputProfile %d 0 R>>]\n>>",
+		pdfString(FacturXFilename), specNum, specNum, metadataNum, iccNum)
+	objects = append(objects, pdfObject{num: file.root, dict: catalog})
+
+	// PDF/A only allows annotations that print, which gofpdf links do not set
+	for _, num := range pages {
+		page, err := file.object(num)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Contains(page, "/Subtype /Link ") {
+			objects = append(objects, pdfObject{num: num, dict: strings.ReplaceAll(page, "/Subtype /Link ", "/Subtype /Link /F 4 ")})
+		}
+	}
+
+	id := md5.Sum(data)
+	file.id = fmt.Sprintf("[<%X> <%X>]", id, id)
+	return file.appendUpdate(objects), nil
+}
+
+// facturXMP is the XMP packet declaring PDF/A-3b conformance and the Factur-X
+// extension schema for the embedded invoice.
+func facturXMP(meta facturXMetadata) []byte {
+	date := meta.date.UTC().Format("2006-01-02T15:04:05Z")
+	property := func(name, description string) string {
+		return `<rdf:li rdf:parseType="Resource"><pdfaProperty:name>` + name + `</pdfaProperty:name>` +
+			`<pdfaProperty:valueType>Text</pdfaProperty:valueType><pdfaProperty:category>external</pdfaProperty:category>` +
+			`<pdfaProperty:description>` + description + `</pdfaProperty:description></rdf:li>` + "\n"
+	}
+	return []byte(`<?xpacket begin="` + "\ufeff" + `" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description rdf:about="" xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/">
+<pdfaid:part>3</pdfaid:part>
+<pdfaid:conformance>B</pdfaid:conformance>
+</rdf:Description>
+<rdf:Description rdf:about="" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:title><rdf:Alt><rdf:li xml:lang="x-default">` + xmlText(meta.title) + `</rdf:li></rdf:Alt></dc:title>
+<dc:creator><rdf:Seq><rdf:li>` + xmlText(meta.author) + `</rdf:li></rdf:Seq></dc:creator>
+</rdf:Description>
+<rdf:Description rdf:about="" xmlns:pdf="http://ns.adobe.com/pdf/1.3/">
+<pdf:Producer>` + xmlText(meta.producer) + `</pdf:Producer>
+</rdf:Description>
+<rdf:Description rdf:about="" xmlns:xmp="http://ns.adobe.com/xap/1.0/">
+<xmp:CreateDate>` + date + `</xmp:CreateDate>
+<xmp:ModifyDate>` + date + `</xmp:ModifyDate>
+</rdf:Description>
+<rdf:Description rdf:about="" xmlns:fx="urn:factur-x:pdfa:CrossIndustryDocument:invoice:1p0#">
+<fx:DocumentType>INVOICE</fx:DocumentType>
+<fx:DocumentFileName>` + FacturXFilename + `</fx:DocumentFileName>
+<fx:Version>1.0</fx:Version>
+<fx:ConformanceLevel>EN 16931</fx:ConformanceLevel>
+</rdf:Description>
+<rdf:Description rdf:about="" xmlns:pdfaExtension="http://www.aiim.org/pdfa/ns/extension/" xmlns:pdfaSchema="http://www.aiim.org/pdfa/ns/schema#" xmlns:pdfaProperty="http://www.aiim.org/pdfa/ns/property#">
+<pdfaExtension:schemas><rdf:Bag><rdf:li rdf:parseType="Resource">
+<pdfaSchema:schema>Factur-X PDFA Extension Schema</pdfaSchema:schema>
+<pdfaSchema:namespaceURI>urn:factur-x:pdfa:CrossIndustryDocument:invoice:1p0#</pdfaSchema:namespaceURI>
+<pdfaSchema:prefix>fx</pdfaSchema:prefix>
+<pdfaSchema:property><rdf:Seq>
+` + property("DocumentFileName", "The name of the embedded XML document") +
+		property("DocumentType", "The type of the hybrid document in capital letters, e.g. INVOICE or ORDER") +
+		property("Version", "The actual version of the standard applying to the embedded XML document") +
+		property("ConformanceLevel", "The conformance level of the embedded XML document") + `</rdf:Seq></pdfaSchema:property>
+</rdf:li></rdf:Bag></pdfaExtension:schemas>
+</rdf:Description>
+</rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`)
+}
+
+// srgbICCProfile builds a small ICC v2 display profile for sRGB (D50 adapted
+// primaries and a 2.2 gamma), the output intent PDF/A needs for the RGB colours
+// gofpdf writes.
+func srgbICCProfile() []byte {
+	s15 := func(v float64) uint32 { return uint32(int32(math.Round(v * 65536))) }
+	xyz := func(x, y, z float64) []byte {
+		b := make([]byte, 20)
+		copy(b, "XYZ ")
+		binary.BigEndian.PutUint32(b[8:], s15(x))
+		binary.BigEndian.PutUint32(b[12:], s15(y))
+		binary.BigEndian.PutUint32(b[16:], s15(z))
+		return b
+	}
+	const name = "sRGB IEC61966-2.1"
+	desc := make([]byte, 12+len(name)+1+78) // empty Unicode and ScriptCode parts
+	copy(desc, "desc")
+	binary.BigEndian.PutUint32(desc[8:], uint32(len(name)+1))
+	copy(desc[12:], name)
+	text := append([]byte("text\x00\x00\x00\x00"), "No copyright, use freely\x00"...)
+	curve := []byte{'c', 'u', 'r', 'v', 0, 0, 0, 0, 0, 0, 0, 1, 0x02, 0x33, 0, 0}
+
+	tags := []struct {
+		sig  string
+		data []byte
+	}{
+		{"desc", desc}, {"cprt", text}, {"wtpt", xyz(0.9642, 1, 0.8249)},
+		{"rXYZ", xyz(0.4361, 0.2225, 0.0139)}, {"gXYZ", xyz(0.3851, 0.7169, 0.0971)}, {"bXYZ", xyz(0.1431, 0.0606, 0.7141)},
+		{"rTRC", curve}, {"gTRC", curve}, {"bTRC", curve},
+	}
+	table := make([]byte, 4+12*len(tags))
+	binary.BigEndian.PutUint32(table, uint32(len(tags)))
+	var body []byte
+	offset := 128 + len(table)
+	for i, tag := range tags {
+		entry := table[4+12*i:]
+		copy(entry, tag.sig)
+		binary.BigEndian.PutUint32(entry[4:], uint32(offset+len(body)))
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(tag.data)))
+		body = append(body, tag.data...)
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+	}
+
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[0:], uint32(128+len(table)+len(body)))
+	binary.BigEndian.PutUint32(header[8:], 0x02100000)
+	copy(header[12:], "mntrRGB XYZ ")
+	copy(header[36:], "acsp")
+	copy(header[68:], xyz(0.9642, 1, 0.8249)[8:])
+	return append(append(header, table...), body...)
+}
+
+// renderFacturXInvoice makes the invoice PDF a Factur-X hybrid carrying the
+// same data as the UBL export, or returns the rule violations that keep the
+// invoice from being a valid EN 16931 document.
+func renderFacturXInvoice(data []byte, invoice models.Invoice, company models.Company, customer models.Customer) ([]byte, []string, error) {
+	doc := buildUBLDocument(invoice, company, customer)
+	if violations := validateUBLDocument(doc); len(violations) > 0 {
+		return nil, violations, nil
+	}
+	cii, err := xml.MarshalIndent(buildCIIDocument(doc), "", "  ")
+	if err != nil {
+		return nil, nil, err
+	}
+	title := "Invoice " + invoice.ReferenceNumber
+	if invoice.DocumentType == "credit_note" {
+		title = "Credit Note " + invoice.ReferenceNumber
+	}
+	meta := facturXMetadata{title: title, author: company.Name, producer: "Billing and Invoice System", date: invoice.Date}
+	data, err = embedFacturX(data, append([]byte(xml.Header), cii...), meta)
+	return data, nil, err
+}
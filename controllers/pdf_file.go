@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	pdfStartXrefPattern = regexp.MustCompile(`startxref\s+(\d+)\s+%%EOF\s*$`)
+	pdfTrailerPattern   = regexp.MustCompile(`(?s)trailer\s*<<(.*?)>>\s*startxref`)
+	pdfSizePattern      = regexp.MustCompile(`/Size (\d+)`)
+	pdfRootPattern      = regexp.MustCompile(`/Root (\d+) 0 R`)
+	pdfInfoPattern      = regexp.MustCompile(`/Info (\d+) 0 R`)
+	pdfPrevPattern      = regexp.MustCompile(`/Prev (\d+)`)
+	pdfIDPattern        = regexp.MustCompile(`/ID\s*(\[[^\]]*\])`)
+	pdfPagesPattern     = regexp.MustCompile(`/Pages (\d+) 0 R`)
+	pdfKidsPattern      = regexp.MustCompile(`/Kids \[([^\]]*)\]`)
+	pdfRefPattern       = regexp.MustCompile(`(\d+) 0 R`)
+)
+
+// pdfFile is the little of a PDF's structure needed to append incremental
+// updates: the cross-reference tables and the trailer entries.
+type pdfFile struct {
+	data    []byte
+	offsets map[int]int
+	xref    int
+	size    int
+	root    int
+	info    int
+	id      string // trailer /ID array, kept across updates
+}
+
+// parsePDFFile reads the cross-reference table at startxref and those of
+// earlier updates it points back to.
+func parsePDFFile(data []byte) (*pdfFile, error) {
+	match := pdfStartXrefPattern.FindSubmatch(data)
+	if match == nil {
+		return nil, fmt.Errorf("startxref not found")
+	}
+	xref, _ := strconv.Atoi(string(match[1]))
+	file := &pdfFile{data: data, offsets: map[int]int{}, xref: xref}
+
+	for section := xref; ; {
+		if section <= 0 || section >= len(data) || !bytes.HasPrefix(data[section:], []byte("xref")) {
+			return nil, fmt.Errorf("invalid xref offset %d", section)
+		}
+		lines := strings.Split(string(data[section:]), "\n")
+		for i := 1; i < len(lines) && !strings.HasPrefix(lines[i], "trailer"); {
+			var first, count int
+			if _, err := fmt.Sscanf(lines[i], "%d %d", &first, &count); err != nil {
+				return nil, fmt.Errorf("invalid xref subsection %q", lines[i])
+			}
+			for n := 0; n < count && i+1+n < len(lines); n++ {
+				var offset, generation int
+				var kind string
+				fmt.Sscanf(lines[i+1+n], "%d %d %s", &offset, &generation, &kind)
+				// Later updates override the objects they redefine
+				if _, seen := file.offsets[first+n]; kind == "n" && !seen {
+					file.offsets[first+n] = offset
+				}
+			}
+			i += count + 1
+		}
+
+		trailer := pdfTrailerPattern.FindSubmatch(data[section:])
+		if trailer == nil {
+			return nil, fmt.Errorf("trailer not found")
+		}
+		if section == xref {
+			if m := pdfSizePattern.FindSubmatch(trailer[1]); m != nil {
+				file.size, _ = strconv.Atoi(string(m[1]))
+			}
+			if m := pdfRootPattern.FindSubmatch(trailer[1]); m != nil {
+				file.root, _ = strconv.Atoi(string(m[1]))
+			}
+			if m := pdfInfoPattern.FindSubmatch(trailer[1]); m != nil {
+				file.info, _ = strconv.Atoi(string(m[1]))
+			}
+			if m := pdfIDPattern.FindSubmatch(trailer[1]); m != nil {
+				file.id = string(m[1])
+			}
+			if file.size == 0 || file.root == 0 {
+				return nil, fmt.Errorf("trailer has no /Size or /Root")
+			}
+		}
+		prev := pdfPrevPattern.FindSubmatch(trailer[1])
+		if prev == nil {
+			break
+		}
+		section, _ = strconv.Atoi(string(prev[1]))
+	}
+	return file, nil
+}
+
+// object returns the dictionary of an indirect object, without its stream.
+func (f *pdfFile) object(num int) (string, error) {
+	offset, ok := f.offsets[num]
+	if !ok {
+		return "", fmt.Errorf("object %d not in xref", num)
+	}
+	body := f.data[offset:]
+	header := fmt.Sprintf("%d 0 obj", num)
+	if !bytes.HasPrefix(body, []byte(header)) {
+		return "", fmt.Errorf("object %d not at its xref offset", num)
+	}
+	end := bytes.Index(body, []byte("endobj"))
+	if end < 0 {
+		return "", fmt.Errorf("object %d has no endobj", num)
+	}
+	dict := strings.TrimSpace(string(body[len(header):end]))
+	if !strings.HasPrefix(dict, "<<") || !strings.HasSuffix(dict, ">>") {
+		return "", fmt.Errorf("object %d is not a dictionary", num)
+	}
+	return dict, nil
+}
+
+// pages returns the object numbers of the document's pages in order. Page
+// trees written by gofpdf are flat, so only the root /Kids are read.
+func (f *pdfFile) pages() ([]int, error) {
+	catalog, err := f.object(f.root)
+	if err != nil {
+		return nil, err
+	}
+	pagesRef := pdfPagesPattern.FindStringSubmatch(catalog)
+	if pagesRef == nil {
+		return nil, fmt.Errorf("catalog has no /Pages")
+	}
+	pagesNum, _ := strconv.Atoi(pagesRef[1])
+	pages, err := f.object(pagesNum)
+	if err != nil {
+		return nil, err
+	}
+	kids := pdfKidsPattern.FindStringSubmatch(pages)
+	if kids == nil {
+		return nil, fmt.Errorf("document has no pages")
+	}
+	var nums []int
+	for _, ref := range pdfRefPattern.FindAllStringSubmatch(kids[1], -1) {
+		num, _ := strconv.Atoi(ref[1])
+		nums = append(nums, num)
+	}
+	if len(nums) == 0 {
+		return nil, fmt.Errorf("document has no pages")
+	}
+	return nums, nil
+}
+
+// newObject reserves the next free object number for an update.
+func (f *pdfFile) newObject() int {
+	f.size++
+	return f.size - 1
+}
+
+// pdfObject is an indirect object written by an incremental update.
+type pdfObject struct {
+	num    int
+	dict   string
+	stream []byte
+}
+
+// appendUpdate returns the document with the objects appended as an
+// incremental update, leaving the original bytes untouched. The trailer
+// points at the file's root, info and ID, so callers change those first.
+func (f *pdfFile) appendUpdate(objects []pdfObject) []byte {
+	var out bytes.Buffer
+	out.Write(f.data)
+	if !bytes.HasSuffix(f.data, []byte("\n")) {
+		out.WriteString("\n")
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].num < objects[j].num })
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\n", obj.num, obj.dict)
+		if obj.stream != nil {
+			out.WriteString("stream\n")
+			out.Write(obj.stream)
+			out.WriteString("\nendstream\n")
+		}
+		out.WriteString("endobj\n")
+	}
+	xref := out.Len()
+	out.WriteString("xref\n0 1\n0000000000 65535 f \n")
+	for i, obj := range objects {
+		fmt.Fprintf(&out, "%d 1\n%010d 00000 n \n", obj.num, offsets[i])
+	}
+	fmt.Fprintf(&out, "trailer\n<<\n/Size %d\n/Root %d 0 R\n", f.size, f.root)
+	if f.info != 0 {
+		fmt.Fprintf(&out, "/Info %d 0 R\n", f.info)
+	}
+	if f.id != "" {
+		fmt.Fprintf(&out, "/ID %s\n", f.id)
+	}
+	fmt.Fprintf(&out, "/Prev %d\n>>\nstartxref\n%d\n%%%%EOF\n", f.xref, xref)
+	return out.Bytes()
+}
+
+// pdfDictWithout removes a top-level key and its value from a dictionary.
+func pdfDictWithout(dict, key string) string {
+	depth := 0
+	for i := 0; i < len(dict); i++ {
+		switch {
+		case strings.HasPrefix(dict[i:], "<<"):
+			depth++
+			i++
+		case strings.HasPrefix(dict[i:], ">>"):
+			depth--
+			i++
+		case dict[i] == '[':
+			depth++
+		case dict[i] == ']':
+			depth--
+		case depth == 1 && strings.HasPrefix(dict[i:], key) && i+len(key) < len(dict) && strings.ContainsRune(" \n\r\t<[(/", rune(dict[i+len(key)])):
+			return dict[:i] + dict[pdfValueEnd(dict, i+len(key)):]
+		}
+	}
+	return dict
+}
+
+var pdfRefValuePattern = regexp.MustCompile(`^\d+ \d+ R`)
+
+// pdfValueEnd returns the offset just past the value following position i:
+// a dictionary, an array, an indirect reference or a single token.
+func pdfValueEnd(dict string, i int) int {
+	for i < len(dict) && strings.ContainsRune(" \n\r\t", rune(dict[i])) {
+		i++
+	}
+	if ref := pdfRefValuePattern.FindString(dict[i:]); ref != "" {
+		return i + len(ref)
+	}
+	opener, closer := "", ""
+	switch {
+	case strings.HasPrefix(dict[i:], "<<"):
+		opener, closer = "<<", ">>"
+	case strings.HasPrefix(dict[i:], "["):
+		opener, closer = "[", "]"
+	default:
+		for i++; i < len(dict) && !strings.ContainsRune(" \n\r\t/<>[]()", rune(dict[i])); i++ {
+		}
+		return i
+	}
+	depth := 0
+	for i < len(dict) {
+		switch {
+		case strings.HasPrefix(dict[i:], opener):
+			depth++
+			i += len(opener)
+		case strings.HasPrefix(dict[i:], closer):
+			depth--
+			i += len(closer)
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return i
+}
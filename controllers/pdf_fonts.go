@@ -0,0 +1,237 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/bisre1921/billing-and-invoice-system/fonts"
+	"github.com/gin-gonic/gin"
+	"github.com/jung-kurt/gofpdf"
+	"golang.org/x/image/font/sfnt"
+)
+
+// DefaultPDFFont is used when the company has not picked a font.
+const DefaultPDFFont = "dejavu_sans"
+
+// PDFFont is a TrueType font that invoice, receipt and report PDFs can be
+// rendered with. Text is embedded as UTF-8, so any script the font has glyphs
+// for prints correctly.
+type PDFFont struct {
+	Name    string   `json:"name"`
+	Source  string   `json:"source"` // bundled or font_dir
+	Scripts []string `json:"scripts"`
+	Bold    bool     `json:"bold"` // false when bold text falls back to the regular face
+	regular []byte
+	bold    []byte
+	glyphs  *sfnt.Font
+}
+
+// fontScripts are probed to tell users which languages a font can print.
+var fontScripts = []struct {
+	name   string
+	sample string
+}{
+	{"Latin", "AZaz"},
+	{"Latin Extended", "éüçñøł"},
+	{"Greek", "αβγΩ"},
+	{"Cyrillic", "абвЖ"},
+	{"Ethiopic", "ሀለአበ"},
+}
+
+var (
+	pdfFontsMu     sync.RWMutex
+	pdfFonts       []PDFFont
+	pdfFontsLoaded bool
+)
+
+// LoadPDFFonts registers the bundled fonts plus every .ttf file in dir (the
+// PDF_FONT_DIR setting). A "-Bold" file is used as the bold face of the font
+// with the same name, so NotoSansEthiopic-Regular.ttf and
+// NotoSansEthiopic-Bold.ttf become the font "noto_sans_ethiopic".
+func LoadPDFFonts(dir string) error {
+	regular, _ := fonts.FS.ReadFile("DejaVuSans.ttf")
+	bold, _ := fonts.FS.ReadFile("DejaVuSans-Bold.ttf")
+	bundled, err := newPDFFont(DefaultPDFFont, "bundled", regular, bold)
+	if err != nil {
+		return err
+	}
+	loaded := []PDFFont{bundled}
+
+	var dirErr error
+	if dir != "" {
+		var extra []PDFFont
+		extra, dirErr = loadFontDir(dir)
+		loaded = append(loaded, extra...)
+	}
+
+	pdfFontsMu.Lock()
+	pdfFonts = loaded
+	pdfFontsLoaded = true
+	pdfFontsMu.Unlock()
+	return dirErr
+}
+
+func loadFontDir(dir string) ([]PDFFont, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.ttf"))
+	if err != nil {
+		return nil, err
+	}
+	regulars := map[string][]byte{}
+	bolds := map[string][]byte{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		stem := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		switch {
+		case strings.HasSuffix(stem, "-Bold"):
+			bolds[fontName(strings.TrimSuffix(stem, "-Bold"))] = data
+		case strings.Contains(stem, "-"):
+			// Italic, Light and other faces are not used
+			if strings.HasSuffix(stem, "-Regular") {
+				regulars[fontName(strings.TrimSuffix(stem, "-Regular"))] = data
+			}
+		default:
+			regulars[fontName(stem)] = data
+		}
+	}
+
+	names := make([]string, 0, len(regulars))
+	for name := range regulars {
+		if name != DefaultPDFFont {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var loaded []PDFFont
+	for _, name := range names {
+		font, err := newPDFFont(name, "font_dir", regulars[name], bolds[name])
+		if err != nil {
+			return loaded, fmt.Errorf("font %s: %v", name, err)
+		}
+		loaded = append(loaded, font)
+	}
+	return loaded, nil
+}
+
+// fontName turns a file name like NotoSansEthiopic into noto_sans_ethiopic.
+func fontName(stem string) string {
+	var b strings.Builder
+	prev := rune(0)
+	for _, r := range stem {
+		switch {
+		case r == '-' || r == ' ' || r == '_':
+			r = '_'
+		case unicode.IsUpper(r) && (unicode.IsLower(prev) || unicode.IsDigit(prev)):
+			b.WriteRune('_')
+		}
+		b.WriteRune(unicode.ToLower(r))
+		prev = r
+	}
+	return b.String()
+}
+
+func newPDFFont(name, source string, regular, bold []byte) (PDFFont, error) {
+	glyphs, err := sfnt.Parse(regular)
+	if err != nil {
+		return PDFFont{}, err
+	}
+	font := PDFFont{Name: name, Source: source, Bold: bold != nil, regular: regular, bold: bold, glyphs: glyphs}
+	for _, script := range fontScripts {
+		if font.covers(script.sample) {
+			font.Scripts = append(font.Scripts, script.name)
+		}
+	}
+	return font, nil
+}
+
+// covers reports whether the font has a glyph for every printable rune of text.
+func (f PDFFont) covers(text string) bool {
+	var buf sfnt.Buffer
+	for _, r := range text {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			continue
+		}
+		if index, err := f.glyphs.GlyphIndex(&buf, r); err != nil || index == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func availablePDFFonts() []PDFFont {
+	pdfFontsMu.RLock()
+	loaded := pdfFontsLoaded
+	pdfFontsMu.RUnlock()
+	if !loaded {
+		if err := LoadPDFFonts(os.Getenv("PDF_FONT_DIR")); err != nil {
+			fmt.Println("Error loading PDF fonts", err)
+		}
+	}
+	pdfFontsMu.RLock()
+	defer pdfFontsMu.RUnlock()
+	return pdfFonts
+}
+
+func findPDFFont(name string) (PDFFont, bool) {
+	for _, font := range availablePDFFonts() {
+		if font.Name == name {
+			return font, true
+		}
+	}
+	return PDFFont{}, false
+}
+
+// selectPDFFont returns the preferred font if it can print every text, and
+// otherwise the first registered font that can, so an Amharic customer name
+// still renders when the company picked a Latin-only font.
+func selectPDFFont(preferred string, texts ...string) PDFFont {
+	all := strings.Join(texts, " ")
+	font, ok := findPDFFont(preferred)
+	if !ok {
+		font, _ = findPDFFont(DefaultPDFFont)
+	}
+	if font.covers(all) {
+		return font
+	}
+	for _, candidate := range availablePDFFonts() {
+		if candidate.covers(all) {
+			return candidate
+		}
+	}
+	fmt.Println("Warning: no PDF font has glyphs for all the text, some characters will be missing. Add a suitable font to PDF_FONT_DIR")
+	return font
+}
+
+// applyPDFFont adds the font to the document under its name for the regular,
+// bold and italic styles and returns the family to pass to SetFont.
+func applyPDFFont(pdf *gofpdf.Fpdf, font PDFFont) string {
+	bold := font.bold
+	if bold == nil {
+		bold = font.regular
+	}
+	pdf.AddUTF8FontFromBytes(font.Name, "", font.regular)
+	pdf.AddUTF8FontFromBytes(font.Name, "I", font.regular)
+	pdf.AddUTF8FontFromBytes(font.Name, "B", bold)
+	pdf.AddUTF8FontFromBytes(font.Name, "BI", bold)
+	return font.Name
+}
+
+// ListPDFFonts godoc
+// @Summary List PDF fonts
+// @Description Fonts accepted by the font setting of company branding, with the scripts each one can print. Extra fonts are loaded from PDF_FONT_DIR
+// @Tags Invoices
+// @Produce json
+// @Success 200 {array} PDFFont
+// @Router /invoice/fonts [get]
+func ListPDFFonts(c *gin.Context) {
+	c.JSON(http.StatusOK, availablePDFFonts())
+}
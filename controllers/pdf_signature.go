@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/digitorus/pkcs7"
+)
+
+// pdfString escapes text for a PDF literal string.
+func pdfString(s string) string {
+	return "(" + strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`, "\r", `\r`, "\n", `\n`).Replace(s) + ")"
+}
+
+// pdfSignature describes who signs a document and with which key.
+type pdfSignature struct {
+	chain  []*x509.Certificate // signing certificate first
+	key    crypto.Signer
+	name   string
+	reason string
+	time   time.Time
+}
+
+// signPDF appends an invisible signature field to the first page holding a
+// detached PKCS#7 signature over the whole file (adbe.pkcs7.detached), as an
+// incremental update so the original bytes stay untouched.
+func signPDF(data []byte, sig pdfSignature) ([]byte, error) {
+	file, err := parsePDFFile(data)
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := file.object(file.root)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := file.pages()
+	if err != nil {
+		return nil, err
+	}
+	pageNum := pages[0]
+	page, err := file.object(pageNum)
+	if err != nil {
+		return nil, err
+	}
+
+	sigNum, fieldNum := file.newObject(), file.newObject()
+	fieldRef := fmt.Sprintf("%d 0 R", fieldNum)
+	if strings.Contains(page, "/Annots [") {
+		page = strings.Replace(page, "/Annots [", "/Annots ["+fieldRef+" ", 1)
+	} else {
+		page = strings.TrimSuffix(page, ">>") + "\n/Annots [" + fieldRef + "]>>"
+	}
+	catalog = strings.TrimSuffix(catalog, ">>") + fmt.Sprintf("/AcroForm <</Fields [%s] /SigFlags 3>>\n>>", fieldRef)
+
+	// Room for the certificates plus the signature itself, hex encoded
+	contentsLen := 4096
+	for _, cert := range sig.chain {
+		contentsLen += len(cert.Raw)
+	}
+	contentsLen *= 2
+	byteRangeStub := "/ByteRange [0 0000000000 0000000000 0000000000]"
+	sigDict := "<</Type /Sig /Filter /Adobe.PPKLite /SubFilter /adbe.pkcs7.detached\n" +
+		byteRangeStub + "\n/Contents <" + strings.Repeat("0", contentsLen) + ">\n" +
+		"/M " + pdfString(sig.time.UTC().Format("D:20060102150405+00'00'")) +
+		" /Name " + pdfString(sig.name) + " /Reason " + pdfString(sig.reason) + ">>"
+	field := fmt.Sprintf("<</Type /Annot /Subtype /Widget /FT /Sig /T (Signature1) /Rect [0 0 0 0] /F 132 /V %d 0 R /P %d 0 R>>", sigNum, pageNum)
+
+	signed := file.appendUpdate([]pdfObject{
+		{num: file.root, dict: catalog},
+		{num: pageNum, dict: page},
+		{num: sigNum, dict: sigDict},
+		{num: fieldNum, dict: field},
+	})
+	contentsStart := bytes.LastIndex(signed, []byte("/Contents <")) + len("/Contents ")
+	contentsEnd := contentsStart + contentsLen + 2
+	byteRange := fmt.Sprintf("/ByteRange [0 %d %d %d]", contentsStart, contentsEnd, len(signed)-contentsEnd)
+	if len(byteRange) > len(byteRangeStub) {
+		return nil, fmt.Errorf("document too large to sign")
+	}
+	byteRange += strings.Repeat(" ", len(byteRangeStub)-len(byteRange))
+	stubAt := bytes.LastIndex(signed, []byte(byteRangeStub))
+	copy(signed[stubAt:], byteRange)
+
+	signedContent := append(append([]byte{}, signed[:contentsStart]...), signed[contentsEnd:]...)
+	signedData, err := pkcs7.NewSignedData(signedContent)
+	if err != nil {
+		return nil, err
+	}
+	signedData.SetDigestAlgorithm(pkcs7.OIDDigestAlgorithmSHA256)
+	if err := signedData.AddSignerChain(sig.chain[0], sig.key, sig.chain[1:], pkcs7.SignerInfoConfig{}); err != nil {
+		return nil, err
+	}
+	signedData.Detach()
+	der, err := signedData.Finish()
+	if err != nil {
+		return nil, err
+	}
+	encoded := hex.EncodeToString(der)
+	if len(encoded) > contentsLen {
+		return nil, fmt.Errorf("signature does not fit its placeholder")
+	}
+	copy(signed[contentsStart+1:], encoded)
+	return signed, nil
+}
@@ -3,20 +3,24 @@ package controllers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type SalesReportRequest struct {
-	CompanyID   string   `json:"company_id" binding:"required"`
-	DateRange   string   `json:"date_range" binding:"required"` // e.g. today, last_7_days, last_month, last_3_months, custom
+	CompanyID   string   `json:"company_id"`
+	DateRange   string   `json:"date_range" binding:"required"` // e.g. today, last_7_days, last_month, last_3_months, this_fiscal_year, last_fiscal_year, custom
 	CustomStart *string  `json:"custom_start,omitempty"`        // for custom range
 	CustomEnd   *string  `json:"custom_end,omitempty"`
 	Statuses    []string `json:"statuses"`   // ["Paid", "Unpaid"]
@@ -24,18 +28,20 @@ type SalesReportRequest struct {
 }
 
 type SalesReportItem struct {
-	InvoiceID    string    `json:"invoice_id"`
-	Date         time.Time `json:"date"`
-	Status       string    `json:"status"`
-	CustomerName string    `json:"customer_name"`
-	Items        []struct {
-		Name      string  `json:"name"`
-		Category  string  `json:"category"`
-		Quantity  int     `json:"quantity"`
-		UnitPrice float64 `json:"unit_price"`
-		Subtotal  float64 `json:"subtotal"`
-	} `json:"items"`
-	TotalAmount float64 `json:"total_amount"`
+	InvoiceID    string            `json:"invoice_id"`
+	Date         time.Time         `json:"date"`
+	Status       string            `json:"status"`
+	CustomerName string            `json:"customer_name"`
+	Items        []SalesReportLine `json:"items"`
+	TotalAmount  float64           `json:"total_amount"`
+}
+
+type SalesReportLine struct {
+	Name      string  `json:"name"`
+	Category  string  `json:"category"`
+	Quantity  int     `json:"quantity"`
+	UnitPrice float64 `json:"unit_price"`
+	Subtotal  float64 `json:"subtotal"`
 }
 
 // GetSalesReport godoc
@@ -47,6 +53,7 @@ type SalesReportItem struct {
 // @Param filters body SalesReportRequest true "Report filters"
 // @Success 200 {array} SalesReportItem
 // @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "No active company in the token"
 // @Router /report/sales [post]
 func GetSalesReport(c *gin.Context) {
 	var req SalesReportRequest
@@ -54,14 +61,47 @@ func GetSalesReport(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
 		return
 	}
+	var ok bool
+	if req.CompanyID, ok = requiredCompanyID(c, req.CompanyID); !ok {
+		return
+	}
+
+	start, end, err := resolveDateRange(req.DateRange, req.CustomStart, req.CustomEnd, fetchCompanySettings(req.CompanyID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	report, err := buildSalesReport(req.CompanyID, start, end, req.Statuses, req.Categories)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invoices"})
+		return
+	}
+
+	// Store the report in MongoDB as JSON string
+	createdBy := c.GetString("userID")
+	if createdBy == "" {
+		createdBy = "system"
+	}
+	reportJSON, err := json.Marshal(report)
+	if err == nil {
+		_, _ = StoreSalesReport(req.CompanyID, "Sales Report", "Auto-generated sales report", createdBy, string(reportJSON))
+	}
 
-	// Date range
+	c.JSON(http.StatusOK, report)
+}
+
+// resolveDateRange turns a named range (today, last_7_days, last_month,
+// last_3_months, this_fiscal_year, last_fiscal_year or custom) into a
+// [start, end) interval in the company's timezone.
+func resolveDateRange(dateRange string, customStart, customEnd *string, settings models.CompanySettings) (time.Time, time.Time, error) {
 	var start, end time.Time
-	now := time.Now()
-	switch req.DateRange {
+	loc := companyLocation(settings)
+	now := time.Now().In(loc)
+	switch dateRange {
 	case "today":
-		start = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-		end = start.Add(24 * time.Hour)
+		start = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+		end = start.AddDate(0, 0, 1)
 	case "last_7_days":
 		end = now
 		start = end.AddDate(0, 0, -7)
@@ -71,48 +111,53 @@ func GetSalesReport(c *gin.Context) {
 	case "last_3_months":
 		end = now
 		start = end.AddDate(0, -3, 0)
+	case "this_fiscal_year":
+		start = fiscalYearStart(settings, now)
+		end = start.AddDate(1, 0, 0)
+	case "last_fiscal_year":
+		end = fiscalYearStart(settings, now)
+		start = end.AddDate(-1, 0, 0)
 	case "custom":
-		if req.CustomStart == nil || req.CustomEnd == nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Custom start and end dates required"})
-			return
+		if customStart == nil || customEnd == nil {
+			return start, end, errors.New("Custom start and end dates required")
 		}
 		var err error
-		start, err = time.Parse("2006-01-02", *req.CustomStart)
+		start, err = time.ParseInLocation("2006-01-02", *customStart, loc)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid custom_start format (expected YYYY-MM-DD)"})
-			return
+			return start, end, errors.New("Invalid custom_start format (expected YYYY-MM-DD)")
 		}
-		end, err = time.Parse("2006-01-02", *req.CustomEnd)
+		end, err = time.ParseInLocation("2006-01-02", *customEnd, loc)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid custom_end format (expected YYYY-MM-DD)"})
-			return
+			return start, end, errors.New("Invalid custom_end format (expected YYYY-MM-DD)")
 		}
-		end = end.Add(24 * time.Hour)
+		end = end.AddDate(0, 0, 1)
 	default:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date_range value"})
-		return
+		return start, end, errors.New("Invalid date_range value")
 	}
+	return start, end, nil
+}
+
+// buildSalesReport collects the invoices of a company dated within [start, end),
+// optionally restricted to the given statuses and item categories.
+func buildSalesReport(companyID string, start, end time.Time, statuses, categories []string) ([]SalesReportItem, error) {
 	filter := bson.M{
-		"company_id": req.CompanyID,
+		"company_id": companyID,
 		"date":       bson.M{"$gte": start, "$lt": end},
 	}
-	if len(req.Statuses) > 0 {
-		// Make sure status values match what's in the database (case-sensitive matching)
-		statusValues := make([]string, len(req.Statuses))
-		for i, s := range req.Statuses {
-			statusValues[i] = s // Ensure status values match exactly what's in the database
-		}
-		filter["status"] = bson.M{"$in": statusValues}
+	if len(statuses) > 0 {
+		// Status values are matched case-sensitively against what's in the database
+		filter["status"] = bson.M{"$in": statuses}
 	}
 
 	cursor, err := config.DB.Collection("invoices").Find(context.Background(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invoices"})
-		return
+		return nil, err
 	}
 	defer cursor.Close(context.Background())
 
 	var report []SalesReportItem
+	var rowCustomers, customerIDs []string // named once all invoices are read
+	seen := map[string]bool{}
 	for cursor.Next(context.Background()) {
 		var inv struct {
 			ID         primitive.ObjectID `bson:"_id"`
@@ -127,74 +172,48 @@ func GetSalesReport(c *gin.Context) {
 				UnitPrice float64 `bson:"unit_price"`
 				Subtotal  float64 `bson:"subtotal"`
 			} `bson:"items"`
-			Amount float64 `bson:"amount"`
+			Amount       float64 `bson:"amount"`
+			DocumentType string  `bson:"document_type"`
 		}
 		if err := cursor.Decode(&inv); err != nil {
 			continue
 		}
-		// Filter by item category if needed
-		var filteredItems []struct {
-			Name      string  `json:"name"`
-			Category  string  `json:"category"`
-			Quantity  int     `json:"quantity"`
-			UnitPrice float64 `json:"unit_price"`
-			Subtotal  float64 `json:"subtotal"`
-		}
-
-		// If there are category filters, we need to check if any items match
-		hasMatchingCategories := len(req.Categories) == 0 // If no categories specified, all items match
 
+		// Filter by item category if needed
+		var filteredItems []SalesReportLine
 		for _, it := range inv.Items {
 			// Make sure Category field exists and isn't empty before comparing
-			itemCategory := it.Category
-			categoryMatches := len(req.Categories) == 0 || (itemCategory != "" && contains(req.Categories, itemCategory))
-
-			if categoryMatches {
-				hasMatchingCategories = true
-				filteredItems = append(filteredItems, struct {
-					Name      string  `json:"name"`
-					Category  string  `json:"category"`
-					Quantity  int     `json:"quantity"`
-					UnitPrice float64 `json:"unit_price"`
-					Subtotal  float64 `json:"subtotal"`
-				}{
-					Name: it.ItemName, Category: itemCategory, Quantity: it.Quantity, UnitPrice: it.UnitPrice, Subtotal: it.Subtotal,
+			if len(categories) == 0 || (it.Category != "" && contains(categories, it.Category)) {
+				filteredItems = append(filteredItems, SalesReportLine{
+					Name: it.ItemName, Category: it.Category, Quantity: it.Quantity, UnitPrice: it.UnitPrice, Subtotal: it.Subtotal,
 				})
 			}
 		}
 
 		// Skip this invoice if no items match the category filters
-		if !hasMatchingCategories || len(filteredItems) == 0 {
+		if len(filteredItems) == 0 {
 			continue
 		}
 
-		// Fetch customer name (optional, can be optimized)
-		customerName := ""
-		if inv.CustomerID != "" {
-			var cust struct {
-				Name string `bson:"name"`
-			}
-			_ = config.DB.Collection("customers").FindOne(context.Background(), bson.M{"_id": inv.CustomerID}).Decode(&cust)
-			customerName = cust.Name
-		}
-
 		report = append(report, SalesReportItem{
-			InvoiceID:    inv.ID.Hex(),
-			Date:         inv.Date,
-			Status:       inv.Status,
-			CustomerName: customerName,
-			Items:        filteredItems,
-			TotalAmount:  inv.Amount,
+			InvoiceID:   inv.ID.Hex(),
+			Date:        inv.Date,
+			Status:      inv.Status,
+			Items:       filteredItems,
+			TotalAmount: documentSign(models.Invoice{DocumentType: inv.DocumentType}) * inv.Amount,
 		})
+		rowCustomers = append(rowCustomers, inv.CustomerID)
+		if !seen[inv.CustomerID] {
+			seen[inv.CustomerID] = true
+			customerIDs = append(customerIDs, inv.CustomerID)
+		}
 	}
 
-	// Store the report in MongoDB as JSON string
-	reportJSON, err := json.Marshal(report)
-	if err == nil {
-		_, _ = StoreSalesReport(req.CompanyID, "Sales Report", "Auto-generated sales report", "system", string(reportJSON))
+	names := lookupCustomerNames(customerIDs)
+	for i := range report {
+		report[i].CustomerName = names[rowCustomers[i]]
 	}
-
-	c.JSON(http.StatusOK, report)
+	return report, nil
 }
 
 // Store a generated report in MongoDB
@@ -220,16 +239,207 @@ func StoreSalesReport(companyID, title, description, createdBy string, content s
 	return res.InsertedID.(primitive.ObjectID), nil
 }
 
+// ListReportTypes godoc
+// @Summary List report types
+// @Description Returns every report type that can be generated along with the parameters it accepts
+// @Tags Reports
+// @Produce json
+// @Success 200 {array} ReportDefinition
+// @Router /report/types [get]
+func ListReportTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, reportDefinitions)
+}
+
+// GenerateReport godoc
+// @Summary Generate a report
+// @Description Validates the parameters for the requested report type, runs it and stores the result as a new version
+// @Tags Reports
+// @Accept json
+// @Produce json
+// @Param report body models.GenerateReportRequest true "Report type and parameters"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /report/generate [post]
+// @Security BearerAuth
+func GenerateReport(c *gin.Context) {
+	var req models.GenerateReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	userID, ok := c.Get("userID")
+	createdBy, _ := userID.(string)
+	if !ok || createdBy == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication token is required."})
+		return
+	}
+	if req.CompanyID, ok = requiredCompanyID(c, req.CompanyID); !ok {
+		return
+	}
+
+	def, ok := findReportDefinition(req.Type)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown report type: " + req.Type})
+		return
+	}
+
+	params, err := def.validateParams(req.Parameters)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report parameters", "details": err.Error()})
+		return
+	}
+
+	report, result, err := generateAndStoreReport(def, req.CompanyID, req.Title, req.Description, createdBy, params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate report", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Report generated successfully",
+		"id":      report.ID,
+		"version": report.Version,
+		"data":    result,
+	})
+}
+
+// generateAndStoreReport runs a report with already validated parameters and
+// stores the result as the next version of the report.
+func generateAndStoreReport(def ReportDefinition, companyID, title, description, createdBy string, params map[string]interface{}) (models.Report, interface{}, error) {
+	result, err := def.run(companyID, params)
+	if err != nil {
+		return models.Report{}, nil, err
+	}
+
+	content, err := json.Marshal(result)
+	if err != nil {
+		return models.Report{}, nil, err
+	}
+
+	version, err := nextReportVersion(companyID, def.Type, title)
+	if err != nil {
+		return models.Report{}, nil, err
+	}
+
+	now := time.Now()
+	report := models.Report{
+		CompanyID:        companyID,
+		Title:            title,
+		Description:      description,
+		CreatedBy:        createdBy,
+		CreatedDate:      now,
+		LastModifiedDate: now,
+		Type:             def.Type,
+		Status:           "Generated",
+		Version:          version,
+		Parameters:       params,
+		Content:          string(content),
+	}
+	res, err := config.DB.Collection("reports").InsertOne(context.Background(), report)
+	if err != nil {
+		return models.Report{}, nil, err
+	}
+	report.ID = res.InsertedID.(primitive.ObjectID)
+	return report, result, nil
+}
+
+// nextReportVersion returns the version number for a new run of the report
+// identified by company, type and title.
+func nextReportVersion(companyID, reportType, title string) (int, error) {
+	var latest models.Report
+	opts := options.FindOne().SetSort(bson.M{"version": -1})
+	err := config.DB.Collection("reports").FindOne(context.Background(), bson.M{
+		"company_id": companyID,
+		"type":       reportType,
+		"title":      title,
+	}, opts).Decode(&latest)
+	if err == mongo.ErrNoDocuments {
+		return 1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return latest.Version + 1, nil
+}
+
+// scopedReportFilter matches the report with the id in the path if it belongs
+// to the token's active company. It writes the error response itself.
+func scopedReportFilter(c *gin.Context) (bson.M, bool) {
+	companyID, ok := scopedCompanyID(c, "")
+	if !ok {
+		return nil, false
+	}
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+		return nil, false
+	}
+	return bson.M{"_id": objID, "company_id": companyID}, true
+}
+
+// ListReportVersions godoc
+// @Summary List versions of a report
+// @Description Fetch every stored version of the report with the given ID (same company, type and title), newest first
+// @Tags Reports
+// @Produce json
+// @Param id path string true "Report ID"
+// @Success 200 {array} object
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "No active company in the token"
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /report/{id}/versions [get]
+func ListReportVersions(c *gin.Context) {
+	filter, ok := scopedReportFilter(c)
+	if !ok {
+		return
+	}
+
+	var report models.Report
+	err := config.DB.Collection("reports").FindOne(context.Background(), filter).Decode(&report)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return
+	}
+
+	opts := options.Find().SetSort(bson.M{"version": -1}).SetProjection(bson.M{"content": 0})
+	cursor, err := config.DB.Collection("reports").Find(context.Background(), bson.M{
+		"company_id": report.CompanyID,
+		"type":       report.Type,
+		"title":      report.Title,
+	}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch report versions"})
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	var versions []models.Report
+	if err := cursor.All(context.Background(), &versions); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode report versions"})
+		return
+	}
+	c.JSON(http.StatusOK, versions)
+}
+
 // ListReports godoc
 // @Summary List all stored reports
-// @Description Fetch all stored reports (basic info only)
+// @Description Fetch the stored reports of the token's active company (basic info only).
 // @Tags Reports
 // @Produce json
 // @Success 200 {array} object
+// @Failure 401 {object} map[string]string "No active company in the token"
 // @Failure 500 {object} map[string]string
 // @Router /report/all [get]
 func ListReports(c *gin.Context) {
-	cursor, err := config.DB.Collection("reports").Find(context.Background(), bson.M{})
+	companyID, ok := scopedCompanyID(c, "")
+	if !ok {
+		return
+	}
+	cursor, err := config.DB.Collection("reports").Find(context.Background(), bson.M{"company_id": companyID})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reports"})
 		return
@@ -248,6 +458,7 @@ func ListReports(c *gin.Context) {
 				"created_date": r["created_date"],
 				"type":         r["type"],
 				"status":       r["status"],
+				"version":      r["version"],
 			})
 		}
 	}
@@ -262,17 +473,16 @@ func ListReports(c *gin.Context) {
 // @Param id path string true "Report ID"
 // @Success 200 {object} object
 // @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "No active company in the token"
 // @Failure 404 {object} map[string]string
 // @Router /report/{id} [get]
 func GetReportDetails(c *gin.Context) {
-	reportID := c.Param("id")
-	objID, err := primitive.ObjectIDFromHex(reportID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+	filter, ok := scopedReportFilter(c)
+	if !ok {
 		return
 	}
 	var report bson.M
-	err = config.DB.Collection("reports").FindOne(context.Background(), bson.M{"_id": objID}).Decode(&report)
+	err := config.DB.Collection("reports").FindOne(context.Background(), filter).Decode(&report)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
 		return
@@ -287,67 +497,75 @@ func GetReportDetails(c *gin.Context) {
 // @Param id path string true "Report ID"
 // @Success 200 {object} map[string]string
 // @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "No active company in the token"
+// @Failure 404 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /report/{id} [delete]
 func DeleteReport(c *gin.Context) {
-	reportID := c.Param("id")
-	objID, err := primitive.ObjectIDFromHex(reportID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+	filter, ok := scopedReportFilter(c)
+	if !ok {
 		return
 	}
-	_, err = config.DB.Collection("reports").DeleteOne(context.Background(), bson.M{"_id": objID})
+	result, err := config.DB.Collection("reports").DeleteOne(context.Background(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete report"})
 		return
 	}
+	if result.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Report deleted successfully"})
 }
 
-// DownloadReportCSV godoc
-// @Summary Download a report as CSV
-// @Description Download a generated report in CSV format
+// DownloadReport godoc
+// @Summary Download a report
+// @Description Download a generated report as CSV (default), flat_csv (one row per record), xlsx, pdf or json
 // @Tags Reports
 // @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce application/pdf
+// @Produce json
 // @Param id path string true "Report ID"
+// @Param format query string false "Export format: csv, flat_csv, xlsx, pdf or json"
 // @Success 200 {file} binary
 // @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "No active company in the token"
 // @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
 // @Router /report/download/{id} [get]
-func DownloadReportCSV(c *gin.Context) {
-	reportID := c.Param("id")
-	objID, err := primitive.ObjectIDFromHex(reportID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID"})
+func DownloadReport(c *gin.Context) {
+	filter, ok := scopedReportFilter(c)
+	if !ok {
 		return
 	}
-	var report bson.M
-	err = config.DB.Collection("reports").FindOne(context.Background(), bson.M{"_id": objID}).Decode(&report)
+
+	format := c.DefaultQuery("format", "csv")
+	if !contains(reportFormats, format) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid format, expected one of %v", reportFormats)})
+		return
+	}
+
+	var report models.Report
+	err := config.DB.Collection("reports").FindOne(context.Background(), filter).Decode(&report)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Report not found"})
 		return
 	}
 
-	// Get the report content
-	content := report["content"].(string)
-
-	// Parse stored JSON into report items
-	var reportItems []SalesReportItem
-	err = json.Unmarshal([]byte(content), &reportItems)
-
-	var csvContent string
-	if err == nil && len(reportItems) > 0 {
-		// If we can parse the content, generate a nicely formatted CSV
-		csvContent = ConvertReportToCSV(reportItems)
-	} else {
-		// Fallback: use the raw content
-		csvContent = content
+	data, contentType, ext, err := renderReport(report, format)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export report", "details": err.Error()})
+		return
 	}
 
-	filename := "sales_report_" + time.Now().Format("2006-01-02") + ".csv"
+	reportType := report.Type
+	if reportType == "" {
+		reportType = "sales"
+	}
+	filename := reportType + "_report_" + time.Now().Format("2006-01-02") + "." + ext
 	c.Header("Content-Disposition", "attachment; filename="+filename)
-	c.Header("Content-Type", "text/csv")
-	c.Writer.Write([]byte(csvContent))
+	c.Data(http.StatusOK, contentType, data)
 }
 
 // ConvertReportToCSV converts a sales report to CSV format
@@ -0,0 +1,515 @@
+package controllers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var reportFormats = []string{"json", "csv", "flat_csv", "xlsx", "pdf"}
+
+// reportColumn kinds control how cells are typed in XLSX and formatted in CSV/PDF.
+const (
+	columnText   = "text"
+	columnInt    = "int"
+	columnNumber = "number"
+	columnDate   = "date"
+)
+
+type reportColumn struct {
+	Header string
+	Kind   string
+}
+
+type reportSummaryRow struct {
+	Label string
+	Value interface{}
+}
+
+// reportTable is the normalized, one-record-per-row shape every report type is
+// converted to before being exported.
+type reportTable struct {
+	Name    string
+	Columns []reportColumn
+	Rows    [][]interface{}
+	Summary []reportSummaryRow
+}
+
+// renderReport serializes a stored report in the requested format and returns
+// the data along with its content type and file extension.
+func renderReport(report models.Report, format string) ([]byte, string, string, error) {
+	if format == "json" {
+		return []byte(report.Content), "application/json", "json", nil
+	}
+
+	reportType := report.Type
+	if reportType == "" {
+		reportType = "sales"
+	}
+
+	if format == "csv" && reportType == "sales" {
+		// Keep the grouped layout existing users of the sales CSV rely on
+		var reportItems []SalesReportItem
+		if err := json.Unmarshal([]byte(report.Content), &reportItems); err != nil || len(reportItems) == 0 {
+			return []byte(report.Content), "text/csv", "csv", nil
+		}
+		return []byte(ConvertReportToCSV(reportItems)), "text/csv", "csv", nil
+	}
+
+	def, ok := findReportDefinition(reportType)
+	if !ok {
+		return nil, "", "", fmt.Errorf("unknown report type: %s", reportType)
+	}
+	table, err := def.table([]byte(report.Content))
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	switch format {
+	case "csv", "flat_csv":
+		data, err := reportTableToCSV(table)
+		return data, "text/csv", "csv", err
+	case "xlsx":
+		data, err := reportTableToXLSX(table)
+		return data, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "xlsx", err
+	case "pdf":
+		data, err := reportTableToPDF(table, report)
+		return data, "application/pdf", "pdf", err
+	default:
+		return nil, "", "", fmt.Errorf("unsupported report format %q", format)
+	}
+}
+
+func formatReportCell(kind string, value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int:
+		return fmt.Sprintf("%d", v)
+	case float64:
+		if kind == columnInt {
+			return fmt.Sprintf("%.0f", v)
+		}
+		return fmt.Sprintf("%.2f", v)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format("2006-01-02")
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func reportTableToCSV(table reportTable) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	header := make([]string, len(table.Columns))
+	for i, col := range table.Columns {
+		header[i] = col.Header
+	}
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	for _, row := range table.Rows {
+		record := make([]string, len(row))
+		for i, value := range row {
+			record[i] = formatReportCell(table.Columns[i].Kind, value)
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func reportTableToXLSX(table reportTable) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheet := table.Name
+	if err := f.SetSheetName("Sheet1", sheet); err != nil {
+		return nil, err
+	}
+
+	headerStyle, err := f.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return nil, err
+	}
+	dateFormat := "yyyy-mm-dd"
+	dateStyle, err := f.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat})
+	if err != nil {
+		return nil, err
+	}
+	moneyStyle, err := f.NewStyle(&excelize.Style{NumFmt: 4}) // #,##0.00
+	if err != nil {
+		return nil, err
+	}
+
+	for i, col := range table.Columns {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		f.SetCellValue(sheet, cell, col.Header)
+	}
+	lastHeader, _ := excelize.CoordinatesToCellName(len(table.Columns), 1)
+	f.SetCellStyle(sheet, "A1", lastHeader, headerStyle)
+
+	for r, row := range table.Rows {
+		for i, value := range row {
+			cell, _ := excelize.CoordinatesToCellName(i+1, r+2)
+			if t, ok := value.(time.Time); ok && t.IsZero() {
+				continue
+			}
+			f.SetCellValue(sheet, cell, value)
+			switch table.Columns[i].Kind {
+			case columnDate:
+				f.SetCellStyle(sheet, cell, cell, dateStyle)
+			case columnNumber:
+				f.SetCellStyle(sheet, cell, cell, moneyStyle)
+			}
+		}
+	}
+
+	for i := range table.Columns {
+		colName, _ := excelize.ColumnNumberToName(i + 1)
+		f.SetColWidth(sheet, colName, colName, 16)
+	}
+	f.SetPanes(sheet, &excelize.Panes{Freeze: true, YSplit: 1, TopLeftCell: "A2", ActivePane: "bottomLeft"})
+	if len(table.Rows) > 0 {
+		lastCell, _ := excelize.CoordinatesToCellName(len(table.Columns), len(table.Rows)+1)
+		f.AutoFilter(sheet, "A1:"+lastCell, nil)
+	}
+
+	if _, err := f.NewSheet("Summary"); err != nil {
+		return nil, err
+	}
+	f.SetCellValue("Summary", "A1", "Metric")
+	f.SetCellValue("Summary", "B1", "Value")
+	f.SetCellStyle("Summary", "A1", "B1", headerStyle)
+	f.SetColWidth("Summary", "A", "A", 28)
+	f.SetColWidth("Summary", "B", "B", 18)
+	for i, row := range table.Summary {
+		f.SetCellValue("Summary", fmt.Sprintf("A%d", i+2), row.Label)
+		f.SetCellValue("Summary", fmt.Sprintf("B%d", i+2), row.Value)
+		if _, ok := row.Value.(float64); ok {
+			f.SetCellStyle("Summary", fmt.Sprintf("B%d", i+2), fmt.Sprintf("B%d", i+2), moneyStyle)
+		}
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func reportTableToPDF(table reportTable, report models.Report) ([]byte, error) {
+	orientation := "P"
+	if len(table.Columns) > 6 {
+		orientation = "L"
+	}
+	var company models.Company
+	if companyID, err := primitive.ObjectIDFromHex(report.CompanyID); err == nil {
+		company, _ = fetchCompanyByID(companyID)
+	}
+	texts := []string{company.Name, company.Address, report.Title}
+	for _, row := range table.Rows {
+		for i, value := range row {
+			texts = append(texts, formatReportCell(table.Columns[i].Kind, value))
+		}
+	}
+
+	pdf := gofpdf.New(orientation, "mm", "A4", "")
+	font := applyPDFFont(pdf, selectPDFFont(company.Branding.Font, texts...))
+	pdf.SetMargins(10, 10, 10)
+	pdf.SetAutoPageBreak(false, 10)
+	pageWidth, pageHeight := pdf.GetPageSize()
+	usable := pageWidth - 20
+
+	// Text columns get twice the width of numeric ones
+	var units float64
+	for _, col := range table.Columns {
+		if col.Kind == columnText {
+			units += 2
+		} else {
+			units++
+		}
+	}
+	widths := make([]float64, len(table.Columns))
+	for i, col := range table.Columns {
+		widths[i] = usable / units
+		if col.Kind == columnText {
+			widths[i] *= 2
+		}
+	}
+
+	tableHeader := func() {
+		pdf.SetFont(font, "B", 8)
+		pdf.SetFillColor(230, 230, 230)
+		for i, col := range table.Columns {
+			pdf.CellFormat(widths[i], 7, col.Header, "1", 0, "C", true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont(font, "", 8)
+	}
+
+	pdf.AddPage()
+	pdf.SetFont(font, "B", 16)
+	pdf.Cell(0, 8, company.Name)
+	pdf.Ln(7)
+	pdf.SetFont(font, "", 9)
+	if company.Address != "" {
+		pdf.Cell(0, 5, company.Address)
+		pdf.Ln(5)
+	}
+	if company.Email != "" {
+		pdf.Cell(0, 5, "Email: "+company.Email)
+		pdf.Ln(5)
+	}
+	pdf.Ln(3)
+	pdf.SetFont(font, "B", 13)
+	pdf.Cell(0, 7, report.Title)
+	pdf.Ln(6)
+	pdf.SetFont(font, "", 9)
+	pdf.Cell(0, 5, fmt.Sprintf("%s report, version %d, generated %s", table.Name, report.Version, report.CreatedDate.Format("2006-01-02 15:04")))
+	pdf.Ln(8)
+
+	tableHeader()
+	for _, row := range table.Rows {
+		if pdf.GetY()+6 > pageHeight-15 {
+			pdf.AddPage()
+			tableHeader()
+		}
+		for i, value := range row {
+			align := "L"
+			if table.Columns[i].Kind == columnNumber || table.Columns[i].Kind == columnInt {
+				align = "R"
+			}
+			pdf.CellFormat(widths[i], 6, formatReportCell(table.Columns[i].Kind, value), "1", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	if len(table.Summary) > 0 {
+		if pdf.GetY()+float64(len(table.Summary))*6+8 > pageHeight-15 {
+			pdf.AddPage()
+		}
+		pdf.Ln(4)
+		pdf.SetFont(font, "B", 10)
+		pdf.Cell(0, 6, "Totals")
+		pdf.Ln(6)
+		for _, row := range table.Summary {
+			pdf.SetFont(font, "", 9)
+			pdf.Cell(60, 6, row.Label)
+			pdf.SetFont(font, "B", 9)
+			pdf.CellFormat(40, 6, formatReportCell(columnNumber, row.Value), "", 0, "R", false, 0, "")
+			pdf.Ln(6)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func salesReportTable(content []byte) (reportTable, error) {
+	var items []SalesReportItem
+	if err := json.Unmarshal(content, &items); err != nil {
+		return reportTable{}, err
+	}
+
+	table := reportTable{
+		Name: "Sales",
+		Columns: []reportColumn{
+			{"Invoice ID", columnText}, {"Date", columnDate}, {"Status", columnText}, {"Customer Name", columnText},
+			{"Item Name", columnText}, {"Category", columnText}, {"Quantity", columnInt}, {"Unit Price", columnNumber},
+			{"Subtotal", columnNumber}, {"Invoice Total", columnNumber},
+		},
+	}
+	var total float64
+	var quantity int
+	for _, item := range items {
+		total += item.TotalAmount
+		for _, line := range item.Items {
+			quantity += line.Quantity
+			table.Rows = append(table.Rows, []interface{}{
+				item.InvoiceID, item.Date, item.Status, item.CustomerName,
+				line.Name, line.Category, line.Quantity, line.UnitPrice, line.Subtotal, item.TotalAmount,
+			})
+		}
+	}
+	table.Summary = []reportSummaryRow{
+		{"Invoices", len(items)},
+		{"Line items", len(table.Rows)},
+		{"Quantity sold", quantity},
+		{"Total sales", total},
+	}
+	return table, nil
+}
+
+func agingReportTable(content []byte) (reportTable, error) {
+	var report AgingReport
+	if err := json.Unmarshal(content, &report); err != nil {
+		return reportTable{}, err
+	}
+
+	table := reportTable{
+		Name: "Aging",
+		Columns: []reportColumn{
+			{"Customer Name", columnText}, {"Invoices", columnInt}, {"Current", columnNumber}, {"1-30 Days", columnNumber},
+			{"31-60 Days", columnNumber}, {"61-90 Days", columnNumber}, {"Over 90 Days", columnNumber}, {"Total", columnNumber},
+		},
+	}
+	for _, row := range report.Rows {
+		table.Rows = append(table.Rows, []interface{}{
+			row.CustomerName, row.Invoices, row.Current, row.Days1To30, row.Days31To60, row.Days61To90, row.Over90, row.Total,
+		})
+	}
+	table.Summary = []reportSummaryRow{
+		{"As of", report.AsOf.Format("2006-01-02")},
+		{"Current", report.Totals.Current},
+		{"1-30 days", report.Totals.Days1To30},
+		{"31-60 days", report.Totals.Days31To60},
+		{"61-90 days", report.Totals.Days61To90},
+		{"Over 90 days", report.Totals.Over90},
+		{"Total outstanding", report.Totals.Total},
+	}
+	return table, nil
+}
+
+func customerActivityReportTable(content []byte) (reportTable, error) {
+	var rows []CustomerActivityRow
+	if err := json.Unmarshal(content, &rows); err != nil {
+		return reportTable{}, err
+	}
+
+	table := reportTable{
+		Name: "Customer Activity",
+		Columns: []reportColumn{
+			{"Customer Name", columnText}, {"Invoices", columnInt}, {"Billed", columnNumber},
+			{"Paid", columnNumber}, {"Written Off", columnNumber}, {"Outstanding", columnNumber}, {"Last Invoice", columnDate},
+		},
+	}
+	var billed, paid, writtenOff, outstanding float64
+	for _, row := range rows {
+		billed += row.TotalBilled
+		paid += row.TotalPaid
+		writtenOff += row.WrittenOff
+		outstanding += row.Outstanding
+		table.Rows = append(table.Rows, []interface{}{
+			row.CustomerName, row.InvoiceCount, row.TotalBilled, row.TotalPaid, row.WrittenOff, row.Outstanding, row.LastInvoiceDate,
+		})
+	}
+	table.Summary = []reportSummaryRow{
+		{"Customers", len(rows)},
+		{"Billed", billed},
+		{"Paid", paid},
+		{"Written off", writtenOff},
+		{"Outstanding", outstanding},
+	}
+	return table, nil
+}
+
+func itemPerformanceReportTable(content []byte) (reportTable, error) {
+	var rows []ItemPerformanceRow
+	if err := json.Unmarshal(content, &rows); err != nil {
+		return reportTable{}, err
+	}
+
+	table := reportTable{
+		Name: "Item Performance",
+		Columns: []reportColumn{
+			{"Item Name", columnText}, {"Invoices", columnInt}, {"Quantity Sold", columnInt}, {"Revenue", columnNumber},
+		},
+	}
+	var revenue float64
+	var quantity int
+	for _, row := range rows {
+		revenue += row.Revenue
+		quantity += row.QuantitySold
+		table.Rows = append(table.Rows, []interface{}{row.ItemName, row.InvoiceCount, row.QuantitySold, row.Revenue})
+	}
+	table.Summary = []reportSummaryRow{
+		{"Items", len(rows)},
+		{"Quantity sold", quantity},
+		{"Revenue", revenue},
+	}
+	return table, nil
+}
+
+func paymentsReportTable(content []byte) (reportTable, error) {
+	var report PaymentsReport
+	if err := json.Unmarshal(content, &report); err != nil {
+		return reportTable{}, err
+	}
+
+	table := reportTable{
+		Name: "Payments",
+		Columns: []reportColumn{
+			{"Invoice ID", columnText}, {"Reference", columnText}, {"Customer Name", columnText},
+			{"Payment Date", columnDate}, {"Method", columnText}, {"Source", columnText},
+			{"Payment Reference", columnText}, {"Amount", columnNumber},
+		},
+	}
+	for _, row := range report.Rows {
+		table.Rows = append(table.Rows, []interface{}{
+			row.InvoiceID, row.ReferenceNumber, row.CustomerName, row.PaymentDate, row.Method, row.Source, row.Reference, row.Amount,
+		})
+	}
+
+	var methods []string
+	for method := range report.TotalsByMethod {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	for _, method := range methods {
+		table.Summary = append(table.Summary, reportSummaryRow{"Total " + method, report.TotalsByMethod[method]})
+	}
+	if total, ok := report.TotalsBySource["customer_credit"]; ok {
+		table.Summary = append(table.Summary, reportSummaryRow{"Total customer credit applied", total})
+	}
+	if total, ok := report.TotalsBySource["credit_note"]; ok {
+		table.Summary = append(table.Summary, reportSummaryRow{"Total credit notes applied", total})
+	}
+	table.Summary = append(table.Summary, reportSummaryRow{"Total received", report.Total})
+	return table, nil
+}
+
+func badDebtReportTable(content []byte) (reportTable, error) {
+	var report BadDebtReport
+	if err := json.Unmarshal(content, &report); err != nil {
+		return reportTable{}, err
+	}
+
+	table := reportTable{
+		Name: "Bad Debt",
+		Columns: []reportColumn{
+			{"Reference", columnText}, {"Customer Name", columnText}, {"Invoice Date", columnDate},
+			{"Written Off", columnDate}, {"Invoice Amount", columnNumber}, {"Amount", columnNumber}, {"Reason", columnText},
+		},
+	}
+	for _, row := range report.Rows {
+		table.Rows = append(table.Rows, []interface{}{
+			row.ReferenceNumber, row.CustomerName, row.InvoiceDate, row.WrittenOffAt, row.InvoiceAmount, row.Amount, row.Reason,
+		})
+	}
+	table.Summary = []reportSummaryRow{
+		{"Invoices", len(report.Rows)},
+		{"Total written off", report.Total},
+	}
+	return table, nil
+}
@@ -0,0 +1,507 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"github.com/robfig/cron/v3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReportSchedulerInterval is how often the scheduler looks for due report schedules.
+var ReportSchedulerInterval = time.Minute
+
+// StartReportScheduler runs due report schedules in the background until ctx is cancelled.
+func StartReportScheduler(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(ReportSchedulerInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				RunDueReportSchedules(now)
+			}
+		}
+	}()
+}
+
+// RunDueReportSchedules executes every active schedule whose next run is at or before now.
+func RunDueReportSchedules(now time.Time) {
+	if config.DB == nil {
+		return
+	}
+
+	cursor, err := config.DB.Collection("report_schedules").Find(context.Background(), bson.M{
+		"active":      true,
+		"next_run_at": bson.M{"$lte": now},
+	})
+	if err != nil {
+		fmt.Println("Error fetching due report schedules:", err)
+		return
+	}
+	var schedules []models.ReportSchedule
+	if err := cursor.All(context.Background(), &schedules); err != nil {
+		fmt.Println("Error decoding report schedules:", err)
+		return
+	}
+
+	for _, schedule := range schedules {
+		next, err := nextScheduleRun(schedule.Cadence, now)
+		if err != nil {
+			fmt.Printf("Skipping report schedule %s: %v\n", schedule.ID.Hex(), err)
+			continue
+		}
+		// Claim the run by moving next_run_at forward; if another instance got
+		// there first nothing is modified and we skip it.
+		res, err := config.DB.Collection("report_schedules").UpdateOne(context.Background(),
+			bson.M{"_id": schedule.ID, "next_run_at": schedule.NextRunAt},
+			bson.M{"$set": bson.M{"next_run_at": next}},
+		)
+		if err != nil || res.ModifiedCount == 0 {
+			continue
+		}
+		executeReportSchedule(schedule, now)
+	}
+}
+
+func nextScheduleRun(cadence string, from time.Time) (time.Time, error) {
+	sched, err := cron.ParseStandard(cadence)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid cadence %q: %v", cadence, err)
+	}
+	return sched.Next(from), nil
+}
+
+// validateReportSchedule checks the report type, parameters, cadence, recipients
+// and format of a schedule and fills in the default format.
+func validateReportSchedule(schedule *models.ReportSchedule) error {
+	def, ok := findReportDefinition(schedule.ReportType)
+	if !ok {
+		return fmt.Errorf("unknown report type: %s", schedule.ReportType)
+	}
+	params, err := def.validateParams(schedule.Parameters)
+	if err != nil {
+		return err
+	}
+	schedule.Parameters = params
+
+	if _, err := nextScheduleRun(schedule.Cadence, time.Now()); err != nil {
+		return err
+	}
+
+	if len(schedule.Recipients) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+	for _, recipient := range schedule.Recipients {
+		if _, err := mail.ParseAddress(recipient); err != nil {
+			return fmt.Errorf("invalid recipient email: %s", recipient)
+		}
+	}
+
+	if schedule.Format == "" {
+		schedule.Format = "xlsx"
+	}
+	if !contains(reportFormats, schedule.Format) {
+		return fmt.Errorf("format must be one of %v", reportFormats)
+	}
+	return nil
+}
+
+// executeReportSchedule generates the report, stores it, queues it by email to the
+// recipients and records the run. Failures are reported to the schedule owner.
+func executeReportSchedule(schedule models.ReportSchedule, now time.Time) models.ReportScheduleRun {
+	run := models.ReportScheduleRun{
+		ScheduleID: schedule.ID,
+		StartedAt:  now,
+		Recipients: schedule.Recipients,
+	}
+
+	err := func() error {
+		def, ok := findReportDefinition(schedule.ReportType)
+		if !ok {
+			return fmt.Errorf("unknown report type: %s", schedule.ReportType)
+		}
+		params, err := def.validateParams(schedule.Parameters)
+		if err != nil {
+			return err
+		}
+
+		report, _, err := generateAndStoreReport(def, schedule.CompanyID, schedule.Title, "Scheduled "+def.Name+" report", schedule.CreatedBy, params)
+		if err != nil {
+			return err
+		}
+		run.ReportID = report.ID
+
+		data, _, ext, err := renderReport(report, schedule.Format)
+		if err != nil {
+			return err
+		}
+
+		filename := fmt.Sprintf("%s_report_%s.%s", def.Type, now.Format("2006-01-02"), ext)
+		body := fmt.Sprintf("Hello,\n\nAttached is the latest %s report (version %d) generated on %s.\n\nBest regards,\n",
+			def.Name, report.Version, now.Format("2006-01-02 15:04"))
+		msg, err := enqueueMail(models.OutboxMessage{
+			CompanyID:   schedule.CompanyID,
+			Category:    "report",
+			ReferenceID: report.ID.Hex(),
+			To:          schedule.Recipients,
+			Subject:     schedule.Title,
+			TextBody:    body,
+			Attachments: []models.MailAttachment{{Filename: filename, Content: data}},
+		})
+		run.MessageID = msg.ID
+		return err
+	}()
+
+	run.FinishedAt = time.Now()
+	run.Status = "Succeeded"
+	if err != nil {
+		run.Status = "Failed"
+		run.Error = err.Error()
+	}
+
+	res, insertErr := config.DB.Collection("report_schedule_runs").InsertOne(context.Background(), run)
+	if insertErr != nil {
+		fmt.Println("Error recording report schedule run:", insertErr)
+	} else {
+		run.ID = res.InsertedID.(primitive.ObjectID)
+	}
+
+	_, updateErr := config.DB.Collection("report_schedules").UpdateOne(context.Background(),
+		bson.M{"_id": schedule.ID},
+		bson.M{"$set": bson.M{"last_run_at": run.StartedAt, "last_status": run.Status}},
+	)
+	if updateErr != nil {
+		fmt.Println("Error updating report schedule:", updateErr)
+	}
+
+	if err != nil {
+		notifyReportScheduleFailure(schedule, err)
+	}
+	return run
+}
+
+func notifyReportScheduleFailure(schedule models.ReportSchedule, runErr error) {
+	userID, err := primitive.ObjectIDFromHex(schedule.CreatedBy)
+	if err != nil {
+		return
+	}
+	var owner models.User
+	if err := config.DB.Collection("users").FindOne(context.Background(), bson.M{"_id": userID}).Decode(&owner); err != nil {
+		fmt.Println("Error fetching report schedule owner:", err)
+		return
+	}
+
+	subject := "Scheduled report failed: " + schedule.Title
+	body := fmt.Sprintf("Hello %s,\n\nThe scheduled report \"%s\" could not be delivered:\n\n%s\n\nIt will be retried at the next scheduled run.\n",
+		owner.Name, schedule.Title, runErr.Error())
+	_, err = enqueueMail(models.OutboxMessage{
+		CompanyID:   schedule.CompanyID,
+		Category:    "notification",
+		ReferenceID: schedule.ID.Hex(),
+		To:          []string{owner.Email},
+		Subject:     subject,
+		TextBody:    body,
+	})
+	if err != nil {
+		fmt.Println("Error queueing report failure notification:", err)
+	}
+}
+
+// CreateReportSchedule godoc
+// @Summary Schedule a report
+// @Description Creates a schedule that generates a report on a cron-like cadence and emails it to the recipients
+// @Tags Reports
+// @Accept json
+// @Produce json
+// @Param schedule body models.ReportSchedule true "Report schedule"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /report/schedule [post]
+// @Security BearerAuth
+func CreateReportSchedule(c *gin.Context) {
+	var schedule models.ReportSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	userID, ok := c.Get("userID")
+	createdBy, _ := userID.(string)
+	if !ok || createdBy == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication token is required."})
+		return
+	}
+	if schedule.CompanyID, ok = requiredCompanyID(c, schedule.CompanyID); !ok {
+		return
+	}
+
+	if err := validateReportSchedule(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report schedule", "details": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	schedule.NextRunAt, _ = nextScheduleRun(schedule.Cadence, now)
+	schedule.ID = primitive.NilObjectID
+	schedule.Active = true
+	schedule.CreatedBy = createdBy
+	schedule.LastRunAt = nil
+	schedule.LastStatus = ""
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+
+	res, err := config.DB.Collection("report_schedules").InsertOne(context.Background(), schedule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create report schedule"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Report schedule created successfully",
+		"id":          res.InsertedID,
+		"next_run_at": schedule.NextRunAt,
+	})
+}
+
+// fetchScopedReportSchedule loads the schedule named in the path from the
+// token's active company, writing the error response itself otherwise.
+func fetchScopedReportSchedule(c *gin.Context, action string) (models.ReportSchedule, bool) {
+	var schedule models.ReportSchedule
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return schedule, false
+	}
+	companyID, ok := activeCompanyID(c, "", action)
+	if !ok {
+		return schedule, false
+	}
+
+	err = config.DB.Collection("report_schedules").FindOne(context.Background(), bson.M{"_id": objID, "company_id": companyID}).Decode(&schedule)
+	if err == mongo.ErrNoDocuments {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report schedule not found"})
+		return schedule, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch report schedule"})
+		return schedule, false
+	}
+	return schedule, true
+}
+
+// ListReportSchedules godoc
+// @Summary List report schedules
+// @Description Fetch all report schedules of a company
+// @Tags Reports
+// @Produce json
+// @Param company_id query string false "Company ID, defaults to the token's active company"
+// @Success 200 {array} models.ReportSchedule
+// @Failure 400 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /report/schedules [get]
+// @Security BearerAuth
+func ListReportSchedules(c *gin.Context) {
+	companyID, ok := activeCompanyID(c, c.Query("company_id"), "view its report schedules")
+	if !ok {
+		return
+	}
+
+	cursor, err := config.DB.Collection("report_schedules").Find(context.Background(), bson.M{"company_id": companyID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch report schedules"})
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	var schedules []models.ReportSchedule
+	if err := cursor.All(context.Background(), &schedules); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode report schedules"})
+		return
+	}
+	c.JSON(http.StatusOK, schedules)
+}
+
+// UpdateReportSchedule godoc
+// @Summary Update a report schedule
+// @Description Change the parameters, cadence, recipients or format of a schedule, or pause and resume it
+// @Tags Reports
+// @Accept json
+// @Produce json
+// @Param id path string true "Schedule ID"
+// @Param schedule body models.UpdateReportScheduleInput true "Fields to update"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /report/schedule/{id} [put]
+// @Security BearerAuth
+func UpdateReportSchedule(c *gin.Context) {
+	var input models.UpdateReportScheduleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+		return
+	}
+
+	schedule, ok := fetchScopedReportSchedule(c, "change its report schedules")
+	if !ok {
+		return
+	}
+
+	wasActive, oldCadence := schedule.Active, schedule.Cadence
+	if input.Title != nil {
+		schedule.Title = *input.Title
+	}
+	if input.Parameters != nil {
+		schedule.Parameters = input.Parameters
+	}
+	if input.Cadence != nil {
+		schedule.Cadence = *input.Cadence
+	}
+	if input.Recipients != nil {
+		schedule.Recipients = input.Recipients
+	}
+	if input.Format != nil {
+		schedule.Format = *input.Format
+	}
+	if input.Active != nil {
+		schedule.Active = *input.Active
+	}
+
+	if err := validateReportSchedule(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report schedule", "details": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	if schedule.Cadence != oldCadence || (schedule.Active && !wasActive) {
+		schedule.NextRunAt, _ = nextScheduleRun(schedule.Cadence, now)
+	}
+
+	_, err := config.DB.Collection("report_schedules").UpdateOne(context.Background(),
+		bson.M{"_id": schedule.ID, "company_id": schedule.CompanyID},
+		bson.M{"$set": bson.M{
+			"title":       schedule.Title,
+			"parameters":  schedule.Parameters,
+			"cadence":     schedule.Cadence,
+			"recipients":  schedule.Recipients,
+			"format":      schedule.Format,
+			"active":      schedule.Active,
+			"next_run_at": schedule.NextRunAt,
+			"updated_at":  now,
+		}},
+	)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update report schedule"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Report schedule updated successfully", "next_run_at": schedule.NextRunAt})
+}
+
+// DeleteReportSchedule godoc
+// @Summary Delete a report schedule
+// @Description Stops and removes a report schedule. Reports already generated are kept.
+// @Tags Reports
+// @Param id path string true "Schedule ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /report/schedule/{id} [delete]
+// @Security BearerAuth
+func DeleteReportSchedule(c *gin.Context) {
+	objID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid schedule ID"})
+		return
+	}
+	companyID, ok := activeCompanyID(c, "", "delete its report schedules")
+	if !ok {
+		return
+	}
+
+	res, err := config.DB.Collection("report_schedules").DeleteOne(context.Background(), bson.M{"_id": objID, "company_id": companyID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete report schedule"})
+		return
+	}
+	if res.DeletedCount == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Report schedule not found"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Report schedule deleted successfully"})
+}
+
+// ListReportScheduleRuns godoc
+// @Summary Get run history of a report schedule
+// @Description Fetch the runs of a report schedule, newest first
+// @Tags Reports
+// @Produce json
+// @Param id path string true "Schedule ID"
+// @Success 200 {array} models.ReportScheduleRun
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /report/schedule/{id}/runs [get]
+// @Security BearerAuth
+func ListReportScheduleRuns(c *gin.Context) {
+	schedule, ok := fetchScopedReportSchedule(c, "view its report schedules")
+	if !ok {
+		return
+	}
+
+	opts := options.Find().SetSort(bson.M{"started_at": -1})
+	cursor, err := config.DB.Collection("report_schedule_runs").Find(context.Background(), bson.M{"schedule_id": schedule.ID}, opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch schedule runs"})
+		return
+	}
+	defer cursor.Close(context.Background())
+
+	var runs []models.ReportScheduleRun
+	if err := cursor.All(context.Background(), &runs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decode schedule runs"})
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
+
+// RunReportScheduleNow godoc
+// @Summary Run a report schedule now
+// @Description Generates and emails the scheduled report immediately without changing its next run
+// @Tags Reports
+// @Produce json
+// @Param id path string true "Schedule ID"
+// @Success 200 {object} models.ReportScheduleRun
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /report/schedule/{id}/run [post]
+// @Security BearerAuth
+func RunReportScheduleNow(c *gin.Context) {
+	schedule, ok := fetchScopedReportSchedule(c, "run its report schedules")
+	if !ok {
+		return
+	}
+
+	run := executeReportSchedule(schedule, time.Now())
+	c.JSON(http.StatusOK, run)
+}
@@ -0,0 +1,601 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReportParam describes a single parameter accepted by a report type.
+type ReportParam struct {
+	Name        string      `json:"name"`
+	Type        string      `json:"type"` // string, number, bool, date, enum, list
+	Required    bool        `json:"required"`
+	Options     []string    `json:"options,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	Description string      `json:"description"`
+}
+
+// ReportDefinition declares a report type, its parameters and how to run it.
+type ReportDefinition struct {
+	Type        string        `json:"type"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Params      []ReportParam `json:"params"`
+
+	run   func(companyID string, params map[string]interface{}) (interface{}, error)
+	table func(content []byte) (reportTable, error)
+}
+
+var dateRangeParams = []ReportParam{
+	{Name: "date_range", Type: "enum", Options: []string{"today", "last_7_days", "last_month", "last_3_months", "this_fiscal_year", "last_fiscal_year", "custom"}, Default: "last_month", Description: "Period covered by the report"},
+	{Name: "custom_start", Type: "date", Description: "Start date (YYYY-MM-DD) when date_range is custom"},
+	{Name: "custom_end", Type: "date", Description: "End date (YYYY-MM-DD) when date_range is custom"},
+}
+
+func withDateRange(params ...ReportParam) []ReportParam {
+	return append(append([]ReportParam{}, dateRangeParams...), params...)
+}
+
+var reportDefinitions = []ReportDefinition{
+	{
+		Type:        "sales",
+		Name:        "Sales",
+		Description: "Invoices issued in a period with their line items",
+		Params: withDateRange(
+			ReportParam{Name: "statuses", Type: "list", Description: "Invoice statuses to include, e.g. Paid, Unpaid"},
+			ReportParam{Name: "categories", Type: "list", Description: "Item categories to include"},
+		),
+		run:   runSalesReport,
+		table: salesReportTable,
+	},
+	{
+		Type:        "aging",
+		Name:        "Receivables Aging",
+		Description: "Unpaid invoice balances per customer grouped by days overdue",
+		Params: []ReportParam{
+			{Name: "as_of", Type: "date", Description: "Date the balances are aged against (YYYY-MM-DD), defaults to today"},
+		},
+		run:   runAgingReport,
+		table: agingReportTable,
+	},
+	{
+		Type:        "tax_summary",
+		Name:        "Tax Summary",
+		Description: "Taxable, zero-rated and exempt sales, tax collected per rate, withholding and credit note adjustments per period, with invoices listed per customer TIN",
+		Params: withDateRange(
+			ReportParam{Name: "period", Type: "enum", Options: []string{"month", "quarter"}, Default: "month", Description: "Filing period the totals are grouped by"},
+		),
+		run:   runTaxSummaryReport,
+		table: taxSummaryReportTable,
+	},
+	{
+		Type:        "customer_activity",
+		Name:        "Customer Activity",
+		Description: "Invoices billed, paid and outstanding per customer",
+		Params:      withDateRange(),
+		run:         runCustomerActivityReport,
+		table:       customerActivityReportTable,
+	},
+	{
+		Type:        "item_performance",
+		Name:        "Item Performance",
+		Description: "Quantity sold and revenue per item",
+		Params: withDateRange(
+			ReportParam{Name: "limit", Type: "number", Default: float64(0), Description: "Maximum number of items to return, 0 for all"},
+		),
+		run:   runItemPerformanceReport,
+		table: itemPerformanceReportTable,
+	},
+	{
+		Type:        "payments",
+		Name:        "Payments",
+		Description: "Payments, applied customer credit and credit notes received against invoices in a period, with the money received totalled per payment method",
+		Params:      withDateRange(),
+		run:         runPaymentsReport,
+		table:       paymentsReportTable,
+	},
+	{
+		Type:        "bad_debt",
+		Name:        "Bad Debt",
+		Description: "Invoice balances written off in a period with their reasons and approvers",
+		Params:      withDateRange(),
+		run:         runBadDebtReport,
+		table:       badDebtReportTable,
+	},
+}
+
+func findReportDefinition(reportType string) (ReportDefinition, bool) {
+	for _, def := range reportDefinitions {
+		if def.Type == reportType {
+			return def, true
+		}
+	}
+	return ReportDefinition{}, false
+}
+
+// validateParams checks raw request parameters against the definition and
+// returns them normalized, with defaults applied.
+func (def ReportDefinition) validateParams(raw map[string]interface{}) (map[string]interface{}, error) {
+	params := map[string]interface{}{}
+	known := map[string]bool{}
+	for _, p := range def.Params {
+		known[p.Name] = true
+		value, ok := raw[p.Name]
+		if !ok || value == nil {
+			if p.Required {
+				return nil, fmt.Errorf("parameter %s is required", p.Name)
+			}
+			if p.Default != nil {
+				params[p.Name] = p.Default
+			}
+			continue
+		}
+
+		switch p.Type {
+		case "string":
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("parameter %s must be a string", p.Name)
+			}
+			params[p.Name] = s
+		case "number":
+			n, ok := value.(float64)
+			if !ok {
+				return nil, fmt.Errorf("parameter %s must be a number", p.Name)
+			}
+			params[p.Name] = n
+		case "bool":
+			b, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("parameter %s must be a boolean", p.Name)
+			}
+			params[p.Name] = b
+		case "date":
+			s, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("parameter %s must be a date (YYYY-MM-DD)", p.Name)
+			}
+			if _, err := time.Parse("2006-01-02", s); err != nil {
+				return nil, fmt.Errorf("parameter %s must be a date (YYYY-MM-DD)", p.Name)
+			}
+			params[p.Name] = s
+		case "enum":
+			s, ok := value.(string)
+			if !ok || !contains(p.Options, s) {
+				return nil, fmt.Errorf("parameter %s must be one of %v", p.Name, p.Options)
+			}
+			params[p.Name] = s
+		case "list":
+			items, ok := value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("parameter %s must be a list of strings", p.Name)
+			}
+			list := make([]string, 0, len(items))
+			for _, item := range items {
+				s, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("parameter %s must be a list of strings", p.Name)
+				}
+				list = append(list, s)
+			}
+			params[p.Name] = list
+		}
+	}
+
+	for name := range raw {
+		if !known[name] {
+			return nil, fmt.Errorf("unknown parameter %s", name)
+		}
+	}
+	return params, nil
+}
+
+func paramString(params map[string]interface{}, name string) string {
+	s, _ := params[name].(string)
+	return s
+}
+
+func paramStrings(params map[string]interface{}, name string) []string {
+	list, _ := params[name].([]string)
+	return list
+}
+
+func paramNumber(params map[string]interface{}, name string) float64 {
+	n, _ := params[name].(float64)
+	return n
+}
+
+// paramDateRange resolves the date range parameters in the timezone and
+// fiscal year of the company.
+func paramDateRange(companyID string, params map[string]interface{}) (time.Time, time.Time, error) {
+	var customStart, customEnd *string
+	if s := paramString(params, "custom_start"); s != "" {
+		customStart = &s
+	}
+	if s := paramString(params, "custom_end"); s != "" {
+		customEnd = &s
+	}
+	return resolveDateRange(paramString(params, "date_range"), customStart, customEnd, fetchCompanySettings(companyID))
+}
+
+// fetchCompanyInvoices returns the invoices of a company matching the extra filter.
+func fetchCompanyInvoices(companyID string, filter bson.M) ([]models.Invoice, error) {
+	query := bson.M{"company_id": companyID}
+	for k, v := range filter {
+		query[k] = v
+	}
+	cursor, err := config.DB.Collection("invoices").Find(context.Background(), query)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var invoices []models.Invoice
+	if err := cursor.All(context.Background(), &invoices); err != nil {
+		return nil, err
+	}
+	return invoices, nil
+}
+
+// lookupCustomerNames resolves customer hex IDs to names in a single query.
+func lookupCustomerNames(customerIDs []string) map[string]string {
+	names := map[string]string{}
+	for id, customer := range lookupCustomers(customerIDs) {
+		names[id] = customer.Name
+	}
+	return names
+}
+
+// lookupCustomers fetches the customers with the given hex IDs in a single query.
+func lookupCustomers(customerIDs []string) map[string]models.Customer {
+	customers := map[string]models.Customer{}
+	var objIDs []primitive.ObjectID
+	for _, id := range customerIDs {
+		if objID, err := primitive.ObjectIDFromHex(id); err == nil {
+			objIDs = append(objIDs, objID)
+		}
+	}
+	if len(objIDs) == 0 {
+		return customers
+	}
+
+	cursor, err := config.DB.Collection("customers").Find(context.Background(), bson.M{"_id": bson.M{"$in": objIDs}})
+	if err != nil {
+		return customers
+	}
+	defer cursor.Close(context.Background())
+	for cursor.Next(context.Background()) {
+		var cust models.Customer
+		if err := cursor.Decode(&cust); err == nil {
+			customers[cust.ID.Hex()] = cust
+		}
+	}
+	return customers
+}
+
+func invoiceCustomerIDs(invoices []models.Invoice) []string {
+	seen := map[string]bool{}
+	var ids []string
+	for _, inv := range invoices {
+		if !seen[inv.CustomerID] {
+			seen[inv.CustomerID] = true
+			ids = append(ids, inv.CustomerID)
+		}
+	}
+	return ids
+}
+
+func runSalesReport(companyID string, params map[string]interface{}) (interface{}, error) {
+	start, end, err := paramDateRange(companyID, params)
+	if err != nil {
+		return nil, err
+	}
+	return buildSalesReport(companyID, start, end, paramStrings(params, "statuses"), paramStrings(params, "categories"))
+}
+
+type AgingReportRow struct {
+	CustomerID   string  `json:"customer_id"`
+	CustomerName string  `json:"customer_name"`
+	Invoices     int     `json:"invoices"`
+	Current      float64 `json:"current"`
+	Days1To30    float64 `json:"days_1_30"`
+	Days31To60   float64 `json:"days_31_60"`
+	Days61To90   float64 `json:"days_61_90"`
+	Over90       float64 `json:"over_90"`
+	Total        float64 `json:"total"`
+}
+
+type AgingReport struct {
+	AsOf   time.Time        `json:"as_of"`
+	Rows   []AgingReportRow `json:"rows"`
+	Totals AgingReportRow   `json:"totals"`
+}
+
+func (row *AgingReportRow) add(amount float64, daysOverdue int) {
+	switch {
+	case daysOverdue <= 0:
+		row.Current += amount
+	case daysOverdue <= 30:
+		row.Days1To30 += amount
+	case daysOverdue <= 60:
+		row.Days31To60 += amount
+	case daysOverdue <= 90:
+		row.Days61To90 += amount
+	default:
+		row.Over90 += amount
+	}
+	row.Invoices++
+	row.Total += amount
+}
+
+func runAgingReport(companyID string, params map[string]interface{}) (interface{}, error) {
+	asOf := time.Now()
+	if s := paramString(params, "as_of"); s != "" {
+		asOf, _ = time.Parse("2006-01-02", s)
+		asOf = asOf.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	invoices, err := fetchCompanyInvoices(companyID, bson.M{"status": "Unpaid", "date": bson.M{"$lte": asOf}})
+	if err != nil {
+		return nil, err
+	}
+	names := lookupCustomerNames(invoiceCustomerIDs(invoices))
+
+	rows := map[string]*AgingReportRow{}
+	report := AgingReport{AsOf: asOf}
+	for _, inv := range invoices {
+		due := inv.Date
+		if inv.DueDate != nil {
+			due = *inv.DueDate
+		}
+		daysOverdue := int(asOf.Sub(due).Hours() / 24)
+
+		row, ok := rows[inv.CustomerID]
+		if !ok {
+			row = &AgingReportRow{CustomerID: inv.CustomerID, CustomerName: names[inv.CustomerID]}
+			rows[inv.CustomerID] = row
+		}
+		row.add(invoiceBalance(inv), daysOverdue)
+		report.Totals.add(invoiceBalance(inv), daysOverdue)
+	}
+
+	for _, row := range rows {
+		report.Rows = append(report.Rows, *row)
+	}
+	sort.Slice(report.Rows, func(i, j int) bool { return report.Rows[i].Total > report.Rows[j].Total })
+	return report, nil
+}
+
+type CustomerActivityRow struct {
+	CustomerID      string    `json:"customer_id"`
+	CustomerName    string    `json:"customer_name"`
+	InvoiceCount    int       `json:"invoice_count"`
+	TotalBilled     float64   `json:"total_billed"`
+	TotalPaid       float64   `json:"total_paid"`
+	WrittenOff      float64   `json:"written_off"`
+	Outstanding     float64   `json:"outstanding"`
+	LastInvoiceDate time.Time `json:"last_invoice_date"`
+}
+
+func runCustomerActivityReport(companyID string, params map[string]interface{}) (interface{}, error) {
+	start, end, err := paramDateRange(companyID, params)
+	if err != nil {
+		return nil, err
+	}
+	invoices, err := fetchCompanyInvoices(companyID, bson.M{"date": bson.M{"$gte": start, "$lt": end}})
+	if err != nil {
+		return nil, err
+	}
+	names := lookupCustomerNames(invoiceCustomerIDs(invoices))
+
+	rows := map[string]*CustomerActivityRow{}
+	for _, inv := range invoices {
+		row, ok := rows[inv.CustomerID]
+		if !ok {
+			row = &CustomerActivityRow{CustomerID: inv.CustomerID, CustomerName: names[inv.CustomerID]}
+			rows[inv.CustomerID] = row
+		}
+		if inv.DocumentType == "credit_note" {
+			row.TotalBilled -= inv.Amount
+			continue
+		}
+		row.InvoiceCount++
+		row.TotalBilled += inv.Amount
+		if inv.Status == "Paid" {
+			row.TotalPaid += inv.Amount
+		} else if inv.WriteOff != nil {
+			row.TotalPaid += inv.AmountPaid
+			row.WrittenOff += inv.WriteOff.Amount
+		} else {
+			row.TotalPaid += inv.AmountPaid
+			row.Outstanding += invoiceBalance(inv)
+		}
+		if inv.Date.After(row.LastInvoiceDate) {
+			row.LastInvoiceDate = inv.Date
+		}
+	}
+
+	var result []CustomerActivityRow
+	for _, row := range rows {
+		result = append(result, *row)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].TotalBilled > result[j].TotalBilled })
+	return result, nil
+}
+
+type ItemPerformanceRow struct {
+	ItemID       string  `json:"item_id"`
+	ItemName     string  `json:"item_name"`
+	InvoiceCount int     `json:"invoice_count"`
+	QuantitySold int     `json:"quantity_sold"`
+	Revenue      float64 `json:"revenue"`
+}
+
+func runItemPerformanceReport(companyID string, params map[string]interface{}) (interface{}, error) {
+	start, end, err := paramDateRange(companyID, params)
+	if err != nil {
+		return nil, err
+	}
+	invoices, err := fetchCompanyInvoices(companyID, bson.M{"date": bson.M{"$gte": start, "$lt": end}})
+	if err != nil {
+		return nil, err
+	}
+
+	rows := map[string]*ItemPerformanceRow{}
+	for _, inv := range invoices {
+		for _, it := range inv.Items {
+			key := it.ItemID
+			if key == "" {
+				key = it.ItemName
+			}
+			row, ok := rows[key]
+			if !ok {
+				row = &ItemPerformanceRow{ItemID: it.ItemID, ItemName: it.ItemName}
+				rows[key] = row
+			}
+			sign := documentSign(inv)
+			row.InvoiceCount++
+			row.QuantitySold += int(sign) * it.Quantity
+			row.Revenue += sign * it.Subtotal
+		}
+	}
+
+	var result []ItemPerformanceRow
+	for _, row := range rows {
+		result = append(result, *row)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Revenue > result[j].Revenue })
+	if limit := int(paramNumber(params, "limit")); limit > 0 && len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
+type PaymentReportRow struct {
+	InvoiceID       string    `json:"invoice_id"`
+	ReferenceNumber string    `json:"reference_number"`
+	CustomerName    string    `json:"customer_name"`
+	PaymentDate     time.Time `json:"payment_date"`
+	Method          string    `json:"method"`
+	Source          string    `json:"source"` // payment, customer_credit or credit_note
+	Reference       string    `json:"reference,omitempty"`
+	Amount          float64   `json:"amount"` // received, including any excess kept as credit
+}
+
+type PaymentsReport struct {
+	Rows           []PaymentReportRow `json:"rows"`
+	TotalsByMethod map[string]float64 `json:"totals_by_method"` // money received per payment method
+	TotalsBySource map[string]float64 `json:"totals_by_source"`
+	Total          float64            `json:"total"` // money received; applied credits and credit notes are not
+}
+
+func runPaymentsReport(companyID string, params map[string]interface{}) (interface{}, error) {
+	start, end, err := paramDateRange(companyID, params)
+	if err != nil {
+		return nil, err
+	}
+	cursor, err := config.DB.Collection("payments").Find(context.Background(), bson.M{
+		"company_id": companyID,
+		"paid_at":    bson.M{"$gte": start, "$lt": end},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+	var payments []models.Payment
+	if err := cursor.All(context.Background(), &payments); err != nil {
+		return nil, err
+	}
+
+	var customerIDs []string
+	for _, payment := range payments {
+		customerIDs = append(customerIDs, payment.CustomerID)
+	}
+	names := lookupCustomerNames(customerIDs)
+
+	report := PaymentsReport{TotalsByMethod: map[string]float64{}, TotalsBySource: map[string]float64{}}
+	for _, payment := range payments {
+		amount := round2(payment.Amount + payment.Excess)
+		method := payment.Method
+		if method == "" {
+			method = "unspecified"
+		}
+		report.Rows = append(report.Rows, PaymentReportRow{
+			InvoiceID:       payment.InvoiceID,
+			ReferenceNumber: payment.ReferenceNumber,
+			CustomerName:    names[payment.CustomerID],
+			PaymentDate:     payment.PaidAt,
+			Method:          method,
+			Source:          payment.Source,
+			Reference:       payment.Reference,
+			Amount:          amount,
+		})
+		report.TotalsBySource[payment.Source] += amount
+		if payment.Source == "payment" {
+			report.TotalsByMethod[method] += amount
+			report.Total += amount
+		}
+	}
+	sort.Slice(report.Rows, func(i, j int) bool { return report.Rows[i].PaymentDate.Before(report.Rows[j].PaymentDate) })
+	return report, nil
+}
+
+type BadDebtReportRow struct {
+	InvoiceID       string    `json:"invoice_id"`
+	ReferenceNumber string    `json:"reference_number"`
+	CustomerName    string    `json:"customer_name"`
+	InvoiceDate     time.Time `json:"invoice_date"`
+	WrittenOffAt    time.Time `json:"written_off_at"`
+	InvoiceAmount   float64   `json:"invoice_amount"`
+	Amount          float64   `json:"amount"` // written off
+	Reason          string    `json:"reason"`
+	ApprovedBy      string    `json:"approved_by"`
+}
+
+type BadDebtReport struct {
+	Rows  []BadDebtReportRow `json:"rows"`
+	Total float64            `json:"total"`
+}
+
+func runBadDebtReport(companyID string, params map[string]interface{}) (interface{}, error) {
+	start, end, err := paramDateRange(companyID, params)
+	if err != nil {
+		return nil, err
+	}
+	invoices, err := fetchCompanyInvoices(companyID, bson.M{
+		"status":                   "WrittenOff",
+		"write_off.written_off_at": bson.M{"$gte": start, "$lt": end},
+	})
+	if err != nil {
+		return nil, err
+	}
+	names := lookupCustomerNames(invoiceCustomerIDs(invoices))
+
+	var report BadDebtReport
+	for _, inv := range invoices {
+		if inv.WriteOff == nil {
+			continue
+		}
+		report.Rows = append(report.Rows, BadDebtReportRow{
+			InvoiceID:       inv.ID.Hex(),
+			ReferenceNumber: inv.ReferenceNumber,
+			CustomerName:    names[inv.CustomerID],
+			InvoiceDate:     inv.Date,
+			WrittenOffAt:    inv.WriteOff.WrittenOffAt,
+			InvoiceAmount:   inv.Amount,
+			Amount:          inv.WriteOff.Amount,
+			Reason:          inv.WriteOff.Reason,
+			ApprovedBy:      inv.WriteOff.ApprovedBy,
+		})
+		report.Total += inv.WriteOff.Amount
+	}
+	sort.Slice(report.Rows, func(i, j int) bool { return report.Rows[i].WrittenOffAt.Before(report.Rows[j].WrittenOffAt) })
+	return report, nil
+}
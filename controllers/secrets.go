@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"fmt"
+	"os"
+)
+
+// requiredSecrets are the environment variables the server does not start
+// without, as links signed or keys sealed with an empty secret are not safe.
+var requiredSecrets = []string{"INVOICE_VERIFICATION_SECRET", "CERTIFICATE_KEY_SECRET"}
+
+// CheckSecrets returns an error naming the first required secret that is not
+// set.
+func CheckSecrets() error {
+	for _, name := range requiredSecrets {
+		if os.Getenv(name) == "" {
+			return fmt.Errorf("%s is not set", name)
+		}
+	}
+	return nil
+}
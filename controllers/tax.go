@@ -0,0 +1,335 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var taxCategories = []string{"standard", "zero_rated", "exempt"}
+
+// validateLineTax checks the tax rate and category of an invoice line and
+// returns the category to store, defaulting to standard.
+func validateLineTax(item models.InvoiceItem) (string, error) {
+	category := item.TaxCategory
+	if category == "" {
+		category = "standard"
+	}
+	if !contains(taxCategories, category) {
+		return "", fmt.Errorf("tax_category must be one of %v", taxCategories)
+	}
+	if item.TaxRate < 0 || item.TaxRate > 100 {
+		return "", fmt.Errorf("tax_rate must be between 0 and 100")
+	}
+	if category != "standard" && item.TaxRate != 0 {
+		return "", fmt.Errorf("%s lines cannot carry a tax rate", category)
+	}
+	return category, nil
+}
+
+// BackfillInvoiceTax godoc
+// @Summary Add tax amounts to invoices that have none
+// @Description For invoices of a company created before tax was recorded, extracts the tax contained in the existing amounts at the given rate. Invoice totals are left unchanged.
+// @Tags Invoices
+// @Accept json
+// @Produce json
+// @Param body body models.BackfillInvoiceTaxRequest true "Company, tax rate and category"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string "No active company in the token"
+// @Failure 403 {object} map[string]string
+// @Failure 500 {object} map[string]string
+// @Router /invoice/tax/backfill [post]
+func BackfillInvoiceTax(c *gin.Context) {
+	var req models.BackfillInvoiceTaxRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input", "details": err.Error()})
+		return
+	}
+	var ok bool
+	if req.CompanyID, ok = activeCompanyID(c, req.CompanyID, "backfill its invoice tax"); !ok {
+		return
+	}
+	category, err := validateLineTax(models.InvoiceItem{TaxRate: req.TaxRate, TaxCategory: req.TaxCategory})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invoices, err := fetchCompanyInvoices(req.CompanyID, bson.M{"tax_amount": bson.M{"$exists": false}})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invoices"})
+		return
+	}
+
+	updated := 0
+	for _, inv := range invoices {
+		// Amounts already include the tax, so split them into net and tax
+		divisor := 1 + req.TaxRate/100
+		var subtotal float64
+		for i, item := range inv.Items {
+			net := item.Subtotal / divisor
+			inv.Items[i].TaxRate = req.TaxRate
+			inv.Items[i].TaxCategory = category
+			inv.Items[i].TaxAmount = item.Subtotal - net
+			inv.Items[i].Subtotal = net
+			subtotal += net
+		}
+		if len(inv.Items) == 0 {
+			subtotal = inv.Amount / divisor
+		}
+
+		_, err := config.DB.Collection("invoices").UpdateOne(context.Background(),
+			bson.M{"_id": inv.ID},
+			bson.M{"$set": bson.M{
+				"items":         inv.Items,
+				"subtotal":      subtotal,
+				"tax_amount":    inv.Amount - subtotal,
+				"document_type": "invoice",
+				"updated_at":    time.Now(),
+			}},
+		)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update invoice " + inv.ID.Hex(), "updated": updated})
+			return
+		}
+		updated++
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Added tax amounts to %d invoices", updated), "updated": updated})
+}
+
+type TaxRateTotal struct {
+	Rate          float64 `json:"rate"`
+	TaxableAmount float64 `json:"taxable_amount"`
+	TaxAmount     float64 `json:"tax_amount"`
+}
+
+type TaxSummaryPeriod struct {
+	Period            string         `json:"period"` // YYYY-MM or YYYY-Qn
+	InvoiceCount      int            `json:"invoice_count"`
+	CreditNoteCount   int            `json:"credit_note_count"`
+	TaxableSales      float64        `json:"taxable_sales"`
+	TaxCollected      float64        `json:"tax_collected"`
+	ByRate            []TaxRateTotal `json:"by_rate"` // sums to TaxableSales and TaxCollected
+	ZeroRatedSales    float64        `json:"zero_rated_sales"`
+	ExemptSales       float64        `json:"exempt_sales"`
+	WithholdingAmount float64        `json:"withholding_amount"`
+	CreditNoteTaxable float64        `json:"credit_note_taxable"`
+	CreditNoteTax     float64        `json:"credit_note_tax"`
+	NetTaxPayable     float64        `json:"net_tax_payable"`
+}
+
+type TaxInvoiceLine struct {
+	InvoiceID         string    `json:"invoice_id"`
+	ReferenceNumber   string    `json:"reference_number"`
+	Date              time.Time `json:"date"`
+	DocumentType      string    `json:"document_type"`
+	TaxableAmount     float64   `json:"taxable_amount"`
+	TaxAmount         float64   `json:"tax_amount"`
+	ZeroRatedAmount   float64   `json:"zero_rated_amount"`
+	ExemptAmount      float64   `json:"exempt_amount"`
+	WithholdingAmount float64   `json:"withholding_amount"`
+}
+
+type TaxCustomerSummary struct {
+	CustomerID    string           `json:"customer_id"`
+	CustomerName  string           `json:"customer_name"`
+	TIN           string           `json:"tin"`
+	TaxableAmount float64          `json:"taxable_amount"`
+	TaxAmount     float64          `json:"tax_amount"`
+	Invoices      []TaxInvoiceLine `json:"invoices"`
+}
+
+type TaxSummaryReport struct {
+	Periods   []TaxSummaryPeriod   `json:"periods"`
+	Customers []TaxCustomerSummary `json:"customers"`
+}
+
+func taxPeriodKey(date time.Time, grouping string) string {
+	if grouping == "quarter" {
+		return fmt.Sprintf("%d-Q%d", date.Year(), (int(date.Month())-1)/3+1)
+	}
+	return date.Format("2006-01")
+}
+
+// invoiceTaxLine splits an invoice into standard-rated, zero-rated and exempt
+// amounts. Credit notes are returned with negative amounts.
+func invoiceTaxLine(inv models.Invoice) (TaxInvoiceLine, map[float64]TaxRateTotal) {
+	sign := 1.0
+	documentType := inv.DocumentType
+	if documentType == "" {
+		documentType = "invoice"
+	}
+	if documentType == "credit_note" {
+		sign = -1
+	}
+
+	line := TaxInvoiceLine{
+		InvoiceID:         inv.ID.Hex(),
+		ReferenceNumber:   inv.ReferenceNumber,
+		Date:              inv.Date,
+		DocumentType:      documentType,
+		WithholdingAmount: sign * inv.WithholdingAmount,
+	}
+	rates := map[float64]TaxRateTotal{}
+	for _, item := range inv.Items {
+		switch item.TaxCategory {
+		case "zero_rated":
+			line.ZeroRatedAmount += sign * item.Subtotal
+		case "exempt":
+			line.ExemptAmount += sign * item.Subtotal
+		default:
+			line.TaxableAmount += sign * item.Subtotal
+			line.TaxAmount += sign * item.TaxAmount
+			total := rates[item.TaxRate]
+			total.Rate = item.TaxRate
+			total.TaxableAmount += sign * item.Subtotal
+			total.TaxAmount += sign * item.TaxAmount
+			rates[item.TaxRate] = total
+		}
+	}
+	return line, rates
+}
+
+func runTaxSummaryReport(companyID string, params map[string]interface{}) (interface{}, error) {
+	start, end, err := paramDateRange(companyID, params)
+	if err != nil {
+		return nil, err
+	}
+	grouping := paramString(params, "period")
+
+	invoices, err := fetchCompanyInvoices(companyID, bson.M{"date": bson.M{"$gte": start, "$lt": end}})
+	if err != nil {
+		return nil, err
+	}
+	customers := lookupCustomers(invoiceCustomerIDs(invoices))
+
+	periods := map[string]*TaxSummaryPeriod{}
+	periodRates := map[string]map[float64]TaxRateTotal{}
+	byCustomer := map[string]*TaxCustomerSummary{}
+
+	for _, inv := range invoices {
+		line, rates := invoiceTaxLine(inv)
+
+		// Periods follow the company's timezone, which the range is in
+		key := taxPeriodKey(inv.Date.In(start.Location()), grouping)
+		period, ok := periods[key]
+		if !ok {
+			period = &TaxSummaryPeriod{Period: key}
+			periods[key] = period
+			periodRates[key] = map[float64]TaxRateTotal{}
+		}
+		if line.DocumentType == "credit_note" {
+			period.CreditNoteCount++
+			period.CreditNoteTaxable -= line.TaxableAmount
+			period.CreditNoteTax -= line.TaxAmount
+		} else {
+			period.InvoiceCount++
+			period.TaxableSales += line.TaxableAmount
+			period.TaxCollected += line.TaxAmount
+			// The rate lines break down the taxable sales and tax collected,
+			// so credit notes are left out of them too
+			for rate, total := range rates {
+				existing := periodRates[key][rate]
+				existing.Rate = rate
+				existing.TaxableAmount += total.TaxableAmount
+				existing.TaxAmount += total.TaxAmount
+				periodRates[key][rate] = existing
+			}
+		}
+		period.ZeroRatedSales += line.ZeroRatedAmount
+		period.ExemptSales += line.ExemptAmount
+		period.WithholdingAmount += line.WithholdingAmount
+
+		customer, ok := byCustomer[inv.CustomerID]
+		if !ok {
+			cust := customers[inv.CustomerID]
+			customer = &TaxCustomerSummary{CustomerID: inv.CustomerID, CustomerName: cust.Name, TIN: cust.TIN}
+			byCustomer[inv.CustomerID] = customer
+		}
+		customer.TaxableAmount += line.TaxableAmount
+		customer.TaxAmount += line.TaxAmount
+		customer.Invoices = append(customer.Invoices, line)
+	}
+
+	report := TaxSummaryReport{Periods: []TaxSummaryPeriod{}, Customers: []TaxCustomerSummary{}}
+	for key, period := range periods {
+		for _, total := range periodRates[key] {
+			period.ByRate = append(period.ByRate, total)
+		}
+		sort.Slice(period.ByRate, func(i, j int) bool { return period.ByRate[i].Rate < period.ByRate[j].Rate })
+		period.NetTaxPayable = period.TaxCollected - period.CreditNoteTax
+		report.Periods = append(report.Periods, *period)
+	}
+	sort.Slice(report.Periods, func(i, j int) bool { return report.Periods[i].Period < report.Periods[j].Period })
+
+	for _, customer := range byCustomer {
+		sort.Slice(customer.Invoices, func(i, j int) bool { return customer.Invoices[i].Date.Before(customer.Invoices[j].Date) })
+		report.Customers = append(report.Customers, *customer)
+	}
+	sort.Slice(report.Customers, func(i, j int) bool {
+		if report.Customers[i].TIN != report.Customers[j].TIN {
+			return report.Customers[i].TIN < report.Customers[j].TIN
+		}
+		return report.Customers[i].CustomerName < report.Customers[j].CustomerName
+	})
+	return report, nil
+}
+
+// taxSummaryReportTable lays the tax report out as a sales declaration: one row
+// per invoice or credit note grouped by buyer TIN, with period totals as summary.
+func taxSummaryReportTable(content []byte) (reportTable, error) {
+	var report TaxSummaryReport
+	if err := json.Unmarshal(content, &report); err != nil {
+		return reportTable{}, err
+	}
+
+	table := reportTable{
+		Name: "VAT Sales Declaration",
+		Columns: []reportColumn{
+			{"No", columnInt}, {"Buyer TIN", columnText}, {"Buyer Name", columnText}, {"Invoice Number", columnText},
+			{"Invoice Date", columnDate}, {"Document Type", columnText}, {"Taxable Amount", columnNumber},
+			{"VAT Amount", columnNumber}, {"Zero Rated Amount", columnNumber}, {"Exempt Amount", columnNumber},
+			{"Withholding Amount", columnNumber},
+		},
+	}
+	for _, customer := range report.Customers {
+		for _, line := range customer.Invoices {
+			table.Rows = append(table.Rows, []interface{}{
+				len(table.Rows) + 1, customer.TIN, customer.CustomerName, line.ReferenceNumber, line.Date, line.DocumentType,
+				line.TaxableAmount, line.TaxAmount, line.ZeroRatedAmount, line.ExemptAmount, line.WithholdingAmount,
+			})
+		}
+	}
+
+	for _, period := range report.Periods {
+		p := period.Period + " "
+		table.Summary = append(table.Summary,
+			reportSummaryRow{p + "taxable sales", period.TaxableSales},
+			reportSummaryRow{p + "tax collected", period.TaxCollected},
+		)
+		for _, rate := range period.ByRate {
+			table.Summary = append(table.Summary,
+				reportSummaryRow{fmt.Sprintf("%staxable sales at %g%%", p, rate.Rate), rate.TaxableAmount},
+				reportSummaryRow{fmt.Sprintf("%stax at %g%%", p, rate.Rate), rate.TaxAmount},
+			)
+		}
+		table.Summary = append(table.Summary,
+			reportSummaryRow{p + "zero rated sales", period.ZeroRatedSales},
+			reportSummaryRow{p + "exempt sales", period.ExemptSales},
+			reportSummaryRow{p + "withholding", period.WithholdingAmount},
+			reportSummaryRow{p + "credit note adjustments", period.CreditNoteTax},
+			reportSummaryRow{p + "net tax payable", period.NetTaxPayable},
+		)
+	}
+	return table, nil
+}
@@ -0,0 +1,9 @@
+// Package fonts bundles the TrueType fonts embedded in generated PDFs. DejaVu
+// Sans covers Latin, Greek and Cyrillic; fonts for other scripts such as
+// Ethiopic are loaded at runtime from PDF_FONT_DIR.
+package fonts
+
+import "embed"
+
+//go:embed *.ttf
+var FS embed.FS
@@ -0,0 +1,251 @@
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"gopkg.in/gomail.v2"
+)
+
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Content     []byte
+}
+
+type Message struct {
+	FromName    string
+	ReplyTo     string
+	To          []string
+	Cc          []string
+	Subject     string
+	TextBody    string
+	HTMLBody    string
+	Attachments []Attachment
+}
+
+// Transport delivers a single message. Implementations must be safe for
+// concurrent use.
+type Transport interface {
+	Send(msg Message) error
+}
+
+// SMTPTransport sends mail through an SMTP server.
+type SMTPTransport struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+}
+
+func (t *SMTPTransport) Send(msg Message) error {
+	d := gomail.NewDialer(t.Host, t.Port, t.Username, t.Password)
+	return d.DialAndSend(buildMessage(t.From, msg))
+}
+
+// FileTransport writes every message as an .eml file into Dir, which is
+// useful for local development without an SMTP server.
+type FileTransport struct {
+	Dir  string
+	From string
+}
+
+func (t *FileTransport) Send(msg Message) error {
+	if err := os.MkdirAll(t.Dir, 0o755); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s.eml", time.Now().Format("20060102T150405.000000000"))
+	f, err := os.Create(filepath.Join(t.Dir, name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = buildMessage(t.From, msg).WriteTo(f)
+	return err
+}
+
+// MemoryTransport keeps sent messages in memory so tests can assert on them.
+// Setting Err makes every Send fail with that error.
+type MemoryTransport struct {
+	mu   sync.Mutex
+	sent []Message
+	Err  error
+}
+
+func NewMemoryTransport() *MemoryTransport {
+	return &MemoryTransport{}
+}
+
+func (t *MemoryTransport) Send(msg Message) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.Err != nil {
+		return t.Err
+	}
+	t.sent = append(t.sent, msg)
+	return nil
+}
+
+// Sent returns a copy of the messages delivered so far.
+func (t *MemoryTransport) Sent() []Message {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Message(nil), t.sent...)
+}
+
+func (t *MemoryTransport) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sent = nil
+	t.Err = nil
+}
+
+func buildMessage(from string, msg Message) *gomail.Message {
+	m := gomail.NewMessage()
+	if msg.FromName != "" {
+		m.SetAddressHeader("From", from, msg.FromName)
+	} else {
+		m.SetHeader("From", from)
+	}
+	if msg.ReplyTo != "" {
+		m.SetHeader("Reply-To", msg.ReplyTo)
+	}
+	m.SetHeader("To", msg.To...)
+	if len(msg.Cc) > 0 {
+		m.SetHeader("Cc", msg.Cc...)
+	}
+	m.SetHeader("Subject", msg.Subject)
+
+	switch {
+	case msg.TextBody != "" && msg.HTMLBody != "":
+		m.SetBody("text/plain", msg.TextBody)
+		m.AddAlternative("text/html", msg.HTMLBody)
+	case msg.HTMLBody != "":
+		m.SetBody("text/html", msg.HTMLBody)
+	default:
+		m.SetBody("text/plain", msg.TextBody)
+	}
+
+	for _, a := range msg.Attachments {
+		content := a.Content
+		settings := []gomail.FileSetting{gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(content)
+			return err
+		})}
+		if a.ContentType != "" {
+			settings = append(settings, gomail.SetHeader(map[string][]string{"Content-Type": {a.ContentType}}))
+		}
+		m.Attach(a.Filename, settings...)
+	}
+	return m
+}
+
+// Config describes a transport: Transport is smtp, file or memory, Dir is
+// where the file transport writes and the rest configure SMTP.
+type Config struct {
+	Transport string
+	Host      string
+	Port      int
+	Username  string
+	Password  string
+	From      string
+	Dir       string
+}
+
+// ConfigFromEnv reads MAIL_TRANSPORT and the EMAIL_HOST, EMAIL_PORT,
+// EMAIL_USERNAME, EMAIL_PASSWORD, EMAIL_FROM and MAIL_DIR environment
+// variables.
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Transport: os.Getenv("MAIL_TRANSPORT"),
+		Host:      os.Getenv("EMAIL_HOST"),
+		Username:  os.Getenv("EMAIL_USERNAME"),
+		Password:  os.Getenv("EMAIL_PASSWORD"),
+		From:      os.Getenv("EMAIL_FROM"),
+		Dir:       os.Getenv("MAIL_DIR"),
+	}
+	if port := os.Getenv("EMAIL_PORT"); port != "" {
+		p, err := strconv.Atoi(port)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid EMAIL_PORT: %v", err)
+		}
+		cfg.Port = p
+	}
+	return cfg, nil
+}
+
+// New builds the transport cfg describes. The sender defaults to the SMTP
+// username and the file transport's directory to "mail".
+func New(cfg Config) (Transport, error) {
+	from := cfg.From
+	if from == "" {
+		from = cfg.Username
+	}
+
+	switch cfg.Transport {
+	case "", "smtp":
+		if cfg.Port <= 0 {
+			return nil, fmt.Errorf("SMTP port is not set")
+		}
+		return &SMTPTransport{
+			Host:     cfg.Host,
+			Port:     cfg.Port,
+			Username: cfg.Username,
+			Password: cfg.Password,
+			From:     from,
+		}, nil
+	case "file":
+		dir := cfg.Dir
+		if dir == "" {
+			dir = "mail"
+		}
+		return &FileTransport{Dir: dir, From: from}, nil
+	case "memory":
+		return NewMemoryTransport(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail transport %q, expected smtp, file or memory", cfg.Transport)
+	}
+}
+
+// FromEnv builds a transport from the environment, see ConfigFromEnv.
+func FromEnv() (Transport, error) {
+	cfg, err := ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return New(cfg)
+}
+
+var (
+	defaultTransport Transport
+	defaultMu        sync.Mutex
+)
+
+// Default returns the transport set with SetDefault, or one built from the
+// environment on first use.
+func Default() (Transport, error) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultTransport == nil {
+		t, err := FromEnv()
+		if err != nil {
+			return nil, err
+		}
+		defaultTransport = t
+	}
+	return defaultTransport, nil
+}
+
+// SetDefault replaces the transport used by Default. Passing nil makes the
+// next call rebuild it from the environment.
+func SetDefault(t Transport) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultTransport = t
+}
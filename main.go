@@ -1,9 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
 
 	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
 	"github.com/bisre1921/billing-and-invoice-system/routes"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -21,11 +24,23 @@ import (
 // @name Authorization
 func main() {
 
+	if err := controllers.CheckSecrets(); err != nil {
+		log.Fatalf("Missing configuration: %v", err)
+	}
+
 	err := config.ConnectDB()
 	if err != nil {
 		log.Fatalf("Database connection failed: %v", err)
 	}
 
+	if err := controllers.LoadPDFFonts(os.Getenv("PDF_FONT_DIR")); err != nil {
+		log.Printf("Loading PDF fonts failed: %v", err)
+	}
+
+	controllers.StartReportScheduler(context.Background())
+	controllers.StartMailWorker(context.Background())
+	controllers.StartCreditHoldMonitor(context.Background())
+
 	r := gin.Default()
 
 	r.Use(cors.New(cors.Config{
@@ -18,26 +18,52 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
-
-		if err != nil || !token.Valid {
+		if !setClaims(c, authHeader) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
+// OptionalAuthMiddleware reads the token when the request has one, so
+// handlers can scope to its active company, and lets anonymous requests
+// through. A token that is sent must be valid.
+func OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader != "" && !setClaims(c, authHeader) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
-
-		c.Set("userID", claims["user_id"])
-		c.Set("email", claims["email"])
 		c.Next()
 	}
 }
+
+// setClaims validates the bearer token and stores its claims on the context.
+// Tokens issued for an active company also carry companyID and role.
+func setClaims(c *gin.Context, authHeader string) bool {
+	tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	})
+
+	if err != nil || !token.Valid {
+		return false
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return false
+	}
+
+	c.Set("userID", claims["user_id"])
+	c.Set("email", claims["email"])
+	if companyID, ok := claims["company_id"].(string); ok && companyID != "" {
+		c.Set("companyID", companyID)
+		c.Set("role", claims["role"])
+	}
+	return true
+}
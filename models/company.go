@@ -7,12 +7,121 @@ import (
 )
 
 type Company struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Name      string             `json:"name" bson:"name"`
-	Email     string             `json:"email" bson:"email"`
-	Owner     string             `json:"owner,omitempty" bson:"owner,omitempty"`
-	Address   string             `json:"address,omitempty" bson:"address,omitempty"`
-	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
-	DeletedAt *time.Time         `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	ID              primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	Name            string              `json:"name" bson:"name"`
+	Email           string              `json:"email" bson:"email"`
+	Owner           string              `json:"owner,omitempty" bson:"owner,omitempty"`
+	Address         string              `json:"address,omitempty" bson:"address,omitempty"`
+	Phone           string              `json:"phone,omitempty" bson:"phone,omitempty"`
+	TIN             string              `json:"tin,omitempty" bson:"tin,omitempty"`
+	Branding        CompanyBranding     `json:"branding" bson:"branding,omitempty"`
+	Logo            []byte              `json:"-" bson:"logo,omitempty"` // served from /company/{id}/logo
+	LogoContentType string              `json:"logo_content_type,omitempty" bson:"logo_content_type,omitempty"`
+	Signing         *SigningCertificate `json:"signing_certificate,omitempty" bson:"signing_certificate,omitempty"`
+	Settings        CompanySettings     `json:"settings" bson:"settings,omitempty"`
+	CreatedAt       time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+	DeletedAt       *time.Time          `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+}
+
+// CompanySettings are the defaults used when the company invoices and
+// reports. Empty values fall back to the system defaults.
+type CompanySettings struct {
+	BaseCurrency                 string               `json:"base_currency,omitempty" bson:"base_currency,omitempty"`                                                              // ISO 4217 code, e.g. ETB
+	Timezone                     string               `json:"timezone,omitempty" bson:"timezone,omitempty"`                                                                        // IANA name, e.g. Africa/Addis_Ababa
+	FiscalYearStart              int                  `json:"fiscal_year_start,omitempty" bson:"fiscal_year_start,omitempty" binding:"omitempty,min=1,max=12"`                     // month, 1 = January
+	DefaultPaymentTermsDays      int                  `json:"default_payment_terms_days,omitempty" bson:"default_payment_terms_days,omitempty" binding:"omitempty,min=1,max=365"`  // due date of credit invoices
+	InvoicePrefix                string               `json:"invoice_prefix,omitempty" bson:"invoice_prefix,omitempty"`                                                            // prepended to reference numbers
+	AutoApplyCredits             bool                 `json:"auto_apply_credits,omitempty" bson:"auto_apply_credits,omitempty"`                                                    // pay new credit invoices from the customer's credit balance
+	CreditHoldOverdueDays        int                  `json:"credit_hold_overdue_days,omitempty" bson:"credit_hold_overdue_days,omitempty" binding:"omitempty,min=1,max=365"`      // put customers on credit hold once a credit invoice is this many days overdue
+	CreditLimitApprovalThreshold float64              `json:"credit_limit_approval_threshold,omitempty" bson:"credit_limit_approval_threshold,omitempty" binding:"omitempty,gt=0"` // credit limits raised above this need the owner's approval
+	Mail                         *CompanyMailSettings `json:"mail,omitempty" bson:"mail,omitempty"`                                                                                // how the company's mail is sent
+}
+
+// CompanyMailSettings is how mail from the company is sent. Empty values fall
+// back to the server's MAIL_TRANSPORT and EMAIL_* settings; the server's SMTP
+// login is only used when the company does not name its own host.
+type CompanyMailSettings struct {
+	Transport string `json:"transport,omitempty" bson:"transport,omitempty"` // smtp or file
+	Host      string `json:"host,omitempty" bson:"host,omitempty"`
+	Port      int    `json:"port,omitempty" bson:"port,omitempty"`
+	Username  string `json:"username,omitempty" bson:"username,omitempty"`
+	Password  []byte `json:"-" bson:"password,omitempty"`          // encrypted, never returned
+	From      string `json:"from,omitempty" bson:"from,omitempty"` // sender address
+}
+
+// CompanyBranding controls how the company's invoices and receipts look.
+type CompanyBranding struct {
+	AccentColor         string        `json:"accent_color,omitempty" bson:"accent_color,omitempty"`     // #RRGGBB
+	InvoiceLayout       string        `json:"invoice_layout,omitempty" bson:"invoice_layout,omitempty"` // see GET /invoice/layouts
+	Font                string        `json:"font,omitempty" bson:"font,omitempty"`                     // see GET /invoice/fonts
+	FooterText          string        `json:"footer_text,omitempty" bson:"footer_text,omitempty"`
+	PaymentInstructions string        `json:"payment_instructions,omitempty" bson:"payment_instructions,omitempty"`
+	BankAccounts        []BankAccount `json:"bank_accounts,omitempty" bson:"bank_accounts,omitempty" binding:"dive"`
+}
+
+type BankAccount struct {
+	BankName      string `json:"bank_name" bson:"bank_name" binding:"required"`
+	AccountName   string `json:"account_name" bson:"account_name" binding:"required"`
+	AccountNumber string `json:"account_number" bson:"account_number" binding:"required"`
+	Branch        string `json:"branch,omitempty" bson:"branch,omitempty"`
+	SwiftCode     string `json:"swift_code,omitempty" bson:"swift_code,omitempty"`
+}
+
+// SigningCertificate is the X.509 certificate a company signs its invoice PDFs
+// with. The private key is stored encrypted and never returned.
+type SigningCertificate struct {
+	Subject      string    `json:"subject" bson:"subject"`
+	Issuer       string    `json:"issuer" bson:"issuer"`
+	SerialNumber string    `json:"serial_number" bson:"serial_number"`
+	Fingerprint  string    `json:"fingerprint" bson:"fingerprint"` // SHA-256 of the certificate
+	NotBefore    time.Time `json:"not_before" bson:"not_before"`
+	NotAfter     time.Time `json:"not_after" bson:"not_after"`
+	AutoSign     bool      `json:"auto_sign" bson:"auto_sign"` // sign every invoice PDF, not only when asked to
+	UploadedAt   time.Time `json:"uploaded_at" bson:"uploaded_at"`
+	Chain        [][]byte  `json:"-" bson:"chain"`         // DER, signing certificate first
+	EncryptedKey []byte    `json:"-" bson:"encrypted_key"` // AES-GCM sealed PKCS#8 private key
+}
+
+// UpdateCompanySettingsInput changes the company settings that are sent and
+// keeps the others.
+type UpdateCompanySettingsInput struct {
+	BaseCurrency                 *string                         `json:"base_currency,omitempty"`
+	Timezone                     *string                         `json:"timezone,omitempty"`
+	FiscalYearStart              *int                            `json:"fiscal_year_start,omitempty" binding:"omitempty,min=1,max=12"`
+	DefaultPaymentTermsDays      *int                            `json:"default_payment_terms_days,omitempty" binding:"omitempty,min=1,max=365"`
+	InvoicePrefix                *string                         `json:"invoice_prefix,omitempty"`
+	AutoApplyCredits             *bool                           `json:"auto_apply_credits,omitempty"`
+	CreditHoldOverdueDays        *int                            `json:"credit_hold_overdue_days,omitempty" binding:"omitempty,min=1,max=365"`
+	CreditLimitApprovalThreshold *float64                        `json:"credit_limit_approval_threshold,omitempty" binding:"omitempty,gt=0"`
+	Mail                         *UpdateCompanyMailSettingsInput `json:"mail,omitempty"`
+}
+
+// UpdateCompanyMailSettingsInput changes the mail settings that are sent. An
+// empty string clears a setting so the server's is used again.
+type UpdateCompanyMailSettingsInput struct {
+	Transport *string `json:"transport,omitempty"`
+	Host      *string `json:"host,omitempty"`
+	Port      *int    `json:"port,omitempty" binding:"omitempty,min=0,max=65535"`
+	Username  *string `json:"username,omitempty"`
+	Password  *string `json:"password,omitempty"`
+	From      *string `json:"from,omitempty"`
+}
+
+type UpdateCompanyInput struct {
+	Name    *string `json:"name,omitempty"`
+	Email   *string `json:"email,omitempty"`
+	Address *string `json:"address,omitempty"`
+	Phone   *string `json:"phone,omitempty"`
+	TIN     *string `json:"tin,omitempty"`
+}
+
+// UserCompany is a company the user owns or works for, with their role in it.
+type UserCompany struct {
+	Company `bson:",inline"`
+	Role    string `json:"role"` // owner or employee
+}
+
+type UpdateSigningCertificateRequest struct {
+	AutoSign bool `json:"auto_sign"`
 }
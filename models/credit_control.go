@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreditRequest asks the company owner for an exception to a customer's
+// credit controls: an override lets one credit invoice through despite a
+// credit hold or the credit limit, a credit_limit request raises the limit.
+type CreditRequest struct {
+	ID               primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CompanyID        primitive.ObjectID `json:"company_id" bson:"company_id"`
+	CustomerID       primitive.ObjectID `json:"customer_id" bson:"customer_id"`
+	Type             string             `json:"type" bson:"type"`     // override or credit_limit
+	Status           string             `json:"status" bson:"status"` // Pending, Approved, Rejected, or Used once an override is spent
+	Amount           float64            `json:"amount" bson:"amount"` // largest invoice an override allows, or the requested credit limit
+	PreviousLimit    float64            `json:"previous_limit,omitempty" bson:"previous_limit,omitempty"`
+	Reason           string             `json:"reason" bson:"reason"`
+	RequestedBy      string             `json:"requested_by" bson:"requested_by"` // user ID
+	RequestedByEmail string             `json:"requested_by_email,omitempty" bson:"requested_by_email,omitempty"`
+	DecidedBy        string             `json:"decided_by,omitempty" bson:"decided_by,omitempty"`
+	DecisionNote     string             `json:"decision_note,omitempty" bson:"decision_note,omitempty"`
+	DecidedAt        *time.Time         `json:"decided_at,omitempty" bson:"decided_at,omitempty"`
+	InvoiceReference string             `json:"invoice_reference,omitempty" bson:"invoice_reference,omitempty"` // invoice an override was used for
+	CreatedAt        time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type CreditRequestInput struct {
+	Type   string  `json:"type" binding:"required,oneof=override credit_limit"`
+	Amount float64 `json:"amount" binding:"required,gt=0"`
+	Reason string  `json:"reason" binding:"required"`
+}
+
+type CreditDecisionInput struct {
+	Note string `json:"note"`
+}
+
+type CreditHoldInput struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// CustomerCreditEvent is an entry in a customer's credit control history.
+type CustomerCreditEvent struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CompanyID   primitive.ObjectID `json:"company_id" bson:"company_id"`
+	CustomerID  primitive.ObjectID `json:"customer_id" bson:"customer_id"`
+	Type        string             `json:"type" bson:"type"` // e.g. hold_placed, hold_released, override_approved, limit_changed
+	Description string             `json:"description" bson:"description"`
+	Amount      float64            `json:"amount,omitempty" bson:"amount,omitempty"`
+	RequestID   string             `json:"request_id,omitempty" bson:"request_id,omitempty"`
+	UserID      string             `json:"user_id,omitempty" bson:"user_id,omitempty"` // empty for automatic changes
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+}
@@ -7,15 +7,55 @@ import (
 )
 
 type Customer struct {
-	ID                     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Name                   string             `json:"name" bson:"name"`
-	Email                  string             `json:"email" bson:"email"`
-	Phone                  string             `json:"phone" bson:"phone"`
-	Address                string             `json:"address" bson:"address"`
-	TIN                    string             `json:"tin" bson:"tin"`
-	MaxCreditAmount        float64            `json:"max_credit_amount" bson:"max_credit_amount"`
-	CurrentCreditAvailable float64            `json:"current_credit_available" bson:"current_credit_available"`
-	CompanyID              primitive.ObjectID `json:"company_id" bson:"company_id"`
-	CreatedAt              time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt              time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+	ID                     primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	Name                   string              `json:"name" bson:"name"`
+	Email                  string              `json:"email" bson:"email"`
+	Phone                  string              `json:"phone" bson:"phone"`
+	Address                string              `json:"address" bson:"address"`
+	BillingAddress         string              `json:"billing_address,omitempty" bson:"billing_address,omitempty"`   // defaults to address
+	ShippingAddress        string              `json:"shipping_address,omitempty" bson:"shipping_address,omitempty"` // defaults to the billing address
+	Contacts               []CustomerContact   `json:"contacts,omitempty" bson:"contacts,omitempty"`
+	Tags                   []string            `json:"tags,omitempty" bson:"tags,omitempty"`
+	Notes                  []CustomerNote      `json:"notes,omitempty" bson:"notes,omitempty"` // internal, added with POST /customer/{id}/notes
+	TIN                    string              `json:"tin" bson:"tin"`
+	MaxCreditAmount        float64             `json:"max_credit_amount" bson:"max_credit_amount"`
+	CurrentCreditAvailable float64             `json:"current_credit_available" bson:"current_credit_available"`
+	CreditBalance          float64             `json:"credit_balance" bson:"credit_balance"` // unapplied prepayments, overpayments and credit notes
+	CompanyID              primitive.ObjectID  `json:"company_id" bson:"company_id"`
+	PaymentTermsID         *primitive.ObjectID `json:"payment_terms_id,omitempty" bson:"payment_terms_id,omitempty"` // default terms of their credit invoices
+	CreditHold             *CustomerCreditHold `json:"credit_hold,omitempty" bson:"credit_hold,omitempty"`           // no credit invoices while set
+	CreatedAt              time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt              time.Time           `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// CustomerCreditHold stops new credit invoices for a customer.
+type CustomerCreditHold struct {
+	Reason   string    `json:"reason" bson:"reason"`
+	Source   string    `json:"source" bson:"source"`                           // manual, write_off or overdue
+	PlacedBy string    `json:"placed_by,omitempty" bson:"placed_by,omitempty"` // user ID
+	PlacedAt time.Time `json:"placed_at" bson:"placed_at"`
+}
+
+// CustomerContact is a person at the customer, such as the one invoices go to.
+type CustomerContact struct {
+	ID      primitive.ObjectID `json:"id" bson:"id"`
+	Name    string             `json:"name" bson:"name"`
+	Role    string             `json:"role" bson:"role"` // billing, purchasing, shipping or other
+	Email   string             `json:"email,omitempty" bson:"email,omitempty"`
+	Phone   string             `json:"phone,omitempty" bson:"phone,omitempty"`
+	Address string             `json:"address,omitempty" bson:"address,omitempty"` // printed on their invoices instead of the billing address
+	Primary bool               `json:"primary,omitempty" bson:"primary,omitempty"` // first choice for the role
+}
+
+// CustomerNote is an internal note about a customer, never shown to them.
+type CustomerNote struct {
+	ID          primitive.ObjectID `json:"id" bson:"id"`
+	Text        string             `json:"text" bson:"text"`
+	AuthorID    string             `json:"author_id,omitempty" bson:"author_id,omitempty"`
+	AuthorEmail string             `json:"author_email,omitempty" bson:"author_email,omitempty"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type CustomerNoteInput struct {
+	Text string `json:"text" binding:"required"`
 }
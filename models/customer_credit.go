@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CustomerCredit is money a customer has with the company that is not yet
+// applied to an invoice: a deposit, a payment in advance, an overpayment or
+// a credit note. Remaining is what is left after applications and refunds.
+type CustomerCredit struct {
+	ID           primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	CompanyID    primitive.ObjectID  `json:"company_id" bson:"company_id"`
+	CustomerID   primitive.ObjectID  `json:"customer_id" bson:"customer_id"`
+	Source       string              `json:"source" bson:"source"`                           // deposit, payment, overpayment or credit_note
+	SourceID     string              `json:"source_id,omitempty" bson:"source_id,omitempty"` // invoice or credit note it came from
+	Method       string              `json:"method,omitempty" bson:"method,omitempty"`       // how the money was received, e.g. cash or bank
+	Reference    string              `json:"reference,omitempty" bson:"reference,omitempty"`
+	Amount       float64             `json:"amount" bson:"amount"`
+	Remaining    float64             `json:"remaining" bson:"remaining"`
+	Applications []CreditApplication `json:"applications,omitempty" bson:"applications,omitempty"`
+	Refunds      []CreditRefund      `json:"refunds,omitempty" bson:"refunds,omitempty"`
+	CreatedAt    time.Time           `json:"created_at" bson:"created_at"`
+}
+
+// CreditApplication is part of a credit used to pay an invoice.
+type CreditApplication struct {
+	InvoiceID string    `json:"invoice_id" bson:"invoice_id"`
+	Amount    float64   `json:"amount" bson:"amount"`
+	AppliedAt time.Time `json:"applied_at" bson:"applied_at"`
+}
+
+// CreditRefund is part of a credit paid back to the customer.
+type CreditRefund struct {
+	Amount     float64   `json:"amount" bson:"amount"`
+	Method     string    `json:"method" bson:"method"`
+	Reference  string    `json:"reference,omitempty" bson:"reference,omitempty"`
+	RefundedAt time.Time `json:"refunded_at" bson:"refunded_at"`
+}
+
+type CustomerCreditInput struct {
+	Amount    float64 `json:"amount" binding:"required,gt=0"`
+	Source    string  `json:"source" binding:"omitempty,oneof=deposit payment"` // defaults to deposit
+	Method    string  `json:"method"`
+	Reference string  `json:"reference"`
+}
+
+type ApplyCreditRequest struct {
+	CreditID string  `json:"credit_id"`                       // applies the customer's credits oldest first when empty
+	Amount   float64 `json:"amount" binding:"omitempty,gt=0"` // defaults to the invoice balance
+}
+
+type RefundCreditRequest struct {
+	Amount    float64 `json:"amount" binding:"required,gt=0"`
+	Method    string  `json:"method" binding:"required"`
+	Reference string  `json:"reference"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// EmailTemplate is a company's customised email for a kind of message. The
+// subject and bodies are Go templates, e.g. "Invoice {{.ReferenceNumber}}".
+type EmailTemplate struct {
+	ID        primitive.ObjectID `json:"id,omitempty" bson:"_id,omitempty"`
+	CompanyID string             `json:"company_id" bson:"company_id"`
+	Kind      string             `json:"kind" bson:"kind"` // invoice, receipt
+	Subject   string             `json:"subject" bson:"subject"`
+	HTMLBody  string             `json:"html_body" bson:"html_body"`
+	TextBody  string             `json:"text_body" bson:"text_body"`
+	IsDefault bool               `json:"is_default" bson:"-"`
+	CreatedAt time.Time          `json:"created_at,omitempty" bson:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at,omitempty" bson:"updated_at"`
+}
+
+type EmailTemplateInput struct {
+	Subject  string `json:"subject" binding:"required"`
+	HTMLBody string `json:"html_body" binding:"required"`
+	TextBody string `json:"text_body"` // generated from the default when empty
+}
+
+type EmailPreview struct {
+	Subject    string `json:"subject"`
+	HTMLBody   string `json:"html_body"`
+	TextBody   string `json:"text_body"`
+	Attachment string `json:"attachment,omitempty"`
+}
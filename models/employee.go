@@ -33,4 +33,11 @@ type Invitation struct {
 	Email	 string             `json:"email" bson:"email"`
 	CompanyID primitive.ObjectID `json:"company_id" bson:"company_id"`
 	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+	// Set once the invited user accepts; only then do they join the company
+	AcceptedAt *time.Time          `json:"accepted_at,omitempty" bson:"accepted_at,omitempty"`
+	UserID     primitive.ObjectID  `json:"user_id,omitempty" bson:"user_id,omitempty"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required"`
 }
\ No newline at end of file
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ImportRowError is a problem with one row of an imported file.
+type ImportRowError struct {
+	Row    int    `json:"row" bson:"row"` // line in the file, the header being line 1
+	Column string `json:"column,omitempty" bson:"column,omitempty"`
+	Value  string `json:"value,omitempty" bson:"value,omitempty"`
+	Error  string `json:"error" bson:"error"`
+}
+
+// ImportResult reports what an import did, or with dry_run would do.
+type ImportResult struct {
+	DryRun        bool             `json:"dry_run"`
+	Rows          int              `json:"rows"`
+	Created       int              `json:"created"`
+	Updated       int              `json:"updated"`
+	Failed        int              `json:"failed"` // rows left out because of errors
+	Errors        []ImportRowError `json:"errors,omitempty"`
+	ErrorReportID string           `json:"error_report_id,omitempty"` // download the errors as CSV with GET /import/errors/{id}
+}
+
+// ImportErrorReport keeps the row errors of an import for download.
+type ImportErrorReport struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CompanyID primitive.ObjectID `json:"company_id" bson:"company_id"`
+	Kind      string             `json:"kind" bson:"kind"` // items or customers
+	FileName  string             `json:"file_name" bson:"file_name"`
+	Errors    []ImportRowError   `json:"errors" bson:"errors"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
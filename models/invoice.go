@@ -7,31 +7,74 @@ import (
 )
 
 type Invoice struct {
-	ID              primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	CustomerID      string             `json:"customer_id" bson:"customer_id" binding:"required"`
-	CompanyID       string             `json:"company_id" bson:"company_id" binding:"required"`
-	ReferenceNumber string             `json:"reference_number" bson:"reference_number" binding:"required"`
-	Date            time.Time          `json:"date" bson:"date"`
-	Terms           string             `json:"terms" bson:"terms"`
-	Status          string             `json:"status" bson:"status"`
-	Amount          float64            `json:"amount" bson:"amount"`
-	PaymentType     string             `json:"payment_type" bson:"payment_type" binding:"required"`
-	DueDate         *time.Time         `json:"due_date,omitempty" bson:"due_date,omitempty"`
-	PaymentDate     time.Time          `json:"payment_date,omitempty" bson:"payment_date,omitempty"`
-	Items           []InvoiceItem      `json:"items" bson:"items"`
-	CreatedAt       time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt       time.Time          `json:"updated_at" bson:"updated_at"`
+	ID                primitive.ObjectID    `json:"id" bson:"_id,omitempty"`
+	CustomerID        string                `json:"customer_id" bson:"customer_id" binding:"required"`
+	CompanyID         string                `json:"company_id" bson:"company_id"` // defaults to the token's active company
+	ReferenceNumber   string                `json:"reference_number" bson:"reference_number" binding:"required"`
+	Date              time.Time             `json:"date" bson:"date"`
+	Terms             string                `json:"terms" bson:"terms"`
+	PaymentTermsID    string                `json:"payment_terms_id,omitempty" bson:"payment_terms_id,omitempty"`     // defaults to the customer's terms
+	CreditOverrideID  string                `json:"credit_override_id,omitempty" bson:"credit_override_id,omitempty"` // approved override for a customer on hold or over their limit
+	BillingContactID  string                `json:"billing_contact_id,omitempty" bson:"billing_contact_id,omitempty"` // defaults to the customer's primary billing contact
+	Status            string                `json:"status" bson:"status"`
+	Amount            float64               `json:"amount" bson:"amount"`
+	AmountPaid        float64               `json:"amount_paid" bson:"amount_paid"` // paid so far, including applied credits
+	Subtotal          float64               `json:"subtotal" bson:"subtotal"`       // total before tax
+	TaxAmount         float64               `json:"tax_amount" bson:"tax_amount"`   // total tax charged
+	WithholdingRate   float64               `json:"withholding_rate,omitempty" bson:"withholding_rate,omitempty"`
+	WithholdingAmount float64               `json:"withholding_amount,omitempty" bson:"withholding_amount,omitempty"`
+	DocumentType      string                `json:"document_type,omitempty" bson:"document_type,omitempty"`             // invoice (default) or credit_note
+	OriginalInvoiceID string                `json:"original_invoice_id,omitempty" bson:"original_invoice_id,omitempty"` // invoice a credit note corrects
+	OriginalReference string                `json:"original_reference,omitempty" bson:"original_reference,omitempty"`
+	Reason            string                `json:"reason,omitempty" bson:"reason,omitempty"`                           // why a credit note was issued
+	CreditNotesIssued int                   `json:"credit_notes_issued,omitempty" bson:"credit_notes_issued,omitempty"` // numbers the credit notes of an invoice
+	PaymentType       string                `json:"payment_type" bson:"payment_type" binding:"required"`
+	DueDate           *time.Time            `json:"due_date,omitempty" bson:"due_date,omitempty"`
+	Installments      []InvoiceInstallment  `json:"installments,omitempty" bson:"installments,omitempty"`
+	EarlyPayment      *EarlyPaymentDiscount `json:"early_payment_discount,omitempty" bson:"early_payment_discount,omitempty"`
+	PaymentDate       time.Time             `json:"payment_date,omitempty" bson:"payment_date,omitempty"`
+	PaymentMethod     string                `json:"payment_method,omitempty" bson:"payment_method,omitempty"` // how it was paid, e.g. cash or bank
+	WriteOff          *InvoiceWriteOff      `json:"write_off,omitempty" bson:"write_off,omitempty"`           // set once the balance is written off as bad debt
+	Items             []InvoiceItem         `json:"items" bson:"items"`
+	CreatedAt         time.Time             `json:"created_at" bson:"created_at"`
+	UpdatedAt         time.Time             `json:"updated_at" bson:"updated_at"`
 }
 
 type InvoiceItem struct {
-	ItemID    string  `json:"item_id" bson:"item_id"`
-	ItemName  string  `json:"item_name" bson:"item_name"`
-	Quantity  int     `json:"quantity" bson:"quantity"`
-	UnitPrice float64 `json:"unit_price" bson:"unit_price"`
-	Discount  float64 `json:"discount" bson:"discount"`
-	Subtotal  float64 `json:"subtotal" bson:"subtotal"`
+	ItemID           string  `json:"item_id" bson:"item_id"`
+	ItemName         string  `json:"item_name" bson:"item_name"`
+	Quantity         int     `json:"quantity" bson:"quantity"`
+	UnitPrice        float64 `json:"unit_price" bson:"unit_price"`
+	Discount         float64 `json:"discount" bson:"discount"`
+	Subtotal         float64 `json:"subtotal" bson:"subtotal"`                             // line total before tax
+	TaxRate          float64 `json:"tax_rate" bson:"tax_rate"`                             // percent
+	TaxCategory      string  `json:"tax_category,omitempty" bson:"tax_category,omitempty"` // standard, zero_rated or exempt
+	TaxAmount        float64 `json:"tax_amount" bson:"tax_amount"`
+	ReturnedQuantity int     `json:"returned_quantity,omitempty" bson:"returned_quantity,omitempty"` // taken back with credit notes
+}
+
+// InvoiceWriteOff records the balance of an invoice given up as bad debt.
+type InvoiceWriteOff struct {
+	Amount       float64   `json:"amount" bson:"amount"`
+	Reason       string    `json:"reason" bson:"reason"`
+	ApprovedBy   string    `json:"approved_by" bson:"approved_by"` // user ID of the owner who approved it
+	WrittenOffAt time.Time `json:"written_off_at" bson:"written_off_at"`
+}
+
+type WriteOffRequest struct {
+	Reason     string `json:"reason" binding:"required"`
+	CreditHold bool   `json:"credit_hold"` // also put the customer on credit hold
+}
+
+type BackfillInvoiceTaxRequest struct {
+	CompanyID   string  `json:"company_id"`
+	TaxRate     float64 `json:"tax_rate"`
+	TaxCategory string  `json:"tax_category"`
 }
 
 type UpdatePaymentStatusRequest struct {
 	PaymentDate time.Time `json:"payment_date"`
+	Amount      float64   `json:"amount" binding:"omitempty,gt=0"` // defaults to the balance; any excess becomes customer credit
+	Method      string    `json:"method"`
+	Reference   string    `json:"reference"`
 }
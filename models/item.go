@@ -14,6 +14,7 @@ type Item struct {
 	Category     string             `json:"category" bson:"category"`
 	SellingPrice float64            `json:"selling_price" bson:"selling_price"`
 	Unit         string             `json:"unit" bson:"unit"`
+	Stock        *int               `json:"stock,omitempty" bson:"stock,omitempty"` // units on hand, absent when stock is not tracked
 	CompanyID    primitive.ObjectID `json:"company_id" bson:"company_id"`
 	CreatedAt    time.Time          `json:"created_at" bson:"created_at"`
 	UpdatedAt    time.Time          `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MailAttachment struct {
+	Filename    string `json:"filename" bson:"filename"`
+	ContentType string `json:"content_type,omitempty" bson:"content_type,omitempty"`
+	Content     []byte `json:"-" bson:"content"`
+	Size        int    `json:"size" bson:"size"`
+}
+
+// OutboxMessage is an email waiting for, or done with, delivery by the mail
+// worker.
+type OutboxMessage struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CompanyID     string             `json:"company_id,omitempty" bson:"company_id,omitempty"`
+	Category      string             `json:"category" bson:"category"` // invoice, invitation, report, notification
+	ReferenceID   string             `json:"reference_id,omitempty" bson:"reference_id,omitempty"`
+	FromName      string             `json:"from_name,omitempty" bson:"from_name,omitempty"`
+	ReplyTo       string             `json:"reply_to,omitempty" bson:"reply_to,omitempty"`
+	To            []string           `json:"to" bson:"to"`
+	Cc            []string           `json:"cc,omitempty" bson:"cc,omitempty"`
+	Subject       string             `json:"subject" bson:"subject"`
+	TextBody      string             `json:"text_body,omitempty" bson:"text_body,omitempty"`
+	HTMLBody      string             `json:"html_body,omitempty" bson:"html_body,omitempty"`
+	Attachments   []MailAttachment   `json:"attachments,omitempty" bson:"attachments,omitempty"`
+	Status        string             `json:"status" bson:"status"` // Pending, Sending, Sent, Dead
+	Attempts      int                `json:"attempts" bson:"attempts"`
+	MaxAttempts   int                `json:"max_attempts" bson:"max_attempts"`
+	LastError     string             `json:"last_error,omitempty" bson:"last_error,omitempty"`
+	NextAttemptAt time.Time          `json:"next_attempt_at" bson:"next_attempt_at"`
+	SentAt        *time.Time         `json:"sent_at,omitempty" bson:"sent_at,omitempty"`
+	CreatedAt     time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at" bson:"updated_at"`
+}
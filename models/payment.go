@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Payment is one receipt towards an invoice: money paid by the customer, their
+// credit applied, or a credit note set against the balance.
+type Payment struct {
+	ID              primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CompanyID       string             `json:"company_id" bson:"company_id"`
+	CustomerID      string             `json:"customer_id" bson:"customer_id"`
+	InvoiceID       string             `json:"invoice_id" bson:"invoice_id"`
+	ReferenceNumber string             `json:"reference_number" bson:"reference_number"`       // of the invoice
+	Amount          float64            `json:"amount" bson:"amount"`                           // taken off the invoice balance
+	Discount        float64            `json:"discount,omitempty" bson:"discount,omitempty"`   // early-payment discount earned with it
+	Excess          float64            `json:"excess,omitempty" bson:"excess,omitempty"`       // paid beyond the balance, kept as customer credit
+	Method          string             `json:"method" bson:"method"`                           // how it was paid, e.g. cash or bank
+	Source          string             `json:"source" bson:"source"`                           // payment, customer_credit or credit_note
+	Reference       string             `json:"reference,omitempty" bson:"reference,omitempty"` // bank reference or credit note number
+	PaidAt          time.Time          `json:"paid_at" bson:"paid_at"`
+	CreatedAt       time.Time          `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PaymentTerms is a company's named rule for when credit invoices fall due,
+// e.g. "Net 30", "EOM + 15" or "2/10 Net 30".
+type PaymentTerms struct {
+	ID              primitive.ObjectID        `json:"id" bson:"_id,omitempty"`
+	CompanyID       primitive.ObjectID        `json:"company_id" bson:"company_id"`
+	Name            string                    `json:"name" bson:"name" binding:"required"`
+	Type            string                    `json:"type" bson:"type" binding:"required"`                                                          // net, end_of_month, due_on_receipt or installments
+	Days            int                       `json:"days" bson:"days" binding:"min=0,max=365"`                                                     // after the invoice date, or after the month end for end_of_month
+	DiscountPercent float64                   `json:"discount_percent,omitempty" bson:"discount_percent,omitempty" binding:"omitempty,gt=0,lt=100"` // early-payment discount
+	DiscountDays    int                       `json:"discount_days,omitempty" bson:"discount_days,omitempty" binding:"min=0,max=365"`               // days the discount is available for
+	Installments    []PaymentTermsInstallment `json:"installments,omitempty" bson:"installments,omitempty" binding:"dive"`
+	CreatedAt       time.Time                 `json:"created_at" bson:"created_at"`
+	UpdatedAt       time.Time                 `json:"updated_at,omitempty" bson:"updated_at,omitempty"`
+}
+
+// PaymentTermsInstallment is one part of an installments schedule.
+type PaymentTermsInstallment struct {
+	Percent float64 `json:"percent" bson:"percent" binding:"gt=0,max=100"` // share of the invoice total
+	Days    int     `json:"days" bson:"days" binding:"min=0,max=730"`      // after the invoice date
+}
+
+// InvoiceInstallment is an amount of an invoice due on a date.
+type InvoiceInstallment struct {
+	Number  int       `json:"number" bson:"number"`
+	DueDate time.Time `json:"due_date" bson:"due_date"`
+	Amount  float64   `json:"amount" bson:"amount"`
+}
+
+// EarlyPaymentDiscount is the discount an invoice earns when it is paid by
+// the deadline. Applied is set when the payment arrived in time.
+type EarlyPaymentDiscount struct {
+	Percent  float64   `json:"percent" bson:"percent"`
+	Amount   float64   `json:"amount" bson:"amount"`
+	Deadline time.Time `json:"deadline" bson:"deadline"`
+	Applied  bool      `json:"applied" bson:"applied"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Refund is money paid back to a customer for goods returned against an
+// invoice. The credit note records what was returned.
+type Refund struct {
+	ID               primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	CompanyID        string             `json:"company_id" bson:"company_id"`
+	CustomerID       string             `json:"customer_id" bson:"customer_id"`
+	InvoiceID        string             `json:"invoice_id" bson:"invoice_id"`
+	CreditNoteID     string             `json:"credit_note_id" bson:"credit_note_id"`
+	Amount           float64            `json:"amount" bson:"amount"`                         // paid back to the customer
+	AppliedToBalance float64            `json:"applied_to_balance" bson:"applied_to_balance"` // taken off what was still owed on the invoice
+	Method           string             `json:"method" bson:"method"`                         // cash, bank, credit or the invoice's payment method
+	Reference        string             `json:"reference,omitempty" bson:"reference,omitempty"`
+	Reason           string             `json:"reason,omitempty" bson:"reason,omitempty"`
+	CreatedAt        time.Time          `json:"created_at" bson:"created_at"`
+}
+
+type InvoiceReturnRequest struct {
+	Lines        []InvoiceReturnLine `json:"lines" binding:"required,min=1,dive"`
+	Reason       string              `json:"reason"`
+	Restock      bool                `json:"restock"`                                                           // put the returned quantities back in stock
+	RefundMethod string              `json:"refund_method" binding:"omitempty,oneof=cash bank original credit"` // defaults to original; credit keeps the refund as customer credit
+	Reference    string              `json:"reference"`
+}
+
+type InvoiceReturnLine struct {
+	Line     int `json:"line" binding:"min=0"`             // position in the invoice's items, from 0
+	Quantity int `json:"quantity" binding:"required,gt=0"` // returned
+}
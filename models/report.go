@@ -7,22 +7,64 @@ import (
 )
 
 type Report struct {
-	ID               primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	CompanyID        string             `json:"company_id" bson:"company_id"`
-	Title            string             `json:"title"`
-	Description      string             `json:"description"`
-	CreatedBy        string             `json:"created_by" bson:"created_by"`
-	CreatedDate      time.Time          `json:"created_date"`
-	LastModifiedDate time.Time          `json:"last_modified_date"`
-	Type             string             `json:"type"`
-	Status           string             `json:"status"`
-	Content          string             `json:"content"`
+	ID               primitive.ObjectID     `json:"id" bson:"_id,omitempty"`
+	CompanyID        string                 `json:"company_id" bson:"company_id"`
+	Title            string                 `json:"title" bson:"title"`
+	Description      string                 `json:"description" bson:"description"`
+	CreatedBy        string                 `json:"created_by" bson:"created_by"`
+	CreatedDate      time.Time              `json:"created_date" bson:"created_date"`
+	LastModifiedDate time.Time              `json:"last_modified_date" bson:"last_modified_date"`
+	Type             string                 `json:"type" bson:"type"`
+	Status           string                 `json:"status" bson:"status"`
+	Version          int                    `json:"version" bson:"version"`
+	Parameters       map[string]interface{} `json:"parameters,omitempty" bson:"parameters,omitempty"`
+	Content          string                 `json:"content" bson:"content"`
 }
 
 type GenerateReportRequest struct {
-	CompanyID   string `json:"company_id" binding:"required"`
-	Title       string `json:"title" binding:"required"`
-	Description string `json:"description" binding:"required"`
-	Type        string `json:"type" binding:"required"`
-	CreatedBy   string `json:"created_by"` 
-}
\ No newline at end of file
+	CompanyID   string                 `json:"company_id"` // defaults to the token's active company
+	Title       string                 `json:"title" binding:"required"`
+	Description string                 `json:"description" binding:"required"`
+	Type        string                 `json:"type" binding:"required"`
+	CreatedBy   string                 `json:"created_by"` // ignored, the requesting user is taken from the token
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+type ReportSchedule struct {
+	ID         primitive.ObjectID     `json:"id" bson:"_id,omitempty"`
+	CompanyID  string                 `json:"company_id" bson:"company_id"` // defaults to the token's active company
+	Title      string                 `json:"title" bson:"title" binding:"required"`
+	ReportType string                 `json:"report_type" bson:"report_type" binding:"required"`
+	Parameters map[string]interface{} `json:"parameters,omitempty" bson:"parameters,omitempty"`
+	Cadence    string                 `json:"cadence" bson:"cadence" binding:"required"` // cron expression, e.g. "0 8 * * 1" or "@weekly"
+	Recipients []string               `json:"recipients" bson:"recipients" binding:"required"`
+	Format     string                 `json:"format" bson:"format"` // json, csv, flat_csv, xlsx, pdf
+	Active     bool                   `json:"active" bson:"active"`
+	CreatedBy  string                 `json:"created_by" bson:"created_by"`
+	NextRunAt  time.Time              `json:"next_run_at" bson:"next_run_at"`
+	LastRunAt  *time.Time             `json:"last_run_at,omitempty" bson:"last_run_at,omitempty"`
+	LastStatus string                 `json:"last_status,omitempty" bson:"last_status,omitempty"`
+	CreatedAt  time.Time              `json:"created_at" bson:"created_at"`
+	UpdatedAt  time.Time              `json:"updated_at" bson:"updated_at"`
+}
+
+type UpdateReportScheduleInput struct {
+	Title      *string                `json:"title,omitempty"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	Cadence    *string                `json:"cadence,omitempty"`
+	Recipients []string               `json:"recipients,omitempty"`
+	Format     *string                `json:"format,omitempty"`
+	Active     *bool                  `json:"active,omitempty"`
+}
+
+type ReportScheduleRun struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ScheduleID primitive.ObjectID `json:"schedule_id" bson:"schedule_id"`
+	ReportID   primitive.ObjectID `json:"report_id,omitempty" bson:"report_id,omitempty"`
+	MessageID  primitive.ObjectID `json:"message_id,omitempty" bson:"message_id,omitempty"` // outbox message carrying the report
+	StartedAt  time.Time          `json:"started_at" bson:"started_at"`
+	FinishedAt time.Time          `json:"finished_at" bson:"finished_at"`
+	Status     string             `json:"status" bson:"status"` // Succeeded, Failed
+	Error      string             `json:"error,omitempty" bson:"error,omitempty"`
+	Recipients []string           `json:"recipients" bson:"recipients"`
+}
@@ -10,7 +10,13 @@ type LoginCredentials struct {
 }
 
 type TokenResponse struct {
-	Token string `json:"token"`
+	Token     string `json:"token"`
+	CompanyID string `json:"company_id,omitempty"` // active company the token is scoped to
+	Role      string `json:"role,omitempty"`       // owner or employee
+}
+
+type SwitchCompanyRequest struct {
+	CompanyID string `json:"company_id" binding:"required"`
 }
 
 type GenericResponse struct {
@@ -12,9 +12,14 @@ func SetupAllRoutes(router *gin.RouterGroup) {
 	_SetupUserRoutes(router)
 	_SetupEmployeeRoutes(router)
 	_SetupCustomerRoutes(router)
+	_SetupPaymentTermsRoutes(router)
 	_SetupItemRoutes(router)
 	_SetupInvoiceRoutes(router)
 	_SetupReportRoutes(router)
+	_SetupDashboardRoutes(router)
+	_SetupMailRoutes(router)
+	_SetupEmailTemplateRoutes(router)
+	_SetupImportRoutes(router)
 }
 
 func _SetupAuthRoutes(router *gin.RouterGroup) {
@@ -22,6 +27,7 @@ func _SetupAuthRoutes(router *gin.RouterGroup) {
 	{
 		auth.POST("/register/user", controllers.RegisterUser)
 		auth.POST("/login", controllers.Login)
+		auth.POST("/switch-company", middleware.AuthMiddleware(), controllers.SwitchCompany)
 	}
 }
 
@@ -30,8 +36,20 @@ func _SetupCompanyRoutes(router *gin.RouterGroup) {
 	company.Use(middleware.AuthMiddleware())
 	{
 		company.POST("/create", controllers.CreateCompany)
+		company.GET("", controllers.ListCompanies)
 		company.GET("/:id", controllers.GetCompany)
+		company.PUT("/:id", controllers.UpdateCompany)
+		company.DELETE("/:id", controllers.DeleteCompany)
+		company.PUT("/:id/settings", controllers.UpdateCompanySettings)
 		company.GET("user/:user_id", controllers.CheckCompanyForUser)
+		company.PUT("/:id/branding", controllers.UpdateCompanyBranding)
+		company.POST("/:id/logo", controllers.UploadCompanyLogo)
+		company.GET("/:id/logo", controllers.GetCompanyLogo)
+		company.DELETE("/:id/logo", controllers.DeleteCompanyLogo)
+		company.POST("/:id/signing-certificate", controllers.UploadSigningCertificate)
+		company.GET("/:id/signing-certificate", controllers.GetSigningCertificate)
+		company.PUT("/:id/signing-certificate", controllers.UpdateSigningCertificate)
+		company.DELETE("/:id/signing-certificate", controllers.DeleteSigningCertificate)
 	}
 }
 
@@ -46,8 +64,10 @@ func _SetupUserRoutes(router *gin.RouterGroup) {
 
 func _SetupEmployeeRoutes(router *gin.RouterGroup) {
 	employee := router.Group("/employee")
+	employee.Use(middleware.AuthMiddleware())
 	{
 		employee.POST("/add", controllers.AddEmployee)
+		employee.POST("/invitation/accept", controllers.AcceptInvitation)
 		employee.DELETE("/delete/:id", controllers.DeleteEmployee)
 		employee.GET("/all", controllers.GetAllEmployees)
 		employee.GET("/:id", controllers.GetEmployee)
@@ -57,17 +77,45 @@ func _SetupEmployeeRoutes(router *gin.RouterGroup) {
 
 func _SetupCustomerRoutes(router *gin.RouterGroup) {
 	customer := router.Group("/customer")
+	customer.Use(middleware.AuthMiddleware())
 	{
 		customer.POST("/register", controllers.RegisterCustomer)
 		customer.PUT("/update/:id", controllers.UpdateCustomer)
 		customer.DELETE("/delete/:id", controllers.DeleteCustomer)
 		customer.GET("/all", controllers.ListCustomers)
+		customer.POST("/import", controllers.ImportCustomers)
+		customer.GET("/export", controllers.ExportCustomers)
 		customer.GET("/:id", controllers.GetCustomer)
+		customer.GET("/:id/credits", controllers.ListCustomerCredits)
+		customer.POST("/:id/credits", controllers.AddCustomerCredit)
+		customer.POST("/:id/credits/:credit_id/refund", controllers.RefundCustomerCredit)
+		customer.POST("/:id/credit-hold", controllers.PlaceCustomerCreditHold)
+		customer.DELETE("/:id/credit-hold", controllers.ReleaseCustomerCreditHold)
+		customer.POST("/:id/credit-requests", controllers.RequestCreditApproval)
+		customer.GET("/:id/credit-history", controllers.GetCustomerCreditHistory)
+		customer.POST("/:id/notes", controllers.AddCustomerNote)
+		customer.DELETE("/:id/notes/:note_id", controllers.DeleteCustomerNote)
+		customer.GET("/credit-requests", controllers.ListCreditRequests)
+		customer.POST("/credit-requests/:request_id/approve", controllers.ApproveCreditRequest)
+		customer.POST("/credit-requests/:request_id/reject", controllers.RejectCreditRequest)
+	}
+}
+
+func _SetupPaymentTermsRoutes(router *gin.RouterGroup) {
+	paymentTerms := router.Group("/payment-terms")
+	paymentTerms.Use(middleware.AuthMiddleware())
+	{
+		paymentTerms.POST("", controllers.CreatePaymentTerms)
+		paymentTerms.GET("", controllers.ListPaymentTerms)
+		paymentTerms.GET("/:id", controllers.GetPaymentTerms)
+		paymentTerms.PUT("/:id", controllers.UpdatePaymentTerms)
+		paymentTerms.DELETE("/:id", controllers.DeletePaymentTerms)
 	}
 }
 
 func _SetupItemRoutes(router *gin.RouterGroup) {
 	item := router.Group("/item")
+	item.Use(middleware.AuthMiddleware())
 	{
 		item.POST("/add", controllers.AddItem)
 		item.PUT("/update/:id", controllers.UpdateItem)
@@ -80,24 +128,88 @@ func _SetupItemRoutes(router *gin.RouterGroup) {
 }
 
 func _SetupInvoiceRoutes(router *gin.RouterGroup) {
+	// Verification links are opened by anyone holding the printed document
+	public := router.Group("/invoice")
+	{
+		public.GET("/layouts", controllers.ListInvoiceLayouts)
+		public.GET("/fonts", controllers.ListPDFFonts)
+		public.GET("/verify", controllers.VerifyInvoice)
+	}
+
 	invoice := router.Group("/invoice")
+	invoice.Use(middleware.AuthMiddleware())
 	{
 		invoice.POST("/generate", controllers.GenerateInvoice)
 		invoice.GET("/:id", controllers.GetInvoice)
 		invoice.GET("/companies/:company_id", controllers.GetInvoicesByCompanyID)
+		invoice.GET("/companies/:company_id/download", controllers.DownloadInvoicesZip)
 		invoice.POST("/send/:id", controllers.SendInvoice)
 		invoice.GET("/download/:id", controllers.DownloadInvoice)
+		invoice.GET("/download/:id/ubl", controllers.DownloadInvoiceUBL)
 		invoice.PUT("/mark-as-paid/:id", controllers.MarkInvoiceAsPaid)
+		invoice.POST("/apply-credit/:id", controllers.ApplyCreditToInvoice)
+		invoice.POST("/return/:id", controllers.ReturnInvoiceItems)
+		invoice.POST("/write-off/:id", controllers.WriteOffInvoice)
+		invoice.GET("/refund/download/:id", controllers.DownloadRefundReceipt)
+		invoice.POST("/tax/backfill", controllers.BackfillInvoiceTax)
+		invoice.GET("/email-preview/:id", controllers.PreviewInvoiceEmail)
 	}
 }
 
 func _SetupReportRoutes(router *gin.RouterGroup) {
 	report := router.Group("/report")
+	report.Use(middleware.AuthMiddleware())
 	{
 		report.POST("/sales", controllers.GetSalesReport)
+		report.GET("/types", controllers.ListReportTypes)
+		report.POST("/generate", controllers.GenerateReport)
 		report.GET("/all", controllers.ListReports)
 		report.GET("/:id", controllers.GetReportDetails)
+		report.GET("/:id/versions", controllers.ListReportVersions)
 		report.DELETE("/:id", controllers.DeleteReport)
-		report.GET("/download/:id", controllers.DownloadReportCSV)
+		report.GET("/download/:id", controllers.DownloadReport)
+		report.POST("/schedule", controllers.CreateReportSchedule)
+		report.GET("/schedules", controllers.ListReportSchedules)
+		report.PUT("/schedule/:id", controllers.UpdateReportSchedule)
+		report.DELETE("/schedule/:id", controllers.DeleteReportSchedule)
+		report.GET("/schedule/:id/runs", controllers.ListReportScheduleRuns)
+		report.POST("/schedule/:id/run", controllers.RunReportScheduleNow)
+	}
+}
+
+func _SetupDashboardRoutes(router *gin.RouterGroup) {
+	dashboard := router.Group("/dashboard")
+	dashboard.Use(middleware.AuthMiddleware())
+	{
+		dashboard.GET("/:company_id", controllers.GetDashboard)
+	}
+}
+
+func _SetupMailRoutes(router *gin.RouterGroup) {
+	mail := router.Group("/mail")
+	mail.Use(middleware.AuthMiddleware())
+	{
+		mail.GET("/outbox", controllers.ListOutboxMessages)
+		mail.GET("/outbox/:id", controllers.GetOutboxMessage)
+		mail.POST("/outbox/:id/retry", controllers.RetryOutboxMessage)
+	}
+}
+
+func _SetupEmailTemplateRoutes(router *gin.RouterGroup) {
+	emailTemplate := router.Group("/email-template")
+	emailTemplate.Use(middleware.AuthMiddleware())
+	{
+		emailTemplate.GET("/:company_id/:kind", controllers.GetEmailTemplate)
+		emailTemplate.PUT("/:company_id/:kind", controllers.UpdateEmailTemplate)
+		emailTemplate.DELETE("/:company_id/:kind", controllers.ResetEmailTemplate)
+		emailTemplate.POST("/:company_id/:kind/preview", controllers.PreviewEmailTemplate)
+	}
+}
+
+func _SetupImportRoutes(router *gin.RouterGroup) {
+	imports := router.Group("/import")
+	imports.Use(middleware.AuthMiddleware())
+	{
+		imports.GET("/errors/:id", controllers.DownloadImportErrorReport)
 	}
 }
@@ -0,0 +1,251 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func testLogoPNG() []byte {
+	var buf bytes.Buffer
+	png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4)))
+	return buf.Bytes()
+}
+
+func TestUpdateCompanyBranding(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	ownerID := primitive.NewObjectID().Hex()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", ownerID)
+		c.Next()
+	})
+	router.PUT("/company/:id/branding", controllers.UpdateCompanyBranding)
+
+	companyID := primitive.NewObjectID()
+	companyDoc := func(owner string) bson.D {
+		return bson.D{
+			{Key: "_id", Value: companyID},
+			{Key: "name", Value: "Test Tech Solutions"},
+			{Key: "owner", Value: owner},
+		}
+	}
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		requestBody    map[string]interface{}
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name: "Valid Branding",
+			requestBody: map[string]interface{}{
+				"accent_color":         "#1A73E8",
+				"footer_text":          "Registered in Addis Ababa",
+				"payment_instructions": "Pay within 7 days quoting the invoice reference",
+				"bank_accounts": []map[string]interface{}{
+					{"bank_name": "Commercial Bank", "account_name": "Test Tech", "account_number": "1000123456"},
+				},
+			},
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc(ownerID)),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Invalid Accent Color",
+			requestBody:    map[string]interface{}{"accent_color": "blue"},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Unknown Font",
+			requestBody:    map[string]interface{}{"font": "comic_sans"},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Bank Account Missing Number",
+			requestBody:    map[string]interface{}{"bank_accounts": []map[string]interface{}{{"bank_name": "Commercial Bank", "account_name": "Test Tech"}}},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Not The Owner",
+			requestBody:    map[string]interface{}{"accent_color": "#000000"},
+			expectedStatus: http.StatusForbidden,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc(primitive.NewObjectID().Hex())))
+			},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("UpdateCompanyBrandingTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				jsonData, _ := json.Marshal(tc.requestBody)
+				req, _ := http.NewRequest("PUT", "/company/"+companyID.Hex()+"/branding", bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+			})
+		}
+	})
+}
+
+func TestUploadCompanyLogo(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	ownerID := primitive.NewObjectID().Hex()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", ownerID)
+		c.Next()
+	})
+	router.POST("/company/:id/logo", controllers.UploadCompanyLogo)
+
+	companyID := primitive.NewObjectID()
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		content        []byte
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "PNG Logo",
+			content:        testLogoPNG(),
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{
+						{Key: "_id", Value: companyID},
+						{Key: "owner", Value: ownerID},
+					}),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Not An Image",
+			content:        []byte("%PDF-1.4 not a logo"),
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("UploadCompanyLogoTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				var body bytes.Buffer
+				writer := multipart.NewWriter(&body)
+				part, _ := writer.CreateFormFile("logo", "logo.png")
+				part.Write(tc.content)
+				writer.Close()
+
+				req, _ := http.NewRequest("POST", "/company/"+companyID.Hex()+"/logo", &body)
+				req.Header.Set("Content-Type", writer.FormDataContentType())
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+			})
+		}
+	})
+}
+
+func TestDownloadBrandedInvoice(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.GET("/invoice/download/:id", controllers.DownloadInvoice)
+
+	invoiceID := primitive.NewObjectID()
+	customerID := primitive.NewObjectID()
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("DownloadBrandedInvoiceTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: invoiceID},
+				{Key: "company_id", Value: companyID.Hex()},
+				{Key: "customer_id", Value: customerID.Hex()},
+				{Key: "reference_number", Value: "INV-001"},
+				{Key: "status", Value: "Unpaid"},
+				{Key: "amount", Value: 100.0},
+			}),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: companyID},
+				{Key: "name", Value: "Test Tech Solutions"},
+				{Key: "tin", Value: "0000111122"},
+				{Key: "logo", Value: testLogoPNG()},
+				{Key: "logo_content_type", Value: "image/png"},
+				{Key: "branding", Value: bson.D{
+					{Key: "accent_color", Value: "#1A73E8"},
+					{Key: "footer_text", Value: "Thank you"},
+					{Key: "bank_accounts", Value: bson.A{bson.D{
+						{Key: "bank_name", Value: "Commercial Bank"},
+						{Key: "account_name", Value: "Test Tech"},
+						{Key: "account_number", Value: "1000123456"},
+					}}},
+				}},
+			}),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: customerID},
+				{Key: "name", Value: "Test Customer"},
+				{Key: "tin", Value: "0012345678"},
+			}),
+		)
+
+		req, _ := http.NewRequest("GET", "/invoice/download/"+invoiceID.Hex(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+		assert.Equal(t, "attachment; filename=invoice_INV-001.pdf", w.Header().Get("Content-Disposition"))
+		assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF")))
+		// The logo is embedded as an image XObject
+		assert.Contains(t, w.Body.String(), "/Subtype /Image")
+	})
+}
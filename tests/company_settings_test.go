@@ -0,0 +1,376 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestUpdateCompanySettings(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	ownerID := primitive.NewObjectID().Hex()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", ownerID)
+		c.Next()
+	})
+	router.PUT("/company/:id/settings", controllers.UpdateCompanySettings)
+
+	companyID := primitive.NewObjectID()
+	companyDoc := func(owner string) bson.D {
+		return bson.D{
+			{Key: "_id", Value: companyID},
+			{Key: "name", Value: "Test Tech Solutions"},
+			{Key: "owner", Value: owner},
+			{Key: "settings", Value: bson.D{{Key: "credit_limit_approval_threshold", Value: 5000.0}}},
+		}
+	}
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		requestBody    map[string]interface{}
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name: "Valid Settings",
+			requestBody: map[string]interface{}{
+				"base_currency":              "ETB",
+				"timezone":                   "Africa/Addis_Ababa",
+				"fiscal_year_start":          7,
+				"default_payment_terms_days": 30,
+				"invoice_prefix":             "TT-",
+			},
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc(ownerID)),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Lowercase Currency",
+			requestBody:    map[string]interface{}{"base_currency": "etb"},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Unknown Timezone",
+			requestBody:    map[string]interface{}{"timezone": "Mars/Olympus_Mons"},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Fiscal Year Start Out Of Range",
+			requestBody:    map[string]interface{}{"fiscal_year_start": 13},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Prefix With Spaces",
+			requestBody:    map[string]interface{}{"invoice_prefix": "TT "},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Not The Owner",
+			requestBody:    map[string]interface{}{"base_currency": "ETB"},
+			expectedStatus: http.StatusForbidden,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc(primitive.NewObjectID().Hex())))
+			},
+		},
+		{
+			name:           "Deleted Company",
+			requestBody:    map[string]interface{}{"base_currency": "ETB"},
+			expectedStatus: http.StatusNotFound,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch))
+			},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("UpdateCompanySettingsTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				mt.ClearEvents()
+				tc.setupMock(mt)
+
+				jsonData, _ := json.Marshal(tc.requestBody)
+				req, _ := http.NewRequest("PUT", "/company/"+companyID.Hex()+"/settings", bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedStatus == http.StatusOK {
+					var settings models.CompanySettings
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &settings))
+					assert.Equal(t, "ETB", settings.BaseCurrency)
+					assert.Equal(t, 7, settings.FiscalYearStart)
+
+					// Deleted companies are not looked up
+					find := mt.GetStartedEvent()
+					if assert.NotNil(t, find) {
+						assert.Equal(t, "find", find.CommandName)
+						_, err := find.Command.LookupErr("filter", "deleted_at", "$exists")
+						assert.NoError(t, err)
+					}
+
+					// Settings that were not sent are kept
+					assert.Equal(t, 5000.0, settings.CreditLimitApprovalThreshold)
+					update := mt.GetStartedEvent()
+					if assert.NotNil(t, update) {
+						set := update.Command.Lookup("updates").Array().Index(0).Value().Document().Lookup("u", "$set").Document()
+						assert.Equal(t, "ETB", set.Lookup("settings.base_currency").StringValue())
+						_, err := set.LookupErr("settings")
+						assert.Error(t, err)
+						_, err = set.LookupErr("settings.credit_limit_approval_threshold")
+						assert.Error(t, err)
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestGenerateInvoiceWithCompanySettings(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.POST("/invoice/generate", controllers.GenerateInvoice)
+
+	customerID := primitive.NewObjectID()
+	settings := bson.D{
+		{Key: "invoice_prefix", Value: "TT-"},
+		{Key: "default_payment_terms_days", Value: 30},
+	}
+
+	// Test cases
+	testCases := []struct {
+		name              string
+		reference         string
+		expectedStatus    int
+		expectedReference string
+		setupMock         func(mt *mtest.T)
+	}{
+		{
+			name:              "Prefix And Payment Terms Applied",
+			reference:         "2025-001",
+			expectedStatus:    http.StatusOK,
+			expectedReference: "TT-2025-001",
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{
+						{Key: "_id", Value: companyID},
+						{Key: "settings", Value: settings},
+					}),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, bson.D{
+						{Key: "_id", Value: customerID},
+						{Key: "current_credit_available", Value: 1000.0},
+					}),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:              "Reference Already Prefixed",
+			reference:         "TT-2025-002",
+			expectedStatus:    http.StatusOK,
+			expectedReference: "TT-2025-002",
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{
+						{Key: "_id", Value: companyID},
+						{Key: "settings", Value: settings},
+					}),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, bson.D{
+						{Key: "_id", Value: customerID},
+						{Key: "current_credit_available", Value: 1000.0},
+					}),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Deleted Company",
+			reference:      "2025-003",
+			expectedStatus: http.StatusNotFound,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch))
+			},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GenerateInvoiceWithCompanySettingsTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				jsonData, _ := json.Marshal(models.Invoice{
+					CustomerID:      customerID.Hex(),
+					CompanyID:       companyID.Hex(),
+					ReferenceNumber: tc.reference,
+					PaymentType:     "credit",
+					Items:           []models.InvoiceItem{{ItemName: "Consulting", Quantity: 1, UnitPrice: 100}},
+				})
+				req, _ := http.NewRequest("POST", "/invoice/generate", bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedStatus == http.StatusOK {
+					var response struct {
+						Invoice models.Invoice `json:"invoice"`
+					}
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					assert.Equal(t, tc.expectedReference, response.Invoice.ReferenceNumber)
+					if assert.NotNil(t, response.Invoice.DueDate) {
+						assert.Equal(t, response.Invoice.Date.AddDate(0, 0, 30).Truncate(time.Second), response.Invoice.DueDate.Truncate(time.Second))
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestUpdateCompanyMailSettings(t *testing.T) {
+	// Setup
+	t.Setenv("CERTIFICATE_KEY_SECRET", "test-key-secret")
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	ownerID := primitive.NewObjectID().Hex()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", ownerID)
+		c.Next()
+	})
+	router.PUT("/company/:id/settings", controllers.UpdateCompanySettings)
+
+	companyID := primitive.NewObjectID()
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		mail           map[string]interface{}
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name: "Own SMTP Server",
+			mail: map[string]interface{}{
+				"transport": "smtp",
+				"host":      "smtp.acme.test",
+				"port":      587,
+				"username":  "billing@acme.test",
+				"password":  "s3cret",
+				"from":      "billing@acme.test",
+			},
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{
+						{Key: "_id", Value: companyID},
+						{Key: "name", Value: "Acme"},
+						{Key: "owner", Value: ownerID},
+					}),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Unknown Transport",
+			mail:           map[string]interface{}{"transport": "pigeon"},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Sender Not An Email Address",
+			mail:           map[string]interface{}{"from": "billing"},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("UpdateCompanyMailSettingsTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				mt.ClearEvents()
+				tc.setupMock(mt)
+
+				jsonData, _ := json.Marshal(map[string]interface{}{"mail": tc.mail})
+				req, _ := http.NewRequest("PUT", "/company/"+companyID.Hex()+"/settings", bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedStatus == http.StatusOK {
+					// The password is never returned
+					assert.NotContains(t, w.Body.String(), "password")
+					assert.NotContains(t, w.Body.String(), "s3cret")
+					var settings models.CompanySettings
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &settings))
+					if assert.NotNil(t, settings.Mail) {
+						assert.Equal(t, "smtp.acme.test", settings.Mail.Host)
+						assert.Equal(t, 587, settings.Mail.Port)
+					}
+
+					// and is stored encrypted
+					var update bson.Raw
+					for evt := mt.GetStartedEvent(); evt != nil; evt = mt.GetStartedEvent() {
+						if evt.CommandName == "update" {
+							update = evt.Command
+						}
+					}
+					if assert.NotNil(t, update) {
+						set := update.Lookup("updates").Array().Index(0).Value().Document().Lookup("u", "$set").Document()
+						assert.Equal(t, "billing@acme.test", set.Lookup("settings.mail.from").StringValue())
+						_, sealed := set.Lookup("settings.mail.password").Binary()
+						assert.NotEmpty(t, sealed)
+						assert.NotContains(t, string(sealed), "s3cret")
+					}
+				}
+			})
+		}
+	})
+}
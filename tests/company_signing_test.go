@@ -0,0 +1,365 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/digitorus/pkcs7"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// testCA is a throwaway certificate authority that issues signing certificates
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T) testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Invoice CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return testCA{cert: cert, key: key}
+}
+
+// issue returns a PEM certificate and PEM private key valid between the given times
+func (ca testCA) issue(t *testing.T, name string, notBefore, notAfter time.Time) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name, Organization: []string{name}},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+}
+
+func certificateUpload(certPEM, keyPEM []byte, autoSign string) (*bytes.Buffer, string) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("certificate", "cert.pem")
+	part.Write(certPEM)
+	part, _ = writer.CreateFormFile("private_key", "key.pem")
+	part.Write(keyPEM)
+	if autoSign != "" {
+		writer.WriteField("auto_sign", autoSign)
+	}
+	writer.Close()
+	return &body, writer.FormDataContentType()
+}
+
+var byteRangePattern = regexp.MustCompile(`/ByteRange \[(\d+) (\d+) (\d+) (\d+)\s*\]`)
+
+// verifyPDFSignature checks the signature covers the whole file and was made
+// with a certificate issued by one of roots, as a PDF reader would
+func verifyPDFSignature(pdf []byte, roots *x509.CertPool) (*x509.Certificate, error) {
+	matches := byteRangePattern.FindAllSubmatch(pdf, -1)
+	if matches == nil {
+		return nil, assert.AnError
+	}
+	var r [4]int
+	for i := range r {
+		r[i], _ = strconv.Atoi(string(matches[len(matches)-1][i+1]))
+	}
+	if r[0] != 0 || r[2]+r[3] != len(pdf) {
+		return nil, assert.AnError
+	}
+	signed := append(append([]byte{}, pdf[:r[1]]...), pdf[r[2]:r[2]+r[3]]...)
+
+	contents, err := hex.DecodeString(string(pdf[r[1]+1 : r[2]-1]))
+	if err != nil {
+		return nil, err
+	}
+	// The placeholder is zero padded after the DER signature
+	rest, err := asn1.Unmarshal(contents, &asn1.RawValue{})
+	if err != nil {
+		return nil, err
+	}
+	p7, err := pkcs7.Parse(contents[:len(contents)-len(rest)])
+	if err != nil {
+		return nil, err
+	}
+	p7.Content = signed
+	if err := p7.VerifyWithChain(roots); err != nil {
+		return nil, err
+	}
+	return p7.GetOnlySigner(), nil
+}
+
+func TestUploadSigningCertificate(t *testing.T) {
+	t.Setenv("CERTIFICATE_KEY_SECRET", "test-secret")
+
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	ownerID := primitive.NewObjectID().Hex()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", ownerID)
+		c.Next()
+	})
+	router.POST("/company/:id/signing-certificate", controllers.UploadSigningCertificate)
+
+	companyID := primitive.NewObjectID()
+	companyDoc := func(owner string) bson.D {
+		return bson.D{{Key: "_id", Value: companyID}, {Key: "name", Value: "Test Tech Solutions"}, {Key: "owner", Value: owner}}
+	}
+
+	ca := newTestCA(t)
+	now := time.Now()
+	certPEM, keyPEM := ca.issue(t, "Test Tech Solutions", now.Add(-time.Hour), now.Add(time.Hour))
+	_, otherKeyPEM := ca.issue(t, "Someone Else", now.Add(-time.Hour), now.Add(time.Hour))
+	expiredPEM, expiredKeyPEM := ca.issue(t, "Test Tech Solutions", now.Add(-2*time.Hour), now.Add(-time.Hour))
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		certPEM        []byte
+		keyPEM         []byte
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "Valid Certificate",
+			certPEM:        certPEM,
+			keyPEM:         keyPEM,
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc(ownerID)),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{name: "Key Does Not Match", certPEM: certPEM, keyPEM: otherKeyPEM, expectedStatus: http.StatusBadRequest, setupMock: func(mt *mtest.T) {}},
+		{name: "Expired Certificate", certPEM: expiredPEM, keyPEM: expiredKeyPEM, expectedStatus: http.StatusBadRequest, setupMock: func(mt *mtest.T) {}},
+		{name: "Not A Certificate", certPEM: []byte("hello"), keyPEM: keyPEM, expectedStatus: http.StatusBadRequest, setupMock: func(mt *mtest.T) {}},
+		{
+			name:           "Not The Owner",
+			certPEM:        certPEM,
+			keyPEM:         keyPEM,
+			expectedStatus: http.StatusForbidden,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc(primitive.NewObjectID().Hex())))
+			},
+		},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("UploadSigningCertificateTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				body, contentType := certificateUpload(tc.certPEM, tc.keyPEM, "")
+				req, _ := http.NewRequest("POST", "/company/"+companyID.Hex()+"/signing-certificate", body)
+				req.Header.Set("Content-Type", contentType)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+				if tc.expectedStatus == http.StatusOK {
+					var response map[string]interface{}
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					assert.Equal(t, "CN=Test Tech Solutions,O=Test Tech Solutions", response["subject"])
+					assert.Len(t, response["fingerprint"], 64)
+					assert.NotContains(t, response, "encrypted_key")
+					assert.NotContains(t, w.Body.String(), "PRIVATE KEY")
+				}
+			})
+		}
+	})
+}
+
+func TestDownloadSignedInvoice(t *testing.T) {
+	t.Setenv("CERTIFICATE_KEY_SECRET", "test-secret")
+
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	ownerID := primitive.NewObjectID().Hex()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", ownerID)
+		c.Next()
+	})
+	router.POST("/company/:id/signing-certificate", controllers.UploadSigningCertificate)
+	router.GET("/invoice/download/:id", controllers.DownloadInvoice)
+	router.GET("/invoice/companies/:company_id/download", controllers.DownloadInvoicesZip)
+
+	invoiceID := primitive.NewObjectID()
+	customerID := primitive.NewObjectID()
+	ca := newTestCA(t)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	certPEM, keyPEM := ca.issue(t, "Test Tech Solutions", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+
+	invoiceDoc := bson.D{
+		{Key: "_id", Value: invoiceID},
+		{Key: "company_id", Value: companyID.Hex()},
+		{Key: "customer_id", Value: customerID.Hex()},
+		{Key: "reference_number", Value: "INV-001"},
+		{Key: "status", Value: "Unpaid"},
+		{Key: "amount", Value: 100.0},
+		{Key: "items", Value: bson.A{bson.D{{Key: "item_name", Value: "Consulting"}, {Key: "quantity", Value: 1}, {Key: "unit_price", Value: 100.0}, {Key: "subtotal", Value: 100.0}}}},
+	}
+	customerDoc := bson.D{{Key: "_id", Value: customerID}, {Key: "name", Value: "Test Customer"}, {Key: "email", Value: "customer@example.com"}}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("DownloadSignedInvoiceTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		// Upload the certificate and keep what would have been stored
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{{Key: "_id", Value: companyID}, {Key: "owner", Value: ownerID}}),
+			mtest.CreateSuccessResponse(),
+		)
+		body, contentType := certificateUpload(certPEM, keyPEM, "false")
+		req, _ := http.NewRequest("POST", "/company/"+companyID.Hex()+"/signing-certificate", body)
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var stored bson.RawValue
+		for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+			if event.CommandName == "update" {
+				update := event.Command.Lookup("updates").Array().Index(0).Value().Document()
+				stored = update.Lookup("u", "$set", "signing_certificate")
+			}
+		}
+		assert.NotContains(t, string(stored.Value), "PRIVATE KEY")
+		companyDoc := bson.D{{Key: "_id", Value: companyID}, {Key: "name", Value: "Test Tech Solutions"}, {Key: "email", Value: "billing@testtech.example"},
+			{Key: "tin", Value: "0000111122"}, {Key: "signing_certificate", Value: stored}}
+
+		download := func(query string, company bson.D) *httptest.ResponseRecorder {
+			mt.AddMockResponses(
+				mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoiceDoc),
+				mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, company),
+				mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc),
+			)
+			req, _ := http.NewRequest("GET", "/invoice/download/"+invoiceID.Hex()+query, nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			return w
+		}
+
+		t.Run("Signed On Request", func(t *testing.T) {
+			w := download("?sign=true", companyDoc)
+			assert.Equal(t, http.StatusOK, w.Code)
+			pdf := w.Body.Bytes()
+			assert.Contains(t, string(pdf), "/SubFilter /adbe.pkcs7.detached")
+
+			signer, err := verifyPDFSignature(pdf, roots)
+			if assert.NoError(t, err) {
+				assert.Equal(t, "Test Tech Solutions", signer.Subject.CommonName)
+			}
+
+			// Any change to the signed bytes breaks the signature
+			tampered := append([]byte{}, pdf...)
+			tampered[len("%PDF-1.3\n")+10] ^= 0xff
+			_, err = verifyPDFSignature(tampered, roots)
+			assert.Error(t, err)
+
+			// And a signature from another CA is not trusted
+			otherRoots := x509.NewCertPool()
+			otherRoots.AddCert(newTestCA(t).cert)
+			_, err = verifyPDFSignature(pdf, otherRoots)
+			assert.Error(t, err)
+		})
+
+		t.Run("Signed Factur-X", func(t *testing.T) {
+			w := download("?sign=true&facturx=true", companyDoc)
+			assert.Equal(t, http.StatusOK, w.Code)
+			pdf := w.Body.Bytes()
+			assert.Contains(t, string(pdf), "/AFRelationship /Alternative")
+
+			// The signature is the last update and covers the embedded invoice
+			_, err := verifyPDFSignature(pdf, roots)
+			assert.NoError(t, err)
+			assert.Equal(t, 3, bytes.Count(pdf, []byte("startxref")))
+		})
+
+		t.Run("Not Signed By Default", func(t *testing.T) {
+			w := download("", companyDoc)
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.NotContains(t, w.Body.String(), "/ByteRange")
+		})
+
+		t.Run("No Certificate", func(t *testing.T) {
+			w := download("?sign=true", bson.D{{Key: "_id", Value: companyID}, {Key: "name", Value: "Test Tech Solutions"}})
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+
+		t.Run("Bulk Download Signed Automatically", func(t *testing.T) {
+			var signing bson.M
+			assert.NoError(t, stored.Unmarshal(&signing))
+			signing["auto_sign"] = true
+			autoSignDoc := bson.D{{Key: "_id", Value: companyID}, {Key: "name", Value: "Test Tech Solutions"}, {Key: "signing_certificate", Value: signing}}
+			mt.AddMockResponses(
+				mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, autoSignDoc),
+				mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoiceDoc),
+				mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc),
+			)
+			req, _ := http.NewRequest("GET", "/invoice/companies/"+companyID.Hex()+"/download?date_range=last_7_days", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+			if assert.NoError(t, err) && assert.Len(t, archive.File, 2) {
+				r, _ := archive.File[0].Open()
+				var pdf bytes.Buffer
+				pdf.ReadFrom(r)
+				_, err := verifyPDFSignature(pdf.Bytes(), roots)
+				assert.NoError(t, err)
+			}
+		})
+	})
+}
@@ -0,0 +1,348 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/bisre1921/billing-and-invoice-system/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func testToken(t *testing.T, claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("test-secret"))
+	assert.NoError(t, err)
+	return token
+}
+
+// asCompanyOwner sets the claims of a token issued to the owner of the
+// company, keeping a user ID set earlier.
+func asCompanyOwner(companyID string) gin.HandlerFunc {
+	userID := primitive.NewObjectID().Hex()
+	return func(c *gin.Context) {
+		if c.GetString("userID") == "" {
+			c.Set("userID", userID)
+		}
+		c.Set("companyID", companyID)
+		c.Set("role", "owner")
+		c.Next()
+	}
+}
+
+func TestSwitchCompany(t *testing.T) {
+	// Setup
+	t.Setenv("JWT_SECRET", "test-secret")
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/auth/switch-company", middleware.AuthMiddleware(), controllers.SwitchCompany)
+
+	userID := primitive.NewObjectID()
+	companyID := primitive.NewObjectID()
+	token := testToken(t, jwt.MapClaims{"user_id": userID.Hex(), "email": "abebe@testtech.com"})
+	userDoc := func(companyIDs ...interface{}) bson.D {
+		return bson.D{
+			{Key: "_id", Value: userID},
+			{Key: "email", Value: "abebe@testtech.com"},
+			{Key: "company_ids", Value: bson.A(companyIDs)},
+		}
+	}
+	companyDoc := func(owner string) bson.D {
+		return bson.D{{Key: "_id", Value: companyID}, {Key: "name", Value: "Test Tech Solutions"}, {Key: "owner", Value: owner}}
+	}
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		companyID      string
+		expectedStatus int
+		expectedRole   string
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "Owner",
+			companyID:      companyID.Hex(),
+			expectedStatus: http.StatusOK,
+			expectedRole:   "owner",
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".users", mtest.FirstBatch, userDoc(companyID)),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc(userID.Hex())),
+				)
+			},
+		},
+		{
+			name:           "Employee",
+			companyID:      companyID.Hex(),
+			expectedStatus: http.StatusOK,
+			expectedRole:   "employee",
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".users", mtest.FirstBatch, userDoc(companyID)),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc(primitive.NewObjectID().Hex())),
+				)
+			},
+		},
+		{
+			name:           "Not A Member",
+			companyID:      companyID.Hex(),
+			expectedStatus: http.StatusForbidden,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".users", mtest.FirstBatch, userDoc(primitive.NewObjectID())),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc(primitive.NewObjectID().Hex())),
+				)
+			},
+		},
+		{
+			name:           "Deleted Company",
+			companyID:      companyID.Hex(),
+			expectedStatus: http.StatusNotFound,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".users", mtest.FirstBatch, userDoc(companyID)),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch),
+				)
+			},
+		},
+		{
+			name:           "Invalid Company ID",
+			companyID:      "invalid-id",
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("SwitchCompanyTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				jsonData, _ := json.Marshal(map[string]string{"company_id": tc.companyID})
+				req, _ := http.NewRequest("POST", "/auth/switch-company", bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", "Bearer "+token)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedStatus == http.StatusOK {
+					var response map[string]interface{}
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					assert.Equal(t, tc.expectedRole, response["role"])
+
+					// The new token carries the company and role
+					claims := jwt.MapClaims{}
+					_, err := jwt.ParseWithClaims(response["token"].(string), claims, func(*jwt.Token) (interface{}, error) {
+						return []byte("test-secret"), nil
+					})
+					assert.NoError(t, err)
+					assert.Equal(t, userID.Hex(), claims["user_id"])
+					assert.Equal(t, companyID.Hex(), claims["company_id"])
+					assert.Equal(t, tc.expectedRole, claims["role"])
+				}
+			})
+		}
+	})
+}
+
+func TestCompanyScopedToken(t *testing.T) {
+	// Setup
+	t.Setenv("JWT_SECRET", "test-secret")
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.Use(middleware.OptionalAuthMiddleware())
+	router.GET("/customer/all", controllers.ListCustomers)
+	router.GET("/invoice/companies/:company_id", controllers.GetInvoicesByCompanyID)
+
+	activeID := primitive.NewObjectID()
+	otherID := primitive.NewObjectID()
+	token := testToken(t, jwt.MapClaims{
+		"user_id":    primitive.NewObjectID().Hex(),
+		"email":      "abebe@testtech.com",
+		"company_id": activeID.Hex(),
+		"role":       "employee",
+	})
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		path           string
+		token          string
+		expectedStatus int
+		expectedFilter interface{}
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "Defaults To Active Company",
+			path:           "/customer/all",
+			token:          token,
+			expectedStatus: http.StatusOK,
+			expectedFilter: activeID,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch))
+			},
+		},
+		{
+			name:           "Query Names Another Company",
+			path:           "/customer/all?company_id=" + otherID.Hex(),
+			token:          token,
+			expectedStatus: http.StatusForbidden,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Path Names Another Company",
+			path:           "/invoice/companies/" + otherID.Hex(),
+			token:          token,
+			expectedStatus: http.StatusForbidden,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Path Names Active Company",
+			path:           "/invoice/companies/" + activeID.Hex(),
+			token:          token,
+			expectedStatus: http.StatusOK,
+			expectedFilter: activeID.Hex(),
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch))
+			},
+		},
+		{
+			name:           "Without Token",
+			path:           "/customer/all?company_id=" + otherID.Hex(),
+			expectedStatus: http.StatusUnauthorized,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Invalid Token",
+			path:           "/customer/all",
+			token:          "not-a-token",
+			expectedStatus: http.StatusUnauthorized,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("CompanyScopedTokenTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				mt.ClearEvents()
+				tc.setupMock(mt)
+
+				req, _ := http.NewRequest("GET", tc.path, nil)
+				if tc.token != "" {
+					req.Header.Set("Authorization", "Bearer "+tc.token)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedFilter != nil {
+					find := mt.GetStartedEvent()
+					if assert.NotNil(t, find) {
+						var command struct {
+							Filter bson.M `bson:"filter"`
+						}
+						assert.NoError(t, bson.Unmarshal(find.Command, &command))
+						assert.Equal(t, tc.expectedFilter, command.Filter["company_id"])
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestSingleRecordScopedToCompany(t *testing.T) {
+	// Setup
+	t.Setenv("JWT_SECRET", "test-secret")
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/invoice/:id", middleware.OptionalAuthMiddleware(), controllers.GetInvoice)
+	router.GET("/customer/:id", middleware.OptionalAuthMiddleware(), controllers.GetCustomer)
+	router.GET("/item/:id", middleware.OptionalAuthMiddleware(), controllers.GetItem)
+	router.DELETE("/item/:id", middleware.OptionalAuthMiddleware(), controllers.DeleteItem)
+
+	companyID := primitive.NewObjectID()
+	otherCompanyID := primitive.NewObjectID()
+	recordID := primitive.NewObjectID()
+	token := testToken(t, jwt.MapClaims{
+		"user_id":    primitive.NewObjectID().Hex(),
+		"email":      "owner@example.com",
+		"company_id": companyID.Hex(),
+		"role":       "owner",
+	})
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		collection     string
+		companyID      interface{}
+		expectedStatus int
+	}{
+		{"Invoice Of Own Company", "GET", "/invoice/", "invoices", companyID.Hex(), http.StatusOK},
+		{"Invoice Of Another Company", "GET", "/invoice/", "invoices", otherCompanyID.Hex(), http.StatusForbidden},
+		{"Customer Of Another Company", "GET", "/customer/", "customers", otherCompanyID, http.StatusForbidden},
+		{"Item Of Another Company", "GET", "/item/", "items", otherCompanyID, http.StatusForbidden},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("SingleRecordScopeTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(1, "test."+tc.collection, mtest.FirstBatch, bson.D{
+					{Key: "_id", Value: recordID},
+					{Key: "company_id", Value: tc.companyID},
+				}))
+
+				req, _ := http.NewRequest(tc.method, tc.path+recordID.Hex(), nil)
+				req.Header.Set("Authorization", "Bearer "+token)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+			})
+		}
+
+		t.Run("Delete Is Limited To The Active Company", func(t *testing.T) {
+			mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+			mt.ClearEvents()
+
+			req, _ := http.NewRequest("DELETE", "/item/"+recordID.Hex(), nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			event := mt.GetStartedEvent()
+			if assert.NotNil(t, event) && assert.Equal(t, "delete", event.CommandName) {
+				filter := event.Command.Lookup("deletes").Array().Index(0).Value().Document().Lookup("q").Document()
+				assert.Equal(t, companyID, filter.Lookup("company_id").ObjectID())
+			}
+		})
+	})
+}
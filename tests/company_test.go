@@ -9,8 +9,10 @@ import (
 
 	"github.com/bisre1921/billing-and-invoice-system/config"
 	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/bisre1921/billing-and-invoice-system/models"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
 )
@@ -164,4 +166,210 @@ func TestCreateCompanyWithoutAuth(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Contains(t, response, "error")
 	assert.Equal(t, "Authentication token is required.", response["error"])
-}
\ No newline at end of file
+}
+func TestListCompanies(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	userID := primitive.NewObjectID()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", userID.Hex())
+		c.Next()
+	})
+	router.GET("/company", controllers.ListCompanies)
+
+	owned := primitive.NewObjectID()
+	employer := primitive.NewObjectID()
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("ListCompaniesTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".users", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: userID},
+				{Key: "email", Value: "abebe@testtech.com"},
+				{Key: "company_ids", Value: bson.A{owned, employer}},
+			}),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: owned}, {Key: "name", Value: "Test Tech Solutions"}, {Key: "owner", Value: userID.Hex()}},
+				bson.D{{Key: "_id", Value: employer}, {Key: "name", Value: "Other Trading"}, {Key: "owner", Value: primitive.NewObjectID().Hex()}},
+			),
+		)
+
+		req, _ := http.NewRequest("GET", "/company", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var companies []models.UserCompany
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &companies))
+		if assert.Len(t, companies, 2) {
+			assert.Equal(t, "Test Tech Solutions", companies[0].Name)
+			assert.Equal(t, "owner", companies[0].Role)
+			assert.Equal(t, "Other Trading", companies[1].Name)
+			assert.Equal(t, "employee", companies[1].Role)
+		}
+
+		// Owned and employing companies are fetched together, without deleted ones
+		mt.GetStartedEvent()
+		find := mt.GetStartedEvent()
+		if assert.NotNil(t, find) {
+			_, err := find.Command.LookupErr("filter", "deleted_at", "$exists")
+			assert.NoError(t, err)
+			ids, _ := find.Command.Lookup("filter", "$or", "1", "_id", "$in").Array().Values()
+			if assert.Len(t, ids, 2) {
+				assert.Equal(t, employer, ids[1].ObjectID())
+			}
+		}
+	})
+}
+
+func TestUpdateCompany(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	ownerID := primitive.NewObjectID().Hex()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", ownerID)
+		c.Next()
+	})
+	router.PUT("/company/:id", controllers.UpdateCompany)
+
+	companyID := primitive.NewObjectID()
+	companyDoc := func(owner, name string) bson.D {
+		return bson.D{
+			{Key: "_id", Value: companyID},
+			{Key: "name", Value: name},
+			{Key: "owner", Value: owner},
+			{Key: "tin", Value: "0098765432"},
+		}
+	}
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		requestBody    map[string]interface{}
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "Rename",
+			requestBody:    map[string]interface{}{"name": "Test Tech PLC"},
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc(ownerID, "Test Tech Solutions")),
+					bson.D{{Key: "ok", Value: 1}, {Key: "value", Value: companyDoc(ownerID, "Test Tech PLC")}},
+				)
+			},
+		},
+		{
+			name:           "Empty Name",
+			requestBody:    map[string]interface{}{"name": "  "},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Not The Owner",
+			requestBody:    map[string]interface{}{"name": "Taken Over Ltd"},
+			expectedStatus: http.StatusForbidden,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc(primitive.NewObjectID().Hex(), "Test Tech Solutions")))
+			},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("UpdateCompanyTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				mt.ClearEvents()
+				tc.setupMock(mt)
+
+				jsonData, _ := json.Marshal(tc.requestBody)
+				req, _ := http.NewRequest("PUT", "/company/"+companyID.Hex(), bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedStatus == http.StatusOK {
+					var company models.Company
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &company))
+					assert.Equal(t, "Test Tech PLC", company.Name)
+
+					// Only the fields sent are changed
+					mt.GetStartedEvent()
+					update := mt.GetStartedEvent()
+					if assert.NotNil(t, update) {
+						set := update.Command.Lookup("update", "$set").Document()
+						assert.Equal(t, "Test Tech PLC", set.Lookup("name").StringValue())
+						_, err := set.LookupErr("tin")
+						assert.Error(t, err)
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestDeleteCompany(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+
+	ownerID := primitive.NewObjectID().Hex()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", ownerID)
+		c.Next()
+	})
+	router.DELETE("/company/:id", controllers.DeleteCompany)
+	router.GET("/company/:id", controllers.GetCompany)
+
+	companyID := primitive.NewObjectID()
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("DeleteCompanyTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: companyID},
+				{Key: "name", Value: "Test Tech Solutions"},
+				{Key: "owner", Value: ownerID},
+			}),
+			mtest.CreateSuccessResponse(),
+		)
+		req, _ := http.NewRequest("DELETE", "/company/"+companyID.Hex(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// The company is soft-deleted, not removed
+		mt.GetStartedEvent()
+		update := mt.GetStartedEvent()
+		if assert.NotNil(t, update) {
+			assert.Equal(t, "update", update.CommandName)
+			_, err := update.Command.LookupErr("updates", "0", "u", "$set", "deleted_at")
+			assert.NoError(t, err)
+		}
+
+		// Once deleted it is no longer found
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch))
+		req, _ = http.NewRequest("GET", "/company/"+companyID.Hex(), nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,693 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/bisre1921/billing-and-invoice-system/middleware"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestGenerateInvoiceCreditHold(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.POST("/invoice/generate", controllers.GenerateInvoice)
+
+	customerID := primitive.NewObjectID()
+	overrideID := primitive.NewObjectID()
+	company := func(settings ...bson.E) bson.D {
+		return bson.D{{Key: "_id", Value: companyID}, {Key: "settings", Value: bson.D(settings)}}
+	}
+	customer := func(fields ...bson.E) bson.D {
+		return append(bson.D{
+			{Key: "_id", Value: customerID},
+			{Key: "company_id", Value: companyID},
+			{Key: "current_credit_available", Value: 1000.0},
+		}, fields...)
+	}
+	onHold := bson.E{Key: "credit_hold", Value: bson.D{{Key: "reason", Value: "Disputed invoices"}, {Key: "source", Value: "manual"}}}
+	override := func(amount float64) bson.D {
+		return bson.D{
+			{Key: "_id", Value: overrideID},
+			{Key: "company_id", Value: companyID},
+			{Key: "customer_id", Value: customerID},
+			{Key: "type", Value: "override"},
+			{Key: "status", Value: "Approved"},
+			{Key: "amount", Value: amount},
+		}
+	}
+	modified := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		overrideID     string
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "Customer On Hold",
+			expectedStatus: http.StatusBadRequest,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, company()),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customer(onHold)),
+				)
+			},
+		},
+		{
+			name:           "Customer Of Another Company",
+			expectedStatus: http.StatusNotFound,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, company()),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch),
+				)
+			},
+		},
+		{
+			name:           "Overdue Invoices Put Customer On Hold",
+			expectedStatus: http.StatusBadRequest,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, company(bson.E{Key: "credit_hold_overdue_days", Value: 30})),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customer()),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, bson.D{{Key: "n", Value: 2}}),
+					modified,
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "No Overdue Invoices",
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, company(bson.E{Key: "credit_hold_overdue_days", Value: 30})),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customer()),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, bson.D{{Key: "n", Value: 0}}),
+					modified,
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Credit Used Meanwhile",
+			expectedStatus: http.StatusBadRequest,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, company()),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customer()),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+				)
+			},
+		},
+		{
+			name:           "Approved Override",
+			overrideID:     overrideID.Hex(),
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, company()),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customer(onHold)),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".credit_requests", mtest.FirstBatch, override(500)),
+					modified,
+					modified,
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Override Below Invoice Amount",
+			overrideID:     overrideID.Hex(),
+			expectedStatus: http.StatusBadRequest,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, company()),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customer(onHold)),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".credit_requests", mtest.FirstBatch, override(50)),
+				)
+			},
+		},
+		{
+			name:           "Override Used Meanwhile",
+			overrideID:     overrideID.Hex(),
+			expectedStatus: http.StatusConflict,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, company()),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customer(onHold)),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".credit_requests", mtest.FirstBatch, override(500)),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+				)
+			},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GenerateInvoiceCreditHoldTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				jsonData, _ := json.Marshal(models.Invoice{
+					CustomerID:       customerID.Hex(),
+					CompanyID:        companyID.Hex(),
+					ReferenceNumber:  "2025-002",
+					PaymentType:      "credit",
+					CreditOverrideID: tc.overrideID,
+					Items:            []models.InvoiceItem{{ItemName: "Consulting", Quantity: 1, UnitPrice: 100}},
+				})
+				req, _ := http.NewRequest("POST", "/invoice/generate", bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+			})
+		}
+	})
+}
+
+func TestCreditHold(t *testing.T) {
+	// Setup
+	t.Setenv("JWT_SECRET", "test-secret")
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	customer := router.Group("/customer", middleware.OptionalAuthMiddleware())
+	customer.POST("/:id/credit-hold", controllers.PlaceCustomerCreditHold)
+	customer.DELETE("/:id/credit-hold", controllers.ReleaseCustomerCreditHold)
+
+	companyID := primitive.NewObjectID()
+	customerID := primitive.NewObjectID()
+	ownerToken := testToken(t, jwt.MapClaims{"user_id": primitive.NewObjectID().Hex(), "company_id": companyID.Hex(), "role": "owner"})
+	employeeToken := testToken(t, jwt.MapClaims{"user_id": primitive.NewObjectID().Hex(), "company_id": companyID.Hex(), "role": "employee"})
+	customerDoc := func(fields ...bson.E) bson.D {
+		return append(bson.D{{Key: "_id", Value: customerID}, {Key: "company_id", Value: companyID}, {Key: "name", Value: "Kebede Traders"}}, fields...)
+	}
+	onHold := bson.E{Key: "credit_hold", Value: bson.D{{Key: "reason", Value: "Disputed invoices"}, {Key: "source", Value: "manual"}}}
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		method         string
+		token          string
+		requestBody    map[string]interface{}
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "Place Hold",
+			method:         "POST",
+			token:          employeeToken,
+			requestBody:    map[string]interface{}{"reason": "Disputed invoices"},
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc()),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+					mtest.CreateSuccessResponse(),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{{Key: "_id", Value: companyID}, {Key: "email", Value: "owner@kebede.com"}}),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Already On Hold",
+			method:         "POST",
+			token:          employeeToken,
+			requestBody:    map[string]interface{}{"reason": "Disputed invoices"},
+			expectedStatus: http.StatusConflict,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc(onHold)),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+				)
+			},
+		},
+		{
+			name:           "Missing Reason",
+			method:         "POST",
+			token:          employeeToken,
+			requestBody:    map[string]interface{}{},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Owner Releases Hold",
+			method:         "DELETE",
+			token:          ownerToken,
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc(onHold)),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Employees Cannot Release",
+			method:         "DELETE",
+			token:          employeeToken,
+			expectedStatus: http.StatusForbidden,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Release Without Hold",
+			method:         "DELETE",
+			token:          ownerToken,
+			expectedStatus: http.StatusBadRequest,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc()))
+			},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("CreditHoldTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				var body *bytes.Buffer = bytes.NewBuffer(nil)
+				if tc.requestBody != nil {
+					jsonData, _ := json.Marshal(tc.requestBody)
+					body = bytes.NewBuffer(jsonData)
+				}
+				req, _ := http.NewRequest(tc.method, "/customer/"+customerID.Hex()+"/credit-hold", body)
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+			})
+		}
+	})
+}
+
+func TestCreditRequests(t *testing.T) {
+	// Setup
+	t.Setenv("JWT_SECRET", "test-secret")
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	customer := router.Group("/customer", middleware.OptionalAuthMiddleware())
+	customer.POST("/:id/credit-requests", controllers.RequestCreditApproval)
+	customer.POST("/credit-requests/:request_id/approve", controllers.ApproveCreditRequest)
+	customer.POST("/credit-requests/:request_id/reject", controllers.RejectCreditRequest)
+
+	companyID := primitive.NewObjectID()
+	customerID := primitive.NewObjectID()
+	requestID := primitive.NewObjectID()
+	ownerToken := testToken(t, jwt.MapClaims{"user_id": primitive.NewObjectID().Hex(), "company_id": companyID.Hex(), "role": "owner"})
+	employeeToken := testToken(t, jwt.MapClaims{"user_id": primitive.NewObjectID().Hex(), "email": "sales@kebede.com", "company_id": companyID.Hex(), "role": "employee"})
+	customerDoc := bson.D{
+		{Key: "_id", Value: customerID},
+		{Key: "company_id", Value: companyID},
+		{Key: "name", Value: "Kebede Traders"},
+		{Key: "max_credit_amount", Value: 1000.0},
+		{Key: "current_credit_available", Value: 600.0},
+	}
+	companyDoc := bson.D{{Key: "_id", Value: companyID}, {Key: "name", Value: "Test Tech"}, {Key: "email", Value: "owner@testtech.com"}}
+	requestDoc := func(status string) bson.D {
+		return bson.D{
+			{Key: "_id", Value: requestID},
+			{Key: "company_id", Value: companyID},
+			{Key: "customer_id", Value: customerID},
+			{Key: "type", Value: "credit_limit"},
+			{Key: "status", Value: status},
+			{Key: "amount", Value: 5000.0},
+			{Key: "requested_by_email", Value: "sales@kebede.com"},
+		}
+	}
+	modified := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		path           string
+		token          string
+		requestBody    map[string]interface{}
+		expectedStatus int
+		expectedState  string
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "Sales Requests Override",
+			path:           "/customer/" + customerID.Hex() + "/credit-requests",
+			token:          employeeToken,
+			requestBody:    map[string]interface{}{"type": "override", "amount": 2500.0, "reason": "Large seasonal order"},
+			expectedStatus: http.StatusCreated,
+			expectedState:  "Pending",
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc),
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Request Without Token",
+			path:           "/customer/" + customerID.Hex() + "/credit-requests",
+			requestBody:    map[string]interface{}{"type": "override", "amount": 2500.0, "reason": "Large seasonal order"},
+			expectedStatus: http.StatusUnauthorized,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Limit Below Used Credit",
+			path:           "/customer/" + customerID.Hex() + "/credit-requests",
+			token:          employeeToken,
+			requestBody:    map[string]interface{}{"type": "credit_limit", "amount": 300.0, "reason": "Lower risk"},
+			expectedStatus: http.StatusBadRequest,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc))
+			},
+		},
+		{
+			name:           "Owner Approves Credit Limit",
+			path:           "/customer/credit-requests/" + requestID.Hex() + "/approve",
+			token:          ownerToken,
+			requestBody:    map[string]interface{}{"note": "Good payment history"},
+			expectedStatus: http.StatusOK,
+			expectedState:  "Approved",
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".credit_requests", mtest.FirstBatch, requestDoc("Pending")),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc),
+					modified,
+					modified,
+					mtest.CreateSuccessResponse(),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Owner Rejects",
+			path:           "/customer/credit-requests/" + requestID.Hex() + "/reject",
+			token:          ownerToken,
+			expectedStatus: http.StatusOK,
+			expectedState:  "Rejected",
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".credit_requests", mtest.FirstBatch, requestDoc("Pending")),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc),
+					modified,
+					mtest.CreateSuccessResponse(),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Employees Cannot Approve",
+			path:           "/customer/credit-requests/" + requestID.Hex() + "/approve",
+			token:          employeeToken,
+			expectedStatus: http.StatusForbidden,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Already Decided",
+			path:           "/customer/credit-requests/" + requestID.Hex() + "/approve",
+			token:          ownerToken,
+			expectedStatus: http.StatusConflict,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".credit_requests", mtest.FirstBatch, requestDoc("Rejected")))
+			},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("CreditRequestTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				var body *bytes.Buffer = bytes.NewBuffer(nil)
+				if tc.requestBody != nil {
+					jsonData, _ := json.Marshal(tc.requestBody)
+					body = bytes.NewBuffer(jsonData)
+				}
+				req, _ := http.NewRequest("POST", tc.path, body)
+				req.Header.Set("Content-Type", "application/json")
+				if tc.token != "" {
+					req.Header.Set("Authorization", "Bearer "+tc.token)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedState != "" {
+					var response models.CreditRequest
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					assert.Equal(t, tc.expectedState, response.Status)
+				}
+			})
+		}
+	})
+}
+
+func TestUpdateCustomerCreditLimitApproval(t *testing.T) {
+	// Setup
+	t.Setenv("JWT_SECRET", "test-secret")
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.PUT("/customer/update/:id", middleware.OptionalAuthMiddleware(), controllers.UpdateCustomer)
+
+	companyID := primitive.NewObjectID()
+	customerID := primitive.NewObjectID()
+	ownerToken := testToken(t, jwt.MapClaims{"user_id": primitive.NewObjectID().Hex(), "company_id": companyID.Hex(), "role": "owner"})
+	employeeToken := testToken(t, jwt.MapClaims{"user_id": primitive.NewObjectID().Hex(), "company_id": companyID.Hex(), "role": "employee"})
+	otherOwnerToken := testToken(t, jwt.MapClaims{"user_id": primitive.NewObjectID().Hex(), "company_id": primitive.NewObjectID().Hex(), "role": "owner"})
+	customerDoc := bson.D{
+		{Key: "_id", Value: customerID},
+		{Key: "company_id", Value: companyID},
+		{Key: "max_credit_amount", Value: 1000.0},
+		{Key: "current_credit_available", Value: 1000.0},
+	}
+	companyDoc := bson.D{
+		{Key: "_id", Value: companyID},
+		{Key: "settings", Value: bson.D{{Key: "credit_limit_approval_threshold", Value: 2000.0}}},
+	}
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		token          string
+		limit          float64
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "Employee Above Threshold Needs Approval",
+			token:          employeeToken,
+			limit:          5000,
+			expectedStatus: http.StatusAccepted,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Employee Below Threshold",
+			token:          employeeToken,
+			limit:          1500,
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Owner Above Threshold",
+			token:          ownerToken,
+			limit:          5000,
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Owner Of Another Company",
+			token:          otherOwnerToken,
+			limit:          5000,
+			expectedStatus: http.StatusForbidden,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc))
+			},
+		},
+		{
+			name:           "Anonymous Caller",
+			limit:          5000,
+			expectedStatus: http.StatusUnauthorized,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("UpdateCustomerCreditLimitTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				jsonData, _ := json.Marshal(map[string]interface{}{"name": "Kebede Traders", "max_credit_amount": tc.limit})
+				req, _ := http.NewRequest("PUT", "/customer/update/"+customerID.Hex(), bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				if tc.token != "" {
+					req.Header.Set("Authorization", "Bearer "+tc.token)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+			})
+		}
+	})
+}
+
+func TestRegisterCustomerCreditLimitApproval(t *testing.T) {
+	// Setup
+	t.Setenv("JWT_SECRET", "test-secret")
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/customer/register", middleware.AuthMiddleware(), controllers.RegisterCustomer)
+
+	companyID := primitive.NewObjectID()
+	ownerToken := testToken(t, jwt.MapClaims{"user_id": primitive.NewObjectID().Hex(), "company_id": companyID.Hex(), "role": "owner"})
+	employeeToken := testToken(t, jwt.MapClaims{"user_id": primitive.NewObjectID().Hex(), "company_id": companyID.Hex(), "role": "employee"})
+	companyDoc := bson.D{
+		{Key: "_id", Value: companyID},
+		{Key: "settings", Value: bson.D{{Key: "credit_limit_approval_threshold", Value: 2000.0}}},
+	}
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		token          string
+		limit          float64
+		expectedStatus int
+		expectedLimit  float64
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "Employee Above Threshold Needs Approval",
+			token:          employeeToken,
+			limit:          5000,
+			expectedStatus: http.StatusAccepted,
+			expectedLimit:  0,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc),
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Employee Below Threshold",
+			token:          employeeToken,
+			limit:          1500,
+			expectedStatus: http.StatusCreated,
+			expectedLimit:  1500,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Owner Above Threshold",
+			token:          ownerToken,
+			limit:          5000,
+			expectedStatus: http.StatusCreated,
+			expectedLimit:  5000,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateSuccessResponse())
+			},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("RegisterCustomerCreditLimitTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+				mt.ClearEvents()
+
+				jsonData, _ := json.Marshal(map[string]interface{}{"name": "Kebede Traders", "tin": "0012345678", "max_credit_amount": tc.limit})
+				req, _ := http.NewRequest("POST", "/customer/register", bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				// The stored customer only gets the limit once it is approved
+				for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+					if event.CommandName == "insert" && event.Command.Lookup("insert").StringValue() == "customers" {
+						customer := event.Command.Lookup("documents").Array().Index(0).Value().Document()
+						assert.Equal(t, tc.expectedLimit, customer.Lookup("max_credit_amount").Double())
+						assert.Equal(t, tc.expectedLimit, customer.Lookup("current_credit_available").Double())
+					}
+				}
+			})
+		}
+	})
+}
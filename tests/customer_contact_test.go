@@ -0,0 +1,301 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestRegisterCustomerContacts(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.POST("/customer/register", controllers.RegisterCustomer)
+
+	customer := func(contacts ...models.CustomerContact) models.Customer {
+		return models.Customer{
+			Name:           "Abebe Wholesale",
+			TIN:            "0012345678",
+			CompanyID:      companyID,
+			BillingAddress: "PO Box 100, Addis Ababa",
+			Contacts:       contacts,
+			Tags:           []string{"Wholesale", " wholesale ", "VIP"},
+		}
+	}
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		customer       models.Customer
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name: "Billing And Purchasing Contacts",
+			customer: customer(
+				models.CustomerContact{Name: "Sara", Role: "billing", Email: "ap@abebe.com", Primary: true},
+				models.CustomerContact{Name: "Dawit", Role: "purchasing", Email: "buying@abebe.com"},
+			),
+			expectedStatus: http.StatusCreated,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateSuccessResponse())
+			},
+		},
+		{
+			name:           "Unknown Role",
+			customer:       customer(models.CustomerContact{Name: "Sara", Role: "ceo"}),
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name: "Two Primary Billing Contacts",
+			customer: customer(
+				models.CustomerContact{Name: "Sara", Role: "billing", Primary: true},
+				models.CustomerContact{Name: "Hana", Role: "billing", Primary: true},
+			),
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Contact Without Name Or Email",
+			customer:       customer(models.CustomerContact{Role: "billing", Phone: "+251911000000"}),
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("RegisterCustomerContactTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				jsonData, _ := json.Marshal(tc.customer)
+				req, _ := http.NewRequest("POST", "/customer/register", bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedStatus == http.StatusCreated {
+					insert := mt.GetStartedEvent()
+					for insert != nil && insert.CommandName != "insert" {
+						insert = mt.GetStartedEvent()
+					}
+					if assert.NotNil(t, insert) {
+						doc := insert.Command.Lookup("documents").Array().Index(0).Value().Document()
+						tags, _ := doc.Lookup("tags").Array().Values()
+						assert.Len(t, tags, 2)
+						contact := doc.Lookup("contacts").Array().Index(0).Value().Document()
+						assert.False(t, contact.Lookup("id").ObjectID().IsZero())
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestSendInvoiceToBillingContact(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.POST("/invoice/send/:id", controllers.SendInvoice)
+
+	invoiceID := primitive.NewObjectID()
+	customerID := primitive.NewObjectID()
+	accountsID := primitive.NewObjectID()
+	contact := func(id primitive.ObjectID, name, email string, primary bool) bson.D {
+		return bson.D{
+			{Key: "id", Value: id},
+			{Key: "name", Value: name},
+			{Key: "role", Value: "billing"},
+			{Key: "email", Value: email},
+			{Key: "primary", Value: primary},
+		}
+	}
+
+	// Test cases
+	testCases := []struct {
+		name             string
+		billingContactID string
+		expectedTo       string
+	}{
+		{
+			name:       "Primary Billing Contact",
+			expectedTo: "ap@abebe.com",
+		},
+		{
+			name:             "Contact Named On Invoice",
+			billingContactID: accountsID.Hex(),
+			expectedTo:       "accounts@abebe.com",
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("SendInvoiceToBillingContactTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, bson.D{
+						{Key: "_id", Value: invoiceID},
+						{Key: "company_id", Value: companyID.Hex()},
+						{Key: "customer_id", Value: customerID.Hex()},
+						{Key: "reference_number", Value: "INV-002"},
+						{Key: "status", Value: "Unpaid"},
+						{Key: "amount", Value: 100.0},
+						{Key: "billing_contact_id", Value: tc.billingContactID},
+					}),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, bson.D{
+						{Key: "_id", Value: customerID},
+						{Key: "name", Value: "Abebe Wholesale"},
+						{Key: "email", Value: "info@abebe.com"},
+						{Key: "contacts", Value: bson.A{
+							contact(primitive.NewObjectID(), "Dawit", "buying@abebe.com", false),
+							contact(accountsID, "Hana", "accounts@abebe.com", false),
+							contact(primitive.NewObjectID(), "Sara", "ap@abebe.com", true),
+						}},
+					}),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{
+						{Key: "_id", Value: companyID},
+						{Key: "name", Value: "Test Company"},
+					}),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".email_templates", mtest.FirstBatch),
+					mtest.CreateSuccessResponse(),
+				)
+
+				req, _ := http.NewRequest("POST", "/invoice/send/"+invoiceID.Hex(), nil)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, http.StatusOK, w.Code)
+
+				insert := mt.GetStartedEvent()
+				for insert != nil && insert.CommandName != "insert" {
+					insert = mt.GetStartedEvent()
+				}
+				if assert.NotNil(t, insert) {
+					doc := insert.Command.Lookup("documents").Array().Index(0).Value().Document()
+					assert.Equal(t, tc.expectedTo, doc.Lookup("to").Array().Index(0).Value().StringValue())
+				}
+			})
+		}
+	})
+}
+
+func TestCustomerNotes(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.POST("/customer/:id/notes", controllers.AddCustomerNote)
+	router.DELETE("/customer/:id/notes/:note_id", controllers.DeleteCustomerNote)
+
+	customerID := primitive.NewObjectID()
+	customerDoc := bson.D{{Key: "_id", Value: customerID}, {Key: "company_id", Value: companyID}}
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		requestBody    map[string]interface{}
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "Add Note",
+			method:         "POST",
+			path:           "/customer/" + customerID.Hex() + "/notes",
+			requestBody:    map[string]interface{}{"text": "Agreed to pay by bank transfer only"},
+			expectedStatus: http.StatusCreated,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+				)
+			},
+		},
+		{
+			name:           "Empty Note",
+			method:         "POST",
+			path:           "/customer/" + customerID.Hex() + "/notes",
+			requestBody:    map[string]interface{}{},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Delete Note",
+			method:         "DELETE",
+			path:           "/customer/" + customerID.Hex() + "/notes/" + primitive.NewObjectID().Hex(),
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+				)
+			},
+		},
+		{
+			name:           "Delete Unknown Note",
+			method:         "DELETE",
+			path:           "/customer/" + customerID.Hex() + "/notes/" + primitive.NewObjectID().Hex(),
+			expectedStatus: http.StatusNotFound,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+				)
+			},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("CustomerNoteTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				var body *bytes.Buffer = bytes.NewBuffer(nil)
+				if tc.requestBody != nil {
+					jsonData, _ := json.Marshal(tc.requestBody)
+					body = bytes.NewBuffer(jsonData)
+				}
+				req, _ := http.NewRequest(tc.method, tc.path, body)
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+			})
+		}
+	})
+}
@@ -0,0 +1,541 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestAddCustomerCredit(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.POST("/customer/:id/credits", controllers.AddCustomerCredit)
+
+	customerID := primitive.NewObjectID()
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		customerID     string
+		requestBody    map[string]interface{}
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "Deposit",
+			customerID:     customerID.Hex(),
+			requestBody:    map[string]interface{}{"amount": 250.0, "method": "bank", "reference": "TRX-1"},
+			expectedStatus: http.StatusCreated,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, bson.D{
+						{Key: "_id", Value: customerID},
+						{Key: "company_id", Value: companyID},
+					}),
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Overpayment Is Not A Manual Source",
+			customerID:     customerID.Hex(),
+			requestBody:    map[string]interface{}{"amount": 250.0, "source": "overpayment"},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Missing Amount",
+			customerID:     customerID.Hex(),
+			requestBody:    map[string]interface{}{"method": "cash"},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Customer Not Found",
+			customerID:     primitive.NewObjectID().Hex(),
+			requestBody:    map[string]interface{}{"amount": 250.0},
+			expectedStatus: http.StatusNotFound,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch))
+			},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("AddCustomerCreditTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				jsonData, _ := json.Marshal(tc.requestBody)
+				req, _ := http.NewRequest("POST", "/customer/"+tc.customerID+"/credits", bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedStatus == http.StatusCreated {
+					var response models.CustomerCredit
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					assert.Equal(t, "deposit", response.Source)
+					assert.Equal(t, 250.0, response.Remaining)
+					assert.Equal(t, companyID, response.CompanyID)
+				}
+			})
+		}
+	})
+}
+
+func TestApplyCreditToInvoice(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.POST("/invoice/apply-credit/:id", controllers.ApplyCreditToInvoice)
+
+	invoiceID := primitive.NewObjectID()
+	customerID := primitive.NewObjectID()
+	invoice := func(status string, amountPaid float64) bson.D {
+		return bson.D{
+			{Key: "_id", Value: invoiceID},
+			{Key: "customer_id", Value: customerID.Hex()},
+			{Key: "company_id", Value: companyID.Hex()},
+			{Key: "status", Value: status},
+			{Key: "amount", Value: 100.0},
+			{Key: "amount_paid", Value: amountPaid},
+			{Key: "payment_type", Value: "credit"},
+		}
+	}
+	credit := func(remaining float64) bson.D {
+		return bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "customer_id", Value: customerID}, {Key: "remaining", Value: remaining}}
+	}
+	modified := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+
+	// Test cases
+	testCases := []struct {
+		name            string
+		requestBody     map[string]interface{}
+		expectedStatus  int
+		expectedApplied float64
+		expectedBalance float64
+		expectedPaid    bool
+		setupMock       func(mt *mtest.T)
+	}{
+		{
+			name:            "Oldest Credits First",
+			expectedStatus:  http.StatusOK,
+			expectedApplied: 100,
+			expectedPaid:    true,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Unpaid", 0)),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customer_credits", mtest.FirstBatch, credit(30), credit(500)),
+					modified,
+					modified,
+					mtest.CreateSuccessResponse(),
+					modified,
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:            "Part Of The Balance",
+			requestBody:     map[string]interface{}{"amount": 25.0},
+			expectedStatus:  http.StatusOK,
+			expectedApplied: 25,
+			expectedBalance: 35,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Unpaid", 40)),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customer_credits", mtest.FirstBatch, credit(500)),
+					modified,
+					mtest.CreateSuccessResponse(),
+					modified,
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Paid Meanwhile Gives The Credit Back",
+			expectedStatus: http.StatusConflict,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Unpaid", 0)),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customer_credits", mtest.FirstBatch, credit(500)),
+					modified,
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+					modified,
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "No Credit Available",
+			expectedStatus: http.StatusBadRequest,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Unpaid", 0)),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customer_credits", mtest.FirstBatch),
+				)
+			},
+		},
+		{
+			name:           "Invoice Already Paid",
+			expectedStatus: http.StatusBadRequest,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Paid", 100)))
+			},
+		},
+		{
+			name:           "Invalid Credit ID",
+			requestBody:    map[string]interface{}{"credit_id": "invalid-id"},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("ApplyCreditToInvoiceTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				var body *bytes.Buffer = bytes.NewBuffer(nil)
+				if tc.requestBody != nil {
+					jsonData, _ := json.Marshal(tc.requestBody)
+					body = bytes.NewBuffer(jsonData)
+				}
+				req, _ := http.NewRequest("POST", "/invoice/apply-credit/"+invoiceID.Hex(), body)
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedStatus == http.StatusOK {
+					var response map[string]interface{}
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					assert.Equal(t, tc.expectedApplied, response["credit_applied"])
+					assert.Equal(t, tc.expectedBalance, response["balance"])
+					assert.Equal(t, tc.expectedPaid, response["paid"])
+				}
+			})
+		}
+	})
+}
+
+func TestRefundCustomerCredit(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.POST("/customer/:id/credits/:credit_id/refund", controllers.RefundCustomerCredit)
+
+	customerID := primitive.NewObjectID()
+	creditID := primitive.NewObjectID()
+	customer := bson.D{{Key: "_id", Value: customerID}, {Key: "company_id", Value: companyID}}
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		requestBody    map[string]interface{}
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "Refund Part Of Credit",
+			requestBody:    map[string]interface{}{"amount": 40.0, "method": "bank"},
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customer),
+					mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+						{Key: "_id", Value: creditID},
+						{Key: "customer_id", Value: customerID},
+						{Key: "amount", Value: 100.0},
+						{Key: "remaining", Value: 60.0},
+					}}),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "More Than Remaining",
+			requestBody:    map[string]interface{}{"amount": 400.0, "method": "bank"},
+			expectedStatus: http.StatusBadRequest,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customer),
+					mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customer_credits", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+				)
+			},
+		},
+		{
+			name:           "Credit Not Found",
+			requestBody:    map[string]interface{}{"amount": 40.0, "method": "bank"},
+			expectedStatus: http.StatusNotFound,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customer),
+					mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customer_credits", mtest.FirstBatch),
+				)
+			},
+		},
+		{
+			name:           "Missing Method",
+			requestBody:    map[string]interface{}{"amount": 40.0},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("RefundCustomerCreditTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				jsonData, _ := json.Marshal(tc.requestBody)
+				req, _ := http.NewRequest("POST", "/customer/"+customerID.Hex()+"/credits/"+creditID.Hex()+"/refund", bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedStatus == http.StatusOK {
+					var response models.CustomerCredit
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					assert.Equal(t, 60.0, response.Remaining)
+				}
+			})
+		}
+	})
+}
+
+func TestMarkInvoiceAsPaidWithAmount(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.PUT("/invoice/mark-as-paid/:id", controllers.MarkInvoiceAsPaid)
+
+	invoiceID := primitive.NewObjectID()
+	invoice := func(status string) bson.D {
+		return bson.D{
+			{Key: "_id", Value: invoiceID},
+			{Key: "customer_id", Value: primitive.NewObjectID().Hex()},
+			{Key: "company_id", Value: companyID.Hex()},
+			{Key: "status", Value: status},
+			{Key: "amount", Value: 100.0},
+			{Key: "payment_type", Value: "credit"},
+		}
+	}
+	modified := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		amount         float64
+		expectedStatus int
+		expectedPaid   float64
+		expectedCredit interface{}
+		expectedMsg    string
+		expectedExcess float64 // of the stored payment
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "Overpayment Kept As Credit",
+			amount:         150,
+			expectedStatus: http.StatusOK,
+			expectedPaid:   100,
+			expectedCredit: 50.0,
+			expectedMsg:    "Invoice marked as paid successfully",
+			expectedExcess: 50,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Unpaid")),
+					modified,
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Part Payment",
+			amount:         40,
+			expectedStatus: http.StatusOK,
+			expectedPaid:   40,
+			expectedMsg:    "Part payment recorded successfully",
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Unpaid")),
+					modified,
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Already Paid",
+			expectedStatus: http.StatusBadRequest,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Paid")))
+			},
+		},
+		{
+			name:           "Paid Meanwhile",
+			amount:         100,
+			expectedStatus: http.StatusConflict,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Unpaid")),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+				)
+			},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("MarkInvoiceAsPaidWithAmountTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+				mt.ClearEvents()
+
+				jsonData, _ := json.Marshal(models.UpdatePaymentStatusRequest{Amount: tc.amount, Method: "bank", Reference: "TRX-1"})
+				req, _ := http.NewRequest("PUT", "/invoice/mark-as-paid/"+invoiceID.Hex(), bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedStatus == http.StatusOK {
+					var response map[string]interface{}
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					assert.Equal(t, tc.expectedMsg, response["message"])
+					assert.Equal(t, tc.expectedPaid, response["amount_paid"])
+					assert.Equal(t, tc.expectedCredit, response["credit_added"])
+
+					// Every payment, part or whole, leaves a receipt
+					var receipts []bson.Raw
+					for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+						if event.CommandName == "insert" && event.Command.Lookup("insert").StringValue() == "payments" {
+							receipts = append(receipts, event.Command.Lookup("documents").Array().Index(0).Value().Document())
+						}
+					}
+					if assert.Len(t, receipts, 1) {
+						assert.Equal(t, tc.expectedPaid, receipts[0].Lookup("amount").Double())
+						excess, _ := receipts[0].Lookup("excess").DoubleOK()
+						assert.Equal(t, tc.expectedExcess, excess)
+						assert.Equal(t, "bank", receipts[0].Lookup("method").StringValue())
+						assert.Equal(t, "payment", receipts[0].Lookup("source").StringValue())
+						assert.Equal(t, "TRX-1", receipts[0].Lookup("reference").StringValue())
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestGenerateInvoiceAutoAppliesCredit(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.POST("/invoice/generate", controllers.GenerateInvoice)
+
+	customerID := primitive.NewObjectID()
+	modified := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GenerateInvoiceAutoAppliesCreditTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: companyID},
+				{Key: "settings", Value: bson.D{{Key: "auto_apply_credits", Value: true}}},
+			}),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: customerID},
+				{Key: "current_credit_available", Value: 1000.0},
+				{Key: "credit_balance", Value: 60.0},
+			}),
+			modified,
+			mtest.CreateSuccessResponse(),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".customer_credits", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "customer_id", Value: customerID},
+				{Key: "remaining", Value: 60.0},
+			}),
+			modified,
+			mtest.CreateSuccessResponse(),
+			modified,
+		)
+
+		jsonData, _ := json.Marshal(models.Invoice{
+			CustomerID:      customerID.Hex(),
+			CompanyID:       companyID.Hex(),
+			ReferenceNumber: "2025-001",
+			PaymentType:     "credit",
+			Items:           []models.InvoiceItem{{ItemName: "Consulting", Quantity: 1, UnitPrice: 100}},
+		})
+		req, _ := http.NewRequest("POST", "/invoice/generate", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Invoice models.Invoice `json:"invoice"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, 60.0, response.Invoice.AmountPaid)
+		assert.Equal(t, "Unpaid", response.Invoice.Status)
+	})
+}
@@ -0,0 +1,345 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// importRequest builds a multipart upload of an import file with form fields.
+func importRequest(t *testing.T, path, filename string, content []byte, fields map[string]string) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range fields {
+		assert.NoError(t, writer.WriteField(name, value))
+	}
+	part, err := writer.CreateFormFile("file", filename)
+	assert.NoError(t, err)
+	part.Write(content)
+	assert.NoError(t, writer.Close())
+
+	req, _ := http.NewRequest("POST", path, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+// xlsxFile writes rows to the first sheet of a new workbook.
+func xlsxFile(t *testing.T, rows [][]interface{}) []byte {
+	f := excelize.NewFile()
+	defer f.Close()
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		assert.NoError(t, f.SetSheetRow("Sheet1", cell, &row))
+	}
+	buf, err := f.WriteToBuffer()
+	assert.NoError(t, err)
+	return buf.Bytes()
+}
+
+func TestImportCustomers(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", primitive.NewObjectID().Hex())
+		c.Set("companyID", companyID.Hex())
+		c.Next()
+	})
+	router.POST("/customer/import", controllers.ImportCustomers)
+
+	existingID := primitive.NewObjectID()
+	companyDoc := bson.D{
+		{Key: "_id", Value: companyID},
+		{Key: "settings", Value: bson.D{{Key: "credit_limit_approval_threshold", Value: 5000.0}}},
+	}
+	existingDoc := bson.D{
+		{Key: "_id", Value: existingID},
+		{Key: "company_id", Value: companyID},
+		{Key: "name", Value: "Abebe Wholesale"},
+		{Key: "tin", Value: "0011111111"},
+		{Key: "max_credit_amount", Value: 1000.0},
+		{Key: "current_credit_available", Value: 600.0},
+	}
+	csvFile := "Name,TIN,Email,Credit Limit,Tags,Contact 1 Name,Contact 1 Role,Contact 1 Email\n" +
+		"Abebe Wholesale,0011111111,,2000,wholesale,Sara,billing,ap@abebe.com\n" +
+		"Kebede Traders,0022222222,info@kebede.com,1500,retail;vip\n"
+
+	// Test cases
+	testCases := []struct {
+		name            string
+		filename        string
+		content         []byte
+		dryRun          bool
+		expectedStatus  int
+		expectedResult  models.ImportResult
+		expectedErrRows []int
+		setupMock       func(mt *mtest.T)
+	}{
+		{
+			name:           "Creates And Updates",
+			filename:       "customers.csv",
+			content:        []byte(csvFile),
+			expectedStatus: http.StatusCreated,
+			expectedResult: models.ImportResult{Rows: 2, Created: 1, Updated: 1},
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, existingDoc),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:     "Dry Run Reports Row Errors",
+			filename: "customers.csv",
+			content: []byte("name,tin,email,max_credit_amount,contact_role\n" +
+				"Kebede Traders,0022222222,info@kebede.com,1500,billing\n" +
+				",0033333333,,abc,\n" +
+				"Almaz Shop,,almaz@example.com,100,\n" +
+				"Kebede Again,0022222222,,,\n" +
+				"Abebe Wholesale,0011111111,,200,\n" +
+				"Big Buyer,0044444444,not-an-email,9000,manager\n"),
+			dryRun:          true,
+			expectedStatus:  http.StatusOK,
+			expectedResult:  models.ImportResult{DryRun: true, Rows: 6, Created: 1, Failed: 5},
+			expectedErrRows: []int{3, 4, 5, 6, 7},
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, existingDoc),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:     "XLSX With Short Rows",
+			filename: "customers.xlsx",
+			content: xlsxFile(t, [][]interface{}{
+				{"name", "tin", "email", "phone", "credit_limit"},
+				{"Kebede Traders", "0022222222"},
+				{"Almaz Shop", "0055555555", "almaz@example.com", "+251911000000", 300},
+			}),
+			expectedStatus: http.StatusCreated,
+			expectedResult: models.ImportResult{Rows: 2, Created: 2},
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}),
+				)
+			},
+		},
+		{
+			name:           "Missing Name Column",
+			filename:       "customers.csv",
+			content:        []byte("tin,email\n0022222222,info@kebede.com\n"),
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Unsupported File",
+			filename:       "customers.txt",
+			content:        []byte(csvFile),
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("ImportCustomersTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				fields := map[string]string{"company_id": companyID.Hex()}
+				if tc.dryRun {
+					fields["dry_run"] = "true"
+				}
+				req := importRequest(t, "/customer/import", tc.filename, tc.content, fields)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedStatus == http.StatusOK || tc.expectedStatus == http.StatusCreated {
+					var result models.ImportResult
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+					errorRows := map[int]bool{}
+					for _, rowError := range result.Errors {
+						errorRows[rowError.Row] = true
+					}
+					for _, row := range tc.expectedErrRows {
+						assert.True(t, errorRows[row], "expected an error for line %d", row)
+					}
+					if len(tc.expectedErrRows) > 0 {
+						assert.NotEmpty(t, result.ErrorReportID)
+					}
+					result.Errors = nil
+					result.ErrorReportID = ""
+					assert.Equal(t, tc.expectedResult, result)
+				}
+			})
+		}
+	})
+}
+
+func TestExportCustomers(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.GET("/customer/export", controllers.ExportCustomers)
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("ExportCustomersTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch,
+			bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "name", Value: "Abebe Wholesale"},
+				{Key: "tin", Value: "0011111111"},
+				{Key: "max_credit_amount", Value: 2000.0},
+				{Key: "tags", Value: bson.A{"wholesale", "vip"}},
+				{Key: "contacts", Value: bson.A{bson.D{
+					{Key: "id", Value: primitive.NewObjectID()},
+					{Key: "name", Value: "Sara"},
+					{Key: "role", Value: "billing"},
+					{Key: "email", Value: "ap@abebe.com"},
+					{Key: "primary", Value: true},
+				}}},
+			},
+			bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "name", Value: "Kebede Traders"},
+				{Key: "tin", Value: "0022222222"},
+			},
+		))
+
+		req, _ := http.NewRequest("GET", "/customer/export?company_id="+companyID.Hex(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "text/csv", w.Header().Get("Content-Type"))
+
+		records, err := csv.NewReader(strings.NewReader(w.Body.String())).ReadAll()
+		assert.NoError(t, err)
+		if assert.Len(t, records, 3) {
+			assert.Equal(t, []string{"name", "tin", "email", "phone", "address", "billing_address", "shipping_address",
+				"max_credit_amount", "current_credit_available", "tags",
+				"contact_1_name", "contact_1_role", "contact_1_email", "contact_1_phone", "contact_1_address", "contact_1_primary"}, records[0])
+			assert.Equal(t, "wholesale;vip", records[1][9])
+			assert.Equal(t, "ap@abebe.com", records[1][12])
+			assert.Equal(t, "", records[2][12])
+		}
+	})
+}
+
+func TestImportRequiresActiveCompany(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/customer/import", controllers.ImportCustomers)
+	router.POST("/item/import", controllers.ImportItems)
+
+	csvFile := []byte("name,tin\nAbebe Wholesale,0011111111\n")
+	fields := map[string]string{"company_id": primitive.NewObjectID().Hex()}
+
+	for _, path := range []string{"/customer/import", "/item/import"} {
+		t.Run(path, func(t *testing.T) {
+			req := importRequest(t, path, "rows.csv", csvFile, fields)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusUnauthorized, w.Code)
+		})
+	}
+}
+
+func TestCustomerExportImportRoundTrip(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", primitive.NewObjectID().Hex())
+		c.Set("companyID", companyID.Hex())
+		c.Set("role", "owner")
+		c.Next()
+	})
+	router.GET("/customer/export", controllers.ExportCustomers)
+	router.POST("/customer/import", controllers.ImportCustomers)
+
+	customerDoc := bson.D{
+		{Key: "_id", Value: primitive.NewObjectID()},
+		{Key: "company_id", Value: companyID},
+		{Key: "name", Value: "Kebede Traders"},
+		{Key: "tin", Value: "0022222222"},
+		{Key: "email", Value: "Info@Kebede.com"},
+		{Key: "max_credit_amount", Value: 1500.0},
+		{Key: "current_credit_available", Value: 1500.0},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("RoundTrip", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc))
+		req, _ := http.NewRequest("GET", "/customer/export?format=xlsx", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{{Key: "_id", Value: companyID}}),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+		mt.ClearEvents()
+		req = importRequest(t, "/customer/import", "customers.xlsx", w.Body.Bytes(), nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var result models.ImportResult
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, models.ImportResult{Rows: 1, Updated: 1}, result)
+
+		// Existing customers are looked up with case-insensitive emails
+		for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+			if event.CommandName == "find" && event.Command.Lookup("find").StringValue() == "customers" {
+				collation, ok := event.Command.Lookup("collation").DocumentOK()
+				if assert.True(t, ok, "customer lookup has no collation") {
+					assert.Equal(t, int32(2), collation.Lookup("strength").Int32())
+				}
+			}
+		}
+	})
+}
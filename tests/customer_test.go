@@ -18,11 +18,12 @@ import (
 func TestRegisterCustomer(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
+	// Create a test company ID
+	companyID := primitive.NewObjectID()
 	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
 	router.POST("/customer/register", controllers.RegisterCustomer)
 
-	// Create a test company ID
-	companyID := primitive.NewObjectID()
 	// Test cases
 	testCases := []struct {
 		name           string
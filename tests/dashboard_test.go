@@ -0,0 +1,129 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestGetDashboard(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyObjID := primitive.NewObjectID()
+	companyID := companyObjID.Hex()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID))
+	router.GET("/dashboard/:company_id", controllers.GetDashboard)
+
+	customerID := primitive.NewObjectID()
+	now := time.Now()
+	kiritimati, err := time.LoadLocation("Pacific/Kiritimati") // UTC+14
+	assert.NoError(t, err)
+
+	invoice := func(amount float64, status string, date time.Time, paid time.Time, due *time.Time) bson.D {
+		doc := bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "company_id", Value: companyID},
+			{Key: "customer_id", Value: customerID.Hex()},
+			{Key: "amount", Value: amount},
+			{Key: "status", Value: status},
+			{Key: "date", Value: date},
+			{Key: "items", Value: bson.A{bson.D{
+				{Key: "item_id", Value: "item-1"},
+				{Key: "item_name", Value: "Laptop"},
+				{Key: "quantity", Value: 1},
+				{Key: "subtotal", Value: amount},
+			}}},
+		}
+		if !paid.IsZero() {
+			doc = append(doc, bson.E{Key: "payment_date", Value: paid})
+		}
+		if due != nil {
+			doc = append(doc, bson.E{Key: "due_date", Value: *due})
+		}
+		return doc
+	}
+	overdue := now.AddDate(0, 0, -3)
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GetDashboardTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: companyObjID},
+				{Key: "settings", Value: bson.D{{Key: "timezone", Value: "Pacific/Kiritimati"}}},
+			}),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch,
+				// Current period: paid after 4 days, and an overdue credit invoice
+				invoice(300, "Paid", now.AddDate(0, 0, -5), now.AddDate(0, 0, -1), nil),
+				invoice(200, "Unpaid", now.AddDate(0, 0, -10), time.Time{}, &overdue),
+				// Previous period
+				invoice(250, "Paid", now.AddDate(0, 0, -40), now.AddDate(0, 0, -39), nil),
+			),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: customerID},
+				{Key: "name", Value: "Test Customer"},
+			}),
+		)
+
+		for i := 0; i < 2; i++ {
+			// The second request is served from the cache without hitting the database
+			req, _ := http.NewRequest("GET", "/dashboard/"+companyID+"?period=month", nil)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code)
+
+			var kpis controllers.DashboardKPIs
+			err := json.Unmarshal(w.Body.Bytes(), &kpis)
+			assert.NoError(t, err)
+			assert.Equal(t, 500.0, kpis.Revenue)
+			assert.Equal(t, 250.0, kpis.PreviousRevenue)
+			assert.InDelta(t, 100.0, *kpis.RevenueChangePercent, 0.001)
+			assert.Equal(t, 200.0, kpis.OutstandingReceivable)
+			assert.Equal(t, 200.0, kpis.OverdueAmount)
+			assert.Equal(t, 2, kpis.InvoicesIssued)
+			assert.Equal(t, 1, kpis.InvoicesPaid)
+			assert.InDelta(t, 4.0, kpis.AverageDaysToPay, 0.01)
+			// Days follow the company's timezone rather than the server's
+			if assert.Len(t, kpis.DailyRevenue, 30) {
+				assert.Equal(t, now.In(kiritimati).Format("2006-01-02"), kpis.DailyRevenue[29].Date)
+				assert.Equal(t, now.AddDate(0, 0, -5).In(kiritimati).Format("2006-01-02"), kpis.DailyRevenue[24].Date)
+				assert.Equal(t, 300.0, kpis.DailyRevenue[24].Revenue)
+			}
+			start := kpis.PeriodStart.In(kiritimati)
+			assert.Equal(t, 0, start.Hour()+start.Minute())
+			if assert.Len(t, kpis.TopCustomers, 1) {
+				assert.Equal(t, "Test Customer", kpis.TopCustomers[0].Name)
+			}
+			if assert.Len(t, kpis.TopItems, 1) {
+				assert.Equal(t, 2, kpis.TopItems[0].Quantity)
+			}
+		}
+	})
+}
+
+func TestGetDashboardInvalidPeriod(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID().Hex()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID))
+	router.GET("/dashboard/:company_id", controllers.GetDashboard)
+
+	req, _ := http.NewRequest("GET", "/dashboard/"+companyID+"?period=decade", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
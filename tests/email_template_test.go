@@ -0,0 +1,115 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestUpdateEmailTemplate(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID().Hex()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID))
+	router.PUT("/email-template/:company_id/:kind", controllers.UpdateEmailTemplate)
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		kind           string
+		requestBody    models.EmailTemplateInput
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name: "Valid Template",
+			kind: "invoice",
+			requestBody: models.EmailTemplateInput{
+				Subject:  "Invoice {{.ReferenceNumber}} from {{.CompanyName}}",
+				HTMLBody: "<p>Dear {{.CustomerName}}, please pay {{.Amount}} by {{.DueDate}}: <a href=\"{{.PaymentLink}}\">pay</a></p>",
+			},
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateSuccessResponse())
+			},
+		},
+		{
+			name: "Unknown Placeholder",
+			kind: "invoice",
+			requestBody: models.EmailTemplateInput{
+				Subject:  "Invoice {{.InvoiceTotal}}",
+				HTMLBody: "<p>Hello</p>",
+			},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name: "Unknown Kind",
+			kind: "reminder",
+			requestBody: models.EmailTemplateInput{
+				Subject:  "Reminder",
+				HTMLBody: "<p>Hello</p>",
+			},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("UpdateEmailTemplateTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				jsonData, _ := json.Marshal(tc.requestBody)
+				req, _ := http.NewRequest("PUT", "/email-template/"+companyID+"/"+tc.kind, bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+			})
+		}
+	})
+}
+
+func TestPreviewEmailTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID().Hex()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID))
+	router.POST("/email-template/:company_id/:kind/preview", controllers.PreviewEmailTemplate)
+
+	jsonData, _ := json.Marshal(models.EmailTemplateInput{
+		Subject:  "Invoice {{.ReferenceNumber}}",
+		HTMLBody: "<p>Dear {{.CustomerName}}, {{.CompanyName}} thanks you</p>",
+	})
+	req, _ := http.NewRequest("POST", "/email-template/"+companyID+"/invoice/preview", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var preview models.EmailPreview
+	err := json.Unmarshal(w.Body.Bytes(), &preview)
+	assert.NoError(t, err)
+	assert.Equal(t, "Invoice INV-0001", preview.Subject)
+	assert.Equal(t, "<p>Dear Jane Customer, Example Company thanks you</p>", preview.HTMLBody)
+	// The text fallback comes from the default template
+	assert.Contains(t, preview.TextBody, "Hello Jane Customer,")
+}
@@ -10,7 +10,9 @@ import (
 
 	"github.com/bisre1921/billing-and-invoice-system/config"
 	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/bisre1921/billing-and-invoice-system/middleware"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -32,17 +34,21 @@ func TestAddEmployee(t *testing.T) {	// Setup test environment variables with mo
 	}()
 
 	// Setup
+	t.Setenv("JWT_SECRET", "test-secret")
 	gin.SetMode(gin.TestMode)
 	router := gin.Default()
-	router.POST("/employee/add", controllers.AddEmployee)
+	router.POST("/employee/add", middleware.OptionalAuthMiddleware(), controllers.AddEmployee)
 
 	// Create test company ID
 	companyID := primitive.NewObjectID()
+	ownerToken := testToken(t, jwt.MapClaims{"user_id": primitive.NewObjectID().Hex(), "company_id": companyID.Hex(), "role": "owner"})
+	employeeToken := testToken(t, jwt.MapClaims{"user_id": primitive.NewObjectID().Hex(), "company_id": companyID.Hex(), "role": "employee"})
 
 	// Test cases
 	testCases := []struct {
 		name           string
 		requestBody    map[string]interface{}
+		token          string
 		expectedStatus int
 		setupMock      func(mt *mtest.T)
 		description    string
@@ -57,6 +63,7 @@ func TestAddEmployee(t *testing.T) {	// Setup test environment variables with mo
 				"position":   "Software Developer",
 				"company_id": companyID.Hex(),
 			},
+			token:          ownerToken,
 			expectedStatus: http.StatusCreated,
 			description:    "Should successfully add employee when company exists",
 			setupMock: func(mt *mtest.T) {
@@ -86,6 +93,7 @@ func TestAddEmployee(t *testing.T) {	// Setup test environment variables with mo
 				"position":   "Senior Developer",
 				"company_id": companyID.Hex(),
 			},
+			token:          ownerToken,
 			expectedStatus: http.StatusCreated,
 			description:    "Should successfully add employee even when company fetch fails (email error is logged but not fatal)",
 			setupMock: func(mt *mtest.T) {
@@ -101,7 +109,7 @@ func TestAddEmployee(t *testing.T) {	// Setup test environment variables with mo
 			},
 		},
 		{
-			name: "Missing Company ID",
+			name: "Defaults To Active Company",
 			requestBody: map[string]interface{}{
 				"name":     "John Doe",
 				"email":    "john.doe@testtech.com",
@@ -109,12 +117,57 @@ func TestAddEmployee(t *testing.T) {	// Setup test environment variables with mo
 				"address":  "456 Employee Street, Tech City, TC 12345",
 				"position": "Software Developer",
 			},
-			expectedStatus: http.StatusBadRequest,
-			description:    "Should return 400 when company_id is missing",
+			token:          ownerToken,
+			expectedStatus: http.StatusCreated,
+			description:    "Should add the employee to the token's company when company_id is missing",
 			setupMock: func(mt *mtest.T) {
-				// No mock response needed for validation errors
+				mt.AddMockResponses(
+					mtest.CreateSuccessResponse(),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{{Key: "_id", Value: companyID}, {Key: "name", Value: "Test Tech"}}),
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name: "Another Company",
+			requestBody: map[string]interface{}{
+				"name":       "John Doe",
+				"email":      "john.doe@testtech.com",
+				"position":   "Software Developer",
+				"company_id": primitive.NewObjectID().Hex(),
+			},
+			token:          ownerToken,
+			expectedStatus: http.StatusForbidden,
+			description:    "Should return 403 when company_id is not the token's company",
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name: "Employee Cannot Add Employees",
+			requestBody: map[string]interface{}{
+				"name":       "John Doe",
+				"email":      "john.doe@testtech.com",
+				"position":   "Software Developer",
+				"company_id": companyID.Hex(),
+			},
+			token:          employeeToken,
+			expectedStatus: http.StatusForbidden,
+			description:    "Should return 403 for employees of the company",
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name: "Anonymous Caller",
+			requestBody: map[string]interface{}{
+				"name":       "John Doe",
+				"email":      "attacker@example.com",
+				"position":   "Software Developer",
+				"company_id": companyID.Hex(),
 			},
-		},		{
+			expectedStatus: http.StatusUnauthorized,
+			description:    "Should return 401 without a company token",
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
 			name: "Invalid Company ID Format",
 			requestBody: map[string]interface{}{
 				"name":       "John Doe",
@@ -124,16 +177,10 @@ func TestAddEmployee(t *testing.T) {	// Setup test environment variables with mo
 				"position":   "Software Developer",
 				"company_id": "invalid-id",
 			},
-			expectedStatus: http.StatusInternalServerError, // Changed from 400 to 500 because invalid ObjectID causes insertion error
-			description:    "Should return 500 when company_id format is invalid (causes insertion error)",
-			setupMock: func(mt *mtest.T) {
-				// Mock invitation insert failure due to invalid ObjectID
-				mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
-					Index:   0,
-					Code:    2,
-					Message: "invalid ObjectId",
-				}))
-			},
+			token:          ownerToken,
+			expectedStatus: http.StatusForbidden,
+			description:    "Should return 403 when company_id is not the token's company",
+			setupMock:      func(mt *mtest.T) {},
 		},
 		{
 			name: "Database Error on Invitation Insert",
@@ -145,6 +192,7 @@ func TestAddEmployee(t *testing.T) {	// Setup test environment variables with mo
 				"position":   "Software Developer",
 				"company_id": companyID.Hex(),
 			},
+			token:          ownerToken,
 			expectedStatus: http.StatusInternalServerError,
 			description:    "Should return 500 when invitation insert fails",
 			setupMock: func(mt *mtest.T) {
@@ -166,6 +214,7 @@ func TestAddEmployee(t *testing.T) {	// Setup test environment variables with mo
 				"position":   "Software Developer",
 				"company_id": companyID.Hex(),
 			},
+			token:          ownerToken,
 			expectedStatus: http.StatusInternalServerError,
 			description:    "Should return 500 when employee insert fails",
 			setupMock: func(mt *mtest.T) {
@@ -202,6 +251,9 @@ func TestAddEmployee(t *testing.T) {	// Setup test environment variables with mo
 				jsonData, _ := json.Marshal(tc.requestBody)
 				req, _ := http.NewRequest("POST", "/employee/add", bytes.NewBuffer(jsonData))
 				req.Header.Set("Content-Type", "application/json")
+				if tc.token != "" {
+					req.Header.Set("Authorization", "Bearer "+tc.token)
+				}
 				w := httptest.NewRecorder()
 
 				// Perform request
@@ -223,7 +275,7 @@ func TestAddEmployee(t *testing.T) {	// Setup test environment variables with mo
 					assert.NotNil(t, response["id"])
 					t.Logf("✅ Success: Employee added with ID %v", response["id"])
 
-				case http.StatusBadRequest:
+				case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden:
 					assert.Contains(t, response, "error")
 					assert.NotEmpty(t, response["error"])
 					t.Logf("✅ Validation: %v", response["error"])
@@ -237,3 +289,108 @@ func TestAddEmployee(t *testing.T) {	// Setup test environment variables with mo
 		}
 	})
 }
+
+func TestAcceptInvitation(t *testing.T) {
+	// Setup
+	t.Setenv("JWT_SECRET", "test-secret")
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/employee/invitation/accept", middleware.AuthMiddleware(), controllers.AcceptInvitation)
+
+	userID := primitive.NewObjectID()
+	companyID := primitive.NewObjectID()
+	token := testToken(t, jwt.MapClaims{"user_id": userID.Hex(), "email": "john.doe@testtech.com"})
+	user := bson.D{{Key: "_id", Value: userID}, {Key: "email", Value: "John.Doe@testtech.com"}}
+	invitation := func(email string) bson.D {
+		return bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "token", Value: "abc123"},
+			{Key: "email", Value: email},
+			{Key: "company_id", Value: companyID},
+		}
+	}
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		token          string
+		expectedStatus int
+		expectJoin     bool
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "Invited Email Joins The Company",
+			token:          token,
+			expectedStatus: http.StatusOK,
+			expectJoin:     true,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".users", mtest.FirstBatch, user),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invitations", mtest.FirstBatch, invitation("john.doe@testtech.com")),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+				)
+			},
+		},
+		{
+			name:           "Invitation For Another Email",
+			token:          token,
+			expectedStatus: http.StatusForbidden,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".users", mtest.FirstBatch, user),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invitations", mtest.FirstBatch, invitation("someone@else.com")),
+				)
+			},
+		},
+		{
+			name:           "Unknown Or Accepted Token",
+			token:          token,
+			expectedStatus: http.StatusNotFound,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".users", mtest.FirstBatch, user),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invitations", mtest.FirstBatch),
+				)
+			},
+		},
+		{
+			name:           "Anonymous Caller",
+			expectedStatus: http.StatusUnauthorized,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("AcceptInvitationTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+				mt.ClearEvents()
+
+				req, _ := http.NewRequest("POST", "/employee/invitation/accept", bytes.NewBufferString(`{"token":"abc123"}`))
+				req.Header.Set("Content-Type", "application/json")
+				if tc.token != "" {
+					req.Header.Set("Authorization", "Bearer "+tc.token)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				// Membership is only added to the users collection on acceptance
+				joined := false
+				for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+					if event.CommandName == "update" && event.Command.Lookup("update").StringValue() == "users" {
+						joined = true
+					}
+				}
+				assert.Equal(t, tc.expectJoin, joined)
+			})
+		}
+	})
+}
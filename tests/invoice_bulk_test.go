@@ -0,0 +1,230 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestDownloadInvoicesZip(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.GET("/invoice/companies/:company_id/download", controllers.DownloadInvoicesZip)
+
+	workers := controllers.BulkDownloadWorkers
+	controllers.BulkDownloadWorkers = 2
+	defer func() { controllers.BulkDownloadWorkers = workers }()
+
+	alice := primitive.NewObjectID()
+	bob := primitive.NewObjectID()
+	companyDoc := bson.D{{Key: "_id", Value: companyID}, {Key: "name", Value: "Test Tech Solutions"}}
+	invoiceDoc := func(reference, status string, customerID primitive.ObjectID, day int) bson.D {
+		return bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "company_id", Value: companyID.Hex()},
+			{Key: "customer_id", Value: customerID.Hex()},
+			{Key: "reference_number", Value: reference},
+			{Key: "date", Value: time.Date(2025, 4, day, 0, 0, 0, 0, time.UTC)},
+			{Key: "status", Value: status},
+			{Key: "amount", Value: 100.0 * float64(day)},
+			{Key: "items", Value: bson.A{bson.D{{Key: "item_name", Value: "Consulting"}, {Key: "quantity", Value: 1}, {Key: "unit_price", Value: 100.0}, {Key: "subtotal", Value: 100.0}}}},
+		}
+	}
+	customerDoc := func(id primitive.ObjectID, name string) bson.D {
+		return bson.D{{Key: "_id", Value: id}, {Key: "name", Value: name}}
+	}
+	period := "?date_range=custom&custom_start=2025-04-01&custom_end=2025-04-30"
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		query          string
+		companyID      string
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+		check          func(t *testing.T, mt *mtest.T, w *httptest.ResponseRecorder)
+	}{
+		{
+			name:           "Invoices And Receipts For The Month",
+			query:          period + "&status=Paid&status=Unpaid",
+			companyID:      companyID.Hex(),
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc),
+					mtest.CreateCursorResponse(1, mt.DB.Name()+".invoices", mtest.FirstBatch,
+						invoiceDoc("INV-001", "Paid", alice, 3), invoiceDoc("INV/002", "Unpaid", bob, 10)),
+					// Customers are looked up once each, in invoice order
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc(alice, "Alice Ltd")),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc(bob, "Bob, Inc")),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.NextBatch,
+						invoiceDoc("INV-003", "Unpaid", alice, 20), invoiceDoc("INV-003", "Unpaid", alice, 28)),
+				)
+			},
+			check: func(t *testing.T, mt *mtest.T, w *httptest.ResponseRecorder) {
+				assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+				assert.Equal(t, "attachment; filename=invoices_Test_Tech_Solutions_20250401_20250430.zip", w.Header().Get("Content-Disposition"))
+
+				archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+				if !assert.NoError(t, err) {
+					return
+				}
+				var names []string
+				for _, file := range archive.File {
+					names = append(names, file.Name)
+				}
+				assert.Equal(t, []string{"receipt_INV-001.pdf", "invoice_INV-002.pdf", "invoice_INV-003.pdf", "invoice_INV-003_2.pdf", "index.csv"}, names)
+				for _, file := range archive.File[:4] {
+					r, _ := file.Open()
+					var pdf bytes.Buffer
+					pdf.ReadFrom(r)
+					assert.True(t, bytes.HasPrefix(pdf.Bytes(), []byte("%PDF-")), file.Name)
+				}
+
+				r, _ := archive.File[4].Open()
+				rows, err := csv.NewReader(r).ReadAll()
+				assert.NoError(t, err)
+				if assert.Len(t, rows, 5) {
+					assert.Equal(t, []string{"file", "reference_number", "date", "customer", "status", "document_type", "amount", "due_date", "error"}, rows[0])
+					assert.Equal(t, []string{"receipt_INV-001.pdf", "INV-001", "2025-04-03", "Alice Ltd", "Paid", "", "300.00", "", ""}, rows[1])
+					assert.Equal(t, "Bob, Inc", rows[2][3])
+					assert.Equal(t, "invoice_INV-003_2.pdf", rows[4][0])
+				}
+
+				// The filter covers the whole last day and both statuses
+				var find bson.Raw
+				for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+					if event.CommandName == "find" && event.Command.Lookup("find").StringValue() == "invoices" {
+						find = event.Command
+					}
+				}
+				if assert.NotNil(t, find) {
+					filter := find.Lookup("filter")
+					assert.Equal(t, companyID.Hex(), filter.Document().Lookup("company_id").StringValue())
+					assert.Equal(t, time.Date(2025, 5, 1, 0, 0, 0, 0, time.UTC), filter.Document().Lookup("date", "$lt").Time().UTC())
+					assert.Equal(t, 2, len(arrayValues(filter.Document().Lookup("status", "$in").Array())))
+				}
+			},
+		},
+		{
+			name:           "Unreadable Invoice Listed In Index",
+			query:          period,
+			companyID:      companyID.Hex(),
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				unreadable := invoiceDoc("INV-BAD", "Unpaid", bob, 5)
+				unreadable[6].Value = "lots" // amount
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch,
+						invoiceDoc("INV-001", "Paid", alice, 3), unreadable),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc(alice, "Alice Ltd")),
+				)
+			},
+			check: func(t *testing.T, mt *mtest.T, w *httptest.ResponseRecorder) {
+				archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+				if !assert.NoError(t, err) || !assert.Len(t, archive.File, 2) {
+					return
+				}
+				assert.Equal(t, "receipt_INV-001.pdf", archive.File[0].Name)
+
+				r, _ := archive.File[1].Open()
+				rows, err := csv.NewReader(r).ReadAll()
+				assert.NoError(t, err)
+				if assert.Len(t, rows, 3) {
+					assert.Equal(t, "", rows[2][0])
+					assert.Equal(t, "INV-BAD", rows[2][1])
+					assert.Equal(t, "2025-04-05", rows[2][2])
+					assert.Contains(t, rows[2][8], "invoice could not be read")
+				}
+			},
+		},
+		{
+			name:           "No Invoices In Period",
+			query:          period,
+			companyID:      companyID.Hex(),
+			expectedStatus: http.StatusNotFound,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch),
+				)
+			},
+		},
+		{
+			name:           "Company Not Found",
+			query:          period,
+			companyID:      companyID.Hex(),
+			expectedStatus: http.StatusNotFound,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch))
+			},
+		},
+		{
+			name:           "Missing Period",
+			query:          "",
+			companyID:      companyID.Hex(),
+			expectedStatus: http.StatusBadRequest,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc))
+			},
+		},
+		{
+			name:           "Unknown Layout",
+			query:          period + "&layout=poster",
+			companyID:      companyID.Hex(),
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Another Company",
+			query:          period,
+			companyID:      primitive.NewObjectID().Hex(),
+			expectedStatus: http.StatusForbidden,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("DownloadInvoicesZipTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				mt.ClearEvents()
+				tc.setupMock(mt)
+
+				req, _ := http.NewRequest("GET", "/invoice/companies/"+tc.companyID+"/download"+tc.query, nil)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+				if tc.check != nil {
+					tc.check(t, mt, w)
+				}
+			})
+		}
+	})
+}
+
+func arrayValues(arr bson.Raw) []bson.RawValue {
+	values, _ := arr.Values()
+	return values
+}
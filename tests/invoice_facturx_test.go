@@ -0,0 +1,321 @@
+package tests
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// ciiSequences is the element order of the D16B CrossIndustryInvoice schema
+// for the aggregates the Factur-X export writes.
+var ciiSequences = map[string][]string{
+	"CrossIndustryInvoice":             {"ExchangedDocumentContext", "ExchangedDocument", "SupplyChainTradeTransaction"},
+	"ExchangedDocument":                {"ID", "TypeCode", "IssueDateTime"},
+	"SupplyChainTradeTransaction":      {"IncludedSupplyChainTradeLineItem", "ApplicableHeaderTradeAgreement", "ApplicableHeaderTradeDelivery", "ApplicableHeaderTradeSettlement"},
+	"IncludedSupplyChainTradeLineItem": {"AssociatedDocumentLineDocument", "SpecifiedTradeProduct", "SpecifiedLineTradeAgreement", "SpecifiedLineTradeDelivery", "SpecifiedLineTradeSettlement"},
+	"SpecifiedTradeProduct":            {"SellerAssignedID", "Name"},
+	"SpecifiedLineTradeAgreement":      {"GrossPriceProductTradePrice", "NetPriceProductTradePrice"},
+	"GrossPriceProductTradePrice":      {"ChargeAmount", "AppliedTradeAllowanceCharge"},
+	"AppliedTradeAllowanceCharge":      {"ChargeIndicator", "ActualAmount"},
+	"SpecifiedLineTradeSettlement":     {"ApplicableTradeTax", "SpecifiedTradeSettlementLineMonetarySummation"},
+	"ApplicableHeaderTradeAgreement":   {"BuyerReference", "SellerTradeParty", "BuyerTradeParty"},
+	"SellerTradeParty":                 {"Name", "DefinedTradeContact", "PostalTradeAddress", "URIUniversalCommunication", "SpecifiedTaxRegistration"},
+	"BuyerTradeParty":                  {"Name", "DefinedTradeContact", "PostalTradeAddress", "URIUniversalCommunication", "SpecifiedTaxRegistration"},
+	"DefinedTradeContact":              {"PersonName", "TelephoneUniversalCommunication", "EmailURIUniversalCommunication"},
+	"PostalTradeAddress":               {"LineOne", "CountryID"},
+	"ApplicableHeaderTradeSettlement": {"PaymentReference", "InvoiceCurrencyCode", "SpecifiedTradeSettlementPaymentMeans", "ApplicableTradeTax",
+		"SpecifiedTradePaymentTerms", "SpecifiedTradeSettlementHeaderMonetarySummation"},
+	"SpecifiedTradeSettlementPaymentMeans": {"TypeCode", "PayeePartyCreditorFinancialAccount", "PayeeSpecifiedCreditorFinancialInstitution"},
+	"ApplicableTradeTax":                   {"CalculatedAmount", "TypeCode", "ExemptionReason", "BasisAmount", "CategoryCode", "RateApplicablePercent"},
+	"SpecifiedTradePaymentTerms":           {"Description", "DueDateDateTime"},
+	"SpecifiedTradeSettlementHeaderMonetarySummation": {"LineTotalAmount", "TaxBasisTotalAmount", "TaxTotalAmount", "GrandTotalAmount",
+		"TotalPrepaidAmount", "DuePayableAmount"},
+}
+
+type ciiTestDocument struct {
+	XMLName   xml.Name
+	Guideline string `xml:"ExchangedDocumentContext>GuidelineSpecifiedDocumentContextParameter>ID"`
+	ID        string `xml:"ExchangedDocument>ID"`
+	TypeCode  string `xml:"ExchangedDocument>TypeCode"`
+	Lines     []struct {
+		Total string `xml:"SpecifiedLineTradeSettlement>SpecifiedTradeSettlementLineMonetarySummation>LineTotalAmount"`
+	} `xml:"SupplyChainTradeTransaction>IncludedSupplyChainTradeLineItem"`
+	Taxes []struct {
+		Calculated string `xml:"CalculatedAmount"`
+		Category   string `xml:"CategoryCode"`
+	} `xml:"SupplyChainTradeTransaction>ApplicableHeaderTradeSettlement>ApplicableTradeTax"`
+	DueDate   string `xml:"SupplyChainTradeTransaction>ApplicableHeaderTradeSettlement>SpecifiedTradePaymentTerms>DueDateDateTime>DateTimeString"`
+	Summation struct {
+		LineTotal string `xml:"LineTotalAmount"`
+		TaxBasis  string `xml:"TaxBasisTotalAmount"`
+		TaxTotal  string `xml:"TaxTotalAmount"`
+		Grand     string `xml:"GrandTotalAmount"`
+		Prepaid   string `xml:"TotalPrepaidAmount"`
+		Due       string `xml:"DuePayableAmount"`
+	} `xml:"SupplyChainTradeTransaction>ApplicableHeaderTradeSettlement>SpecifiedTradeSettlementHeaderMonetarySummation"`
+}
+
+var (
+	embeddedFilePattern = regexp.MustCompile(`(?s)/Type /EmbeddedFile .*?/Length (\d+).*?>>\nstream\n`)
+	iccStreamPattern    = regexp.MustCompile(`<</N 3 /Length (\d+)>>\nstream\n`)
+)
+
+// embeddedFacturX returns the inflated factur-x.xml attachment of a PDF.
+func embeddedFacturX(t *testing.T, pdf []byte) []byte {
+	loc := embeddedFilePattern.FindSubmatchIndex(pdf)
+	if !assert.NotNil(t, loc, "no embedded file") {
+		return nil
+	}
+	length, _ := strconv.Atoi(string(pdf[loc[2]:loc[3]]))
+	reader, err := zlib.NewReader(bytes.NewReader(pdf[loc[1] : loc[1]+length]))
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	data, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	return data
+}
+
+func amountOf(s string) float64 {
+	v, _ := strconv.ParseFloat(s, 64)
+	return v
+}
+
+// checkCIIRules checks the EN 16931 calculation rules on the embedded XML
+func checkCIIRules(t *testing.T, doc ciiTestDocument) {
+	var lines, taxes float64
+	for _, line := range doc.Lines {
+		lines += amountOf(line.Total)
+	}
+	for _, tax := range doc.Taxes {
+		taxes += amountOf(tax.Calculated)
+	}
+	sum := doc.Summation
+	assert.InDelta(t, lines, amountOf(sum.LineTotal), 0.001, "BR-CO-10")
+	assert.InDelta(t, taxes, amountOf(sum.TaxTotal), 0.001, "BR-CO-14")
+	assert.InDelta(t, amountOf(sum.TaxBasis)+amountOf(sum.TaxTotal), amountOf(sum.Grand), 0.001, "BR-CO-15")
+	assert.InDelta(t, amountOf(sum.Grand)-amountOf(sum.Prepaid), amountOf(sum.Due), 0.001, "BR-CO-16")
+}
+
+// validateFacturXSchema runs xmllint against the Factur-X EN 16931 schema
+// when FACTURX_XSD_DIR points at the XSD folder of the Factur-X package.
+func validateFacturXSchema(t *testing.T, data []byte) {
+	dir := os.Getenv("FACTURX_XSD_DIR")
+	if dir == "" {
+		t.Log("FACTURX_XSD_DIR not set, skipping XSD validation")
+		return
+	}
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Skip("xmllint not installed")
+	}
+	schemas, _ := filepath.Glob(filepath.Join(dir, "*EN16931*.xsd"))
+	if !assert.NotEmpty(t, schemas, "no EN16931 schema in FACTURX_XSD_DIR") {
+		return
+	}
+	file := filepath.Join(t.TempDir(), controllers.FacturXFilename)
+	assert.NoError(t, os.WriteFile(file, data, 0o600))
+	out, err := exec.Command(xmllint, "--noout", "--schema", schemas[0], file).CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
+
+func TestDownloadFacturXInvoice(t *testing.T) {
+	t.Setenv("INVOICE_CURRENCY", "EUR")
+	t.Setenv("INVOICE_COUNTRY", "FR")
+
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.GET("/invoice/download/:id", controllers.DownloadInvoice)
+
+	invoiceID := primitive.NewObjectID()
+	customerID := primitive.NewObjectID()
+
+	invoiceDoc := func(documentType, status string) bson.D {
+		return bson.D{
+			{Key: "_id", Value: invoiceID},
+			{Key: "company_id", Value: companyID.Hex()},
+			{Key: "customer_id", Value: customerID.Hex()},
+			{Key: "reference_number", Value: "FX-2025-001"},
+			{Key: "date", Value: time.Date(2025, 5, 2, 0, 0, 0, 0, time.UTC)},
+			{Key: "due_date", Value: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)},
+			{Key: "status", Value: status},
+			{Key: "document_type", Value: documentType},
+			{Key: "items", Value: bson.A{
+				bson.D{{Key: "item_name", Value: "Consulting"}, {Key: "quantity", Value: 4}, {Key: "unit_price", Value: 250.0}, {Key: "discount", Value: 5.0},
+					{Key: "subtotal", Value: 950.0}, {Key: "tax_rate", Value: 20.0}, {Key: "tax_category", Value: "standard"}},
+				bson.D{{Key: "item_name", Value: "Training"}, {Key: "quantity", Value: 1}, {Key: "unit_price", Value: 300.0},
+					{Key: "subtotal", Value: 300.0}, {Key: "tax_category", Value: "exempt"}},
+			}},
+		}
+	}
+	companyDoc := bson.D{{Key: "_id", Value: companyID}, {Key: "name", Value: "Société Générale de Test"}, {Key: "email", Value: "factures@example.fr"},
+		{Key: "address", Value: "1 rue de la Paix, Paris"}, {Key: "tin", Value: "FR12345678901"}}
+	customerDoc := func(email string) bson.D {
+		return bson.D{{Key: "_id", Value: customerID}, {Key: "name", Value: "Acme GmbH"}, {Key: "email", Value: email}}
+	}
+	responses := func(mt *mtest.T, invoice, customer bson.D) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customer),
+		)
+	}
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+		check          func(t *testing.T, pdf []byte)
+	}{
+		{
+			name:           "Factur-X Invoice",
+			query:          "?facturx=true",
+			expectedStatus: http.StatusOK,
+			setupMock:      func(mt *mtest.T) { responses(mt, invoiceDoc("", "Unpaid"), customerDoc("ap@acme.example")) },
+			check: func(t *testing.T, pdf []byte) {
+				// PDF/A-3b structure
+				assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.")))
+				assert.Equal(t, "%\xE2\xE3\xCF\xD3\n", string(pdf[bytes.IndexByte(pdf, '\n')+1:][:6]))
+				assert.Contains(t, string(pdf), "<pdfaid:part>3</pdfaid:part>")
+				assert.Contains(t, string(pdf), "<pdfaid:conformance>B</pdfaid:conformance>")
+				assert.Contains(t, string(pdf), "<fx:ConformanceLevel>EN 16931</fx:ConformanceLevel>")
+				assert.Contains(t, string(pdf), "/Subtype /text#2Fxml")
+				assert.Contains(t, string(pdf), "/AFRelationship /Alternative")
+				assert.Regexp(t, `/AF \[\d+ 0 R\]`, string(pdf))
+				assert.Contains(t, string(pdf), "/OutputIntents [<</Type /OutputIntent /S /GTS_PDFA1")
+				assert.Regexp(t, `/ID \[<[0-9A-F]{32}> <[0-9A-F]{32}>\]\n/Prev`, string(pdf))
+				// The verification QR code link prints, as PDF/A requires
+				assert.Contains(t, string(pdf), "/Subtype /Link /F 4 ")
+				// Non-ASCII metadata goes into the info dictionary as UTF-16
+				assert.Contains(t, string(pdf), "/Author <FEFF0053006F0063006900E9007400E9")
+
+				// The output intent profile is a well-formed ICC header
+				if m := iccStreamPattern.FindSubmatchIndex(pdf); assert.NotNil(t, m) {
+					length, _ := strconv.Atoi(string(pdf[m[2]:m[3]]))
+					icc := pdf[m[1] : m[1]+length]
+					assert.Equal(t, uint32(length), binary.BigEndian.Uint32(icc))
+					assert.Equal(t, "mntrRGB XYZ ", string(icc[12:24]))
+					assert.Equal(t, "acsp", string(icc[36:40]))
+				}
+
+				data := embeddedFacturX(t, pdf)
+				checkXMLSequence(t, data, ciiSequences)
+				validateFacturXSchema(t, data)
+				var doc ciiTestDocument
+				if assert.NoError(t, xml.Unmarshal(data, &doc)) {
+					assert.Equal(t, "urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100", doc.XMLName.Space)
+					assert.Equal(t, "urn:cen.eu:en16931:2017", doc.Guideline)
+					assert.Equal(t, "FX-2025-001", doc.ID)
+					assert.Equal(t, "380", doc.TypeCode)
+					assert.Equal(t, "20250601", doc.DueDate)
+					assert.Len(t, doc.Lines, 2)
+					assert.Len(t, doc.Taxes, 2)
+					assert.Equal(t, "190.00", doc.Summation.TaxTotal)
+					assert.Equal(t, "1440.00", doc.Summation.Due)
+					checkCIIRules(t, doc)
+				}
+				assert.Contains(t, string(data), `<ram:TaxTotalAmount currencyID="EUR">190.00</ram:TaxTotalAmount>`)
+				assert.Contains(t, string(data), `<ram:ID schemeID="VA">FR12345678901</ram:ID>`)
+			},
+		},
+		{
+			name:           "Factur-X Receipt",
+			query:          "?facturx=true",
+			expectedStatus: http.StatusOK,
+			setupMock:      func(mt *mtest.T) { responses(mt, invoiceDoc("", "Paid"), customerDoc("ap@acme.example")) },
+			check: func(t *testing.T, pdf []byte) {
+				var doc ciiTestDocument
+				assert.NoError(t, xml.Unmarshal(embeddedFacturX(t, pdf), &doc))
+				assert.Equal(t, "1440.00", doc.Summation.Prepaid)
+				assert.Equal(t, "0.00", doc.Summation.Due)
+				checkCIIRules(t, doc)
+			},
+		},
+		{
+			name:           "Factur-X Credit Note",
+			query:          "?facturx=true",
+			expectedStatus: http.StatusOK,
+			setupMock:      func(mt *mtest.T) { responses(mt, invoiceDoc("credit_note", ""), customerDoc("ap@acme.example")) },
+			check: func(t *testing.T, pdf []byte) {
+				data := embeddedFacturX(t, pdf)
+				checkXMLSequence(t, data, ciiSequences)
+				var doc ciiTestDocument
+				assert.NoError(t, xml.Unmarshal(data, &doc))
+				assert.Equal(t, "381", doc.TypeCode)
+				assert.Equal(t, "", doc.DueDate)
+				assert.Contains(t, string(pdf), "/Title (Credit Note FX-2025-001)")
+			},
+		},
+		{
+			name:           "Plain PDF By Default",
+			query:          "",
+			expectedStatus: http.StatusOK,
+			setupMock:      func(mt *mtest.T) { responses(mt, invoiceDoc("", "Unpaid"), customerDoc("ap@acme.example")) },
+			check: func(t *testing.T, pdf []byte) {
+				assert.NotContains(t, string(pdf), controllers.FacturXFilename)
+				assert.NotContains(t, string(pdf), "pdfaid")
+			},
+		},
+		{
+			name:           "Customer Without Email",
+			query:          "?facturx=true",
+			expectedStatus: http.StatusUnprocessableEntity,
+			setupMock:      func(mt *mtest.T) { responses(mt, invoiceDoc("", "Unpaid"), customerDoc("")) },
+			check: func(t *testing.T, pdf []byte) {
+				assert.Contains(t, string(pdf), "PEPPOL-EN16931-R010")
+			},
+		},
+		{
+			name:           "Invalid Factur-X Flag",
+			query:          "?facturx=maybe",
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("DownloadFacturXInvoiceTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				req, _ := http.NewRequest("GET", "/invoice/download/"+invoiceID.Hex()+tc.query, nil)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+				if tc.check != nil {
+					tc.check(t, w.Body.Bytes())
+				}
+			})
+		}
+	})
+}
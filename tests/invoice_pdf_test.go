@@ -0,0 +1,271 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf16"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// Run `go test ./tests -run TestInvoicePDFLayouts -update` to rewrite the golden files
+var updateGolden = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func TestInvoicePDFLayouts(t *testing.T) {
+	controllers.PDFCompression = false
+	defer func() { controllers.PDFCompression = true }()
+	// The QR code holds a signed link, so pin what goes into it
+	t.Setenv("INVOICE_VERIFICATION_SECRET", "golden-secret")
+	t.Setenv("API_BASE_URL", "https://billing.example.com/api")
+
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID, _ := primitive.ObjectIDFromHex("65a0000000000000000000c1")
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.GET("/invoice/download/:id", controllers.DownloadInvoice)
+
+	invoiceID, _ := primitive.ObjectIDFromHex("65a0000000000000000000a1")
+	customerID, _ := primitive.ObjectIDFromHex("65a0000000000000000000d1")
+	date := time.Date(2025, 3, 14, 9, 30, 0, 0, time.UTC)
+	due := date.AddDate(0, 0, 30)
+
+	invoiceDoc := func(status string, itemCount int) bson.D {
+		items := bson.A{}
+		for i := 1; i <= itemCount; i++ {
+			name := fmt.Sprintf("Item %d", i)
+			if i%3 == 0 {
+				name = fmt.Sprintf("Item %d with a long description that has to wrap onto several lines of the item column", i)
+			}
+			items = append(items, bson.D{
+				{Key: "item_name", Value: name},
+				{Key: "quantity", Value: i},
+				{Key: "unit_price", Value: 12.5},
+				{Key: "discount", Value: 0.0},
+				{Key: "subtotal", Value: 12.5 * float64(i)},
+			})
+		}
+		doc := bson.D{
+			{Key: "_id", Value: invoiceID},
+			{Key: "company_id", Value: companyID.Hex()},
+			{Key: "customer_id", Value: customerID.Hex()},
+			{Key: "reference_number", Value: "INV-2025-042"},
+			{Key: "status", Value: status},
+			{Key: "date", Value: date},
+			{Key: "due_date", Value: due},
+			{Key: "amount", Value: 1000.0},
+			{Key: "items", Value: items},
+		}
+		if status == "Paid" {
+			doc = append(doc, bson.E{Key: "payment_date", Value: date})
+		}
+		return doc
+	}
+
+	// Test cases
+	testCases := []struct {
+		name          string
+		layout        string
+		status        string
+		itemCount     int
+		expectedPages int
+	}{
+		{name: "classic_a4_invoice", layout: "classic_a4", status: "Unpaid", itemCount: 40, expectedPages: 3},
+		{name: "modern_a4_invoice", layout: "modern_a4", status: "Unpaid", itemCount: 40, expectedPages: 3},
+		{name: "letter_receipt", layout: "letter", status: "Paid", itemCount: 3, expectedPages: 1},
+		{name: "thermal_80mm_receipt", layout: "thermal_80mm", status: "Paid", itemCount: 5, expectedPages: 1},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("InvoicePDFLayoutTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoiceDoc(tc.status, tc.itemCount)),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{
+						{Key: "_id", Value: companyID},
+						{Key: "name", Value: "Test Tech Solutions"},
+						{Key: "address", Value: "123 Business District, Tech City"},
+						{Key: "email", Value: "info@testtech.com"},
+						{Key: "tin", Value: "0000111122"},
+						{Key: "branding", Value: bson.D{
+							{Key: "accent_color", Value: "#1A73E8"},
+							{Key: "footer_text", Value: "Test Tech Solutions PLC"},
+							{Key: "payment_instructions", Value: "Pay within 30 days quoting the invoice reference."},
+						}},
+					}),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, bson.D{
+						{Key: "_id", Value: customerID},
+						{Key: "name", Value: "Test Customer"},
+						{Key: "address", Value: "456 Market Street"},
+						{Key: "tin", Value: "0012345678"},
+					}),
+				)
+
+				req, _ := http.NewRequest("GET", "/invoice/download/"+invoiceID.Hex()+"?layout="+tc.layout, nil)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, http.StatusOK, w.Code)
+				assert.Equal(t, tc.expectedPages, strings.Count(w.Body.String(), "/Type /Page\n"))
+
+				golden := filepath.Join("testdata", tc.name+".golden.pdf")
+				if *updateGolden {
+					assert.NoError(t, os.MkdirAll("testdata", 0o755))
+					assert.NoError(t, os.WriteFile(golden, w.Body.Bytes(), 0o644))
+				}
+				expected, err := os.ReadFile(golden)
+				if assert.NoError(t, err, "missing golden file, run with -update") {
+					assert.True(t, bytes.Equal(expected, w.Body.Bytes()), "%s differs from the golden file, run with -update if the change is intended", tc.name)
+				}
+			})
+		}
+	})
+}
+
+func TestDownloadInvoiceUnknownLayout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/invoice/download/:id", controllers.DownloadInvoice)
+
+	req, _ := http.NewRequest("GET", "/invoice/download/"+primitive.NewObjectID().Hex()+"?layout=a5_landscape", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// pdfText is how a UTF-8 font writes text into an uncompressed content stream
+func pdfText(s string) []byte {
+	var b []byte
+	for _, r := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(r>>8), byte(r))
+	}
+	return b
+}
+
+func TestInvoicePDFUnicodeText(t *testing.T) {
+	controllers.PDFCompression = false
+	defer func() { controllers.PDFCompression = true }()
+	// EthiopicTest.ttf maps the Ethiopic block onto Latin glyphs, standing in
+	// for a real font such as Noto Sans Ethiopic dropped into PDF_FONT_DIR
+	assert.NoError(t, controllers.LoadPDFFonts("testdata/fonts"))
+	defer controllers.LoadPDFFonts("")
+
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.GET("/invoice/download/:id", controllers.DownloadInvoice)
+
+	invoiceID := primitive.NewObjectID()
+	customerID := primitive.NewObjectID()
+
+	// Test cases
+	testCases := []struct {
+		name         string
+		companyFont  string
+		customerName string
+		itemName     string
+		expectedFont string
+	}{
+		{name: "Accented Latin", customerName: "Société Générale Crème", itemName: "Café Müller Ñandú", expectedFont: controllers.DefaultPDFFont},
+		{name: "Greek And Cyrillic", customerName: "Αθηνά Παπαδοπούλου", itemName: "Борщ и пельмени", expectedFont: controllers.DefaultPDFFont},
+		{name: "Amharic Falls Back To A Font With Ethiopic Glyphs", customerName: "አበበ በቀለ", itemName: "ቡና ጀበና", expectedFont: "ethiopic_test"},
+		{name: "Company Font", companyFont: "ethiopic_test", customerName: "Test Customer", itemName: "Coffee", expectedFont: "ethiopic_test"},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("InvoicePDFUnicodeTextTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, bson.D{
+						{Key: "_id", Value: invoiceID},
+						{Key: "company_id", Value: companyID.Hex()},
+						{Key: "customer_id", Value: customerID.Hex()},
+						{Key: "reference_number", Value: "INV-001"},
+						{Key: "status", Value: "Unpaid"},
+						{Key: "amount", Value: 25.0},
+						{Key: "items", Value: bson.A{bson.D{
+							{Key: "item_name", Value: tc.itemName},
+							{Key: "quantity", Value: 1},
+							{Key: "unit_price", Value: 25.0},
+							{Key: "subtotal", Value: 25.0},
+						}}},
+					}),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{
+						{Key: "_id", Value: companyID},
+						{Key: "name", Value: "Test Tech Solutions"},
+						{Key: "branding", Value: bson.D{{Key: "font", Value: tc.companyFont}}},
+					}),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, bson.D{
+						{Key: "_id", Value: customerID},
+						{Key: "name", Value: tc.customerName},
+					}),
+				)
+
+				req, _ := http.NewRequest("GET", "/invoice/download/"+invoiceID.Hex(), nil)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, http.StatusOK, w.Code)
+				body := w.Body.Bytes()
+				assert.Contains(t, w.Body.String(), "/Encoding /Identity-H")
+				assert.Contains(t, w.Body.String(), "/BaseFont /utf8"+tc.expectedFont+"\n")
+				assert.True(t, bytes.Contains(body, pdfText(tc.customerName)), "customer name is not in the PDF")
+				assert.True(t, bytes.Contains(body, pdfText(tc.itemName)), "item name is not in the PDF")
+			})
+		}
+	})
+}
+
+func TestListPDFFonts(t *testing.T) {
+	assert.NoError(t, controllers.LoadPDFFonts("testdata/fonts"))
+	defer controllers.LoadPDFFonts("")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/invoice/fonts", controllers.ListPDFFonts)
+
+	req, _ := http.NewRequest("GET", "/invoice/fonts", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var fonts []controllers.PDFFont
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &fonts))
+	if assert.Len(t, fonts, 2) {
+		assert.Equal(t, controllers.DefaultPDFFont, fonts[0].Name)
+		assert.Equal(t, "bundled", fonts[0].Source)
+		assert.True(t, fonts[0].Bold)
+		assert.Subset(t, fonts[0].Scripts, []string{"Latin", "Latin Extended", "Greek", "Cyrillic"})
+		assert.NotContains(t, fonts[0].Scripts, "Ethiopic")
+
+		assert.Equal(t, "ethiopic_test", fonts[1].Name)
+		assert.Equal(t, "font_dir", fonts[1].Source)
+		assert.Contains(t, fonts[1].Scripts, "Ethiopic")
+	}
+}
@@ -0,0 +1,428 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestReturnInvoiceItems(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.POST("/invoice/return/:id", controllers.ReturnInvoiceItems)
+
+	invoiceID := primitive.NewObjectID()
+	invoice := func(status, paymentType string, returned int) bson.D {
+		return bson.D{
+			{Key: "_id", Value: invoiceID},
+			{Key: "customer_id", Value: primitive.NewObjectID().Hex()},
+			{Key: "company_id", Value: companyID.Hex()},
+			{Key: "reference_number", Value: "INV-7"},
+			{Key: "status", Value: status},
+			{Key: "payment_type", Value: paymentType},
+			{Key: "amount", Value: 230.0},
+			{Key: "items", Value: bson.A{
+				bson.D{
+					{Key: "item_id", Value: primitive.NewObjectID().Hex()},
+					{Key: "item_name", Value: "Chair"},
+					{Key: "quantity", Value: 2},
+					{Key: "unit_price", Value: 50.0},
+					{Key: "subtotal", Value: 100.0},
+					{Key: "tax_amount", Value: 15.0},
+					{Key: "returned_quantity", Value: returned},
+				},
+				bson.D{
+					{Key: "item_id", Value: primitive.NewObjectID().Hex()},
+					{Key: "item_name", Value: "Table"},
+					{Key: "quantity", Value: 1},
+					{Key: "unit_price", Value: 100.0},
+					{Key: "subtotal", Value: 100.0},
+					{Key: "tax_amount", Value: 15.0},
+				},
+				bson.D{
+					{Key: "item_id", Value: primitive.NewObjectID().Hex()},
+					{Key: "item_name", Value: "Free Sample"},
+					{Key: "quantity", Value: 1},
+				},
+			}},
+		}
+	}
+	modified := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+	reserved := func(issued int) bson.D {
+		return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{{Key: "_id", Value: invoiceID}, {Key: "credit_notes_issued", Value: issued}}})
+	}
+
+	// Test cases
+	testCases := []struct {
+		name            string
+		requestBody     map[string]interface{}
+		expectedStatus  int
+		expectedCredit  float64
+		expectedApplied float64
+		expectedRefund  float64
+		expectedMethod  string
+		expectedUpdates int    // invoice updates, when checked
+		expectedNumber  string // credit note reference, when checked
+		setupMock       func(mt *mtest.T)
+	}{
+		{
+			name:           "Paid Invoice Refunded In Cash",
+			requestBody:    map[string]interface{}{"lines": []map[string]interface{}{{"line": 0, "quantity": 1}}, "refund_method": "cash", "restock": true},
+			expectedStatus: http.StatusCreated,
+			expectedCredit: 57.5,
+			expectedRefund: 57.5,
+			expectedMethod: "cash",
+			expectedNumber: "CN-INV-7-2",
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Paid", "cash", 0)),
+					reserved(2),
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(),
+					modified,
+				)
+			},
+		},
+		{
+			name:           "Original Method Of A Cash Sale",
+			requestBody:    map[string]interface{}{"lines": []map[string]interface{}{{"line": 1, "quantity": 1}}},
+			expectedStatus: http.StatusCreated,
+			expectedCredit: 115,
+			expectedRefund: 115,
+			expectedMethod: "cash",
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Paid", "cash", 0)),
+					reserved(1),
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:            "Unpaid Invoice Balance Reduced",
+			requestBody:     map[string]interface{}{"lines": []map[string]interface{}{{"line": 0, "quantity": 2}}},
+			expectedStatus:  http.StatusCreated,
+			expectedCredit:  115,
+			expectedApplied: 115,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Unpaid", "credit", 0)),
+					reserved(1),
+					mtest.CreateSuccessResponse(),
+					modified,
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:            "Free Line Leaves The Balance Alone",
+			requestBody:     map[string]interface{}{"lines": []map[string]interface{}{{"line": 2, "quantity": 1}}},
+			expectedStatus:  http.StatusCreated,
+			expectedUpdates: 1, // only the reservation; nothing is paid
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Unpaid", "credit", 0)),
+					reserved(1),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:            "Failed Credit Note Releases The Quantities",
+			requestBody:     map[string]interface{}{"lines": []map[string]interface{}{{"line": 0, "quantity": 1}}},
+			expectedStatus:  http.StatusInternalServerError,
+			expectedUpdates: 2, // the reservation and its release
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Paid", "cash", 0)),
+					reserved(1),
+					mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11000, Message: "duplicate key"}),
+					modified,
+				)
+			},
+		},
+		{
+			name:           "More Than Was Sold",
+			requestBody:    map[string]interface{}{"lines": []map[string]interface{}{{"line": 0, "quantity": 2}}},
+			expectedStatus: http.StatusBadRequest,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Paid", "cash", 1)))
+			},
+		},
+		{
+			name:           "No Such Line",
+			requestBody:    map[string]interface{}{"lines": []map[string]interface{}{{"line": 5, "quantity": 1}}},
+			expectedStatus: http.StatusBadRequest,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Paid", "cash", 0)))
+			},
+		},
+		{
+			name:           "Returned Meanwhile",
+			requestBody:    map[string]interface{}{"lines": []map[string]interface{}{{"line": 0, "quantity": 1}}},
+			expectedStatus: http.StatusConflict,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Paid", "cash", 0)),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+				)
+			},
+		},
+		{
+			name:           "Unknown Refund Method",
+			requestBody:    map[string]interface{}{"lines": []map[string]interface{}{{"line": 0, "quantity": 1}}, "refund_method": "cheque"},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "No Lines",
+			requestBody:    map[string]interface{}{"lines": []map[string]interface{}{}},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("ReturnInvoiceItemsTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+				mt.ClearEvents()
+
+				jsonData, _ := json.Marshal(tc.requestBody)
+				req, _ := http.NewRequest("POST", "/invoice/return/"+invoiceID.Hex(), bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedUpdates > 0 {
+					updates := 0
+					for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+						switch event.CommandName {
+						case "update", "findAndModify":
+							if event.Command.Lookup(event.CommandName).StringValue() == "invoices" {
+								updates++
+							}
+						}
+					}
+					assert.Equal(t, tc.expectedUpdates, updates)
+				}
+
+				if tc.expectedStatus == http.StatusCreated {
+					var response struct {
+						CreditNote       models.Invoice `json:"credit_note"`
+						AppliedToBalance float64        `json:"applied_to_balance"`
+						Refund           *models.Refund `json:"refund"`
+					}
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					assert.Equal(t, "credit_note", response.CreditNote.DocumentType)
+					assert.Equal(t, invoiceID.Hex(), response.CreditNote.OriginalInvoiceID)
+					assert.Equal(t, tc.expectedCredit, response.CreditNote.Amount)
+					if tc.expectedNumber != "" {
+						assert.Equal(t, tc.expectedNumber, response.CreditNote.ReferenceNumber)
+					}
+					assert.Equal(t, tc.expectedApplied, response.AppliedToBalance)
+					if tc.expectedRefund > 0 {
+						if assert.NotNil(t, response.Refund) {
+							assert.Equal(t, tc.expectedRefund, response.Refund.Amount)
+							assert.Equal(t, tc.expectedMethod, response.Refund.Method)
+						}
+					} else {
+						assert.Nil(t, response.Refund)
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestGenerateInvoiceTakesStock(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.POST("/invoice/generate", controllers.GenerateInvoice)
+
+	firstID := primitive.NewObjectID()
+	secondID := primitive.NewObjectID()
+	modified := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+	notMatched := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0})
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		expectedStatus int
+		expectedStock  []int32 // $inc applied to the items, in order
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "Enough Stock",
+			expectedStatus: http.StatusOK,
+			expectedStock:  []int32{-2, -1},
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{{Key: "_id", Value: companyID}}),
+					modified,
+					modified,
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Item Without Stock Tracking",
+			expectedStatus: http.StatusOK,
+			expectedStock:  []int32{-2, -1},
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{{Key: "_id", Value: companyID}}),
+					modified,
+					notMatched,
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".items", mtest.FirstBatch),
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Not Enough Stock Puts Taken Stock Back",
+			expectedStatus: http.StatusBadRequest,
+			expectedStock:  []int32{-2, -1, 2},
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{{Key: "_id", Value: companyID}}),
+					modified,
+					notMatched,
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".items", mtest.FirstBatch, bson.D{{Key: "n", Value: 1}}),
+					modified,
+				)
+			},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GenerateInvoiceStockTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+				mt.ClearEvents()
+
+				jsonData, _ := json.Marshal(models.Invoice{
+					CustomerID:      primitive.NewObjectID().Hex(),
+					CompanyID:       companyID.Hex(),
+					ReferenceNumber: "INV-2025-020",
+					PaymentType:     "cash",
+					Items: []models.InvoiceItem{
+						{ItemID: firstID.Hex(), ItemName: "Printer Paper", Quantity: 2, UnitPrice: 10},
+						{ItemID: secondID.Hex(), ItemName: "Toner", Quantity: 1, UnitPrice: 80},
+					},
+				})
+				req, _ := http.NewRequest("POST", "/invoice/generate", bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				var stock []int32
+				for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+					if event.CommandName == "update" && event.Command.Lookup("update").StringValue() == "items" {
+						update := event.Command.Lookup("updates").Array().Index(0).Value().Document()
+						stock = append(stock, update.Lookup("u", "$inc", "stock").Int32())
+					}
+				}
+				assert.Equal(t, tc.expectedStock, stock)
+			})
+		}
+	})
+}
+
+func TestDownloadRefundReceipt(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.GET("/invoice/refund/download/:id", controllers.DownloadRefundReceipt)
+
+	refundID := primitive.NewObjectID()
+	creditNoteID := primitive.NewObjectID()
+	date := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("Receipt", func(mt *mtest.T) {
+		config.DB = mt.DB
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".refunds", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: refundID},
+				{Key: "company_id", Value: companyID.Hex()},
+				{Key: "credit_note_id", Value: creditNoteID.Hex()},
+				{Key: "amount", Value: 57.5},
+				{Key: "method", Value: "cash"},
+				{Key: "created_at", Value: date},
+			}),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: creditNoteID},
+				{Key: "company_id", Value: companyID.Hex()},
+				{Key: "customer_id", Value: primitive.NewObjectID().Hex()},
+				{Key: "reference_number", Value: "CN-INV-7-1"},
+				{Key: "document_type", Value: "credit_note"},
+				{Key: "status", Value: "Issued"},
+				{Key: "amount", Value: 57.5},
+				{Key: "date", Value: date},
+			}),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: companyID},
+				{Key: "name", Value: "Acme"},
+			}),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch),
+		)
+
+		req, _ := http.NewRequest("GET", "/invoice/refund/download/"+refundID.Hex(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "refund_CN-INV-7-1.pdf")
+	})
+
+	mt.Run("Not Found", func(mt *mtest.T) {
+		config.DB = mt.DB
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".refunds", mtest.FirstBatch))
+
+		req, _ := http.NewRequest("GET", "/invoice/refund/download/"+refundID.Hex(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
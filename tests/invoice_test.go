@@ -20,7 +20,9 @@ import (
 func TestGenerateInvoice(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
 	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
 	router.POST("/invoice/generate", controllers.GenerateInvoice)
 	// Test cases
 	testCases := []struct {
@@ -32,7 +34,7 @@ func TestGenerateInvoice(t *testing.T) {
 			name: "Valid Invoice Generation",
 			requestBody: models.Invoice{
 				CustomerID:      primitive.NewObjectID().Hex(),
-				CompanyID:       primitive.NewObjectID().Hex(),
+				CompanyID:       companyID.Hex(),
 				ReferenceNumber: "INV-2025-001",
 				PaymentType:     "cash",
 				Terms:           "Net 30",
@@ -55,10 +57,19 @@ func TestGenerateInvoice(t *testing.T) {
 			},
 			expectedStatus: http.StatusOK,
 			setupMock: func(mt *mtest.T) {
-				mt.AddMockResponses(bson.D{
-					{Key: "ok", Value: 1},
-					{Key: "insertedId", Value: primitive.NewObjectID()},
-				})
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{
+					{Key: "_id", Value: primitive.NewObjectID()},
+					{Key: "name", Value: "Test Tech Solutions"},
+				}),
+					// The items do not track stock
+					mtest.CreateSuccessResponse(),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".items", mtest.FirstBatch),
+					mtest.CreateSuccessResponse(),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".items", mtest.FirstBatch),
+					bson.D{
+						{Key: "ok", Value: 1},
+						{Key: "insertedId", Value: primitive.NewObjectID()},
+					})
 			},		},
 	}
 	// Run tests using MongoDB mock
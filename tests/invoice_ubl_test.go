@@ -0,0 +1,320 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// ublSequences is the element order the UBL 2.1 schemas prescribe for the
+// aggregates the export writes, restricted to the elements BIS Billing 3.0
+// allows. Children not listed here are a schema violation too.
+var ublSequences = map[string][]string{
+	"Invoice": {"CustomizationID", "ProfileID", "ID", "IssueDate", "DueDate", "InvoiceTypeCode", "Note", "DocumentCurrencyCode",
+		"BuyerReference", "AccountingSupplierParty", "AccountingCustomerParty", "PaymentMeans", "PaymentTerms", "TaxTotal",
+		"LegalMonetaryTotal", "InvoiceLine"},
+	"CreditNote": {"CustomizationID", "ProfileID", "ID", "IssueDate", "CreditNoteTypeCode", "Note", "DocumentCurrencyCode",
+		"BuyerReference", "AccountingSupplierParty", "AccountingCustomerParty", "PaymentMeans", "PaymentTerms", "TaxTotal",
+		"LegalMonetaryTotal", "CreditNoteLine"},
+	"Party":              {"EndpointID", "PartyName", "PostalAddress", "PartyTaxScheme", "PartyLegalEntity", "Contact"},
+	"PostalAddress":      {"StreetName", "Country"},
+	"PartyTaxScheme":     {"CompanyID", "TaxScheme"},
+	"Contact":            {"Name", "Telephone", "ElectronicMail"},
+	"PaymentMeans":       {"PaymentMeansCode", "PaymentID", "PayeeFinancialAccount"},
+	"TaxTotal":           {"TaxAmount", "TaxSubtotal"},
+	"TaxSubtotal":        {"TaxableAmount", "TaxAmount", "TaxCategory"},
+	"TaxCategory":        {"ID", "Percent", "TaxExemptionReason", "TaxScheme"},
+	"LegalMonetaryTotal": {"LineExtensionAmount", "TaxExclusiveAmount", "TaxInclusiveAmount", "PrepaidAmount", "PayableAmount"},
+	"InvoiceLine":        {"ID", "InvoicedQuantity", "LineExtensionAmount", "Item", "Price"},
+	"CreditNoteLine":     {"ID", "CreditedQuantity", "LineExtensionAmount", "Item", "Price"},
+	"Item":               {"Name", "SellersItemIdentification", "ClassifiedTaxCategory"},
+	"Price":              {"PriceAmount", "AllowanceCharge"},
+	"AllowanceCharge":    {"ChargeIndicator", "Amount", "BaseAmount"},
+}
+
+// checkXMLSequence walks the document and reports children that are out of
+// schema order or not allowed in their parent.
+func checkXMLSequence(t *testing.T, data []byte, sequences map[string][]string) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	type frame struct {
+		name string
+		last int
+	}
+	var stack []*frame
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			return
+		}
+		switch el := token.(type) {
+		case xml.StartElement:
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				if sequence, ok := sequences[parent.name]; ok {
+					position := -1
+					for i, name := range sequence {
+						if name == el.Name.Local {
+							position = i
+						}
+					}
+					assert.NotEqual(t, -1, position, "%s not allowed in %s", el.Name.Local, parent.name)
+					assert.GreaterOrEqual(t, position, parent.last, "%s out of order in %s", el.Name.Local, parent.name)
+					parent.last = position
+				}
+			}
+			stack = append(stack, &frame{name: el.Name.Local})
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+}
+
+// validateWithXSD runs xmllint against the official UBL 2.1 schemas when
+// UBL_XSD_DIR points at the xsd directory of the OASIS distribution.
+func validateWithXSD(t *testing.T, data []byte, root string) {
+	dir := os.Getenv("UBL_XSD_DIR")
+	if dir == "" {
+		t.Log("UBL_XSD_DIR not set, skipping XSD validation")
+		return
+	}
+	xmllint, err := exec.LookPath("xmllint")
+	if err != nil {
+		t.Skip("xmllint not installed")
+	}
+	file := filepath.Join(t.TempDir(), "document.xml")
+	assert.NoError(t, os.WriteFile(file, data, 0o600))
+	schema := filepath.Join(dir, "maindoc", "UBL-"+root+"-2.1.xsd")
+	out, err := exec.Command(xmllint, "--noout", "--schema", schema, file).CombinedOutput()
+	assert.NoError(t, err, string(out))
+}
+
+type ublTestDocument struct {
+	XMLName       xml.Name
+	ID            string `xml:"ID"`
+	DueDate       string `xml:"DueDate"`
+	TypeCode      string `xml:"InvoiceTypeCode"`
+	CreditCode    string `xml:"CreditNoteTypeCode"`
+	Currency      string `xml:"DocumentCurrencyCode"`
+	SupplierVAT   string `xml:"AccountingSupplierParty>Party>PartyTaxScheme>CompanyID"`
+	SupplierEmail string `xml:"AccountingSupplierParty>Party>EndpointID"`
+	TaxTotal      string `xml:"TaxTotal>TaxAmount"`
+	Subtotals     []struct {
+		Taxable  string `xml:"TaxableAmount"`
+		Tax      string `xml:"TaxAmount"`
+		Category string `xml:"TaxCategory>ID"`
+		Percent  string `xml:"TaxCategory>Percent"`
+		Reason   string `xml:"TaxCategory>TaxExemptionReason"`
+	} `xml:"TaxTotal>TaxSubtotal"`
+	LineTotal string `xml:"LegalMonetaryTotal>LineExtensionAmount"`
+	Inclusive string `xml:"LegalMonetaryTotal>TaxInclusiveAmount"`
+	Prepaid   string `xml:"LegalMonetaryTotal>PrepaidAmount"`
+	Payable   string `xml:"LegalMonetaryTotal>PayableAmount"`
+}
+
+func TestDownloadInvoiceUBL(t *testing.T) {
+	t.Setenv("INVOICE_CURRENCY", "ETB")
+	t.Setenv("INVOICE_COUNTRY", "ET")
+
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.GET("/invoice/download/:id/ubl", controllers.DownloadInvoiceUBL)
+
+	invoiceID := primitive.NewObjectID()
+	customerID := primitive.NewObjectID()
+	dueDate := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	items := bson.A{
+		bson.D{{Key: "item_id", Value: "SKU-1"}, {Key: "item_name", Value: "Consulting"}, {Key: "quantity", Value: 2}, {Key: "unit_price", Value: 500.0},
+			{Key: "discount", Value: 10.0}, {Key: "subtotal", Value: 900.0}, {Key: "tax_rate", Value: 15.0}, {Key: "tax_category", Value: "standard"}},
+		bson.D{{Key: "item_name", Value: "Export Freight"}, {Key: "quantity", Value: 1}, {Key: "unit_price", Value: 200.0},
+			{Key: "subtotal", Value: 200.0}, {Key: "tax_category", Value: "zero_rated"}},
+		bson.D{{Key: "item_name", Value: "Medical Supplies"}, {Key: "quantity", Value: 3}, {Key: "unit_price", Value: 33.333},
+			{Key: "subtotal", Value: 99.999}, {Key: "tax_category", Value: "exempt"}},
+	}
+	invoiceDoc := func(documentType, status string) bson.D {
+		return bson.D{
+			{Key: "_id", Value: invoiceID},
+			{Key: "company_id", Value: companyID.Hex()},
+			{Key: "customer_id", Value: customerID.Hex()},
+			{Key: "reference_number", Value: "INV-2025-010"},
+			{Key: "date", Value: time.Date(2025, 5, 2, 0, 0, 0, 0, time.UTC)},
+			{Key: "due_date", Value: dueDate},
+			{Key: "terms", Value: "Net 30"},
+			{Key: "status", Value: status},
+			{Key: "document_type", Value: documentType},
+			{Key: "items", Value: items},
+		}
+	}
+	customerDoc := bson.D{{Key: "_id", Value: customerID}, {Key: "name", Value: "Acme Trading"}, {Key: "email", Value: "ap@acme.example"},
+		{Key: "address", Value: "Bole Road 12"}, {Key: "tin", Value: "0011223344"}}
+	companyDoc := func(email string) bson.D {
+		return bson.D{{Key: "_id", Value: companyID}, {Key: "name", Value: "Test Tech Solutions"}, {Key: "email", Value: email},
+			{Key: "tin", Value: "0000111122"}, {Key: "branding", Value: bson.D{{Key: "bank_accounts", Value: bson.A{
+				bson.D{{Key: "bank_name", Value: "CBE"}, {Key: "account_name", Value: "Test Tech"}, {Key: "account_number", Value: "1000123456"}},
+			}}}}}
+	}
+	responses := func(mt *mtest.T, invoice, company bson.D) {
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customerDoc),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, company),
+		)
+	}
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		invoiceID      string
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+		check          func(t *testing.T, w *httptest.ResponseRecorder)
+	}{
+		{
+			name:           "Unpaid Invoice",
+			invoiceID:      invoiceID.Hex(),
+			expectedStatus: http.StatusOK,
+			setupMock:      func(mt *mtest.T) { responses(mt, invoiceDoc("", "Unpaid"), companyDoc("billing@testtech.example")) },
+			check: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert.Equal(t, "attachment; filename=invoice_INV-2025-010.xml", w.Header().Get("Content-Disposition"))
+				checkXMLSequence(t, w.Body.Bytes(), ublSequences)
+				validateWithXSD(t, w.Body.Bytes(), "Invoice")
+
+				var doc ublTestDocument
+				assert.NoError(t, xml.Unmarshal(w.Body.Bytes(), &doc))
+				assert.Equal(t, "Invoice", doc.XMLName.Local)
+				assert.Equal(t, "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2", doc.XMLName.Space)
+				assert.Equal(t, "380", doc.TypeCode)
+				assert.Equal(t, "2025-06-01", doc.DueDate)
+				assert.Equal(t, "ETB", doc.Currency)
+				assert.Equal(t, "ET0000111122", doc.SupplierVAT)
+				assert.Equal(t, "billing@testtech.example", doc.SupplierEmail)
+
+				// One subtotal per category, exempt amounts with a reason
+				assert.Len(t, doc.Subtotals, 3)
+				assert.Equal(t, "E", doc.Subtotals[0].Category)
+				assert.Equal(t, "100.00", doc.Subtotals[0].Taxable)
+				assert.NotEmpty(t, doc.Subtotals[0].Reason)
+				assert.Equal(t, "S", doc.Subtotals[1].Category)
+				assert.Equal(t, "15", doc.Subtotals[1].Percent)
+				assert.Equal(t, "135.00", doc.Subtotals[1].Tax)
+				assert.Equal(t, "Z", doc.Subtotals[2].Category)
+				assert.Equal(t, "0.00", doc.Subtotals[2].Tax)
+
+				assert.Equal(t, "135.00", doc.TaxTotal)
+				assert.Equal(t, "1200.00", doc.LineTotal)
+				assert.Equal(t, "1335.00", doc.Inclusive)
+				assert.Equal(t, "", doc.Prepaid)
+				assert.Equal(t, "1335.00", doc.Payable)
+				assert.Contains(t, w.Body.String(), "<cbc:PriceAmount currencyID=\"ETB\">450.00</cbc:PriceAmount>")
+				assert.Contains(t, w.Body.String(), "<cbc:BaseAmount currencyID=\"ETB\">500.00</cbc:BaseAmount>")
+				assert.Contains(t, w.Body.String(), "<cbc:ID>1000123456</cbc:ID>")
+			},
+		},
+		{
+			name:           "Paid Invoice",
+			invoiceID:      invoiceID.Hex(),
+			expectedStatus: http.StatusOK,
+			setupMock:      func(mt *mtest.T) { responses(mt, invoiceDoc("", "Paid"), companyDoc("billing@testtech.example")) },
+			check: func(t *testing.T, w *httptest.ResponseRecorder) {
+				var doc ublTestDocument
+				assert.NoError(t, xml.Unmarshal(w.Body.Bytes(), &doc))
+				assert.Equal(t, "1335.00", doc.Prepaid)
+				assert.Equal(t, "0.00", doc.Payable)
+			},
+		},
+		{
+			name:           "Credit Note",
+			invoiceID:      invoiceID.Hex(),
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				responses(mt, invoiceDoc("credit_note", ""), companyDoc("billing@testtech.example"))
+			},
+			check: func(t *testing.T, w *httptest.ResponseRecorder) {
+				assert.Equal(t, "attachment; filename=credit_note_INV-2025-010.xml", w.Header().Get("Content-Disposition"))
+				checkXMLSequence(t, w.Body.Bytes(), ublSequences)
+				validateWithXSD(t, w.Body.Bytes(), "CreditNote")
+
+				var doc ublTestDocument
+				assert.NoError(t, xml.Unmarshal(w.Body.Bytes(), &doc))
+				assert.Equal(t, "CreditNote", doc.XMLName.Local)
+				assert.Equal(t, "381", doc.CreditCode)
+				assert.Equal(t, "", doc.DueDate)
+				assert.Contains(t, w.Body.String(), "<cbc:CreditedQuantity unitCode=\"C62\">2</cbc:CreditedQuantity>")
+				assert.NotContains(t, w.Body.String(), "InvoiceLine")
+			},
+		},
+		{
+			name:           "Company Without Email",
+			invoiceID:      invoiceID.Hex(),
+			expectedStatus: http.StatusUnprocessableEntity,
+			setupMock:      func(mt *mtest.T) { responses(mt, invoiceDoc("", "Unpaid"), companyDoc("")) },
+			check: func(t *testing.T, w *httptest.ResponseRecorder) {
+				var response map[string]interface{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				violations, _ := response["violations"].([]interface{})
+				if assert.Len(t, violations, 1) {
+					assert.True(t, strings.HasPrefix(violations[0].(string), "PEPPOL-EN16931-R020"))
+				}
+			},
+		},
+		{
+			name:           "Invalid Invoice ID",
+			invoiceID:      "invalid-id",
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Invoice Not Found",
+			invoiceID:      invoiceID.Hex(),
+			expectedStatus: http.StatusNotFound,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch))
+			},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("DownloadInvoiceUBLTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				req, _ := http.NewRequest("GET", "/invoice/download/"+tc.invoiceID+"/ubl", nil)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+				if tc.check != nil {
+					tc.check(t, w)
+				}
+			})
+		}
+	})
+}
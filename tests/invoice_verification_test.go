@@ -0,0 +1,184 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestVerifyInvoice(t *testing.T) {
+	t.Setenv("INVOICE_VERIFICATION_SECRET", "test-secret")
+
+	// Setup
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/invoice/verify", controllers.VerifyInvoice)
+
+	companyID := primitive.NewObjectID()
+	invoice := models.Invoice{
+		ID:              primitive.NewObjectID(),
+		CompanyID:       companyID.Hex(),
+		CustomerID:      primitive.NewObjectID().Hex(),
+		ReferenceNumber: "INV-2025-007",
+		Date:            time.Date(2025, 5, 2, 0, 0, 0, 0, time.UTC),
+		Amount:          1150,
+	}
+	company := models.Company{ID: companyID, Name: "Test Tech Solutions", TIN: "0000111122"}
+
+	verifyPath := func(edit func(url.Values)) string {
+		link, _ := url.Parse(controllers.InvoiceVerificationURL(invoice, company))
+		query := link.Query()
+		if edit != nil {
+			edit(query)
+		}
+		return "/invoice/verify?" + query.Encode()
+	}
+	invoiceDoc := func(amount float64) bson.D {
+		return bson.D{
+			{Key: "_id", Value: invoice.ID},
+			{Key: "company_id", Value: invoice.CompanyID},
+			{Key: "customer_id", Value: invoice.CustomerID},
+			{Key: "reference_number", Value: invoice.ReferenceNumber},
+			{Key: "date", Value: invoice.Date},
+			{Key: "amount", Value: amount},
+		}
+	}
+	companyDoc := bson.D{{Key: "_id", Value: companyID}, {Key: "name", Value: company.Name}, {Key: "tin", Value: company.TIN}}
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedResult string
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "Genuine Invoice",
+			path:           verifyPath(nil),
+			expectedStatus: http.StatusOK,
+			expectedResult: "genuine",
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoiceDoc(1150)),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc),
+				)
+			},
+		},
+		{
+			name:           "Amount Edited In Link",
+			path:           verifyPath(func(q url.Values) { q.Set("amount", "115.00") }),
+			expectedStatus: http.StatusOK,
+			expectedResult: "invalid_signature",
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Invoice Changed Since Issue",
+			path:           verifyPath(nil),
+			expectedStatus: http.StatusOK,
+			expectedResult: "altered",
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoiceDoc(1300)),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, companyDoc),
+				)
+			},
+		},
+		{
+			name:           "Invoice Does Not Exist",
+			path:           verifyPath(nil),
+			expectedStatus: http.StatusNotFound,
+			expectedResult: "not_found",
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch))
+			},
+		},
+		{
+			name:           "Missing Signature",
+			path:           verifyPath(func(q url.Values) { q.Del("sig") }),
+			expectedStatus: http.StatusBadRequest,
+			expectedResult: "invalid_link",
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("VerifyInvoiceTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				req, _ := http.NewRequest("GET", tc.path, nil)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+				var response map[string]interface{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tc.expectedResult, response["result"])
+				assert.Equal(t, tc.expectedResult == "genuine", response["valid"])
+				// Only what the printed document already shows comes back
+				assert.NotContains(t, w.Body.String(), invoice.CustomerID)
+				assert.NotContains(t, w.Body.String(), company.Name)
+			})
+		}
+	})
+}
+
+func TestInvoiceVerificationURL(t *testing.T) {
+	t.Setenv("INVOICE_VERIFICATION_SECRET", "test-secret")
+	t.Setenv("API_BASE_URL", "https://billing.example.com/api/")
+
+	invoice := models.Invoice{ID: primitive.NewObjectID(), ReferenceNumber: "INV-1", Amount: 10, Date: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)}
+	link := controllers.InvoiceVerificationURL(invoice, models.Company{TIN: "123"})
+	assert.True(t, strings.HasPrefix(link, "https://billing.example.com/api/invoice/verify?"))
+
+	parsed, err := url.Parse(link)
+	assert.NoError(t, err)
+	query := parsed.Query()
+	assert.Equal(t, "INV-1", query.Get("number"))
+	assert.Equal(t, "10.00", query.Get("amount"))
+	assert.Equal(t, "2025-01-02", query.Get("date"))
+	assert.Equal(t, "123", query.Get("tin"))
+	assert.NotEmpty(t, query.Get("sig"))
+
+	// A different secret signs differently
+	t.Setenv("INVOICE_VERIFICATION_SECRET", "other-secret")
+	assert.NotEqual(t, link, controllers.InvoiceVerificationURL(invoice, models.Company{TIN: "123"}))
+}
+
+func TestCheckSecrets(t *testing.T) {
+	t.Setenv("INVOICE_VERIFICATION_SECRET", "")
+	t.Setenv("CERTIFICATE_KEY_SECRET", "test-secret")
+	err := controllers.CheckSecrets()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "INVOICE_VERIFICATION_SECRET")
+	}
+
+	t.Setenv("INVOICE_VERIFICATION_SECRET", "test-secret")
+	t.Setenv("CERTIFICATE_KEY_SECRET", "")
+	err = controllers.CheckSecrets()
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "CERTIFICATE_KEY_SECRET")
+	}
+
+	t.Setenv("CERTIFICATE_KEY_SECRET", "test-secret")
+	assert.NoError(t, controllers.CheckSecrets())
+}
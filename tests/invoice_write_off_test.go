@@ -0,0 +1,153 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/bisre1921/billing-and-invoice-system/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestWriteOffInvoice(t *testing.T) {
+	// Setup
+	t.Setenv("JWT_SECRET", "test-secret")
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.POST("/invoice/write-off/:id", middleware.OptionalAuthMiddleware(), controllers.WriteOffInvoice)
+
+	invoiceID := primitive.NewObjectID()
+	companyID := primitive.NewObjectID()
+	ownerToken := testToken(t, jwt.MapClaims{"user_id": primitive.NewObjectID().Hex(), "company_id": companyID.Hex(), "role": "owner"})
+	employeeToken := testToken(t, jwt.MapClaims{"user_id": primitive.NewObjectID().Hex(), "company_id": companyID.Hex(), "role": "employee"})
+	invoice := func(status string, company primitive.ObjectID) bson.D {
+		return bson.D{
+			{Key: "_id", Value: invoiceID},
+			{Key: "customer_id", Value: primitive.NewObjectID().Hex()},
+			{Key: "company_id", Value: company.Hex()},
+			{Key: "reference_number", Value: "INV-9"},
+			{Key: "status", Value: status},
+			{Key: "payment_type", Value: "credit"},
+			{Key: "amount", Value: 100.0},
+			{Key: "amount_paid", Value: 40.0},
+		}
+	}
+	modified := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+
+	// Test cases
+	testCases := []struct {
+		name               string
+		token              string
+		requestBody        map[string]interface{}
+		expectedStatus     int
+		expectedWrittenOff float64
+		setupMock          func(mt *mtest.T)
+	}{
+		{
+			name:               "Remaining Balance With Credit Hold",
+			token:              ownerToken,
+			requestBody:        map[string]interface{}{"reason": "Customer went out of business", "credit_hold": true},
+			expectedStatus:     http.StatusOK,
+			expectedWrittenOff: 60,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Unpaid", companyID)),
+					modified,
+					modified,
+					modified,
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "Employees Cannot Approve",
+			token:          employeeToken,
+			requestBody:    map[string]interface{}{"reason": "Uncollectable"},
+			expectedStatus: http.StatusForbidden,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "No Token",
+			requestBody:    map[string]interface{}{"reason": "Uncollectable"},
+			expectedStatus: http.StatusUnauthorized,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Missing Reason",
+			token:          ownerToken,
+			requestBody:    map[string]interface{}{},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Invoice Already Paid",
+			token:          ownerToken,
+			requestBody:    map[string]interface{}{"reason": "Uncollectable"},
+			expectedStatus: http.StatusBadRequest,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Paid", companyID)))
+			},
+		},
+		{
+			name:           "Invoice Of Another Company",
+			token:          ownerToken,
+			requestBody:    map[string]interface{}{"reason": "Uncollectable"},
+			expectedStatus: http.StatusForbidden,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Unpaid", primitive.NewObjectID())))
+			},
+		},
+		{
+			name:           "Paid Meanwhile",
+			token:          ownerToken,
+			requestBody:    map[string]interface{}{"reason": "Uncollectable"},
+			expectedStatus: http.StatusConflict,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice("Unpaid", companyID)),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+				)
+			},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("WriteOffInvoiceTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				jsonData, _ := json.Marshal(tc.requestBody)
+				req, _ := http.NewRequest("POST", "/invoice/write-off/"+invoiceID.Hex(), bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				if tc.token != "" {
+					req.Header.Set("Authorization", "Bearer "+tc.token)
+				}
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedStatus == http.StatusOK {
+					var response map[string]interface{}
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					assert.Equal(t, tc.expectedWrittenOff, response["written_off"])
+					assert.Equal(t, true, response["credit_hold"])
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,350 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestImportItems(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", primitive.NewObjectID().Hex())
+		c.Set("companyID", companyID.Hex())
+		c.Next()
+	})
+	router.POST("/item/import", controllers.ImportItems)
+
+	existingDoc := bson.D{
+		{Key: "_id", Value: primitive.NewObjectID()},
+		{Key: "company_id", Value: companyID},
+		{Key: "code", Value: "P-100"},
+		{Key: "name", Value: "Printer Paper"},
+		{Key: "category", Value: "Stationery"},
+		{Key: "selling_price", Value: 250.0},
+	}
+	csvFile := "code,name,category,selling_price,unit\n" +
+		"P-100,Printer Paper A4,Stationery,275,ream\n" +
+		"P-200,Toner,Supplies,1800,piece\n"
+	var large strings.Builder
+	large.WriteString("code,name,category,price\n")
+	for i := 0; i < 1200; i++ {
+		fmt.Fprintf(&large, "C-%d,Item %d,General,%d\n", i, i, i+1)
+	}
+	modified := mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1})
+	var tooLong strings.Builder
+	tooLong.WriteString("code,name,category,price\n")
+	for i := 0; i <= controllers.MaxImportRows; i++ {
+		tooLong.WriteString("C,Item,General,1\n")
+	}
+
+	// Prices and stock shown as #,##0.00, as in exports
+	formatted := excelize.NewFile()
+	defer formatted.Close()
+	formatted.SetSheetRow("Sheet1", "A1", &[]interface{}{"code", "name", "category", "selling_price", "stock"})
+	formatted.SetSheetRow("Sheet1", "A2", &[]interface{}{"T-1", "Hammer", "Tools", 1500.0, 2000})
+	moneyStyle, err := formatted.NewStyle(&excelize.Style{NumFmt: 4})
+	assert.NoError(t, err)
+	assert.NoError(t, formatted.SetCellStyle("Sheet1", "D2", "E2", moneyStyle))
+	formattedFile, err := formatted.WriteToBuffer()
+	assert.NoError(t, err)
+
+	// Test cases
+	testCases := []struct {
+		name            string
+		filename        string
+		content         []byte
+		fields          map[string]string
+		expectedStatus  int
+		expectedResult  *models.ImportResult
+		expectedErrRows []int
+		expectedWrites  int
+		setupMock       func(mt *mtest.T)
+	}{
+		{
+			name:            "Short Rows Are Row Errors",
+			filename:        "items.csv",
+			content:         []byte("code,name,category,selling_price,unit\nA-1,Widget\nA-2,Gadget,Tools,35.5,piece\n"),
+			expectedStatus:  http.StatusCreated,
+			expectedResult:  &models.ImportResult{Rows: 2, Created: 1, Failed: 1},
+			expectedErrRows: []int{2},
+			expectedWrites:  1,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".items", mtest.FirstBatch),
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+				)
+			},
+		},
+		{
+			name:           "Columns Matched By Header",
+			filename:       "items.csv",
+			content:        []byte("Price,Category,Name,Code,Stock\n12.5,Tools,Hammer,T-1,40\n"),
+			expectedStatus: http.StatusCreated,
+			expectedResult: &models.ImportResult{Rows: 1, Created: 1},
+			expectedWrites: 1,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".items", mtest.FirstBatch),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+				)
+			},
+		},
+		{
+			name:     "XLSX",
+			filename: "items.xlsx",
+			content: xlsxFile(t, [][]interface{}{
+				{"code", "name", "category", "selling_price"},
+				{"T-1", "Hammer", "Tools", 12.5},
+				{"T-2", "Saw", "Tools", 30},
+			}),
+			expectedStatus: http.StatusCreated,
+			expectedResult: &models.ImportResult{Rows: 2, Created: 2},
+			expectedWrites: 1,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".items", mtest.FirstBatch),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 2}),
+				)
+			},
+		},
+		{
+			name:            "Existing Code Without Upsert",
+			filename:        "items.csv",
+			content:         []byte(csvFile),
+			expectedStatus:  http.StatusCreated,
+			expectedResult:  &models.ImportResult{Rows: 2, Created: 1, Failed: 1},
+			expectedErrRows: []int{2},
+			expectedWrites:  1,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".items", mtest.FirstBatch, existingDoc),
+					mtest.CreateSuccessResponse(),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+				)
+			},
+		},
+		{
+			name:           "Upsert Updates Existing Code",
+			filename:       "items.csv",
+			content:        []byte(csvFile),
+			fields:         map[string]string{"mode": "upsert"},
+			expectedStatus: http.StatusCreated,
+			expectedResult: &models.ImportResult{Rows: 2, Created: 1, Updated: 1},
+			expectedWrites: 2, // a batch is sent as one insert and one update command
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".items", mtest.FirstBatch, existingDoc),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+					modified,
+				)
+			},
+		},
+		{
+			name:            "Dry Run",
+			filename:        "items.csv",
+			content:         []byte(csvFile + "P-200,Toner Again,Supplies,-5,piece\n"),
+			fields:          map[string]string{"mode": "upsert", "dry_run": "true"},
+			expectedStatus:  http.StatusOK,
+			expectedResult:  &models.ImportResult{DryRun: true, Rows: 3, Created: 1, Updated: 1, Failed: 1},
+			expectedErrRows: []int{4},
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".items", mtest.FirstBatch, existingDoc),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "All Or Nothing With Errors",
+			filename:       "items.csv",
+			content:        []byte(csvFile + "P-300,Stapler,Stationery,abc,piece\n"),
+			fields:         map[string]string{"mode": "upsert", "all_or_nothing": "true"},
+			expectedStatus: http.StatusBadRequest,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".items", mtest.FirstBatch, existingDoc),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name:           "All Or Nothing Rolls Back A Failed Write",
+			filename:       "items.csv",
+			content:        []byte(csvFile),
+			fields:         map[string]string{"mode": "upsert", "all_or_nothing": "true"},
+			expectedStatus: http.StatusInternalServerError,
+			expectedWrites: 2, // the failed insert and the update putting P-100 back
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".items", mtest.FirstBatch, existingDoc),
+					mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 11000, Message: "duplicate key"}),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+					modified,
+				)
+			},
+		},
+		{
+			name:           "Large Files Are Written In Batches",
+			filename:       "items.csv",
+			content:        []byte(large.String()),
+			expectedStatus: http.StatusCreated,
+			expectedResult: &models.ImportResult{Rows: 1200, Created: 1200},
+			expectedWrites: 3,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".items", mtest.FirstBatch),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 500}),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 500}),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 200}),
+				)
+			},
+		},
+		{
+			name:           "Missing Required Column",
+			filename:       "items.csv",
+			content:        []byte("code,name,category\nA-1,Widget,Tools\n"),
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "XLSX With Formatted Numbers",
+			filename:       "items.xlsx",
+			content:        formattedFile.Bytes(),
+			expectedStatus: http.StatusCreated,
+			expectedResult: &models.ImportResult{Rows: 1, Created: 1},
+			expectedWrites: 1,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".items", mtest.FirstBatch),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+				)
+			},
+		},
+		{
+			name:           "Too Many Rows",
+			filename:       "items.csv",
+			content:        []byte(tooLong.String()),
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Too Large File",
+			filename:       "items.csv",
+			content:        []byte(csvFile + strings.Repeat(" ", controllers.MaxImportFileSize)),
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Unknown Mode",
+			filename:       "items.csv",
+			content:        []byte(csvFile),
+			fields:         map[string]string{"mode": "replace"},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("ImportItemsTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+				mt.ClearEvents()
+
+				fields := map[string]string{"company_id": companyID.Hex()}
+				for name, value := range tc.fields {
+					fields[name] = value
+				}
+				req := importRequest(t, "/item/import", tc.filename, tc.content, fields)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				// Item writes are inserts, updates or deletes of the items
+				// collection; error reports go to import_reports
+				writes := 0
+				for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+					switch event.CommandName {
+					case "insert", "update":
+						if event.Command.Lookup(event.CommandName).StringValue() == "items" {
+							writes++
+						}
+					}
+				}
+				assert.Equal(t, tc.expectedWrites, writes)
+
+				if tc.expectedResult != nil {
+					var result models.ImportResult
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+					errorRows := map[int]bool{}
+					for _, rowError := range result.Errors {
+						errorRows[rowError.Row] = true
+					}
+					for _, row := range tc.expectedErrRows {
+						assert.True(t, errorRows[row], "expected an error for line %d", row)
+					}
+					result.Errors = nil
+					result.ErrorReportID = ""
+					assert.Equal(t, *tc.expectedResult, result)
+				}
+			})
+		}
+	})
+}
+
+func TestDownloadImportErrorReport(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.GET("/import/errors/:id", controllers.DownloadImportErrorReport)
+
+	reportID := primitive.NewObjectID()
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("DownloadImportErrorReportTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".import_reports", mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: reportID},
+			{Key: "company_id", Value: companyID},
+			{Key: "kind", Value: "items"},
+			{Key: "errors", Value: bson.A{
+				bson.D{{Key: "row", Value: 3}, {Key: "column", Value: "selling_price"}, {Key: "value", Value: "abc"}, {Key: "error", Value: "not a number"}},
+			}},
+		}))
+
+		req, _ := http.NewRequest("GET", "/import/errors/"+reportID.Hex(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "row,column,value,error\n3,selling_price,abc,not a number\n", w.Body.String())
+		assert.Contains(t, w.Header().Get("Content-Disposition"), "items_import_errors_")
+	})
+}
@@ -18,7 +18,9 @@ import (
 func TestAddItem(t *testing.T) {
 	// Setup
 	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
 	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
 	router.POST("/item/add", controllers.AddItem)
 	// Test cases
 	testCases := []struct {
@@ -37,7 +39,7 @@ func TestAddItem(t *testing.T) {
 				"buying_price":  19.99,
 				"quantity":      100,
 				"unit":          "pcs",
-				"company_id":    primitive.NewObjectID().Hex(),
+				"company_id":    companyID.Hex(),
 				"discount":      10.0,
 			},
 			expectedStatus: http.StatusCreated,
@@ -0,0 +1,349 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/bisre1921/billing-and-invoice-system/mailer"
+	"github.com/bisre1921/billing-and-invoice-system/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestSendInvoiceQueuesMail(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.POST("/invoice/send/:id", controllers.SendInvoice)
+
+	invoiceID := primitive.NewObjectID()
+	customerID := primitive.NewObjectID()
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("SendInvoiceTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: invoiceID},
+				{Key: "company_id", Value: companyID.Hex()},
+				{Key: "customer_id", Value: customerID.Hex()},
+				{Key: "reference_number", Value: "INV-001"},
+				{Key: "status", Value: "Paid"},
+				{Key: "amount", Value: 100.0},
+			}),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: customerID},
+				{Key: "name", Value: "Test Customer"},
+				{Key: "email", Value: "customer@example.com"},
+			}),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: companyID},
+				{Key: "name", Value: "Test Company"},
+				{Key: "email", Value: "billing@example.com"},
+			}),
+			// No customised template, so the default is used
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".email_templates", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+		)
+
+		req, _ := http.NewRequest("POST", "/invoice/send/"+invoiceID.Hex(), nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response map[string]interface{}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		assert.Equal(t, "Payment Receipt queued for delivery to customer@example.com", response["message"])
+		assert.NotEmpty(t, response["message_id"])
+
+		// The message is written to the outbox rather than sent in the request
+		insert := mt.GetStartedEvent()
+		for insert != nil && insert.CommandName != "insert" {
+			insert = mt.GetStartedEvent()
+		}
+		if assert.NotNil(t, insert) {
+			doc := insert.Command.Lookup("documents").Array().Index(0).Value().Document()
+			assert.Equal(t, "mail_outbox", insert.Command.Lookup("insert").StringValue())
+			assert.Equal(t, "Pending", doc.Lookup("status").StringValue())
+			assert.Equal(t, "Test Company", doc.Lookup("from_name").StringValue())
+			assert.Equal(t, "billing@example.com", doc.Lookup("reply_to").StringValue())
+			assert.Contains(t, doc.Lookup("html_body").StringValue(), "<p>Hello Test Customer,</p>")
+			assert.Contains(t, doc.Lookup("text_body").StringValue(), "Amount Paid: $100.00")
+			attachment := doc.Lookup("attachments").Array().Index(0).Value().Document()
+			assert.Equal(t, "receipt_INV-001.pdf", attachment.Lookup("filename").StringValue())
+			assert.Equal(t, "application/pdf", attachment.Lookup("content_type").StringValue())
+		}
+	})
+}
+
+func TestProcessMailOutbox(t *testing.T) {
+	transport := mailer.NewMemoryTransport()
+	mailer.SetDefault(transport)
+	defer mailer.SetDefault(nil)
+
+	outboxMessage := func(attempts int) bson.D {
+		return bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "category", Value: "invoice"},
+			{Key: "to", Value: bson.A{"customer@example.com"}},
+			{Key: "subject", Value: "Your Invoice"},
+			{Key: "text_body", Value: "Hello"},
+			{Key: "status", Value: "Sending"},
+			{Key: "attempts", Value: attempts},
+			{Key: "max_attempts", Value: 3},
+		}
+	}
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		attempts       int
+		sendErr        error
+		expectedStatus string
+		expectedSent   int
+	}{
+		{name: "Delivered", attempts: 0, expectedStatus: "Sent", expectedSent: 1},
+		{name: "Retried After Failure", attempts: 0, sendErr: errors.New("connection refused"), expectedStatus: "Pending"},
+		{name: "Dead Lettered After Last Attempt", attempts: 2, sendErr: errors.New("connection refused"), expectedStatus: "Dead"},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("ProcessMailOutboxTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				transport.Reset()
+				transport.Err = tc.sendErr
+				mt.ClearEvents()
+
+				mt.AddMockResponses(
+					mtest.CreateSuccessResponse(bson.E{Key: "value", Value: outboxMessage(tc.attempts)}),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+					// Nothing else is due
+					mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}),
+				)
+
+				now := time.Now()
+				attempted := controllers.ProcessMailOutbox(now)
+				assert.Equal(t, 1, attempted)
+				assert.Len(t, transport.Sent(), tc.expectedSent)
+
+				var update bson.Raw
+				for evt := mt.GetStartedEvent(); evt != nil; evt = mt.GetStartedEvent() {
+					if evt.CommandName == "update" {
+						update = evt.Command
+					}
+				}
+				if assert.NotNil(t, update) {
+					set := update.Lookup("updates").Array().Index(0).Value().Document().Lookup("u", "$set").Document()
+					assert.Equal(t, tc.expectedStatus, set.Lookup("status").StringValue())
+					assert.Equal(t, int32(tc.attempts+1), set.Lookup("attempts").Int32())
+					if tc.expectedStatus == "Pending" {
+						next := set.Lookup("next_attempt_at").Time()
+						assert.WithinDuration(t, now.Add(controllers.MailRetryBaseDelay), next, time.Second)
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestProcessMailOutboxUsesCompanyMailSettings(t *testing.T) {
+	transport := mailer.NewMemoryTransport()
+	mailer.SetDefault(transport)
+	defer mailer.SetDefault(nil)
+
+	companyID := primitive.NewObjectID()
+
+	// Test cases
+	testCases := []struct {
+		name         string
+		mail         interface{}
+		expectedFile string // sender of the .eml written, empty when the default transport is used
+	}{
+		{
+			name:         "Company Transport And Sender",
+			mail:         bson.D{{Key: "transport", Value: "file"}, {Key: "from", Value: "billing@acme.test"}},
+			expectedFile: "billing@acme.test",
+		},
+		{
+			name: "No Mail Settings",
+		},
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("CompanyMailSettingsTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				dir := t.TempDir()
+				t.Setenv("MAIL_DIR", dir)
+				t.Setenv("EMAIL_PORT", "")
+				transport.Reset()
+
+				company := bson.D{{Key: "_id", Value: companyID}, {Key: "name", Value: "Acme"}}
+				if tc.mail != nil {
+					company = append(company, bson.E{Key: "settings", Value: bson.D{{Key: "mail", Value: tc.mail}}})
+				}
+				mt.AddMockResponses(
+					mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+						{Key: "_id", Value: primitive.NewObjectID()},
+						{Key: "company_id", Value: companyID.Hex()},
+						{Key: "to", Value: bson.A{"customer@example.com"}},
+						{Key: "subject", Value: "Your Invoice"},
+						{Key: "text_body", Value: "Hello"},
+						{Key: "status", Value: "Sending"},
+						{Key: "max_attempts", Value: 3},
+					}}),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, company),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+					// Nothing else is due
+					mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}),
+				)
+
+				assert.Equal(t, 1, controllers.ProcessMailOutbox(time.Now()))
+
+				files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+				if tc.expectedFile == "" {
+					assert.Empty(t, files)
+					assert.Len(t, transport.Sent(), 1)
+					return
+				}
+				assert.Empty(t, transport.Sent())
+				if assert.Len(t, files, 1) {
+					eml, _ := os.ReadFile(files[0])
+					assert.Contains(t, string(eml), "From: "+tc.expectedFile)
+				}
+			})
+		}
+	})
+}
+
+func TestOutboxScopedToCompany(t *testing.T) {
+	// Setup
+	t.Setenv("JWT_SECRET", "test-secret")
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.Use(middleware.AuthMiddleware())
+	router.GET("/mail/outbox", controllers.ListOutboxMessages)
+	router.GET("/mail/outbox/:id", controllers.GetOutboxMessage)
+	router.POST("/mail/outbox/:id/retry", controllers.RetryOutboxMessage)
+
+	companyID := primitive.NewObjectID().Hex()
+	messageID := primitive.NewObjectID().Hex()
+	companyToken := testToken(t, jwt.MapClaims{"user_id": primitive.NewObjectID().Hex(), "company_id": companyID, "role": "employee"})
+	userToken := testToken(t, jwt.MapClaims{"user_id": primitive.NewObjectID().Hex()})
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "List Filters By Active Company",
+			method:         "GET",
+			path:           "/mail/outbox",
+			token:          companyToken,
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".mail_outbox", mtest.FirstBatch))
+			},
+		},
+		{
+			name:           "List Without Active Company",
+			method:         "GET",
+			path:           "/mail/outbox?company_id=" + companyID,
+			token:          userToken,
+			expectedStatus: http.StatusUnauthorized,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Message Of Another Company",
+			method:         "GET",
+			path:           "/mail/outbox/" + messageID,
+			token:          companyToken,
+			expectedStatus: http.StatusNotFound,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".mail_outbox", mtest.FirstBatch))
+			},
+		},
+		{
+			name:           "Retry Of Another Company",
+			method:         "POST",
+			path:           "/mail/outbox/" + messageID + "/retry",
+			token:          companyToken,
+			expectedStatus: http.StatusNotFound,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+			},
+		},
+		{
+			name:           "Retry Without Active Company",
+			method:         "POST",
+			path:           "/mail/outbox/" + messageID + "/retry",
+			token:          userToken,
+			expectedStatus: http.StatusUnauthorized,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("OutboxScopedToCompanyTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+				mt.ClearEvents()
+
+				req, _ := http.NewRequest(tc.method, tc.path, nil)
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				// Every query that reaches the database names the company
+				if event := mt.GetStartedEvent(); event != nil {
+					var command struct {
+						Filter  bson.M   `bson:"filter"`
+						Updates []bson.M `bson:"updates"`
+					}
+					assert.NoError(t, bson.Unmarshal(event.Command, &command))
+					filter := command.Filter
+					if len(command.Updates) > 0 {
+						filter, _ = command.Updates[0]["q"].(bson.M)
+					}
+					assert.Equal(t, companyID, filter["company_id"])
+				}
+			})
+		}
+	})
+}
@@ -0,0 +1,406 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestCreatePaymentTerms(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.POST("/payment-terms", controllers.CreatePaymentTerms)
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		requestBody    map[string]interface{}
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name: "Net With Early Payment Discount",
+			requestBody: map[string]interface{}{
+				"company_id": companyID.Hex(), "name": "2/10 Net 30", "type": "net",
+				"days": 30, "discount_percent": 2, "discount_days": 10,
+			},
+			expectedStatus: http.StatusCreated,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateSuccessResponse())
+			},
+		},
+		{
+			name: "End Of Month",
+			requestBody: map[string]interface{}{
+				"company_id": companyID.Hex(), "name": "EOM + 15", "type": "end_of_month", "days": 15,
+			},
+			expectedStatus: http.StatusCreated,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateSuccessResponse())
+			},
+		},
+		{
+			name: "Installments",
+			requestBody: map[string]interface{}{
+				"company_id": companyID.Hex(), "name": "Three payments", "type": "installments",
+				"installments": []map[string]interface{}{
+					{"percent": 40, "days": 0}, {"percent": 30, "days": 30}, {"percent": 30, "days": 60},
+				},
+			},
+			expectedStatus: http.StatusCreated,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateSuccessResponse())
+			},
+		},
+		{
+			name: "Installments Not Adding Up",
+			requestBody: map[string]interface{}{
+				"company_id": companyID.Hex(), "name": "Two payments", "type": "installments",
+				"installments": []map[string]interface{}{{"percent": 50, "days": 30}, {"percent": 40, "days": 60}},
+			},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name: "Discount Period Longer Than Terms",
+			requestBody: map[string]interface{}{
+				"company_id": companyID.Hex(), "name": "2/30 Net 30", "type": "net",
+				"days": 30, "discount_percent": 2, "discount_days": 30,
+			},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name: "Due On Receipt With Days",
+			requestBody: map[string]interface{}{
+				"company_id": companyID.Hex(), "name": "Due on receipt", "type": "due_on_receipt", "days": 5,
+			},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name: "Unknown Type",
+			requestBody: map[string]interface{}{
+				"company_id": companyID.Hex(), "name": "Net 30", "type": "whenever", "days": 30,
+			},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Another Company",
+			requestBody:    map[string]interface{}{"company_id": primitive.NewObjectID().Hex(), "name": "Net 30", "type": "net", "days": 30},
+			expectedStatus: http.StatusForbidden,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("CreatePaymentTermsTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				jsonData, _ := json.Marshal(tc.requestBody)
+				req, _ := http.NewRequest("POST", "/payment-terms", bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedStatus == http.StatusCreated {
+					var response models.PaymentTerms
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					assert.False(t, response.ID.IsZero())
+					assert.Equal(t, companyID, response.CompanyID)
+				}
+			})
+		}
+	})
+}
+
+func TestGenerateInvoiceWithPaymentTerms(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.POST("/invoice/generate", controllers.GenerateInvoice)
+
+	customerID := primitive.NewObjectID()
+	termsID := primitive.NewObjectID()
+	company := bson.D{
+		{Key: "_id", Value: companyID},
+		{Key: "settings", Value: bson.D{{Key: "timezone", Value: "Africa/Addis_Ababa"}}},
+	}
+	customer := func(fields ...bson.E) bson.D {
+		return append(bson.D{
+			{Key: "_id", Value: customerID},
+			{Key: "company_id", Value: companyID},
+			{Key: "current_credit_available", Value: 1000.0},
+		}, fields...)
+	}
+	terms := func(fields ...bson.E) bson.D {
+		return append(bson.D{{Key: "_id", Value: termsID}, {Key: "company_id", Value: companyID}}, fields...)
+	}
+	addis, _ := time.LoadLocation("Africa/Addis_Ababa")
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		termsID        string
+		dueDate        *time.Time
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+		check          func(t *testing.T, invoice models.Invoice)
+	}{
+		{
+			name:           "Customer Default Net Terms With Discount",
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, company),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customer(bson.E{Key: "payment_terms_id", Value: termsID})),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".payment_terms", mtest.FirstBatch, terms(
+						bson.E{Key: "name", Value: "2/10 Net 30"}, bson.E{Key: "type", Value: "net"}, bson.E{Key: "days", Value: 30},
+						bson.E{Key: "discount_percent", Value: 2.0}, bson.E{Key: "discount_days", Value: 10},
+					)),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+			check: func(t *testing.T, invoice models.Invoice) {
+				assert.Equal(t, "2/10 Net 30", invoice.Terms)
+				assert.Equal(t, termsID.Hex(), invoice.PaymentTermsID)
+				if assert.NotNil(t, invoice.DueDate) {
+					assert.Equal(t, invoice.Date.AddDate(0, 0, 30).Unix(), invoice.DueDate.Unix())
+				}
+				if assert.NotNil(t, invoice.EarlyPayment) {
+					assert.Equal(t, 2.0, invoice.EarlyPayment.Amount)
+					last := invoice.Date.In(addis).AddDate(0, 0, 10)
+					assert.Equal(t, time.Date(last.Year(), last.Month(), last.Day(), 23, 59, 59, 0, addis).Unix(), invoice.EarlyPayment.Deadline.Unix())
+					assert.False(t, invoice.EarlyPayment.Applied)
+				}
+			},
+		},
+		{
+			name:           "Installments Named On Invoice",
+			termsID:        termsID.Hex(),
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, company),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customer()),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".payment_terms", mtest.FirstBatch, terms(
+						bson.E{Key: "name", Value: "Three payments"}, bson.E{Key: "type", Value: "installments"},
+						bson.E{Key: "installments", Value: bson.A{
+							bson.D{{Key: "percent", Value: 33.33}, {Key: "days", Value: 0}},
+							bson.D{{Key: "percent", Value: 33.33}, {Key: "days", Value: 30}},
+							bson.D{{Key: "percent", Value: 33.34}, {Key: "days", Value: 60}},
+						}},
+					)),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+			check: func(t *testing.T, invoice models.Invoice) {
+				if assert.Len(t, invoice.Installments, 3) {
+					assert.Equal(t, 33.33, invoice.Installments[0].Amount)
+					assert.Equal(t, 33.33, invoice.Installments[1].Amount)
+					assert.Equal(t, 33.34, invoice.Installments[2].Amount)
+					assert.Equal(t, invoice.Date.AddDate(0, 0, 30).Unix(), invoice.Installments[1].DueDate.Unix())
+					if assert.NotNil(t, invoice.DueDate) {
+						assert.Equal(t, invoice.Installments[2].DueDate.Unix(), invoice.DueDate.Unix())
+					}
+				}
+			},
+		},
+		{
+			name:           "End Of Month In Company Timezone",
+			termsID:        termsID.Hex(),
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, company),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customer()),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".payment_terms", mtest.FirstBatch, terms(
+						bson.E{Key: "name", Value: "EOM + 15"}, bson.E{Key: "type", Value: "end_of_month"}, bson.E{Key: "days", Value: 15},
+					)),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+			check: func(t *testing.T, invoice models.Invoice) {
+				issued := invoice.Date.In(addis)
+				if assert.NotNil(t, invoice.DueDate) {
+					due := invoice.DueDate.In(addis)
+					monthEnd := time.Date(issued.Year(), issued.Month()+1, 0, 0, 0, 0, 0, addis)
+					assert.Equal(t, monthEnd.AddDate(0, 0, 15).Format("2006-01-02"), due.Format("2006-01-02"))
+				}
+			},
+		},
+		{
+			name:           "Due Date Overrides Customer Terms",
+			dueDate:        func() *time.Time { d := time.Now().AddDate(0, 0, 3).Truncate(time.Second); return &d }(),
+			expectedStatus: http.StatusOK,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, company),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customer(bson.E{Key: "payment_terms_id", Value: termsID})),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+			check: func(t *testing.T, invoice models.Invoice) {
+				assert.Empty(t, invoice.PaymentTermsID)
+				assert.Nil(t, invoice.EarlyPayment)
+			},
+		},
+		{
+			name:           "Terms And Due Date Both Given",
+			termsID:        termsID.Hex(),
+			dueDate:        func() *time.Time { d := time.Now().AddDate(0, 0, 3); return &d }(),
+			expectedStatus: http.StatusBadRequest,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, company),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customer()),
+				)
+			},
+		},
+		{
+			name:           "Terms Of Another Company",
+			termsID:        termsID.Hex(),
+			expectedStatus: http.StatusBadRequest,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, company),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, customer()),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".payment_terms", mtest.FirstBatch),
+				)
+			},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GenerateInvoiceWithPaymentTermsTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+
+				jsonData, _ := json.Marshal(models.Invoice{
+					CustomerID:      customerID.Hex(),
+					CompanyID:       companyID.Hex(),
+					ReferenceNumber: "2025-001",
+					PaymentType:     "credit",
+					PaymentTermsID:  tc.termsID,
+					DueDate:         tc.dueDate,
+					Items:           []models.InvoiceItem{{ItemName: "Consulting", Quantity: 1, UnitPrice: 100}},
+				})
+				req, _ := http.NewRequest("POST", "/invoice/generate", bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.check != nil {
+					var response struct {
+						Invoice models.Invoice `json:"invoice"`
+					}
+					assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+					tc.check(t, response.Invoice)
+				}
+			})
+		}
+	})
+}
+
+func TestMarkInvoiceAsPaidEarlyPayment(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.PUT("/invoice/mark-as-paid/:id", controllers.MarkInvoiceAsPaid)
+
+	invoiceID := primitive.NewObjectID()
+	deadline := time.Date(2025, 3, 11, 23, 59, 59, 0, time.UTC)
+	invoice := bson.D{
+		{Key: "_id", Value: invoiceID},
+		{Key: "customer_id", Value: primitive.NewObjectID().Hex()},
+		{Key: "company_id", Value: companyID.Hex()},
+		{Key: "status", Value: "Unpaid"},
+		{Key: "amount", Value: 500.0},
+		{Key: "payment_type", Value: "credit"},
+		{Key: "early_payment_discount", Value: bson.D{
+			{Key: "percent", Value: 2.0},
+			{Key: "amount", Value: 10.0},
+			{Key: "deadline", Value: deadline},
+		}},
+	}
+
+	// Test cases
+	testCases := []struct {
+		name             string
+		paymentDate      time.Time
+		expectedDiscount float64
+	}{
+		{name: "Paid In Time", paymentDate: deadline.Add(-time.Hour), expectedDiscount: 10},
+		{name: "Paid Late", paymentDate: deadline.Add(time.Hour), expectedDiscount: 0},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("MarkInvoiceAsPaidEarlyPaymentTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoice),
+					mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+					mtest.CreateSuccessResponse(),
+				)
+
+				jsonData, _ := json.Marshal(models.UpdatePaymentStatusRequest{PaymentDate: tc.paymentDate})
+				req, _ := http.NewRequest("PUT", "/invoice/mark-as-paid/"+invoiceID.Hex(), bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, http.StatusOK, w.Code)
+
+				var response map[string]interface{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, tc.expectedDiscount, response["discount_applied"])
+				assert.Equal(t, 500-tc.expectedDiscount, response["amount_paid"])
+				assert.Equal(t, 0.0, response["balance"])
+			})
+		}
+	})
+}
@@ -0,0 +1,636 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/bisre1921/billing-and-invoice-system/middleware"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestGenerateReport(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID().Hex()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID))
+
+	userID := primitive.NewObjectID().Hex()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Next()
+	})
+	router.POST("/report/generate", controllers.GenerateReport)
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		requestBody    map[string]interface{}
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name: "Valid Payments Report",
+			requestBody: map[string]interface{}{
+				"company_id":  companyID,
+				"title":       "Monthly Payments",
+				"description": "Payments received last month",
+				"type":        "payments",
+				"parameters":  map[string]interface{}{"date_range": "last_month"},
+			},
+			expectedStatus: http.StatusCreated,
+			setupMock: func(mt *mtest.T) {
+				ns := mt.DB.Name() + ".payments"
+				mt.AddMockResponses(
+					// Company settings for the period
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{
+						{Key: "_id", Value: companyID},
+						{Key: "settings", Value: bson.D{{Key: "timezone", Value: "Africa/Addis_Ababa"}}},
+					}),
+					// Payments in range: money received and a credit note
+					mtest.CreateCursorResponse(0, ns, mtest.FirstBatch, bson.D{
+						{Key: "_id", Value: primitive.NewObjectID()},
+						{Key: "company_id", Value: companyID},
+						{Key: "reference_number", Value: "INV-001"},
+						{Key: "amount", Value: 120.0},
+						{Key: "excess", Value: 30.0},
+						{Key: "method", Value: "cash"},
+						{Key: "source", Value: "payment"},
+					}, bson.D{
+						{Key: "_id", Value: primitive.NewObjectID()},
+						{Key: "company_id", Value: companyID},
+						{Key: "reference_number", Value: "INV-002"},
+						{Key: "amount", Value: 40.0},
+						{Key: "method", Value: "credit_note"},
+						{Key: "source", Value: "credit_note"},
+					}),
+					// No previous version
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".reports", mtest.FirstBatch),
+					mtest.CreateSuccessResponse(),
+				)
+			},
+		},
+		{
+			name: "Unknown Report Type",
+			requestBody: map[string]interface{}{
+				"company_id":  companyID,
+				"title":       "Mystery",
+				"description": "Not a real report",
+				"type":        "mystery",
+			},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name: "Invalid Parameter Value",
+			requestBody: map[string]interface{}{
+				"company_id":  companyID,
+				"title":       "Sales",
+				"description": "Sales for an unsupported range",
+				"type":        "sales",
+				"parameters":  map[string]interface{}{"date_range": "last_decade"},
+			},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name: "Unknown Parameter",
+			requestBody: map[string]interface{}{
+				"company_id":  companyID,
+				"title":       "Aging",
+				"description": "Aging with a typo",
+				"type":        "aging",
+				"parameters":  map[string]interface{}{"asof": "2025-01-31"},
+			},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GenerateReportTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				// Setup mock
+				tc.setupMock(mt)
+
+				// Create request
+				jsonData, _ := json.Marshal(tc.requestBody)
+				req, _ := http.NewRequest("POST", "/report/generate", bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+
+				// Perform request
+				router.ServeHTTP(w, req)
+
+				// Check response
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedStatus == http.StatusCreated {
+					var response map[string]interface{}
+					err := json.Unmarshal(w.Body.Bytes(), &response)
+					assert.NoError(t, err)
+					assert.Equal(t, "Report generated successfully", response["message"])
+					assert.Equal(t, float64(1), response["version"])
+
+					data := response["data"].(map[string]interface{})
+					assert.Equal(t, 150.0, data["total"])
+					assert.Equal(t, map[string]interface{}{"cash": 150.0}, data["totals_by_method"])
+					assert.Equal(t, map[string]interface{}{"payment": 150.0, "credit_note": 40.0}, data["totals_by_source"])
+				}
+			})
+		}
+	})
+}
+
+func TestGetSalesReportRecordsCreator(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	userID := primitive.NewObjectID().Hex()
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Set("companyID", companyID.Hex())
+		c.Next()
+	})
+	router.POST("/report/sales", controllers.GetSalesReport)
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("SalesReportCreator", func(mt *mtest.T) {
+		config.DB = mt.DB
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{{Key: "_id", Value: companyID}}),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: primitive.NewObjectID()},
+				{Key: "status", Value: "Paid"},
+				{Key: "amount", Value: 150.0},
+				{Key: "items", Value: bson.A{bson.D{{Key: "item_name", Value: "Consulting"}, {Key: "quantity", Value: 1}, {Key: "subtotal", Value: 150.0}}}},
+			}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		body, _ := json.Marshal(map[string]interface{}{"date_range": "last_7_days"})
+		req, _ := http.NewRequest("POST", "/report/sales", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var createdBy string
+		for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+			if event.CommandName == "insert" {
+				createdBy = event.Command.Lookup("documents").Array().Index(0).Value().Document().Lookup("created_by").StringValue()
+			}
+		}
+		assert.Equal(t, userID, createdBy)
+	})
+}
+
+func TestGetSalesReportNamesCustomersInOneQuery(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.POST("/report/sales", controllers.GetSalesReport)
+
+	alice := primitive.NewObjectID()
+	bob := primitive.NewObjectID()
+	invoiceDoc := func(customerID primitive.ObjectID) bson.D {
+		return bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "customer_id", Value: customerID.Hex()},
+			{Key: "status", Value: "Paid"},
+			{Key: "amount", Value: 150.0},
+			{Key: "items", Value: bson.A{bson.D{{Key: "item_name", Value: "Consulting"}, {Key: "quantity", Value: 1}, {Key: "subtotal", Value: 150.0}}}},
+		}
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("SalesReportCustomerNames", func(mt *mtest.T) {
+		config.DB = mt.DB
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{{Key: "_id", Value: companyID}}),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch, invoiceDoc(alice), invoiceDoc(bob), invoiceDoc(alice)),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: alice}, {Key: "name", Value: "Alice Ltd"}},
+				bson.D{{Key: "_id", Value: bob}, {Key: "name", Value: "Bob, Inc"}}),
+			mtest.CreateSuccessResponse(),
+		)
+
+		body, _ := json.Marshal(map[string]interface{}{"date_range": "last_7_days"})
+		req, _ := http.NewRequest("POST", "/report/sales", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var report []controllers.SalesReportItem
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		var names []string
+		for _, row := range report {
+			names = append(names, row.CustomerName)
+		}
+		assert.Equal(t, []string{"Alice Ltd", "Bob, Inc", "Alice Ltd"}, names)
+
+		// Each customer is asked for once, in a single query
+		var customerFinds int
+		for event := mt.GetStartedEvent(); event != nil; event = mt.GetStartedEvent() {
+			if event.CommandName == "find" && event.Command.Lookup("find").StringValue() == "customers" {
+				customerFinds++
+				assert.Len(t, arrayValues(event.Command.Lookup("filter", "_id", "$in").Array()), 2)
+			}
+		}
+		assert.Equal(t, 1, customerFinds)
+	})
+}
+
+func TestListReportTypes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.GET("/report/types", controllers.ListReportTypes)
+
+	req, _ := http.NewRequest("GET", "/report/types", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var types []map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &types)
+	assert.NoError(t, err)
+
+	var names []string
+	for _, def := range types {
+		names = append(names, def["type"].(string))
+	}
+	assert.ElementsMatch(t, []string{"sales", "aging", "tax_summary", "customer_activity", "item_performance", "payments", "bad_debt"}, names)
+}
+
+func TestCreateReportSchedule(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID().Hex()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID))
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", primitive.NewObjectID().Hex())
+		c.Next()
+	})
+	router.POST("/report/schedule", controllers.CreateReportSchedule)
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		requestBody    map[string]interface{}
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name: "Weekly Sales Summary",
+			requestBody: map[string]interface{}{
+				"company_id":  companyID,
+				"title":       "Weekly Sales",
+				"report_type": "sales",
+				"parameters":  map[string]interface{}{"date_range": "last_7_days"},
+				"cadence":     "0 8 * * 1",
+				"recipients":  []string{"owner@example.com"},
+			},
+			expectedStatus: http.StatusCreated,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateSuccessResponse())
+			},
+		},
+		{
+			name: "Invalid Cadence",
+			requestBody: map[string]interface{}{
+				"company_id":  companyID,
+				"title":       "Weekly Sales",
+				"report_type": "sales",
+				"cadence":     "every monday",
+				"recipients":  []string{"owner@example.com"},
+			},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name: "Invalid Recipient",
+			requestBody: map[string]interface{}{
+				"company_id":  companyID,
+				"title":       "Weekly Sales",
+				"report_type": "sales",
+				"cadence":     "@weekly",
+				"recipients":  []string{"not-an-email"},
+			},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name: "Unsupported Format",
+			requestBody: map[string]interface{}{
+				"company_id":  companyID,
+				"title":       "Aging",
+				"report_type": "aging",
+				"cadence":     "@monthly",
+				"recipients":  []string{"owner@example.com"},
+				"format":      "docx",
+			},
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("CreateReportScheduleTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				// Setup mock
+				tc.setupMock(mt)
+
+				// Create request
+				jsonData, _ := json.Marshal(tc.requestBody)
+				req, _ := http.NewRequest("POST", "/report/schedule", bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+
+				// Perform request
+				router.ServeHTTP(w, req)
+
+				// Check response
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedStatus == http.StatusCreated {
+					var response map[string]interface{}
+					err := json.Unmarshal(w.Body.Bytes(), &response)
+					assert.NoError(t, err)
+					assert.Equal(t, "Report schedule created successfully", response["message"])
+					assert.NotEmpty(t, response["next_run_at"])
+				}
+			})
+		}
+	})
+}
+
+func TestReportScheduleScopedToCompany(t *testing.T) {
+	// Setup
+	t.Setenv("JWT_SECRET", "test-secret")
+	gin.SetMode(gin.TestMode)
+	router := gin.Default()
+	router.Use(middleware.AuthMiddleware())
+	router.PUT("/report/schedule/:id", controllers.UpdateReportSchedule)
+	router.DELETE("/report/schedule/:id", controllers.DeleteReportSchedule)
+	router.POST("/report/schedule/:id/run", controllers.RunReportScheduleNow)
+
+	companyID := primitive.NewObjectID().Hex()
+	scheduleID := primitive.NewObjectID().Hex()
+	companyToken := testToken(t, jwt.MapClaims{"user_id": primitive.NewObjectID().Hex(), "company_id": companyID, "role": "employee"})
+	userToken := testToken(t, jwt.MapClaims{"user_id": primitive.NewObjectID().Hex()})
+	recipients := `{"recipients":["attacker@example.com"]}`
+
+	// Test cases
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		token          string
+		expectedStatus int
+		setupMock      func(mt *mtest.T)
+	}{
+		{
+			name:           "Update Schedule Of Another Company",
+			method:         "PUT",
+			path:           "/report/schedule/" + scheduleID,
+			body:           recipients,
+			token:          companyToken,
+			expectedStatus: http.StatusNotFound,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".report_schedules", mtest.FirstBatch))
+			},
+		},
+		{
+			name:           "Update Without Active Company",
+			method:         "PUT",
+			path:           "/report/schedule/" + scheduleID,
+			body:           recipients,
+			token:          userToken,
+			expectedStatus: http.StatusUnauthorized,
+			setupMock:      func(mt *mtest.T) {},
+		},
+		{
+			name:           "Delete Schedule Of Another Company",
+			method:         "DELETE",
+			path:           "/report/schedule/" + scheduleID,
+			token:          companyToken,
+			expectedStatus: http.StatusNotFound,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}))
+			},
+		},
+		{
+			name:           "Run Schedule Of Another Company",
+			method:         "POST",
+			path:           "/report/schedule/" + scheduleID + "/run",
+			token:          companyToken,
+			expectedStatus: http.StatusNotFound,
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".report_schedules", mtest.FirstBatch))
+			},
+		},
+		{
+			name:           "Run Without Active Company",
+			method:         "POST",
+			path:           "/report/schedule/" + scheduleID + "/run",
+			token:          userToken,
+			expectedStatus: http.StatusUnauthorized,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("ReportScheduleScopedToCompanyTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				tc.setupMock(mt)
+				mt.ClearEvents()
+
+				req, _ := http.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				// The schedule is only looked up within the token's company
+				if event := mt.GetStartedEvent(); event != nil {
+					var command struct {
+						Filter  bson.M   `bson:"filter"`
+						Deletes []bson.M `bson:"deletes"`
+					}
+					assert.NoError(t, bson.Unmarshal(event.Command, &command))
+					filter := command.Filter
+					if len(command.Deletes) > 0 {
+						filter, _ = command.Deletes[0]["q"].(bson.M)
+					}
+					assert.Equal(t, companyID, filter["company_id"])
+				}
+			})
+		}
+	})
+}
+
+func TestDownloadReport(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID.Hex()))
+	router.GET("/report/download/:id", controllers.DownloadReport)
+
+	reportID := primitive.NewObjectID()
+	content := `[{"invoice_id":"inv1","date":"2025-03-01T00:00:00Z","status":"Paid","customer_name":"Abebe, Ltd","items":[` +
+		`{"name":"Laptop","category":"Electronics","quantity":1,"unit_price":900,"subtotal":900},` +
+		`{"name":"Mouse","category":"Electronics","quantity":2,"unit_price":50,"subtotal":100}],"total_amount":1000}]`
+	reportDoc := bson.D{
+		{Key: "_id", Value: reportID},
+		{Key: "company_id", Value: companyID.Hex()},
+		{Key: "title", Value: "Sales Report"},
+		{Key: "type", Value: "sales"},
+		{Key: "version", Value: 1},
+		{Key: "content", Value: content},
+	}
+
+	// Test cases
+	testCases := []struct {
+		name                string
+		format              string
+		expectedStatus      int
+		expectedContentType string
+		setupMock           func(mt *mtest.T)
+		check               func(t *testing.T, body []byte)
+	}{
+		{
+			name:                "Flat CSV Has One Row Per Line Item",
+			format:              "flat_csv",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "text/csv",
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".reports", mtest.FirstBatch, reportDoc))
+			},
+			check: func(t *testing.T, body []byte) {
+				records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+				assert.NoError(t, err)
+				assert.Len(t, records, 3)
+				assert.Equal(t, "inv1", records[1][0])
+				assert.Equal(t, "inv1", records[2][0])
+				assert.Equal(t, "Abebe, Ltd", records[2][3])
+			},
+		},
+		{
+			name:                "XLSX With Typed Cells And Summary",
+			format:              "xlsx",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".reports", mtest.FirstBatch, reportDoc))
+			},
+			check: func(t *testing.T, body []byte) {
+				f, err := excelize.OpenReader(bytes.NewReader(body))
+				assert.NoError(t, err)
+				defer f.Close()
+				assert.Equal(t, []string{"Sales", "Summary"}, f.GetSheetList())
+
+				rows, err := f.GetRows("Sales")
+				assert.NoError(t, err)
+				assert.Len(t, rows, 3)
+
+				cellType, err := f.GetCellType("Sales", "I2")
+				assert.NoError(t, err)
+				assert.NotEqual(t, excelize.CellTypeSharedString, cellType)
+			},
+		},
+		{
+			name:                "PDF",
+			format:              "pdf",
+			expectedStatus:      http.StatusOK,
+			expectedContentType: "application/pdf",
+			setupMock: func(mt *mtest.T) {
+				mt.AddMockResponses(
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".reports", mtest.FirstBatch, reportDoc),
+					mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{
+						{Key: "_id", Value: companyID},
+						{Key: "name", Value: "Test Tech Solutions"},
+					}),
+				)
+			},
+			check: func(t *testing.T, body []byte) {
+				assert.True(t, bytes.HasPrefix(body, []byte("%PDF")))
+				// Text is written with the embedded Unicode font, not a core font
+				assert.Contains(t, string(body), "/BaseFont /utf8"+controllers.DefaultPDFFont)
+			},
+		},
+		{
+			name:           "Invalid Format",
+			format:         "docx",
+			expectedStatus: http.StatusBadRequest,
+			setupMock:      func(mt *mtest.T) {},
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("DownloadReportTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				// Setup mock
+				tc.setupMock(mt)
+
+				req, _ := http.NewRequest("GET", "/report/download/"+reportID.Hex()+"?format="+tc.format, nil)
+				w := httptest.NewRecorder()
+
+				// Perform request
+				router.ServeHTTP(w, req)
+
+				// Check response
+				assert.Equal(t, tc.expectedStatus, w.Code)
+				if tc.expectedStatus == http.StatusOK {
+					assert.Equal(t, tc.expectedContentType, w.Header().Get("Content-Type"))
+					tc.check(t, w.Body.Bytes())
+				}
+			})
+		}
+	})
+}
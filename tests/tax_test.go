@@ -0,0 +1,215 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bisre1921/billing-and-invoice-system/config"
+	"github.com/bisre1921/billing-and-invoice-system/controllers"
+	"github.com/bisre1921/billing-and-invoice-system/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestGenerateInvoiceWithTax(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID().Hex()
+	router := gin.Default()
+	router.Use(asCompanyOwner(companyID))
+	router.POST("/invoice/generate", controllers.GenerateInvoice)
+
+	newInvoice := func(items ...models.InvoiceItem) models.Invoice {
+		return models.Invoice{
+			CustomerID:      primitive.NewObjectID().Hex(),
+			CompanyID:       companyID,
+			ReferenceNumber: "INV-2025-010",
+			PaymentType:     "cash",
+			WithholdingRate: 2,
+			Items:           items,
+		}
+	}
+
+	// Test cases
+	testCases := []struct {
+		name             string
+		requestBody      models.Invoice
+		expectedStatus   int
+		expectedSubtotal float64
+		expectedTax      float64
+	}{
+		{
+			name: "Standard And Exempt Lines",
+			requestBody: newInvoice(
+				models.InvoiceItem{ItemName: "Consulting", Quantity: 2, UnitPrice: 100, TaxRate: 15},
+				models.InvoiceItem{ItemName: "Bread", Quantity: 1, UnitPrice: 50, TaxCategory: "exempt"},
+			),
+			expectedStatus:   http.StatusOK,
+			expectedSubtotal: 250,
+			expectedTax:      30,
+		},
+		{
+			name: "Rate On Exempt Line",
+			requestBody: newInvoice(
+				models.InvoiceItem{ItemName: "Bread", Quantity: 1, UnitPrice: 50, TaxRate: 15, TaxCategory: "exempt"},
+			),
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Unknown Tax Category",
+			requestBody: newInvoice(
+				models.InvoiceItem{ItemName: "Bread", Quantity: 1, UnitPrice: 50, TaxCategory: "luxury"},
+			),
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	// Run tests using MongoDB mock
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("GenerateInvoiceWithTaxTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				if tc.expectedStatus == http.StatusOK {
+					mt.AddMockResponses(
+						mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{{Key: "_id", Value: primitive.NewObjectID()}}),
+						mtest.CreateSuccessResponse(),
+					)
+				}
+
+				jsonData, _ := json.Marshal(tc.requestBody)
+				req, _ := http.NewRequest("POST", "/invoice/generate", bytes.NewBuffer(jsonData))
+				req.Header.Set("Content-Type", "application/json")
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+
+				assert.Equal(t, tc.expectedStatus, w.Code)
+
+				if tc.expectedStatus == http.StatusOK {
+					var response struct {
+						Invoice models.Invoice `json:"invoice"`
+					}
+					err := json.Unmarshal(w.Body.Bytes(), &response)
+					assert.NoError(t, err)
+					assert.Equal(t, tc.expectedSubtotal, response.Invoice.Subtotal)
+					assert.Equal(t, tc.expectedTax, response.Invoice.TaxAmount)
+					assert.Equal(t, tc.expectedSubtotal+tc.expectedTax, response.Invoice.Amount)
+					assert.Equal(t, 5.0, response.Invoice.WithholdingAmount)
+					assert.Equal(t, "standard", response.Invoice.Items[0].TaxCategory)
+				}
+			})
+		}
+	})
+}
+
+func TestTaxSummaryReport(t *testing.T) {
+	// Setup
+	gin.SetMode(gin.TestMode)
+	companyID := primitive.NewObjectID().Hex()
+	router := gin.Default()
+	router.Use(func(c *gin.Context) {
+		c.Set("userID", primitive.NewObjectID().Hex())
+		c.Next()
+	})
+	router.Use(asCompanyOwner(companyID))
+	router.POST("/report/generate", controllers.GenerateReport)
+
+	customerID := primitive.NewObjectID()
+	date := time.Date(2025, 2, 10, 0, 0, 0, 0, time.UTC)
+
+	invoice := func(reference, documentType string, rate, net, tax float64) bson.D {
+		return bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "company_id", Value: companyID},
+			{Key: "customer_id", Value: customerID.Hex()},
+			{Key: "reference_number", Value: reference},
+			{Key: "document_type", Value: documentType},
+			{Key: "date", Value: date},
+			{Key: "amount", Value: net + tax},
+			{Key: "items", Value: bson.A{bson.D{
+				{Key: "item_name", Value: "Consulting"},
+				{Key: "subtotal", Value: net},
+				{Key: "tax_rate", Value: rate},
+				{Key: "tax_category", Value: "standard"},
+				{Key: "tax_amount", Value: tax},
+			}}},
+		}
+	}
+
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("TaxSummaryReportTests", func(mt *mtest.T) {
+		config.DB = mt.DB
+
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{{Key: "_id", Value: companyID}}),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".invoices", mtest.FirstBatch,
+				invoice("INV-001", "invoice", 15, 1000, 150),
+				invoice("INV-002", "invoice", 5, 400, 20),
+				invoice("CN-001", "credit_note", 15, 200, 30),
+			),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".customers", mtest.FirstBatch, bson.D{
+				{Key: "_id", Value: customerID},
+				{Key: "name", Value: "Test Customer"},
+				{Key: "tin", Value: "0012345678"},
+			}),
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".reports", mtest.FirstBatch),
+			mtest.CreateSuccessResponse(),
+		)
+
+		jsonData, _ := json.Marshal(map[string]interface{}{
+			"company_id":  companyID,
+			"title":       "VAT February",
+			"description": "VAT declaration",
+			"type":        "tax_summary",
+			"parameters": map[string]interface{}{
+				"date_range":   "custom",
+				"custom_start": "2025-02-01",
+				"custom_end":   "2025-02-28",
+			},
+		})
+		req, _ := http.NewRequest("POST", "/report/generate", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var response struct {
+			Data controllers.TaxSummaryReport `json:"data"`
+		}
+		err := json.Unmarshal(w.Body.Bytes(), &response)
+		assert.NoError(t, err)
+		if assert.Len(t, response.Data.Periods, 1) {
+			period := response.Data.Periods[0]
+			assert.Equal(t, "2025-02", period.Period)
+			assert.Equal(t, 1400.0, period.TaxableSales)
+			assert.Equal(t, 170.0, period.TaxCollected)
+			assert.Equal(t, 30.0, period.CreditNoteTax)
+			assert.Equal(t, 140.0, period.NetTaxPayable)
+
+			// The rate lines add up to the period totals
+			var taxable, tax float64
+			for _, rate := range period.ByRate {
+				taxable += rate.TaxableAmount
+				tax += rate.TaxAmount
+			}
+			assert.Len(t, period.ByRate, 2)
+			assert.InDelta(t, period.TaxableSales, taxable, 0.001)
+			assert.InDelta(t, period.TaxCollected, tax, 0.001)
+		}
+		if assert.Len(t, response.Data.Customers, 1) {
+			assert.Equal(t, "0012345678", response.Data.Customers[0].TIN)
+			assert.Equal(t, 140.0, response.Data.Customers[0].TaxAmount)
+		}
+	})
+}